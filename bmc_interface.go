@@ -1,18 +1,143 @@
 package main
 
-// BMCClient interface defines common operations for all BMC types
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// BMCClient interface defines common operations for all BMC types. Every operation stops
+// when ctx is canceled or its deadline passes.
 type BMCClient interface {
-	GetSystemInfo() (*SystemInfo, error)
-	SetPowerState(state PowerState) error
-	GetVirtualMedia() ([]VirtualMediaInfo, error)
-	MountVirtualMedia(imageURL string) error
-	UnmountVirtualMedia() error
+	GetSystemInfo(ctx context.Context) (*SystemInfo, error)
+	SetPowerState(ctx context.Context, state PowerState) error
+	GetVirtualMedia(ctx context.Context) ([]VirtualMediaInfo, error)
+	MountVirtualMedia(ctx context.Context, imageURL string) error
+	UnmountVirtualMedia(ctx context.Context) error
+	SetBootOverride(ctx context.Context, target BootTarget, persistent bool) error
 }
 
 // BMCType represents the type of BMC hardware
 type BMCType string
 
 const (
-	BMCTypeILO   BMCType = "ilo"
-	BMCTypeIDRAC BMCType = "idrac"
+	BMCTypeILO        BMCType = "ilo"
+	BMCTypeIDRAC      BMCType = "idrac"
+	BMCTypeSupermicro BMCType = "supermicro"
+	BMCTypeXCC        BMCType = "xcc"
+	BMCTypeOpenBMC    BMCType = "openbmc"
+	BMCTypeIPMI       BMCType = "ipmi"
 )
+
+// EventLogReader is implemented by BMC clients that can read the system event log
+type EventLogReader interface {
+	GetEventLog(ctx context.Context) ([]EventLogEntry, error)
+}
+
+// SensorReader is implemented by BMC clients that can read sensor values
+type SensorReader interface {
+	GetSensors(ctx context.Context) ([]SensorReading, error)
+}
+
+// RedfishRequester is implemented by BMC clients that can send raw Redfish requests using
+// their configured host, credentials and session handling
+type RedfishRequester interface {
+	RedfishRequest(ctx context.Context, method, endpoint string, body []byte, header http.Header) (*http.Response, error)
+}
+
+// ScreenCapturer is implemented by BMC clients that can capture the host's screen as a PNG
+// image without opening the remote console
+type ScreenCapturer interface {
+	CaptureScreen(ctx context.Context) ([]byte, error)
+}
+
+// EventLogEntry represents a system event log entry
+type EventLogEntry struct {
+	ID       string    `json:"id" yaml:"id"`
+	Created  time.Time `json:"created" yaml:"created"`
+	Severity string    `json:"severity" yaml:"severity"`
+	Message  string    `json:"message" yaml:"message"`
+}
+
+// SensorReading represents the current value of a sensor
+type SensorReading struct {
+	Name      string  `json:"name" yaml:"name"`
+	Value     float64 `json:"value" yaml:"value"`
+	Units     string  `json:"units" yaml:"units"`
+	Status    string  `json:"status" yaml:"status"`
+	Available bool    `json:"available" yaml:"available"`
+}
+
+// closeClient releases any session the client holds on the BMC. Clients that authenticate
+// per request have nothing to release and do not implement io.Closer.
+func closeClient(client BMCClient) {
+	if closer, ok := client.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+// redfishResetType returns the ComputerSystem.Reset type for a power state. PowerCycle is
+// optional in Redfish and iLO, XCC and many Supermicro boards reject it, so a power cycle
+// uses it only when the system lists it among its allowable reset types, and ForceRestart
+// otherwise.
+func redfishResetType(ctx context.Context, requester RedfishRequester, system string, state PowerState) (string, error) {
+	if state != PowerStateCycle {
+		return string(state), nil
+	}
+	var resource struct {
+		Actions struct {
+			Reset struct {
+				AllowableValues []string `json:"ResetType@Redfish.AllowableValues"`
+			} `json:"#ComputerSystem.Reset"`
+		} `json:"Actions"`
+	}
+	if err := redfishJSON(ctx, requester, http.MethodGet, system, nil, &resource); err != nil {
+		return "", err
+	}
+	if containsString(resource.Actions.Reset.AllowableValues, string(PowerStateCycle)) {
+		return string(PowerStateCycle), nil
+	}
+	return "ForceRestart", nil
+}
+
+// BootTarget represents a Redfish boot source override target
+type BootTarget string
+
+const (
+	BootTargetNone BootTarget = "None"
+	BootTargetPXE  BootTarget = "Pxe"
+	BootTargetCD   BootTarget = "Cd"
+	BootTargetHDD  BootTarget = "Hdd"
+	BootTargetBIOS BootTarget = "BiosSetup"
+	BootTargetUSB  BootTarget = "Usb"
+)
+
+// BootOverrideRequest represents a boot source override change request
+type BootOverrideRequest struct {
+	Boot BootOverride `json:"Boot"`
+}
+
+// BootOverride holds the boot source override properties of a ComputerSystem
+type BootOverride struct {
+	BootSourceOverrideTarget  string `json:"BootSourceOverrideTarget"`
+	BootSourceOverrideEnabled string `json:"BootSourceOverrideEnabled"`
+}
+
+// newBootOverrideRequest builds the Redfish boot override body for a target
+func newBootOverrideRequest(target BootTarget, persistent bool) BootOverrideRequest {
+	enabled := "Once"
+	if persistent {
+		enabled = "Continuous"
+	}
+	if target == BootTargetNone {
+		enabled = "Disabled"
+	}
+
+	return BootOverrideRequest{
+		Boot: BootOverride{
+			BootSourceOverrideTarget:  string(target),
+			BootSourceOverrideEnabled: enabled,
+		},
+	}
+}
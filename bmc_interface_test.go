@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"testing"
 )
 
@@ -34,6 +35,60 @@ func TestBMCInterface_IDRACClient(t *testing.T) {
 	_ = client // This assignment proves the interface is implemented
 }
 
+func TestBMCInterface_SupermicroClient(t *testing.T) {
+	// Test that SupermicroClient implements BMCClient interface
+	var client BMCClient
+	supermicroClient := &SupermicroClient{
+		baseURL:  "https://test.example.com",
+		username: "ADMIN",
+		password: "password",
+	}
+
+	// This should compile without errors if SupermicroClient implements BMCClient
+	client = supermicroClient
+	_ = client
+}
+
+func TestBMCInterface_XCCClient(t *testing.T) {
+	// Test that XCCClient implements BMCClient interface
+	var client BMCClient
+	xccClient := &XCCClient{
+		baseURL:  "https://test.example.com",
+		username: "USERID",
+		password: "PASSW0RD",
+	}
+
+	// This should compile without errors if XCCClient implements BMCClient
+	client = xccClient
+	_ = client
+}
+
+func TestBMCInterface_OpenBMCClient(t *testing.T) {
+	// Test that OpenBMCClient implements BMCClient interface
+	var client BMCClient
+	openBMCClient := &OpenBMCClient{
+		baseURL:  "https://test.example.com",
+		username: "root",
+		password: "0penBmc",
+	}
+
+	// This should compile without errors if OpenBMCClient implements BMCClient
+	client = openBMCClient
+	_ = client
+}
+
+func TestBMCInterface_IPMIClient(t *testing.T) {
+	// Test that IPMIClient implements BMCClient and the optional read interfaces
+	var client BMCClient = NewIPMIClient("test.example.com", "admin", "password", 623)
+
+	if _, ok := client.(EventLogReader); !ok {
+		t.Error("Expected IPMIClient to implement EventLogReader")
+	}
+	if _, ok := client.(SensorReader); !ok {
+		t.Error("Expected IPMIClient to implement SensorReader")
+	}
+}
+
 func TestBMCTypes(t *testing.T) {
 	// Test BMC type constants
 	if BMCTypeILO != "ilo" {
@@ -42,6 +97,18 @@ func TestBMCTypes(t *testing.T) {
 	if BMCTypeIDRAC != "idrac" {
 		t.Errorf("Expected BMCTypeIDRAC to be 'idrac', got: %s", BMCTypeIDRAC)
 	}
+	if BMCTypeSupermicro != "supermicro" {
+		t.Errorf("Expected BMCTypeSupermicro to be 'supermicro', got: %s", BMCTypeSupermicro)
+	}
+	if BMCTypeXCC != "xcc" {
+		t.Errorf("Expected BMCTypeXCC to be 'xcc', got: %s", BMCTypeXCC)
+	}
+	if BMCTypeOpenBMC != "openbmc" {
+		t.Errorf("Expected BMCTypeOpenBMC to be 'openbmc', got: %s", BMCTypeOpenBMC)
+	}
+	if BMCTypeIPMI != "ipmi" {
+		t.Errorf("Expected BMCTypeIPMI to be 'ipmi', got: %s", BMCTypeIPMI)
+	}
 }
 
 func TestPowerStates(t *testing.T) {
@@ -53,3 +120,40 @@ func TestPowerStates(t *testing.T) {
 		t.Errorf("Expected PowerStateOff to be 'ForceOff', got: %s", PowerStateOff)
 	}
 }
+
+func TestRedfishResetType(t *testing.T) {
+	tests := []struct {
+		profile string
+		system  string
+		cycle   string
+	}{
+		{"ilo5", "/redfish/v1/Systems/1", "ForceRestart"},
+		{"idrac9", "/redfish/v1/Systems/System.Embedded.1", "PowerCycle"},
+	}
+	for _, test := range tests {
+		_, server := newMockBMCServer(t, test.profile)
+		client := &ILOClient{baseURL: server.URL, username: "admin", password: "password", httpClient: server.Client()}
+
+		resetType, err := redfishResetType(context.Background(), client, test.system, PowerStateCycle)
+		if err != nil || resetType != test.cycle {
+			t.Errorf("%s: expected %s, got %q (%v)", test.profile, test.cycle, resetType, err)
+		}
+		if resetType, _ := redfishResetType(context.Background(), client, test.system, PowerStateOff); resetType != "ForceOff" {
+			t.Errorf("%s: expected ForceOff, got %q", test.profile, resetType)
+		}
+	}
+}
+
+func TestNewBootOverrideRequest(t *testing.T) {
+	// One-time override by default
+	req := newBootOverrideRequest(BootTargetCD, false)
+	if req.Boot.BootSourceOverrideEnabled != "Once" {
+		t.Errorf("Expected 'Once', got: %s", req.Boot.BootSourceOverrideEnabled)
+	}
+
+	// Clearing the override disables it regardless of persistence
+	req = newBootOverrideRequest(BootTargetNone, true)
+	if req.Boot.BootSourceOverrideEnabled != "Disabled" {
+		t.Errorf("Expected 'Disabled', got: %s", req.Boot.BootSourceOverrideEnabled)
+	}
+}
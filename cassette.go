@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// redactedValue replaces credentials and tokens in recorded traffic
+const redactedValue = "REDACTED"
+
+// redactedHeaders are never written to a cassette
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "X-Auth-Token", "Cookie", "Set-Cookie"}
+
+// cassetteInteraction is one recorded request and response
+type cassetteInteraction struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+// cassetteRequest is the recorded request. URL holds the path and query only, so a
+// cassette can be replayed against any host.
+type cassetteRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"headers,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// cassetteResponse is the recorded response. Bodies that are not valid UTF-8 are stored
+// base64 encoded.
+type cassetteResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 string      `json:"body_base64,omitempty"`
+}
+
+// recordingTransport writes every exchange to a cassette directory, one numbered JSON file
+// per interaction, continuing the numbering of any cassette already in the directory
+type recordingTransport struct {
+	dir  string
+	next http.RoundTripper
+
+	mu  sync.Mutex
+	seq int
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := cassetteInteraction{
+		Request: cassetteRequest{
+			Method: req.Method,
+			URL:    req.URL.RequestURI(),
+			Header: redactHeader(req.Header),
+			Body:   string(redactBody(reqBody)),
+		},
+		Response: cassetteResponse{
+			StatusCode: resp.StatusCode,
+			Header:     redactHeader(resp.Header),
+		},
+	}
+	if utf8.Valid(respBody) {
+		interaction.Response.Body = string(redactBody(respBody))
+	} else {
+		interaction.Response.BodyBase64 = base64.StdEncoding.EncodeToString(respBody)
+	}
+
+	if err := t.write(interaction); err != nil {
+		return nil, fmt.Errorf("error recording interaction: %w", err)
+	}
+
+	return resp, nil
+}
+
+func (t *recordingTransport) write(interaction cassetteInteraction) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.seq == 0 {
+		if err := os.MkdirAll(t.dir, 0700); err != nil {
+			return err
+		}
+		files, err := cassetteFiles(t.dir)
+		if err != nil {
+			return err
+		}
+		if len(files) > 0 {
+			t.seq, _ = strconv.Atoi(strings.SplitN(filepath.Base(files[len(files)-1]), "-", 2)[0])
+		}
+	}
+	t.seq++
+
+	data, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%04d-%s.json", t.seq, strings.ToLower(interaction.Request.Method))
+	return os.WriteFile(filepath.Join(t.dir, name), data, 0600)
+}
+
+// replayTransport answers requests from a cassette without touching the network. Each
+// recorded interaction is used once, in recording order, so repeated requests for the
+// same resource see the responses the BMC gave at the time.
+type replayTransport struct {
+	mu           sync.Mutex
+	dir          string
+	interactions []cassetteInteraction
+	used         []bool
+	err          error
+}
+
+// newReplayTransport loads the cassette in dir. Load errors are reported by every request.
+func newReplayTransport(dir string) *replayTransport {
+	t := &replayTransport{dir: dir}
+
+	files, err := cassetteFiles(dir)
+	if err == nil && len(files) == 0 {
+		err = fmt.Errorf("no recorded interactions found")
+	}
+	for _, file := range files {
+		data, readErr := os.ReadFile(file)
+		if readErr != nil {
+			err = readErr
+			break
+		}
+		var interaction cassetteInteraction
+		if decodeErr := json.Unmarshal(data, &interaction); decodeErr != nil {
+			err = fmt.Errorf("error decoding %s: %w", file, decodeErr)
+			break
+		}
+		t.interactions = append(t.interactions, interaction)
+	}
+	if err != nil {
+		t.err = fmt.Errorf("error loading cassette %s: %w", dir, err)
+	}
+	t.used = make([]bool, len(t.interactions))
+
+	return t
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_, _ = io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, interaction := range t.interactions {
+		if t.used[i] || interaction.Request.Method != req.Method || interaction.Request.URL != req.URL.RequestURI() {
+			continue
+		}
+		t.used[i] = true
+
+		body := []byte(interaction.Response.Body)
+		if interaction.Response.BodyBase64 != "" {
+			decoded, err := base64.StdEncoding.DecodeString(interaction.Response.BodyBase64)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding recorded body: %w", err)
+			}
+			body = decoded
+		}
+
+		header := interaction.Response.Header
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no recorded response for %s %s in %s", req.Method, req.URL.RequestURI(), t.dir)
+}
+
+// cassetteFiles returns the interaction files in dir in recording order
+func cassetteFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "[0-9]*-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// redactHeader copies a header, replacing credential and token values
+func redactHeader(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	redacted := header.Clone()
+	for _, name := range redactedHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, redactedValue)
+		}
+	}
+	return redacted
+}
+
+// redactBody replaces password, token and secret fields in a JSON body, as well as
+// passwords embedded in URLs. Non-JSON bodies are returned unchanged.
+func redactBody(body []byte) []byte {
+	var value interface{}
+	if len(body) == 0 || json.Unmarshal(body, &value) != nil {
+		return body
+	}
+
+	if !redactValue(&value) {
+		return body
+	}
+	redacted, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// redactValue redacts a decoded JSON value in place and reports whether anything changed
+func redactValue(value *interface{}) bool {
+	changed := false
+
+	switch v := (*value).(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if isSecretKey(key) {
+				if item != redactedValue {
+					v[key] = redactedValue
+					changed = true
+				}
+				continue
+			}
+			if redactValue(&item) {
+				v[key] = item
+				changed = true
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			if redactValue(&v[i]) {
+				changed = true
+			}
+		}
+	case string:
+		if u, err := url.Parse(v); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), redactedValue)
+				*value = u.String()
+				changed = true
+			}
+		}
+	}
+
+	return changed
+}
+
+// isSecretKey reports whether a JSON field name holds a credential
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	return strings.Contains(key, "password") || strings.Contains(key, "token") || strings.Contains(key, "secret")
+}
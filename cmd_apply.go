@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var manifestFile string
+
+const manifestHelp = `The manifest declares the desired state; settings it leaves out are not managed:
+
+  power: on
+  reboot: true                 # restart to apply BIOS settings and the boot override
+  boot:
+    target: cd                 # none, pxe, cd, hdd, bios or usb
+    persistent: false          # a one-time override is used up by the next boot
+  virtual_media:
+    image: http://10.0.0.5/images/ubuntu-24.04.iso   # "" ejects
+  bios:
+    BootMode: Uefi
+  ntp:
+    enabled: true
+    servers: [10.0.0.1, 10.0.0.2]
+  dns:
+    servers: [10.0.0.53]
+  users:
+    - username: ops
+      role: Operator
+      password_env: OPS_PASSWORD   # only used to create the account
+    - username: olduser
+      absent: true`
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show the changes needed to reach a server manifest",
+	Long: `Compare a manifest with the current state of the server and show the changes 'apply'
+would make, in the order it would make them, without changing anything.
+
+` + manifestHelp + `
+
+Example:
+  bmc-cli plan -f server.yaml
+  bmc-cli --host r740-01 plan -f server.yaml --output json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plan, cleanup, err := planFromManifest(cmd)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		if printed, err := printStructured(plan); printed {
+			return err
+		}
+		printPlan(plan)
+		return nil
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Bring a server to the state declared in a manifest",
+	Long: `Make only the changes needed to bring the server to the state declared in a manifest,
+in a safe order: accounts and BMC network settings first, then virtual media is mounted
+before the boot override that uses it, BIOS settings are staged before the power change or
+restart that applies them. Applying the same manifest again changes nothing.
+
+` + manifestHelp + `
+
+Example:
+  bmc-cli plan -f server.yaml
+  bmc-cli apply -f server.yaml`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plan, cleanup, err := planFromManifest(cmd)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		if len(plan.Changes) == 0 {
+			fmt.Println("No changes. The server matches the manifest.")
+			return nil
+		}
+
+		applied := 0
+		err = applyPlan(cmd.Context(), plan, func(change planChange) {
+			fmt.Printf("Applying %s\n", formatPlanChange(change))
+			applied++
+		})
+		if err != nil {
+			return fmt.Errorf("apply stopped after %d of %d changes: %w", applied-1, len(plan.Changes), err)
+		}
+
+		for _, note := range plan.Notes {
+			fmt.Printf("Note: %s\n", note)
+		}
+		fmt.Printf("Apply complete: %d changes\n", len(plan.Changes))
+		return nil
+	},
+}
+
+// planFromManifest loads the --file manifest and plans it against the configured BMC
+func planFromManifest(cmd *cobra.Command) (*serverPlan, func(), error) {
+	if manifestFile == "" {
+		return nil, nil, fmt.Errorf("--file is required")
+	}
+	manifest, err := loadManifest(manifestFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := NewBMCClient()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create BMC client: %w", err)
+	}
+
+	plan, err := planServer(cmd.Context(), client, config.BMCType, manifest)
+	if err != nil {
+		closeClient(client)
+		return nil, nil, err
+	}
+	return plan, func() { closeClient(client) }, nil
+}
+
+// printPlan prints the changes of a plan, one per line, in the order they are applied
+func printPlan(plan *serverPlan) {
+	for _, change := range plan.Changes {
+		fmt.Printf("  %s\n", formatPlanChange(change))
+	}
+	for _, note := range plan.Notes {
+		fmt.Printf("Note: %s\n", note)
+	}
+	if len(plan.Changes) == 0 {
+		fmt.Println("No changes. The server matches the manifest.")
+		return
+	}
+	fmt.Printf("Plan: %d changes\n", len(plan.Changes))
+}
+
+// formatPlanChange formats a change as "+ create", "~ update" or "- delete"
+func formatPlanChange(change planChange) string {
+	switch change.Action {
+	case planCreate:
+		return fmt.Sprintf("+ %s: %s", change.Resource, change.To)
+	case planDelete:
+		return fmt.Sprintf("- %s: %s", change.Resource, change.From)
+	}
+	from, to := change.From, change.To
+	if from == "" {
+		from = "(none)"
+	}
+	if to == "" {
+		to = "(none)"
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", change.Resource, from, to)
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd, applyCmd)
+	for _, cmd := range []*cobra.Command{planCmd, applyCmd} {
+		cmd.Flags().StringVarP(&manifestFile, "file", "f", "", "server manifest (YAML), or - for standard input")
+	}
+}
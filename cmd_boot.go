@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var bootPersistent bool
+
+// bootTargets maps the command line names to Redfish boot source override targets
+var bootTargets = map[string]BootTarget{
+	"none": BootTargetNone,
+	"pxe":  BootTargetPXE,
+	"cd":   BootTargetCD,
+	"hdd":  BootTargetHDD,
+	"bios": BootTargetBIOS,
+	"usb":  BootTargetUSB,
+}
+
+var bootCmd = &cobra.Command{
+	Use:   "boot",
+	Short: "Boot device management commands",
+	Long:  `Commands for overriding the device the server boots from`,
+}
+
+var bootSetCmd = &cobra.Command{
+	Use:   "set [none|pxe|cd|hdd|bios|usb]",
+	Short: "Set the boot device override",
+	Long: `Set the boot source override for the next boot. Use --persistent to keep the
+override for every boot, or 'none' to clear it.
+
+Example:
+  bmc-cli boot set cd
+  bmc-cli boot set pxe --persistent`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, ok := bootTargets[strings.ToLower(args[0])]
+		if !ok {
+			return fmt.Errorf("unknown boot target: %s (valid targets: none, pxe, cd, hdd, bios, usb)", args[0])
+		}
+
+		client, err := NewBMCClient()
+		if err != nil {
+			return fmt.Errorf("failed to create BMC client: %w", err)
+		}
+		defer closeClient(client)
+
+		fmt.Printf("Setting boot override to %s...\n", target)
+		if err := client.SetBootOverride(cmd.Context(), target, bootPersistent); err != nil {
+			return fmt.Errorf("failed to set boot override: %w", err)
+		}
+
+		fmt.Println("Boot override set successfully")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bootCmd)
+	bootCmd.AddCommand(bootSetCmd)
+	bootSetCmd.Flags().BoolVar(&bootPersistent, "persistent", false, "keep the override for every boot instead of only the next one")
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var browseCmd = &cobra.Command{
+	Use:   "browse [path]",
+	Short: "Interactively browse the Redfish resource tree",
+	Long: `Browse the Redfish API of the configured BMC, starting at /redfish/v1 (or the given
+path). Each resource shows its properties, the @odata.id links it contains and its Actions.
+Follow a link by typing its number; type 'h' for the list of commands.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := NewBMCClient()
+		if err != nil {
+			return fmt.Errorf("failed to create BMC client: %w", err)
+		}
+		defer closeClient(client)
+
+		requester, ok := client.(RedfishRequester)
+		if !ok {
+			return fmt.Errorf("browsing is not supported for BMC type %s", config.BMCType)
+		}
+
+		start := "/redfish/v1"
+		if len(args) == 1 {
+			start = redfishPath(args[0])
+		}
+
+		browser := &redfishBrowser{
+			requester: requester,
+			in:        bufio.NewScanner(os.Stdin),
+			out:       os.Stdout,
+			clipboard: isTerminal(os.Stdout),
+		}
+		return browser.run(cmd.Context(), start)
+	},
+}
+
+// browserLink is an @odata.id reference found in a resource
+type browserLink struct {
+	Label string
+	Path  string
+}
+
+// browserAction is an action advertised in a resource's Actions object
+type browserAction struct {
+	Name       string
+	Target     string
+	Parameters map[string][]string
+}
+
+// redfishBrowser is a line-oriented Redfish resource browser
+type redfishBrowser struct {
+	requester RedfishRequester
+	in        *bufio.Scanner
+	out       io.Writer
+	clipboard bool
+
+	history  []string
+	path     string
+	raw      []byte
+	resource map[string]interface{}
+	links    []browserLink
+	actions  []browserAction
+}
+
+const browserHelp = `Commands:
+  <n>         follow link n
+  b           go back
+  g <path>    go to a path (relative to /redfish/v1 unless it starts with /)
+  j           show the raw JSON of the resource
+  c           copy a 'redfish get' command for this resource
+  c a<n>      copy a 'redfish post' command for action n
+  h           show this help
+  q           quit`
+
+func (b *redfishBrowser) run(ctx context.Context, start string) error {
+	if err := b.visit(ctx, start); err != nil {
+		return err
+	}
+
+	// Lines are read in the background so that Ctrl-C, which cancels ctx, ends the browser
+	// at the prompt instead of after the next line
+	lines := make(chan string)
+	var scanErr error
+	go func() {
+		defer close(lines)
+		for b.in.Scan() {
+			select {
+			case lines <- b.in.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+		scanErr = b.in.Err()
+	}()
+
+	for {
+		fmt.Fprintf(b.out, "\n%s> ", b.path)
+		var line string
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(b.out)
+			return ctx.Err()
+		case text, ok := <-lines:
+			if !ok {
+				fmt.Fprintln(b.out)
+				return scanErr
+			}
+			line = text
+		}
+
+		input := strings.TrimSpace(line)
+		command, argument, _ := strings.Cut(input, " ")
+		argument = strings.TrimSpace(argument)
+
+		var err error
+		switch {
+		case input == "":
+			continue
+		case command == "q" || command == "quit":
+			return nil
+		case command == "h" || command == "?":
+			fmt.Fprintln(b.out, browserHelp)
+		case command == "b":
+			if len(b.history) == 0 {
+				fmt.Fprintln(b.out, "Already at the first resource")
+				continue
+			}
+			previous := b.history[len(b.history)-1]
+			b.history = b.history[:len(b.history)-1]
+			err = b.load(ctx, previous)
+		case command == "g" && argument != "":
+			err = b.visit(ctx, redfishPath(argument))
+		case command == "j":
+			fmt.Fprintln(b.out, prettyJSON(b.raw))
+		case command == "c":
+			b.copyCommand(argument)
+		default:
+			n, convErr := strconv.Atoi(command)
+			if convErr != nil || n < 1 || n > len(b.links) {
+				fmt.Fprintf(b.out, "Unknown command %q (type 'h' for help)\n", input)
+				continue
+			}
+			err = b.visit(ctx, b.links[n-1].Path)
+		}
+
+		if err != nil {
+			fmt.Fprintf(b.out, "Error: %v\n", err)
+		}
+	}
+}
+
+// visit loads a resource and records the current one in the history
+func (b *redfishBrowser) visit(ctx context.Context, path string) error {
+	previous := b.path
+	if err := b.load(ctx, path); err != nil {
+		return err
+	}
+	if previous != "" {
+		b.history = append(b.history, previous)
+	}
+	return nil
+}
+
+// load fetches and displays a resource
+func (b *redfishBrowser) load(ctx context.Context, path string) error {
+	resp, err := b.requester.RedfishRequest(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s failed with status %d", path, resp.StatusCode)
+	}
+
+	var resource map[string]interface{}
+	if err := json.Unmarshal(body, &resource); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+
+	b.path = path
+	b.raw = body
+	b.resource = resource
+	b.links = resourceLinks(resource)
+	b.actions = resourceActions(resource)
+	b.show()
+	return nil
+}
+
+// show prints the properties, links and actions of the current resource
+func (b *redfishBrowser) show() {
+	fmt.Fprintf(b.out, "\n== %s ==\n", b.path)
+
+	for _, key := range sortedKeys(b.resource) {
+		value := b.resource[key]
+		if key == "Actions" || key == "@odata.id" {
+			continue
+		}
+		if link, ok := value.(map[string]interface{}); ok && len(link) == 1 && link["@odata.id"] != nil {
+			continue
+		}
+		fmt.Fprintf(b.out, "  %s: %s\n", key, summarizeValue(value))
+	}
+
+	if len(b.links) > 0 {
+		fmt.Fprintln(b.out, "\nLinks:")
+		for i, link := range b.links {
+			fmt.Fprintf(b.out, "  [%d] %s -> %s\n", i+1, link.Label, link.Path)
+		}
+	}
+
+	if len(b.actions) > 0 {
+		fmt.Fprintln(b.out, "\nActions:")
+		for i, action := range b.actions {
+			fmt.Fprintf(b.out, "  [a%d] %s -> %s\n", i+1, action.Name, action.Target)
+			for _, name := range sortedKeys(action.Parameters) {
+				fmt.Fprintf(b.out, "        %s: %s\n", name, strings.Join(action.Parameters[name], ", "))
+			}
+		}
+	}
+}
+
+// copyCommand prints a redfish passthrough command and copies it to the clipboard
+func (b *redfishBrowser) copyCommand(argument string) {
+	command := "bmc-cli redfish get " + b.path
+
+	if argument != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(argument, "a"))
+		if !strings.HasPrefix(argument, "a") || err != nil || n < 1 || n > len(b.actions) {
+			fmt.Fprintf(b.out, "Unknown action %q\n", argument)
+			return
+		}
+		command = actionCommand(b.actions[n-1])
+	}
+
+	fmt.Fprintln(b.out, command)
+	if b.clipboard {
+		// OSC 52 asks the terminal to place the text on the clipboard
+		fmt.Fprintf(b.out, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(command)))
+		fmt.Fprintln(b.out, "(copied to clipboard)")
+	}
+}
+
+// actionCommand builds a redfish post command for an action, filling each parameter
+// with its first allowable value
+func actionCommand(action browserAction) string {
+	command := "bmc-cli redfish post " + action.Target
+	if len(action.Parameters) == 0 {
+		return command
+	}
+
+	body := map[string]string{}
+	for name, values := range action.Parameters {
+		if len(values) > 0 {
+			body[name] = values[0]
+		}
+	}
+	data, _ := json.Marshal(body)
+	return fmt.Sprintf("%s --data %s", command, shellQuote(string(data)))
+}
+
+// resourceLinks collects every @odata.id reference below the top level of a resource
+func resourceLinks(resource map[string]interface{}) []browserLink {
+	var links []browserLink
+	seen := map[string]bool{}
+	if self, ok := resource["@odata.id"].(string); ok {
+		seen[self] = true
+	}
+
+	var walk func(label string, value interface{})
+	walk = func(label string, value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if id, ok := v["@odata.id"].(string); ok && label != "" && !seen[id] {
+				seen[id] = true
+				links = append(links, browserLink{Label: label, Path: id})
+			}
+			for _, key := range sortedKeys(v) {
+				if key == "Actions" && label == "" {
+					continue
+				}
+				child := key
+				if label != "" {
+					child = label + "." + key
+				}
+				walk(child, v[key])
+			}
+		case []interface{}:
+			for i, item := range v {
+				walk(fmt.Sprintf("%s[%d]", label, i), item)
+			}
+		}
+	}
+	walk("", resource)
+
+	return links
+}
+
+// resourceActions lists the actions of a resource with their allowable parameter values
+func resourceActions(resource map[string]interface{}) []browserAction {
+	actionsObject, ok := resource["Actions"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var actions []browserAction
+	for _, name := range sortedKeys(actionsObject) {
+		definition, ok := actionsObject[name].(map[string]interface{})
+		if !ok || !strings.HasPrefix(name, "#") {
+			continue
+		}
+
+		action := browserAction{Name: name, Parameters: map[string][]string{}}
+		action.Target, _ = definition["target"].(string)
+		for key, value := range definition {
+			parameter, found := strings.CutSuffix(key, "@Redfish.AllowableValues")
+			values, isList := value.([]interface{})
+			if !found || !isList {
+				continue
+			}
+			for _, v := range values {
+				action.Parameters[parameter] = append(action.Parameters[parameter], fmt.Sprint(v))
+			}
+		}
+		actions = append(actions, action)
+	}
+
+	return actions
+}
+
+// summarizeValue renders a property value on a single line
+func summarizeValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return "null"
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		if len(data) > 100 {
+			return string(data[:97]) + "..."
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func init() {
+	rootCmd.AddCommand(browseCmd)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedfishBrowser_Navigate(t *testing.T) {
+	_, server := newMockBMCServer(t, "ilo5")
+
+	// Create client with test server URL
+	client := &ILOClient{
+		baseURL:    server.URL,
+		username:   "admin",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	var out bytes.Buffer
+	browser := &redfishBrowser{
+		requester: client,
+		in:        bufio.NewScanner(strings.NewReader("g Systems/1\nc a1\nb\nq\n")),
+		out:       &out,
+	}
+	if err := browser.run(context.Background(), "/redfish/v1"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	output := out.String()
+	for _, expected := range []string{
+		"== /redfish/v1 ==",
+		"Systems -> /redfish/v1/Systems",
+		"== /redfish/v1/Systems/1 ==",
+		"PowerState: On",
+		"[a1] #ComputerSystem.Reset -> /redfish/v1/Systems/1/Actions/ComputerSystem.Reset",
+		`bmc-cli redfish post /redfish/v1/Systems/1/Actions/ComputerSystem.Reset --data '{"ResetType":"On"}'`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q", expected)
+		}
+	}
+	if browser.path != "/redfish/v1" {
+		t.Errorf("Expected to be back at the service root, got: %s", browser.path)
+	}
+}
+
+func TestRedfishBrowser_CanceledAtPrompt(t *testing.T) {
+	_, server := newMockBMCServer(t, "ilo5")
+	client := &ILOClient{baseURL: server.URL, username: "admin", password: "password", httpClient: server.Client()}
+
+	// Nothing is ever typed, as when Ctrl-C is pressed at the prompt
+	in, input := io.Pipe()
+	defer input.Close()
+	browser := &redfishBrowser{requester: client, in: bufio.NewScanner(in), out: io.Discard}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- browser.run(ctx, "/redfish/v1") }()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the browser to stop on cancellation, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the browser to stop at the prompt")
+	}
+}
+
+func TestActionCommand(t *testing.T) {
+	action := browserAction{
+		Target:     "/redfish/v1/Managers/1/Actions/Oem/Hpe/HpeiLO.SetBanner",
+		Parameters: map[string][]string{"Banner": {"Don't reboot"}},
+	}
+	command := actionCommand(action)
+	data, ok := strings.CutPrefix(command, "bmc-cli redfish post "+action.Target+" --data ")
+	if !ok {
+		t.Fatalf("Unexpected command: %s", command)
+	}
+	output, err := exec.Command("sh", "-c", "printf %s "+data).Output()
+	if err != nil || string(output) != `{"Banner":"Don't reboot"}` {
+		t.Errorf("Expected the body to survive the shell, got %q (%v) from %s", output, err, command)
+	}
+}
+
+func TestResourceLinks(t *testing.T) {
+	resource := map[string]interface{}{
+		"@odata.id": "/redfish/v1/Systems",
+		"Members": []interface{}{
+			map[string]interface{}{"@odata.id": "/redfish/v1/Systems/1"},
+			map[string]interface{}{"@odata.id": "/redfish/v1/Systems/2"},
+		},
+		"Links": map[string]interface{}{
+			"ManagedBy": []interface{}{map[string]interface{}{"@odata.id": "/redfish/v1/Managers/1"}},
+			"Self":      map[string]interface{}{"@odata.id": "/redfish/v1/Systems"},
+		},
+	}
+
+	links := resourceLinks(resource)
+	if len(links) != 3 {
+		t.Fatalf("Expected 3 links, got: %+v", links)
+	}
+	if links[0].Label != "Links.ManagedBy[0]" || links[1].Label != "Members[0]" || links[2].Path != "/redfish/v1/Systems/2" {
+		t.Errorf("Unexpected links: %+v", links)
+	}
+}
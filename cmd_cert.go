@@ -0,0 +1,561 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// iloHTTPSCertPath is the iLO OEM resource for the HTTPS certificate, which iLO 5 does not
+	// expose through the standard CertificateService
+	iloHTTPSCertPath = "/redfish/v1/Managers/1/SecurityService/HttpsCert"
+	// idracCardServicePath hosts the iDRAC OEM actions for uploading an SSL key and certificate
+	idracCardServicePath = "/redfish/v1/Dell/Managers/iDRAC.Embedded.1/DelliDRACCardService"
+
+	// csrPollInterval is how often iLO is asked whether it has finished generating a CSR
+	csrPollInterval = 10 * time.Second
+	// csrTimeout bounds the wait for iLO to generate a CSR, which can take several minutes
+	csrTimeout = 10 * time.Minute
+)
+
+var (
+	csrRequest certificateSigningRequest
+	csrOut     string
+	certKey    string
+)
+
+var certCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "BMC HTTPS certificate management commands",
+	Long: `Show the certificates installed on the BMC, have the BMC generate a certificate signing
+request for its HTTPS interface and install the signed certificate, so BMCs can present
+certificates issued by your own CA and be verified with tls.ca_file.`,
+}
+
+var certShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the certificates installed on the BMC",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manager, cleanup, err := newCertificateManager()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		certificates, err := manager.list(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to read certificates: %w", err)
+		}
+
+		for i, cert := range certificates {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("Location:    %s\n", cert.Location)
+			fmt.Printf("Subject:     %s\n", cert.Subject)
+			fmt.Printf("Issuer:      %s\n", cert.Issuer)
+			fmt.Printf("Valid from:  %s\n", cert.ValidNotBefore)
+			fmt.Printf("Valid until: %s\n", cert.ValidNotAfter)
+			if cert.Fingerprint != "" {
+				fmt.Printf("Fingerprint: %s\n", cert.Fingerprint)
+			}
+		}
+		if len(certificates) == 0 {
+			fmt.Println("No certificates found")
+		}
+		return nil
+	},
+}
+
+var certCSRCmd = &cobra.Command{
+	Use:   "csr",
+	Short: "Generate a certificate signing request for the BMC's HTTPS interface",
+	Long: `Have the BMC generate a new key pair for its HTTPS interface and return the certificate
+signing request. Sign it with your CA and install the result with 'cert install'.
+
+Example:
+  bmc-cli cert csr --common-name bmc-r740.example.com --organization Example \
+    --country US --alt-name 10.0.0.21 --out bmc-r740.csr`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if csrRequest.CommonName == "" {
+			return fmt.Errorf("--common-name is required")
+		}
+
+		manager, cleanup, err := newCertificateManager()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		csr, err := manager.generateCSR(cmd.Context(), csrRequest)
+		if err != nil {
+			return fmt.Errorf("failed to generate CSR: %w", err)
+		}
+
+		if csrOut == "" {
+			fmt.Print(ensureTrailingNewline(csr))
+			return nil
+		}
+		if err := os.WriteFile(csrOut, []byte(ensureTrailingNewline(csr)), 0644); err != nil {
+			return fmt.Errorf("error writing CSR: %w", err)
+		}
+		fmt.Printf("Certificate signing request written to %s\n", csrOut)
+		return nil
+	},
+}
+
+var certInstallCmd = &cobra.Command{
+	Use:   "install [certificate.pem]",
+	Short: "Install a certificate signed from the BMC's CSR",
+	Long: `Install the certificate your CA issued for a CSR from 'cert csr' as the HTTPS
+certificate of the BMC. Some BMCs restart their web server (iLO resets) to use it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		certPEM, cert, err := readCertificateFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		manager, cleanup, err := newCertificateManager()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		if err := manager.install(cmd.Context(), certPEM); err != nil {
+			return fmt.Errorf("failed to install certificate: %w", err)
+		}
+		printInstalledCertificate(cert)
+		return nil
+	},
+}
+
+var certReplaceCmd = &cobra.Command{
+	Use:   "replace [location] [certificate.pem]",
+	Short: "Replace a certificate on the BMC, optionally with its private key",
+	Long: `Replace the certificate at a location shown by 'cert show' with ReplaceCertificate.
+Use --key to upload a private key generated outside the BMC together with the certificate.
+
+Example:
+  bmc-cli cert replace /redfish/v1/Managers/bmc/NetworkProtocol/HTTPS/Certificates/1 \
+    bmc.pem --key bmc.key`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		certPEM, cert, err := readCertificateFile(args[1])
+		if err != nil {
+			return err
+		}
+
+		var keyPEM string
+		if certKey != "" {
+			data, err := os.ReadFile(certKey)
+			if err != nil {
+				return fmt.Errorf("error reading key: %w", err)
+			}
+			if block, _ := pem.Decode(data); block == nil || !strings.Contains(block.Type, "PRIVATE KEY") {
+				return fmt.Errorf("%s does not contain a PEM private key", certKey)
+			}
+			keyPEM = string(data)
+		}
+
+		manager, cleanup, err := newCertificateManager()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		if err := manager.replace(cmd.Context(), redfishPath(args[0]), certPEM, keyPEM); err != nil {
+			return fmt.Errorf("failed to replace certificate: %w", err)
+		}
+		printInstalledCertificate(cert)
+		return nil
+	},
+}
+
+// newCertificateManager creates a certificate manager for the configured BMC and returns a
+// function releasing its session
+func newCertificateManager() (*certificateManager, func(), error) {
+	client, err := NewBMCClient()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create BMC client: %w", err)
+	}
+
+	requester, ok := client.(RedfishRequester)
+	if !ok {
+		closeClient(client)
+		return nil, nil, fmt.Errorf("certificate management is not supported for BMC type %s", config.BMCType)
+	}
+	return &certificateManager{requester: requester, vendor: config.BMCType}, func() { closeClient(client) }, nil
+}
+
+// certificateSigningRequest holds the subject of a CSR
+type certificateSigningRequest struct {
+	CommonName         string
+	Organization       string
+	OrganizationalUnit string
+	City               string
+	State              string
+	Country            string
+	AlternativeNames   []string
+	KeyBitLength       int
+}
+
+// certificateInfo summarizes an installed certificate
+type certificateInfo struct {
+	Location       string
+	Subject        string
+	Issuer         string
+	ValidNotBefore string
+	ValidNotAfter  string
+	Fingerprint    string
+}
+
+// redfishCertificateIdentifier is the Subject or Issuer of a Redfish Certificate resource
+type redfishCertificateIdentifier struct {
+	CommonName         string `json:"CommonName"`
+	Organization       string `json:"Organization"`
+	OrganizationalUnit string `json:"OrganizationalUnit"`
+	City               string `json:"City"`
+	State              string `json:"State"`
+	Country            string `json:"Country"`
+}
+
+func (i redfishCertificateIdentifier) String() string {
+	var parts []string
+	for _, part := range []struct{ key, value string }{
+		{"CN", i.CommonName}, {"OU", i.OrganizationalUnit}, {"O", i.Organization},
+		{"L", i.City}, {"ST", i.State}, {"C", i.Country},
+	} {
+		if part.value != "" {
+			parts = append(parts, part.key+"="+part.value)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// iloHTTPSCert is the iLO OEM HTTPS certificate resource
+type iloHTTPSCert struct {
+	CertificateSigningRequest  string `json:"CertificateSigningRequest"`
+	X509CertificateInformation struct {
+		Issuer         string `json:"Issuer"`
+		Subject        string `json:"Subject"`
+		ValidNotAfter  string `json:"ValidNotAfter"`
+		ValidNotBefore string `json:"ValidNotBefore"`
+	} `json:"X509CertificateInformation"`
+}
+
+// certificateManager manages BMC certificates through the Redfish CertificateService, using
+// the iLO and iDRAC OEM actions where the standard ones are missing
+type certificateManager struct {
+	requester RedfishRequester
+	vendor    BMCType
+
+	// pollInterval defaults to csrPollInterval
+	pollInterval time.Duration
+}
+
+// list returns the certificates installed on the BMC
+func (m *certificateManager) list(ctx context.Context) ([]certificateInfo, error) {
+	if m.vendor == BMCTypeILO {
+		var cert iloHTTPSCert
+		if err := redfishJSON(ctx, m.requester, http.MethodGet, iloHTTPSCertPath, nil, &cert); err != nil {
+			return nil, err
+		}
+		info := cert.X509CertificateInformation
+		return []certificateInfo{{
+			Location:       iloHTTPSCertPath,
+			Subject:        info.Subject,
+			Issuer:         info.Issuer,
+			ValidNotBefore: info.ValidNotBefore,
+			ValidNotAfter:  info.ValidNotAfter,
+		}}, nil
+	}
+
+	var locations struct {
+		Links struct {
+			Certificates []redfishLink `json:"Certificates"`
+		} `json:"Links"`
+	}
+	if err := redfishJSON(ctx, m.requester, http.MethodGet, "/redfish/v1/CertificateService/CertificateLocations", nil, &locations); err != nil {
+		return nil, err
+	}
+
+	var certificates []certificateInfo
+	for _, link := range locations.Links.Certificates {
+		var cert struct {
+			Subject           redfishCertificateIdentifier `json:"Subject"`
+			Issuer            redfishCertificateIdentifier `json:"Issuer"`
+			ValidNotBefore    string                       `json:"ValidNotBefore"`
+			ValidNotAfter     string                       `json:"ValidNotAfter"`
+			CertificateString string                       `json:"CertificateString"`
+		}
+		if err := redfishJSON(ctx, m.requester, http.MethodGet, link.OdataID, nil, &cert); err != nil {
+			return nil, err
+		}
+
+		info := certificateInfo{
+			Location:       link.OdataID,
+			Subject:        cert.Subject.String(),
+			Issuer:         cert.Issuer.String(),
+			ValidNotBefore: cert.ValidNotBefore,
+			ValidNotAfter:  cert.ValidNotAfter,
+		}
+		if parsed, err := parseCertificatePEM(cert.CertificateString); err == nil {
+			info.Fingerprint = certificateFingerprint(parsed)
+		}
+		certificates = append(certificates, info)
+	}
+	return certificates, nil
+}
+
+// generateCSR has the BMC create a new HTTPS key pair and returns the PEM encoded CSR
+func (m *certificateManager) generateCSR(ctx context.Context, request certificateSigningRequest) (string, error) {
+	if m.vendor == BMCTypeILO {
+		return m.generateILOCSR(ctx, request)
+	}
+
+	collection, err := m.httpsCertificateCollection(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	body := map[string]interface{}{
+		"CertificateCollection": redfishLink{OdataID: collection},
+		"CommonName":            request.CommonName,
+		"KeyPairAlgorithm":      "TPM_ALG_RSA",
+		"KeyBitLength":          request.KeyBitLength,
+	}
+	for key, value := range map[string]string{
+		"Organization":       request.Organization,
+		"OrganizationalUnit": request.OrganizationalUnit,
+		"City":               request.City,
+		"State":              request.State,
+		"Country":            request.Country,
+	} {
+		if value != "" {
+			body[key] = value
+		}
+	}
+	if len(request.AlternativeNames) > 0 {
+		body["AlternativeNames"] = request.AlternativeNames
+	}
+
+	var result struct {
+		CSRString string `json:"CSRString"`
+	}
+	if err := redfishJSON(ctx, m.requester, http.MethodPost, "/redfish/v1/CertificateService/Actions/CertificateService.GenerateCSR", body, &result); err != nil {
+		return "", err
+	}
+	if result.CSRString == "" {
+		return "", fmt.Errorf("BMC did not return a CSR")
+	}
+	return result.CSRString, nil
+}
+
+// generateILOCSR starts CSR generation on iLO, which completes in the background, and polls
+// the HTTPS certificate resource until the CSR appears
+func (m *certificateManager) generateILOCSR(ctx context.Context, request certificateSigningRequest) (string, error) {
+	body := map[string]interface{}{
+		"CommonName": request.CommonName,
+		"OrgName":    request.Organization,
+		"OrgUnit":    request.OrganizationalUnit,
+		"City":       request.City,
+		"State":      request.State,
+		"Country":    request.Country,
+		"IncludeIP":  false,
+	}
+	if err := redfishJSON(ctx, m.requester, http.MethodPost, iloHTTPSCertPath+"/Actions/HpeHttpsCert.GenerateCSR", body, nil); err != nil {
+		return "", err
+	}
+
+	interval := m.pollInterval
+	if interval == 0 {
+		interval = csrPollInterval
+	}
+	ctx, cancel := context.WithTimeout(ctx, csrTimeout)
+	defer cancel()
+
+	for {
+		var cert iloHTTPSCert
+		if err := redfishJSON(ctx, m.requester, http.MethodGet, iloHTTPSCertPath, nil, &cert); err != nil {
+			return "", err
+		}
+		if cert.CertificateSigningRequest != "" {
+			return cert.CertificateSigningRequest, nil
+		}
+
+		if verbose {
+			fmt.Println("Waiting for iLO to generate the CSR")
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return "", fmt.Errorf("CSR not ready: %w", ctx.Err())
+		}
+	}
+}
+
+// install replaces the HTTPS certificate with one signed from the BMC's CSR
+func (m *certificateManager) install(ctx context.Context, certPEM string) error {
+	switch m.vendor {
+	case BMCTypeILO:
+		return redfishJSON(ctx, m.requester, http.MethodPost, iloHTTPSCertPath+"/Actions/HpeHttpsCert.ImportCertificate",
+			map[string]string{"Certificate": certPEM}, nil)
+	case BMCTypeIDRAC:
+		return m.importIDRACCertificate(ctx, certPEM)
+	}
+
+	collection, err := m.httpsCertificateCollection(ctx)
+	if err != nil {
+		return err
+	}
+
+	var members struct {
+		Members []redfishLink `json:"Members"`
+	}
+	if err := redfishJSON(ctx, m.requester, http.MethodGet, collection, nil, &members); err != nil {
+		return err
+	}
+
+	// Without an installed certificate there is nothing to replace, so add one instead
+	if len(members.Members) == 0 {
+		return redfishJSON(ctx, m.requester, http.MethodPost, collection,
+			map[string]string{"CertificateString": certPEM, "CertificateType": "PEM"}, nil)
+	}
+	return m.replaceCertificate(ctx, members.Members[0].OdataID, certPEM)
+}
+
+// replace replaces the certificate at location, uploading keyPEM with it when given
+func (m *certificateManager) replace(ctx context.Context, location, certPEM, keyPEM string) error {
+	switch {
+	case keyPEM == "":
+		return m.replaceCertificate(ctx, location, certPEM)
+	case m.vendor == BMCTypeILO:
+		return fmt.Errorf("iLO does not accept private keys; use 'cert csr' and 'cert install' instead")
+	case m.vendor == BMCTypeIDRAC:
+		if err := redfishJSON(ctx, m.requester, http.MethodPost, idracCardServicePath+"/Actions/DelliDRACCardService.UploadSSLKey",
+			map[string]string{"SSLKeyString": keyPEM}, nil); err != nil {
+			return fmt.Errorf("error uploading key: %w", err)
+		}
+		return m.importIDRACCertificate(ctx, certPEM)
+	default:
+		// The key travels in the same PEM bundle as the certificate
+		return m.replaceCertificate(ctx, location, ensureTrailingNewline(certPEM)+keyPEM)
+	}
+}
+
+// replaceCertificate calls the standard ReplaceCertificate action
+func (m *certificateManager) replaceCertificate(ctx context.Context, location, certPEM string) error {
+	body := map[string]interface{}{
+		"CertificateString": certPEM,
+		"CertificateType":   "PEM",
+		"CertificateUri":    redfishLink{OdataID: location},
+	}
+	return redfishJSON(ctx, m.requester, http.MethodPost, "/redfish/v1/CertificateService/Actions/CertificateService.ReplaceCertificate", body, nil)
+}
+
+// importIDRACCertificate imports the iDRAC web server certificate with the OEM action
+func (m *certificateManager) importIDRACCertificate(ctx context.Context, certPEM string) error {
+	body := map[string]string{"CertificateType": "Server", "SSLCertificateFile": certPEM}
+	return redfishJSON(ctx, m.requester, http.MethodPost, idracCardServicePath+"/Actions/DelliDRACCardService.ImportSSLCertificate", body, nil)
+}
+
+// httpsCertificateCollection finds the HTTPS certificate collection of the first manager
+func (m *certificateManager) httpsCertificateCollection(ctx context.Context) (string, error) {
+	var managers struct {
+		Members []redfishLink `json:"Members"`
+	}
+	if err := redfishJSON(ctx, m.requester, http.MethodGet, "/redfish/v1/Managers", nil, &managers); err != nil {
+		return "", err
+	}
+	if len(managers.Members) == 0 {
+		return "", fmt.Errorf("no managers found")
+	}
+
+	var manager struct {
+		NetworkProtocol redfishLink `json:"NetworkProtocol"`
+	}
+	if err := redfishJSON(ctx, m.requester, http.MethodGet, managers.Members[0].OdataID, nil, &manager); err != nil {
+		return "", err
+	}
+	if manager.NetworkProtocol.OdataID == "" {
+		return "", fmt.Errorf("manager %s has no NetworkProtocol", managers.Members[0].OdataID)
+	}
+
+	var protocol struct {
+		HTTPS struct {
+			Certificates redfishLink `json:"Certificates"`
+		} `json:"HTTPS"`
+	}
+	if err := redfishJSON(ctx, m.requester, http.MethodGet, manager.NetworkProtocol.OdataID, nil, &protocol); err != nil {
+		return "", err
+	}
+	if protocol.HTTPS.Certificates.OdataID == "" {
+		return "", fmt.Errorf("BMC does not expose an HTTPS certificate collection")
+	}
+	return protocol.HTTPS.Certificates.OdataID, nil
+}
+
+// readCertificateFile reads a PEM certificate and returns it with its parsed form
+func readCertificateFile(path string) (string, *x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading certificate: %w", err)
+	}
+	cert, err := parseCertificatePEM(string(data))
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return string(data), cert, nil
+}
+
+// parseCertificatePEM parses the first certificate of a PEM bundle
+func parseCertificatePEM(data string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// printInstalledCertificate reports a new certificate and what it means for a pinned fingerprint
+func printInstalledCertificate(cert *x509.Certificate) {
+	fmt.Printf("Installed certificate for %s issued by %s\n", cert.Subject, cert.Issuer)
+	fmt.Printf("Fingerprint: %s\n", certificateFingerprint(cert))
+	fmt.Println("If the previous certificate was pinned in known_hosts, replace its fingerprint with this one or set tls.ca_file.")
+}
+
+func ensureTrailingNewline(s string) string {
+	if strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
+
+func init() {
+	rootCmd.AddCommand(certCmd)
+	certCmd.AddCommand(certShowCmd)
+	certCmd.AddCommand(certCSRCmd)
+	certCmd.AddCommand(certInstallCmd)
+	certCmd.AddCommand(certReplaceCmd)
+
+	certCSRCmd.Flags().StringVar(&csrRequest.CommonName, "common-name", "", "common name (usually the BMC's DNS name)")
+	certCSRCmd.Flags().StringVar(&csrRequest.Organization, "organization", "", "organization")
+	certCSRCmd.Flags().StringVar(&csrRequest.OrganizationalUnit, "org-unit", "", "organizational unit")
+	certCSRCmd.Flags().StringVar(&csrRequest.City, "city", "", "city or locality")
+	certCSRCmd.Flags().StringVar(&csrRequest.State, "state", "", "state or province")
+	certCSRCmd.Flags().StringVar(&csrRequest.Country, "country", "", "two-letter country code")
+	certCSRCmd.Flags().StringArrayVar(&csrRequest.AlternativeNames, "alt-name", nil, "subject alternative name (repeatable)")
+	certCSRCmd.Flags().IntVar(&csrRequest.KeyBitLength, "key-bits", 2048, "RSA key length")
+	certCSRCmd.Flags().StringVarP(&csrOut, "out", "o", "", "write the CSR to this file instead of stdout")
+
+	certReplaceCmd.Flags().StringVar(&certKey, "key", "", "PEM private key to upload with the certificate")
+}
@@ -0,0 +1,510 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkAll      bool
+	checkSelector string
+	checkWorkers  int
+)
+
+// Outcomes of a single check
+const (
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+	checkSkip = "SKIP"
+)
+
+// certificateExpiryWarning is how close to expiry a BMC certificate is reported
+const certificateExpiryWarning = 30 * 24 * time.Hour
+
+// requiredPrivileges are the Redfish privileges the commands of this tool rely on, with what
+// they are needed for
+var requiredPrivileges = []struct{ Name, Purpose string }{
+	{"ConfigureComponents", "power, boot and virtual media"},
+	{"ConfigureManager", "certificates and BMC settings"},
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check connectivity and credentials for BMCs",
+	Long: `Verify, step by step, that a BMC can be managed: its name resolves (DNS), its port
+accepts connections (TCP), its certificate verifies (TLS), it serves the Redfish service
+root, the configured credentials are accepted (auth) and the account's role grants the
+privileges this tool needs. A failed step skips the ones after it. IPMI BMCs are checked by
+opening a session.
+
+Without --all the active BMC is checked; with --all every BMC section with a host, every
+context and every inventory host is checked. --selector checks the inventory hosts it
+matches. The command fails if any check fails; warnings do not fail it.
+
+Example:
+  bmc-cli check
+  bmc-cli check --all --output json
+  bmc-cli check --selector rack=r12 --workers 8`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targets, err := checkTargets(checkAll, checkSelector)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("no BMC hosts configured")
+		}
+
+		reports := runHostChecks(cmd.Context(), targets, checkWorkers)
+		if err := cmd.Context().Err(); err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, report := range reports {
+			if !report.OK {
+				failed++
+			}
+		}
+
+		if printed, err := printStructured(reports); !printed {
+			printCheckReports(reports)
+			fmt.Printf("\n%d BMC(s) checked: %d passed, %d failed\n", len(reports), len(reports)-failed, failed)
+		} else if err != nil {
+			return err
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d BMC(s) failed checks", failed, len(reports))
+		}
+		return nil
+	},
+}
+
+// checkTarget is a BMC to check, with the configuration selecting it
+type checkTarget struct {
+	Name   string
+	Config Config
+}
+
+// checkResult is the outcome of one step
+type checkResult struct {
+	Check  string `json:"check" yaml:"check"`
+	Status string `json:"status" yaml:"status"`
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+}
+
+// hostReport is the outcome of every step for one BMC
+type hostReport struct {
+	Name    string        `json:"name" yaml:"name"`
+	BMCType BMCType       `json:"bmc_type" yaml:"bmc_type"`
+	Host    string        `json:"host" yaml:"host"`
+	Port    int           `json:"port" yaml:"port"`
+	OK      bool          `json:"ok" yaml:"ok"`
+	Checks  []checkResult `json:"checks" yaml:"checks"`
+}
+
+// checkTargets returns the active BMC, the inventory hosts matching selector, or with all
+// every configured BMC section, context and inventory host once per distinct BMC type, host
+// and port
+func checkTargets(all bool, selector string) ([]checkTarget, error) {
+	if !all && selector == "" {
+		name := config.CurrentContext
+		if hostName != "" {
+			name = hostName
+		} else if name == "" {
+			name = string(config.BMCType)
+		}
+		return []checkTarget{{Name: name, Config: config}}, nil
+	}
+
+	var targets []checkTarget
+	seen := map[string]bool{}
+	add := func(name string, cfg Config) {
+		section, ok := cfg.activeSection()
+		if !ok || *section.Host == "" {
+			return
+		}
+		key := fmt.Sprintf("%s|%s|%d", cfg.BMCType, strings.ToLower(*section.Host), *section.Port)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		targets = append(targets, checkTarget{Name: name, Config: cfg})
+	}
+
+	addHost := func(h HostConfig) error {
+		cfg := baseConfig
+		if err := cfg.useHost(h); err != nil {
+			return err
+		}
+		add(h.Name, cfg)
+		return nil
+	}
+
+	if !all {
+		s, err := parseSelector(selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range baseConfig.Hosts {
+			if !s.matches(h) {
+				continue
+			}
+			if err := addHost(h); err != nil {
+				return nil, err
+			}
+		}
+		return targets, nil
+	}
+
+	for _, bmcType := range supportedBMCTypes {
+		cfg := baseConfig
+		cfg.BMCType = bmcType
+		add(string(bmcType), cfg)
+	}
+	for _, name := range baseConfig.contextNames() {
+		cfg := baseConfig
+		cfg.CurrentContext = name
+		if err := cfg.overlay(baseConfig.Contexts[name]); err != nil {
+			return nil, fmt.Errorf("context %q: %w", name, err)
+		}
+		add(name, cfg)
+	}
+	for _, h := range baseConfig.Hosts {
+		if err := addHost(h); err != nil {
+			return nil, err
+		}
+	}
+	return targets, nil
+}
+
+// runHostChecks checks the targets with bounded concurrency, returning reports in order
+func runHostChecks(ctx context.Context, targets []checkTarget, workers int) []hostReport {
+	if workers < 1 {
+		workers = 1
+	}
+
+	reports := make([]hostReport, len(targets))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			reports[i] = checkHost(ctx, target)
+		}()
+	}
+	wg.Wait()
+	return reports
+}
+
+// checkHost runs the checks for one BMC, skipping the steps after the first failure
+func checkHost(ctx context.Context, target checkTarget) hostReport {
+	cfg := target.Config
+	section, _ := cfg.activeSection()
+	host, port := *section.Host, *section.Port
+	report := hostReport{Name: target.Name, BMCType: cfg.BMCType, Host: host, Port: port, OK: true}
+
+	step := func(name string, check func() (string, string)) {
+		if !report.OK || ctx.Err() != nil {
+			report.Checks = append(report.Checks, checkResult{Check: name, Status: checkSkip})
+			return
+		}
+		status, detail := check()
+		if status == checkFail {
+			report.OK = false
+		}
+		report.Checks = append(report.Checks, checkResult{Check: name, Status: status, Detail: detail})
+	}
+
+	proxied := section.Proxy != nil && (section.Proxy.URL != "" || section.Proxy.Jump != "")
+	step("dns", func() (string, string) { return checkDNS(ctx, host, proxied) })
+
+	// IPMI has no HTTP stack to probe; opening a session covers reachability and credentials
+	if section.UseHTTPS == nil {
+		step("ipmi", func() (string, string) {
+			client, err := cfg.newClient()
+			if err != nil {
+				return checkFail, err.Error()
+			}
+			defer closeClient(client)
+			info, err := client.GetSystemInfo(ctx)
+			if err != nil {
+				return checkFail, err.Error()
+			}
+			return checkPass, fmt.Sprintf("session established as %s, power %s", *section.Username, info.PowerState)
+		})
+		return report
+	}
+
+	transport, err := newBMCTransport(host, port, *section.TLS, *section.Proxy)
+	if err != nil {
+		step("tcp", func() (string, string) { return checkFail, err.Error() })
+		return report
+	}
+	defer transport.CloseIdleConnections()
+
+	step("tcp", func() (string, string) { return checkTCP(ctx, transport, host, port, section.Proxy.URL) })
+	if *section.UseHTTPS {
+		step("tls", func() (string, string) { return checkTLS(ctx, transport, host, port, section.Proxy.URL) })
+	}
+	step("redfish", func() (string, string) { return checkServiceRoot(ctx, transport, host, port, *section.UseHTTPS) })
+
+	var requester RedfishRequester
+	step("auth", func() (string, string) {
+		client, err := cfg.newClient()
+		if err != nil {
+			return checkFail, err.Error()
+		}
+		var ok bool
+		if requester, ok = client.(RedfishRequester); !ok {
+			closeClient(client)
+			return checkSkip, "not supported for this BMC type"
+		}
+		status, detail := checkAuthentication(ctx, requester, *section.Username)
+		if status != checkPass {
+			closeClient(client)
+			requester = nil
+		}
+		return status, detail
+	})
+	if client, ok := requester.(BMCClient); ok {
+		defer closeClient(client)
+	}
+
+	step("privileges", func() (string, string) {
+		if requester == nil {
+			return checkSkip, ""
+		}
+		return checkPrivileges(ctx, requester, *section.Username)
+	})
+	return report
+}
+
+func checkDNS(ctx context.Context, host string, proxied bool) (string, string) {
+	if net.ParseIP(host) != nil {
+		return checkPass, "IP address"
+	}
+	if proxied {
+		return checkSkip, "resolved by the proxy or jump host"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	addresses, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return checkFail, err.Error()
+	}
+	return checkPass, strings.Join(addresses, ", ")
+}
+
+// dialBMC connects to the BMC the way its HTTP transport does, through any jump host
+func dialBMC(ctx context.Context, transport *http.Transport, address string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	if transport.DialContext != nil {
+		return transport.DialContext(ctx, "tcp", address)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+func checkTCP(ctx context.Context, transport *http.Transport, host string, port int, proxyURL string) (string, string) {
+	if proxyURL != "" {
+		return checkSkip, "connected through the proxy"
+	}
+	start := time.Now()
+	conn, err := dialBMC(ctx, transport, bmcAddress(host, port))
+	if err != nil {
+		return checkFail, err.Error()
+	}
+	conn.Close()
+	return checkPass, fmt.Sprintf("connected in %s", time.Since(start).Round(time.Millisecond))
+}
+
+func checkTLS(ctx context.Context, transport *http.Transport, host string, port int, proxyURL string) (string, string) {
+	if proxyURL != "" {
+		return checkSkip, "verified by the redfish check through the proxy"
+	}
+	conn, err := dialBMC(ctx, transport, bmcAddress(host, port))
+	if err != nil {
+		return checkFail, err.Error()
+	}
+	defer conn.Close()
+
+	tlsConfig := transport.TLSClientConfig.Clone()
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	handshakeCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	if err := tlsConn.HandshakeContext(handshakeCtx); err != nil {
+		return checkFail, err.Error()
+	}
+
+	leaf := tlsConn.ConnectionState().PeerCertificates[0]
+	subject := leaf.Subject.CommonName
+	if subject == "" {
+		subject = leaf.Subject.String()
+	}
+	remaining := time.Until(leaf.NotAfter)
+	detail := fmt.Sprintf("%s, expires %s", subject, leaf.NotAfter.Format("2006-01-02"))
+	switch {
+	case remaining <= 0:
+		return checkFail, fmt.Sprintf("%s, expired on %s", subject, leaf.NotAfter.Format("2006-01-02"))
+	case remaining < certificateExpiryWarning:
+		return checkWarn, fmt.Sprintf("%s (in %d days)", detail, int(remaining.Hours()/24))
+	}
+	return checkPass, detail
+}
+
+// checkServiceRoot fetches /redfish/v1, which services expose without authentication
+func checkServiceRoot(ctx context.Context, transport *http.Transport, host string, port int, useHTTPS bool) (string, string) {
+	scheme := "http"
+	if useHTTPS {
+		scheme = "https"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s/redfish/v1", scheme, bmcAddress(host, port)), nil)
+	if err != nil {
+		return checkFail, err.Error()
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := newHTTPClient(transport).Do(req)
+	if err != nil {
+		return checkFail, err.Error()
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return checkFail, fmt.Sprintf("GET /redfish/v1 returned status %d", resp.StatusCode)
+	}
+
+	var root struct {
+		RedfishVersion string
+		Vendor         string
+		Product        string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&root); err != nil {
+		return checkFail, fmt.Sprintf("invalid service root: %v", err)
+	}
+	detail := "Redfish " + root.RedfishVersion
+	if vendor := strings.TrimSpace(root.Vendor + " " + root.Product); vendor != "" {
+		detail += " (" + vendor + ")"
+	}
+	return checkPass, detail
+}
+
+// checkAuthentication reads the systems collection, which requires a valid login
+func checkAuthentication(ctx context.Context, requester RedfishRequester, username string) (string, string) {
+	resp, err := requester.RedfishRequest(ctx, http.MethodGet, "/redfish/v1/Systems", nil, nil)
+	if err != nil {
+		return checkFail, err.Error()
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return checkFail, fmt.Sprintf("credentials for %s rejected (status %d)", username, resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return checkFail, fmt.Sprintf("GET /redfish/v1/Systems returned status %d", resp.StatusCode)
+	}
+	return checkPass, "logged in as " + username
+}
+
+// checkPrivileges looks up the account's role and reports privileges it lacks
+func checkPrivileges(ctx context.Context, requester RedfishRequester, username string) (string, string) {
+	role, privileges, err := accountPrivileges(ctx, requester, username)
+	if err != nil {
+		return checkWarn, "could not determine the account's privileges: " + err.Error()
+	}
+
+	var missing []string
+	for _, required := range requiredPrivileges {
+		if !slices.Contains(privileges, required.Name) {
+			missing = append(missing, fmt.Sprintf("%s (needed for %s)", required.Name, required.Purpose))
+		}
+	}
+	if len(missing) > 0 {
+		return checkWarn, fmt.Sprintf("role %s lacks %s", role, strings.Join(missing, ", "))
+	}
+	return checkPass, fmt.Sprintf("role %s: %s", role, strings.Join(privileges, ", "))
+}
+
+// accountPrivileges finds the account in the AccountService and returns its role and the
+// privileges assigned to that role
+func accountPrivileges(ctx context.Context, requester RedfishRequester, username string) (string, []string, error) {
+	var root struct{ AccountService redfishLink }
+	if err := redfishJSON(ctx, requester, http.MethodGet, "/redfish/v1", nil, &root); err != nil {
+		return "", nil, err
+	}
+	if root.AccountService.OdataID == "" {
+		return "", nil, fmt.Errorf("the service has no AccountService")
+	}
+
+	var service struct{ Accounts, Roles redfishLink }
+	if err := redfishJSON(ctx, requester, http.MethodGet, root.AccountService.OdataID, nil, &service); err != nil {
+		return "", nil, err
+	}
+	var accounts struct{ Members []redfishLink }
+	if err := redfishJSON(ctx, requester, http.MethodGet, service.Accounts.OdataID, nil, &accounts); err != nil {
+		return "", nil, err
+	}
+
+	roleID := ""
+	for _, member := range accounts.Members {
+		var account struct{ UserName, RoleId string }
+		if err := redfishJSON(ctx, requester, http.MethodGet, member.OdataID, nil, &account); err != nil {
+			return "", nil, err
+		}
+		if account.UserName == username {
+			roleID = account.RoleId
+			break
+		}
+	}
+	if roleID == "" {
+		return "", nil, fmt.Errorf("account %s not found", username)
+	}
+
+	var role struct{ AssignedPrivileges []string }
+	if err := redfishJSON(ctx, requester, http.MethodGet, strings.TrimSuffix(service.Roles.OdataID, "/")+"/"+roleID, nil, &role); err != nil {
+		return roleID, nil, err
+	}
+	sort.Strings(role.AssignedPrivileges)
+	return roleID, role.AssignedPrivileges, nil
+}
+
+// printCheckReports prints a pass/fail table per BMC
+func printCheckReports(reports []hostReport) {
+	for i, report := range reports {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s: %s %s\n", report.Name, report.BMCType, bmcAddress(report.Host, report.Port))
+		for _, result := range report.Checks {
+			fmt.Println(strings.TrimRight(fmt.Sprintf("  %-4s  %-10s  %s", result.Status, result.Check, result.Detail), " "))
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.Flags().BoolVar(&checkAll, "all", false, "check every configured BMC, context and inventory host instead of the active one")
+	checkCmd.Flags().StringVarP(&checkSelector, "selector", "l", "", "check the inventory hosts matching a selector (such as rack=r12,group=gpu)")
+	checkCmd.Flags().IntVar(&checkWorkers, "workers", 4, "number of BMCs checked concurrently")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newCheckTarget returns a target for an iDRAC emulator served over HTTPS
+func newCheckTarget(t *testing.T, password string) checkTarget {
+	bmc, err := NewMockBMC("idrac9", "admin", "password")
+	if err != nil {
+		t.Fatalf("Failed to create mock BMC: %v", err)
+	}
+	server := httptest.NewTLSServer(bmc)
+	t.Cleanup(server.Close)
+
+	knownHostsFile = filepath.Join(t.TempDir(), "known_hosts")
+	t.Cleanup(func() { knownHostsFile = "" })
+
+	host, portText, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "https://"))
+	port, _ := strconv.Atoi(portText)
+	return checkTarget{
+		Name: "lab",
+		Config: Config{
+			BMCType: BMCTypeIDRAC,
+			IDRAC:   IDRACConfig{Host: host, Port: port, Username: "admin", Password: password, UseHTTPS: true},
+		},
+	}
+}
+
+func checkStatuses(report hostReport) string {
+	var statuses []string
+	for _, result := range report.Checks {
+		statuses = append(statuses, result.Check+"="+result.Status)
+	}
+	return strings.Join(statuses, " ")
+}
+
+func TestCheckHost(t *testing.T) {
+	report := checkHost(context.Background(), newCheckTarget(t, "password"))
+
+	expected := "dns=PASS tcp=PASS tls=PASS redfish=PASS auth=PASS privileges=PASS"
+	if got := checkStatuses(report); got != expected || !report.OK {
+		t.Errorf("Expected %s, got %s (ok %t): %+v", expected, got, report.OK, report.Checks)
+	}
+	if detail := report.Checks[5].Detail; !strings.Contains(detail, "role Administrator") {
+		t.Errorf("Expected the account's role, got: %s", detail)
+	}
+}
+
+func TestCheckHost_WrongPassword(t *testing.T) {
+	report := checkHost(context.Background(), newCheckTarget(t, "wrong"))
+
+	expected := "dns=PASS tcp=PASS tls=PASS redfish=PASS auth=FAIL privileges=SKIP"
+	if got := checkStatuses(report); got != expected || report.OK {
+		t.Errorf("Expected %s, got %s (ok %t)", expected, got, report.OK)
+	}
+	if detail := report.Checks[4].Detail; !strings.Contains(detail, "rejected (status 401)") {
+		t.Errorf("Expected rejected credentials, got: %s", detail)
+	}
+}
+
+func TestCheckHost_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	address := listener.Addr().(*net.TCPAddr)
+	listener.Close()
+
+	target := checkTarget{Name: "gone", Config: Config{
+		BMCType: BMCTypeIDRAC,
+		IDRAC:   IDRACConfig{Host: "127.0.0.1", Port: address.Port, Username: "root", Password: "calvin", UseHTTPS: true},
+	}}
+	report := checkHost(context.Background(), target)
+
+	expected := "dns=PASS tcp=FAIL tls=SKIP redfish=SKIP auth=SKIP privileges=SKIP"
+	if got := checkStatuses(report); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestCheckTargets_All(t *testing.T) {
+	originalBase := baseConfig
+	defer func() { baseConfig = originalBase }()
+
+	baseConfig = Config{
+		ILO:   ILOConfig{Host: "10.0.0.1", Port: 443},
+		IDRAC: IDRACConfig{Host: "10.0.0.2", Port: 443},
+		Contexts: map[string]ContextConfig{
+			"a-same":  {BMCType: BMCTypeIDRAC},
+			"b-other": {BMCType: BMCTypeIDRAC, Host: "10.0.0.3"},
+		},
+		Hosts: []HostConfig{
+			{Name: "r740-01", BMCType: BMCTypeIDRAC, Address: "10.0.0.2"},
+			{Name: "dl380-01", BMCType: BMCTypeILO, Address: "10.0.0.4", Labels: map[string]string{"rack": "r12"}},
+		},
+	}
+
+	targets, err := checkTargets(true, "")
+	if err != nil {
+		t.Fatalf("checkTargets failed: %v", err)
+	}
+	var names []string
+	for _, target := range targets {
+		section, _ := target.Config.activeSection()
+		names = append(names, target.Name+"@"+*section.Host)
+	}
+	// The context and inventory host pointing at the idrac section's host are checked only once
+	if got := strings.Join(names, " "); got != "ilo@10.0.0.1 idrac@10.0.0.2 b-other@10.0.0.3 dl380-01@10.0.0.4" {
+		t.Errorf("Unexpected targets: %s", got)
+	}
+
+	targets, err = checkTargets(false, "rack=r12")
+	if err != nil {
+		t.Fatalf("checkTargets failed: %v", err)
+	}
+	if len(targets) != 1 || targets[0].Name != "dl380-01" || targets[0].Config.BMCType != BMCTypeILO {
+		t.Errorf("Unexpected selected targets: %+v", targets)
+	}
+}
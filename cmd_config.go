@@ -1,15 +1,45 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
+var (
+	encryptRecipients     []string
+	encryptEncryptedRegex string
+	encryptWholeFile      bool
+	cryptInPlace          bool
+	viewRedact            bool
+)
+
+// Configuration commands read the configuration themselves, so that a missing or invalid
+// setting can still be inspected and fixed with them
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Configuration management commands",
-	Long:  `Commands for managing BMC CLI configuration`,
+	Long: `Commands for managing BMC CLI configuration.
+
+The configuration is read from --config, ./config.yaml, or the per-user file
+<user config dir>/bmc-cli/config.yaml ($XDG_CONFIG_HOME/bmc-cli/config.yaml on Linux).
+Named contexts select which BMC commands talk to and how output is formatted:
+
+  current_context: lab
+  contexts:
+    lab:
+      bmc_type: idrac
+      host: 10.6.75.19
+      output: json`,
+	Annotations: map[string]string{skipConfigAnnotation: "true"},
 }
 
 var generateConfigCmd = &cobra.Command{
@@ -21,30 +51,470 @@ var generateConfigCmd = &cobra.Command{
 	},
 }
 
+// configSummary is the connection settings 'config show' reports for the active BMC
+type configSummary struct {
+	ConfigFile string  `json:"config_file,omitempty" yaml:"config_file,omitempty"`
+	Context    string  `json:"context,omitempty" yaml:"context,omitempty"`
+	BMCType    BMCType `json:"bmc_type" yaml:"bmc_type"`
+	Host       string  `json:"host" yaml:"host"`
+	Username   string  `json:"username" yaml:"username"`
+	Port       int     `json:"port" yaml:"port"`
+	UseHTTPS   *bool   `json:"use_https,omitempty" yaml:"use_https,omitempty"`
+	Password   string  `json:"password" yaml:"password"`
+}
+
 var showConfigCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show current configuration",
-	Long:  `Display the current configuration values (without showing passwords)`,
+	Long:  `Display the connection settings of the active BMC type and context (without showing passwords)`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := readConfig(); err != nil {
+			return err
+		}
+		section, ok := config.activeSection()
+		if !ok {
+			return fmt.Errorf("unsupported BMC type: %s (supported types: ilo, idrac, supermicro, xcc, openbmc, ipmi)", config.BMCType)
+		}
+
+		summary := configSummary{
+			ConfigFile: configFilePath(),
+			Context:    config.CurrentContext,
+			BMCType:    config.BMCType,
+			Host:       *section.Host,
+			Username:   *section.Username,
+			Port:       *section.Port,
+			UseHTTPS:   section.UseHTTPS,
+			Password:   describePasswordSource(*section.Password, *section.Source),
+		}
+		if printed, err := printStructured(summary); printed {
+			return err
+		}
+
 		fmt.Println("Current Configuration:")
 		fmt.Println("=====================")
-		fmt.Printf("Host: %s\n", config.ILO.Host)
-		fmt.Printf("Username: %s\n", config.ILO.Username)
-		fmt.Printf("Port: %d\n", config.ILO.Port)
-		fmt.Printf("Use HTTPS: %t\n", config.ILO.UseHTTPS)
+		if summary.ConfigFile != "" {
+			fmt.Printf("Config File: %s\n", summary.ConfigFile)
+		}
+		if summary.Context != "" {
+			fmt.Printf("Context: %s\n", summary.Context)
+		}
+		fmt.Printf("BMC Type: %s\n", summary.BMCType)
+		fmt.Printf("Host: %s\n", valueOrNotSet(summary.Host))
+		fmt.Printf("Username: %s\n", valueOrNotSet(summary.Username))
+		fmt.Printf("Port: %d\n", summary.Port)
+		if summary.UseHTTPS != nil {
+			fmt.Printf("Use HTTPS: %t\n", *summary.UseHTTPS)
+		}
+		fmt.Printf("Password: %s\n", summary.Password)
+		return nil
+	},
+}
+
+var useConfigCmd = &cobra.Command{
+	Use:   "use [context]",
+	Short: "Switch the current context",
+	Long: `Set current_context in the configuration file. Without an argument, list the
+contexts and mark the current one.
+
+Example:
+  bmc-cli config use lab`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := writableConfigFile()
+		if err != nil {
+			return err
+		}
+		names, current, err := configFileContexts(path)
+		if err != nil {
+			return err
+		}
 
-		if config.ILO.Password != "" {
-			fmt.Printf("Password: %s\n", "***configured***")
-		} else {
-			fmt.Printf("Password: %s\n", "***not configured***")
+		if len(args) == 0 {
+			if len(names) == 0 {
+				fmt.Println("No contexts defined")
+				return nil
+			}
+			for _, name := range names {
+				marker := " "
+				if name == current {
+					marker = "*"
+				}
+				fmt.Printf("%s %s\n", marker, name)
+			}
+			return nil
 		}
 
+		name := strings.ToLower(args[0])
+		if !slices.Contains(names, name) {
+			if len(names) == 0 {
+				return fmt.Errorf("context %q not found: no contexts are defined in %s (add one with 'bmc-cli config set contexts.%s.host <host>')", name, path, name)
+			}
+			return fmt.Errorf("context %q not found (available: %s)", name, strings.Join(names, ", "))
+		}
+		if err := setConfigValue(path, "current_context", name); err != nil {
+			return err
+		}
+		fmt.Printf("Switched to context %q\n", name)
 		return nil
 	},
 }
 
+var setConfigCmd = &cobra.Command{
+	Use:   "set [key] [value]",
+	Short: "Set a configuration value",
+	Long: `Set a value in the configuration file (or create the per-user file), keeping its
+comments. Keys are dotted paths; values are parsed as YAML, so numbers and booleans keep
+their type. Encrypted files are re-encrypted for the same recipients.
+
+Example:
+  bmc-cli config set idrac.host 10.6.75.19
+  bmc-cli config set contexts.lab.bmc_type idrac
+  bmc-cli config set contexts.lab.output json`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := writableConfigFile()
+		if err != nil {
+			return err
+		}
+		if err := setConfigValue(path, args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Set %s in %s\n", args[0], path)
+		return nil
+	},
+}
+
+var getConfigCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Print a configuration value",
+	Long: `Print the effective value of a dotted configuration key, after environment variables
+and the active context are applied.
+
+Example:
+  bmc-cli config get current_context
+  bmc-cli config get idrac.host`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := readConfig(); err != nil {
+			return err
+		}
+		value := lookupSetting(viper.AllSettings(), args[0])
+		if value == nil {
+			return fmt.Errorf("%s is not set", args[0])
+		}
+		if printed, err := printStructured(value); printed {
+			return err
+		}
+		if settings, ok := value.(map[string]interface{}); ok {
+			return printYAML(settings)
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var viewConfigCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Print the effective configuration",
+	Long: `Print the configuration as YAML (or JSON with --output json) after environment
+variables, defaults and the active context are applied. Use --redact before sharing it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := readConfig(); err != nil {
+			return err
+		}
+		settings := viper.AllSettings()
+		if viewRedact {
+			redactSettings(settings)
+		}
+		if printed, err := printStructured(settings); printed {
+			return err
+		}
+		return printYAML(settings)
+	},
+}
+
+var validateConfigCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check a configuration file for mistakes",
+	Long: `Check a configuration file without contacting any BMC: unknown keys (with a suggestion
+for typos), values of the wrong type, ports out of range, unsupported BMC types and output
+formats, a current_context that is not defined, and hosts configured in more than one
+section. Encrypted files are decrypted first.
+
+Example:
+  bmc-cli config validate
+  bmc-cli config validate inventory.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFileArg(args)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading config file: %w", err)
+		}
+
+		problems, err := validateConfigFile(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if printed, err := printStructured(problems); printed {
+			if err == nil && len(problems) > 0 {
+				err = fmt.Errorf("%d problem(s) found in %s", len(problems), path)
+			}
+			return err
+		}
+
+		for _, problem := range problems {
+			fmt.Printf("%s:%d: %s: %s\n", path, problem.Line, problem.Key, problem.Message)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%d problem(s) found in %s", len(problems), path)
+		}
+		fmt.Printf("%s is valid\n", path)
+		return nil
+	},
+}
+
+// describePasswordSource says where a password comes from without revealing it
+func describePasswordSource(password string, source PasswordSource) string {
+	switch {
+	case password != "":
+		return "***configured***"
+	case source.PasswordFile != "":
+		return "from password_file " + source.PasswordFile
+	case source.PasswordCmd != "":
+		return "from password_cmd"
+	case source.PasswordEnv != "":
+		return "from $" + source.PasswordEnv
+	}
+	return "from the keyring"
+}
+
+// lookupSetting returns the value of a dotted key in a settings tree, or nil
+func lookupSetting(settings map[string]interface{}, key string) interface{} {
+	var value interface{} = settings
+	for _, k := range strings.Split(strings.ToLower(key), ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[k]
+	}
+	return value
+}
+
+func valueOrNotSet(value string) string {
+	if value == "" {
+		return "(not set)"
+	}
+	return value
+}
+
+var encryptConfigCmd = &cobra.Command{
+	Use:   "encrypt [file]",
+	Short: "Encrypt a configuration file with age",
+	Long: `Encrypt a configuration file so it can be committed without exposing BMC passwords.
+
+By default the file is encrypted in the SOPS format: only the values of keys matching
+--encrypted-regex are replaced with ENC[...] values, so hosts and usernames stay readable
+and diffable, and the file can also be decrypted with the sops tool. An empty regex
+encrypts every value except those under keys ending in _unencrypted. With --whole-file the
+file is encrypted as a single armored age payload instead.
+
+The recipients default to the public keys of the age identities in $SOPS_AGE_KEY,
+$SOPS_AGE_KEY_FILE or the SOPS default key file.
+
+Example:
+  bmc-cli config encrypt -i config.yaml
+  bmc-cli config encrypt -r age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p config.yaml > config.enc.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFileArg(args)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading config file: %w", err)
+		}
+		if isAgeEncrypted(data) || bytes.Contains(data, []byte("ENC[")) {
+			return fmt.Errorf("%s is already encrypted", path)
+		}
+
+		recipients := encryptRecipients
+		if len(recipients) == 0 {
+			if recipients, err = defaultAgeRecipients(); err != nil {
+				return fmt.Errorf("no --recipient given: %w", err)
+			}
+		}
+
+		encrypted, err := encryptConfig(data, configEncryption{
+			WholeFile:      encryptWholeFile,
+			Recipients:     recipients,
+			EncryptedRegex: encryptEncryptedRegex,
+		})
+		if err != nil {
+			return err
+		}
+		return writeConfigOutput(path, encrypted)
+	},
+}
+
+var decryptConfigCmd = &cobra.Command{
+	Use:   "decrypt [file]",
+	Short: "Decrypt an encrypted configuration file",
+	Long: `Decrypt an age or SOPS encrypted configuration file and print it, or replace the
+file with its plaintext with --in-place.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFileArg(args)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading config file: %w", err)
+		}
+
+		plaintext, encryption, err := decryptConfig(data)
+		if err != nil {
+			return err
+		}
+		if encryption == nil {
+			return fmt.Errorf("%s is not encrypted", path)
+		}
+		return writeConfigOutput(path, plaintext)
+	},
+}
+
+var editConfigCmd = &cobra.Command{
+	Use:   "edit [file]",
+	Short: "Edit an encrypted configuration file",
+	Long: `Decrypt a configuration file to a private temporary file, open it in $EDITOR and
+encrypt the result again for the same recipients. The plaintext never replaces the
+encrypted file.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFileArg(args)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading config file: %w", err)
+		}
+
+		plaintext, encryption, err := decryptConfig(data)
+		if err != nil {
+			return err
+		}
+		if encryption == nil {
+			return fmt.Errorf("%s is not encrypted (run 'bmc-cli config encrypt' first)", path)
+		}
+
+		edited, err := editInEditor(plaintext)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(edited, plaintext) {
+			fmt.Fprintln(os.Stderr, "No changes made")
+			return nil
+		}
+		var parsed yaml.Node
+		if err := yaml.Unmarshal(edited, &parsed); err != nil {
+			return fmt.Errorf("edited config is not valid YAML, changes discarded: %w", err)
+		}
+
+		encrypted, err := encryptConfig(edited, *encryption)
+		if err != nil {
+			return err
+		}
+		return writeFilePreservingMode(path, encrypted)
+	},
+}
+
+// configFileArg returns the file named on the command line, or the default config file
+func configFileArg(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	if path := configFilePath(); path != "" {
+		return path, nil
+	}
+	return "", fmt.Errorf("no config file found (pass a file or use --config)")
+}
+
+// writeConfigOutput replaces path with data for --in-place, or prints data
+func writeConfigOutput(path string, data []byte) error {
+	if cryptInPlace {
+		return writeFilePreservingMode(path, data)
+	}
+	_, err := os.Stdout.Write(data)
+	return err
+}
+
+// writeFilePreservingMode overwrites an existing file, keeping its permissions
+func writeFilePreservingMode(path string, data []byte) error {
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	return nil
+}
+
+// editInEditor opens data in $EDITOR (default vi) through a private temporary file and
+// returns the edited contents; the temporary file is removed afterwards
+func editInEditor(data []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "bmc-cli-edit-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		return nil, err
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	// Run through the shell so EDITOR may carry arguments, such as "code --wait"
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", file)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor failed: %w", err)
+	}
+
+	return os.ReadFile(file)
+}
+
 func init() {
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(generateConfigCmd)
 	configCmd.AddCommand(showConfigCmd)
+	configCmd.AddCommand(useConfigCmd)
+	configCmd.AddCommand(setConfigCmd)
+	configCmd.AddCommand(getConfigCmd)
+	configCmd.AddCommand(viewConfigCmd)
+	configCmd.AddCommand(validateConfigCmd)
+	configCmd.AddCommand(encryptConfigCmd)
+	configCmd.AddCommand(decryptConfigCmd)
+	configCmd.AddCommand(editConfigCmd)
+
+	encryptConfigCmd.Flags().StringArrayVarP(&encryptRecipients, "recipient", "r", nil, "age recipient public key (repeatable)")
+	encryptConfigCmd.Flags().StringVar(&encryptEncryptedRegex, "encrypted-regex", defaultEncryptedRegex, "encrypt the values of keys matching this regex (empty encrypts every value)")
+	encryptConfigCmd.Flags().BoolVar(&encryptWholeFile, "whole-file", false, "encrypt the whole file as one age payload instead of per value")
+	encryptConfigCmd.Flags().BoolVarP(&cryptInPlace, "in-place", "i", false, "overwrite the file instead of printing the result")
+	viewConfigCmd.Flags().BoolVar(&viewRedact, "redact", false, "replace passwords and proxy credentials with REDACTED")
+	decryptConfigCmd.Flags().BoolVarP(&cryptInPlace, "in-place", "i", false, "overwrite the file instead of printing the result")
 }
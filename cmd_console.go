@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	consolePort    int
+	consoleCommand string
+	consoleEscape  string
+	consoleLog     string
+
+	consoleUntil string
+	consoleWait  time.Duration
+	consoleOut   string
+)
+
+var consoleCmd = &cobra.Command{
+	Use:   "console",
+	Short: "Attach to the host serial console through the BMC's SSH interface",
+	Long: `Open an SSH session to the BMC with the configured credentials and attach to the host
+serial console (serial over LAN), with the terminal in raw mode. The command that attaches
+depends on the BMC type:
+
+  ilo         vsp
+  idrac       console com2
+  supermicro  start /system1/sol1
+  xcc         console 1
+  openbmc     (the console is served directly on SSH port 2200)
+
+Type ~. at the start of a line to detach (~~ sends a ~). The BMC's SSH host key is pinned
+in the known hosts file on first use.
+
+Example:
+  bmc-cli console
+  bmc-cli --host r740-01 console --log r740-01.log
+  bmc-cli console --command "connect com2" --escape-char '^'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		escape, err := parseEscapeChar(consoleEscape)
+		if err != nil {
+			return err
+		}
+
+		var out io.Writer = os.Stdout
+		if consoleLog != "" {
+			logFile, err := os.OpenFile(consoleLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+			if err != nil {
+				return fmt.Errorf("error opening console log: %w", err)
+			}
+			defer logFile.Close()
+			out = io.MultiWriter(os.Stdout, logFile)
+		}
+
+		opts := consoleOptions{Port: consolePort, Command: consoleCommand}
+		if width, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			opts.Width, opts.Height = width, height
+		}
+		console, err := config.openConsole(cmd.Context(), opts)
+		if err != nil {
+			return err
+		}
+		defer console.Close()
+
+		fmt.Fprintf(os.Stderr, "Connected to the serial console through %s", console.Address)
+		if console.Command != "" {
+			fmt.Fprintf(os.Stderr, " (%s)", console.Command)
+		}
+		if escape != 0 {
+			fmt.Fprintf(os.Stderr, ". Type %c. at the start of a line to detach.", escape)
+		}
+		fmt.Fprintln(os.Stderr)
+
+		stdin := int(os.Stdin.Fd())
+		if term.IsTerminal(stdin) {
+			state, err := term.MakeRaw(stdin)
+			if err != nil {
+				return fmt.Errorf("error switching the terminal to raw mode: %w", err)
+			}
+			defer term.Restore(stdin, state)
+		}
+
+		err = attachConsole(cmd.Context(), console, os.Stdin, out, escape)
+		// Raw mode disables the newline translation, so return to the start of the line
+		fmt.Fprint(os.Stderr, "\r\nConsole closed\r\n")
+		return err
+	},
+}
+
+var consoleLogCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Save the host serial console to a file until a pattern appears",
+	Long: `Stream the host serial console to a file (or standard output) without a terminal,
+one timestamped line at a time with terminal control sequences removed, and exit when a
+line matches --until (a regular expression; the partial last line, such as a login prompt,
+is matched too) or --wait expires. The command fails on a timeout or when the console
+closes before --until matched, so CI pipelines can wait for an OS installation to finish.
+Without --until it logs until --wait expires, which is then not an error.
+
+Example:
+  bmc-cli --host r740-01 console log --until "login:" --wait 30m --out r740-01.log
+  bmc-cli console log --until "Installation (complete|finished)" --wait 1h`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var until *regexp.Regexp
+		if consoleUntil != "" {
+			var err error
+			if until, err = regexp.Compile(consoleUntil); err != nil {
+				return fmt.Errorf("invalid --until pattern: %w", err)
+			}
+		}
+		if consoleWait <= 0 {
+			return fmt.Errorf("--wait must be positive")
+		}
+
+		var out io.Writer = os.Stdout
+		if consoleOut != "" && consoleOut != "-" {
+			file, err := os.OpenFile(consoleOut, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+			if err != nil {
+				return fmt.Errorf("error opening console log: %w", err)
+			}
+			defer file.Close()
+			out = file
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), consoleWait)
+		defer cancel()
+		console, err := config.openConsole(ctx, consoleOptions{Port: consolePort, Command: consoleCommand})
+		if err != nil {
+			return err
+		}
+		defer console.Close()
+		fmt.Fprintf(os.Stderr, "Logging the serial console through %s\n", console.Address)
+
+		started := time.Now()
+		err = logConsole(ctx, console, newConsoleLogger(out, until))
+		elapsed := time.Since(started).Round(time.Second)
+		switch {
+		case err == nil:
+			fmt.Fprintf(os.Stderr, "Matched %q after %s\n", consoleUntil, elapsed)
+			return nil
+		case errors.Is(err, context.DeadlineExceeded) && cmd.Context().Err() == nil:
+			if until == nil {
+				return nil
+			}
+			return fmt.Errorf("timed out after %s waiting for %q", consoleWait, consoleUntil)
+		case errors.Is(err, io.EOF):
+			if until == nil {
+				return fmt.Errorf("console closed after %s", elapsed)
+			}
+			return fmt.Errorf("console closed after %s before %q appeared", elapsed, consoleUntil)
+		}
+		return err
+	},
+}
+
+// parseEscapeChar parses --escape-char: a single character, ^X for a control character or
+// none to disable detaching
+func parseEscapeChar(value string) (byte, error) {
+	switch {
+	case value == "none":
+		return 0, nil
+	case len(value) == 1:
+		return value[0], nil
+	case len(value) == 2 && value[0] == '^' && value[1] >= '@' && value[1] <= '_':
+		return value[1] - '@', nil
+	}
+	return 0, fmt.Errorf("invalid escape character %q (use a single character, ^X or none)", value)
+}
+
+func init() {
+	rootCmd.AddCommand(consoleCmd)
+	consoleCmd.AddCommand(consoleLogCmd)
+	consoleCmd.PersistentFlags().IntVar(&consolePort, "ssh-port", 0, "SSH port of the BMC (default 22, 2200 for OpenBMC)")
+	consoleCmd.PersistentFlags().StringVar(&consoleCommand, "command", "", "BMC CLI command that attaches to the serial console (default depends on the BMC type)")
+	consoleCmd.Flags().StringVar(&consoleEscape, "escape-char", "~", "escape character for detaching (~. at the start of a line), ^X, or none")
+	consoleCmd.Flags().StringVar(&consoleLog, "log", "", "append the console output to a file")
+	consoleLogCmd.Flags().StringVar(&consoleUntil, "until", "", "regular expression that ends logging when a console line matches it")
+	consoleLogCmd.Flags().DurationVar(&consoleWait, "wait", 30*time.Minute, "how long to wait for --until")
+	consoleLogCmd.Flags().StringVarP(&consoleOut, "out", "o", "", "append the log to a file instead of standard output")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var credsCmd = &cobra.Command{
+	Use:   "creds",
+	Short: "Manage BMC passwords in the OS keyring",
+	Long: `Store BMC passwords in the OS keyring (the Secret Service on Linux, the Keychain on
+macOS, the Credential Manager on Windows). A BMC without a password, password_file,
+password_cmd or password_env in the configuration uses the password stored for its host.`,
+	Annotations: map[string]string{skipConfigAnnotation: "true"},
+}
+
+var credsSetCmd = &cobra.Command{
+	Use:   "set [host]",
+	Short: "Store the password for a BMC host",
+	Long: `Store the password for a BMC host in the OS keyring. The password is prompted for
+without echo, or read from the first line of stdin when it is not a terminal.
+
+Example:
+  bmc-cli creds set 192.168.1.101
+  pass show bmc/r740 | bmc-cli creds set 192.168.1.101`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		password, err := readPassword(fmt.Sprintf("Password for %s: ", args[0]))
+		if err != nil {
+			return err
+		}
+		if password == "" {
+			return fmt.Errorf("password must not be empty")
+		}
+
+		if err := storePassword(args[0], password); err != nil {
+			return err
+		}
+		fmt.Printf("Password for %s stored in the keyring\n", args[0])
+		return nil
+	},
+}
+
+var credsDeleteCmd = &cobra.Command{
+	Use:   "delete [host]",
+	Short: "Remove the stored password for a BMC host",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := deletePassword(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Password for %s removed from the keyring\n", args[0])
+		return nil
+	},
+}
+
+// readPassword prompts for a password on the terminal, or reads a line from piped stdin
+func readPassword(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("error reading password: %w", err)
+		}
+		return string(password), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("error reading password from stdin: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func init() {
+	rootCmd.AddCommand(credsCmd)
+	credsCmd.AddCommand(credsSetCmd)
+	credsCmd.AddCommand(credsDeleteCmd)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	dumpOut         string
+	dumpWorkers     int
+	dumpIncludeLogs bool
+	dumpTar         bool
+)
+
+var dumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Dump the Redfish resource tree to disk",
+	Long: `Crawl every resource reachable from the Redfish service root and write it to disk in
+the DMTF mockup layout (<out>/redfish/v1/.../index.json), for offline analysis, vendor
+support cases or seeding tests. Log entries are skipped unless --include-logs is given.
+Passwords and tokens are redacted.
+
+Example:
+  bmc-cli dump --out ./r740-dump --tar`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if dumpOut == "" {
+			return fmt.Errorf("--out is required")
+		}
+
+		client, err := NewBMCClient()
+		if err != nil {
+			return fmt.Errorf("failed to create BMC client: %w", err)
+		}
+		defer closeClient(client)
+
+		requester, ok := client.(RedfishRequester)
+		if !ok {
+			return fmt.Errorf("dumping is not supported for BMC type %s", config.BMCType)
+		}
+
+		dumper := &redfishDumper{
+			requester:   requester,
+			outDir:      dumpOut,
+			workers:     dumpWorkers,
+			includeLogs: dumpIncludeLogs,
+		}
+		if err := dumper.dump(cmd.Context(), "/redfish/v1"); err != nil {
+			return err
+		}
+
+		fmt.Printf("Wrote %d resources to %s\n", dumper.written, dumpOut)
+		for _, failure := range dumper.failures {
+			fmt.Printf("Skipped %s\n", failure)
+		}
+
+		if dumpTar {
+			dir, archive, err := archivePath(dumpOut)
+			if err != nil {
+				return err
+			}
+			if err := writeTarGz(dir, archive); err != nil {
+				return fmt.Errorf("failed to create archive: %w", err)
+			}
+			fmt.Printf("Created %s\n", archive)
+		}
+		return nil
+	},
+}
+
+// redfishDumper crawls a Redfish service with bounded concurrency, visiting each resource once
+type redfishDumper struct {
+	requester   RedfishRequester
+	outDir      string
+	workers     int
+	includeLogs bool
+
+	mu       sync.Mutex
+	visited  map[string]bool
+	written  int
+	failures []string
+}
+
+// dump crawls the tree starting at root. The root is fetched before any concurrent
+// requests so clients that log in lazily establish their session only once. Once ctx is
+// canceled no new requests are started and the error is returned after the workers finish.
+func (d *redfishDumper) dump(ctx context.Context, root string) error {
+	d.visited = map[string]bool{root: true}
+	if d.workers < 1 {
+		d.workers = 1
+	}
+
+	links, err := d.fetch(ctx, root)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, d.workers)
+
+	var crawl func(path string)
+	crawl = func(path string) {
+		defer wg.Done()
+
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			return
+		}
+		links, err := d.fetch(ctx, path)
+		<-sem
+
+		if err != nil {
+			d.mu.Lock()
+			d.failures = append(d.failures, fmt.Sprintf("%s: %v", path, err))
+			d.mu.Unlock()
+			return
+		}
+		d.follow(links, &wg, crawl)
+	}
+	d.follow(links, &wg, crawl)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	sort.Strings(d.failures)
+	return nil
+}
+
+// follow starts a crawl of every link that has not been visited yet
+func (d *redfishDumper) follow(links []string, wg *sync.WaitGroup, crawl func(string)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, link := range links {
+		if d.visited[link] || !d.shouldVisit(link) {
+			continue
+		}
+		d.visited[link] = true
+		wg.Add(1)
+		go crawl(link)
+	}
+}
+
+// shouldVisit keeps the crawl inside the Redfish tree and skips log entries unless
+// requested. Links are cleaned by fetch, so dot segments cannot lead out of the tree.
+func (d *redfishDumper) shouldVisit(endpoint string) bool {
+	if !strings.HasPrefix(endpoint, "/redfish/v1/") {
+		return false
+	}
+	if !d.includeLogs && strings.Contains(endpoint, "/LogServices/") && strings.Contains(endpoint, "/Entries") {
+		return false
+	}
+	return true
+}
+
+// fetch retrieves a resource, writes it to disk and returns the links it contains
+func (d *redfishDumper) fetch(ctx context.Context, endpoint string) ([]string, error) {
+	file, err := d.outputFile(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.requester.RedfishRequest(ctx, http.MethodGet, endpoint, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var resource map[string]interface{}
+	if err := json.Unmarshal(body, &resource); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(file, []byte(prettyJSON(redactBody(body))+"\n"), 0644); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.written++
+	d.mu.Unlock()
+
+	var links []string
+	for _, link := range resourceLinks(resource) {
+		// References into a resource (Thermal#/Fans/0) are part of the resource itself
+		target, _, _ := strings.Cut(link.Path, "#")
+		links = append(links, path.Clean(target))
+	}
+	return links, nil
+}
+
+// outputFile returns where a resource is written in the mockup layout, refusing paths
+// that would end up outside the output directory
+func (d *redfishDumper) outputFile(endpoint string) (string, error) {
+	dir := filepath.Join(d.outDir, filepath.FromSlash(strings.TrimPrefix(endpoint, "/")))
+	if rel, err := filepath.Rel(d.outDir, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("resource path %s leads outside the output directory", endpoint)
+	}
+	return filepath.Join(dir, "index.json"), nil
+}
+
+// archivePath returns the absolute path of dir and of the tarball written next to it. For
+// --out . the tarball goes to the parent directory, so the archive never contains itself.
+func archivePath(dir string) (string, string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("error resolving %s: %w", dir, err)
+	}
+	parent := filepath.Dir(abs)
+	if parent == abs {
+		return "", "", fmt.Errorf("cannot archive %s: it has no parent directory to write the archive to", abs)
+	}
+	return abs, filepath.Join(parent, filepath.Base(abs)+".tar.gz"), nil
+}
+
+// writeTarGz archives dir into a gzip-compressed tarball rooted at the directory name
+func writeTarGz(dir, archive string) error {
+	f, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	base := filepath.Dir(filepath.Clean(dir))
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func init() {
+	rootCmd.AddCommand(dumpCmd)
+	dumpCmd.Flags().StringVarP(&dumpOut, "out", "o", "", "directory to write the dump to")
+	dumpCmd.Flags().IntVar(&dumpWorkers, "workers", 4, "number of concurrent requests")
+	dumpCmd.Flags().BoolVar(&dumpIncludeLogs, "include-logs", false, "also dump log service entries")
+	dumpCmd.Flags().BoolVar(&dumpTar, "tar", false, "also write the dump as <out>.tar.gz")
+}
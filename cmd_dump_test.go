@@ -0,0 +1,207 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRedfishDumper_MockupLayout(t *testing.T) {
+	_, server := newMockBMCServer(t, "idrac9")
+
+	// Create client with test server URL
+	client := &IDRACClient{
+		baseURL:    server.URL,
+		username:   "admin",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	out := filepath.Join(t.TempDir(), "dump")
+	dumper := &redfishDumper{requester: client, outDir: out, workers: 3}
+	if err := dumper.dump(context.Background(), "/redfish/v1"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for _, path := range []string{
+		"redfish/v1",
+		"redfish/v1/Systems/System.Embedded.1",
+		"redfish/v1/Systems/System.Embedded.1/Bios/Settings",
+		"redfish/v1/Managers/iDRAC.Embedded.1/VirtualMedia/CD",
+		"redfish/v1/TaskService/Tasks",
+	} {
+		if _, err := os.Stat(filepath.Join(out, path, "index.json")); err != nil {
+			t.Errorf("Expected %s/index.json to be written: %v", path, err)
+		}
+	}
+
+	// Every resource is fetched exactly once even though several link to each other; the
+	// emulator has no SessionService resource, which is reported rather than aborting the dump
+	if dumper.written+len(dumper.failures) != len(dumper.visited) {
+		t.Errorf("Expected %d resources visited, got %d written and %d failed", len(dumper.visited), dumper.written, len(dumper.failures))
+	}
+	if len(dumper.failures) != 1 {
+		t.Errorf("Expected only SessionService to fail, got: %v", dumper.failures)
+	}
+}
+
+func TestRedfishDumper_SkipsLogEntries(t *testing.T) {
+	var mu sync.Mutex
+	requested := map[string]int{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested[r.URL.Path]++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/redfish/v1":
+			_, _ = w.Write([]byte(`{"@odata.id":"/redfish/v1","Managers":{"@odata.id":"/redfish/v1/Managers/1"}}`))
+		case "/redfish/v1/Managers/1":
+			// Links back to the root and into itself must not loop
+			_, _ = w.Write([]byte(`{"@odata.id":"/redfish/v1/Managers/1","Links":{"Root":{"@odata.id":"/redfish/v1"}},
+				"LogServices":{"@odata.id":"/redfish/v1/Managers/1/LogServices/IEL"},
+				"Thermal":{"@odata.id":"/redfish/v1/Managers/1#/Fans/0"}}`))
+		case "/redfish/v1/Managers/1/LogServices/IEL":
+			_, _ = w.Write([]byte(`{"@odata.id":"/redfish/v1/Managers/1/LogServices/IEL","Entries":{"@odata.id":"/redfish/v1/Managers/1/LogServices/IEL/Entries"}}`))
+		case "/redfish/v1/Managers/1/LogServices/IEL/Entries":
+			_, _ = w.Write([]byte(`{"@odata.id":"/redfish/v1/Managers/1/LogServices/IEL/Entries","Members":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &ILOClient{
+		baseURL:    server.URL,
+		username:   "admin",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	dumper := &redfishDumper{requester: client, outDir: t.TempDir(), workers: 2}
+	if err := dumper.dump(context.Background(), "/redfish/v1"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if requested["/redfish/v1/Managers/1/LogServices/IEL/Entries"] != 0 {
+		t.Error("Expected log entries to be skipped")
+	}
+	for path, count := range requested {
+		if count != 1 {
+			t.Errorf("Expected %s to be requested once, got: %d", path, count)
+		}
+	}
+
+	requested = map[string]int{}
+	dumper = &redfishDumper{requester: client, outDir: t.TempDir(), workers: 2, includeLogs: true}
+	if err := dumper.dump(context.Background(), "/redfish/v1"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if requested["/redfish/v1/Managers/1/LogServices/IEL/Entries"] != 1 {
+		t.Error("Expected log entries to be dumped with includeLogs")
+	}
+}
+
+func TestRedfishDumper_StaysInOutputDirectory(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"@odata.id":"/redfish/v1","Escape":{"@odata.id":"/redfish/v1/../../../escape"},
+			"Systems":{"@odata.id":"/redfish/v1/Systems/../Systems/1/"}}`))
+	}))
+	defer server.Close()
+
+	client := &IDRACClient{baseURL: server.URL, username: "admin", password: "password", httpClient: server.Client()}
+	dir := t.TempDir()
+	dumper := &redfishDumper{requester: client, outDir: filepath.Join(dir, "dump"), workers: 2}
+	if err := dumper.dump(context.Background(), "/redfish/v1"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	sort.Strings(requested)
+	if strings.Join(requested, " ") != "/redfish/v1 /redfish/v1/Systems/1" {
+		t.Errorf("Expected only cleaned links inside the tree to be fetched, got: %v", requested)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing written outside the output directory: %v", err)
+	}
+	if _, err := dumper.outputFile("/redfish/v1/../../../escape"); err == nil {
+		t.Error("Expected an error for a path leading outside the output directory")
+	}
+}
+
+func TestArchivePath(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{filepath.Join(root, "dump"), filepath.Join(root, "dump") + string(filepath.Separator), filepath.Join(root, "dump", ".")} {
+		abs, archive, err := archivePath(dir)
+		if err != nil || abs != filepath.Join(root, "dump") || archive != filepath.Join(root, "dump.tar.gz") {
+			t.Errorf("%s: expected the archive next to the directory, got %s and %s (%v)", dir, abs, archive, err)
+		}
+	}
+
+	// --out . archives the working directory into its parent
+	t.Chdir(root)
+	wd, _ := os.Getwd()
+	if _, archive, err := archivePath("."); err != nil || archive != wd+".tar.gz" {
+		t.Errorf("Expected %s.tar.gz, got %s (%v)", wd, archive, err)
+	}
+
+	if _, _, err := archivePath(string(filepath.Separator)); err == nil {
+		t.Error("Expected error archiving the root directory")
+	}
+}
+
+func TestWriteTarGz(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dump")
+	if err := os.MkdirAll(filepath.Join(dir, "redfish", "v1"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "redfish", "v1", "index.json"), []byte("{}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	archive := dir + ".tar.gz"
+	if err := writeTarGz(dir, archive); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read gzip: %v", err)
+	}
+
+	found := false
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		if header.Name == "dump/redfish/v1/index.json" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected dump/redfish/v1/index.json in the archive")
+	}
+}
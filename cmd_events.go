@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	eventsDestination string
+	eventsTypes       []string
+	eventsContext     string
+
+	eventsListen  string
+	eventsCert    string
+	eventsKey     string
+	eventsForward string
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Redfish event subscription commands",
+	Long: `Subscribe to Redfish events so the BMC pushes alerts and status changes to a receiver,
+instead of polling it, and run such a receiver with events listen.`,
+}
+
+var eventsSubscribeCmd = &cobra.Command{
+	Use:   "subscribe",
+	Short: "Subscribe a destination to the BMC's events",
+	Long: `Register an event destination on the BMC's Redfish EventService. The BMC posts the
+selected event types to the destination as they happen. Most BMCs only accept HTTPS
+destinations and do not verify their certificate.
+
+Example:
+  bmc-cli events subscribe --destination https://10.0.0.5:8443/hook --types Alert,StatusChange
+  bmc-cli --host r740-01 events subscribe --destination https://10.0.0.5:8443/hook --event-context r740-01`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if eventsDestination == "" {
+			return fmt.Errorf("--destination is required")
+		}
+		service, cleanup, err := newEventService()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		subscription, err := service.subscribe(cmd.Context(), eventsDestination, eventsTypes, eventsContext)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe: %w", err)
+		}
+		if printed, err := printStructured(subscription); printed {
+			return err
+		}
+		fmt.Printf("Created subscription %s for %s events to %s\n", subscription.ID, strings.Join(subscription.EventTypes, ", "), subscription.Destination)
+		return nil
+	},
+}
+
+var eventsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the BMC's event subscriptions",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, cleanup, err := newEventService()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		subscriptions, err := service.list(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to list subscriptions: %w", err)
+		}
+		if printed, err := printStructured(subscriptions); printed {
+			return err
+		}
+
+		if len(subscriptions) == 0 {
+			fmt.Println("No event subscriptions")
+			return nil
+		}
+		fmt.Printf("%-6s %-40s %-28s %s\n", "ID", "Destination", "Event Types", "Context")
+		fmt.Println("---------------------------------------------------------------------------------")
+		for _, subscription := range subscriptions {
+			line := fmt.Sprintf("%-6s %-40s %-28s %s", subscription.ID, subscription.Destination, strings.Join(subscription.EventTypes, ","), subscription.Context)
+			fmt.Println(strings.TrimRight(line, " "))
+		}
+		return nil
+	},
+}
+
+var eventsDeleteCmd = &cobra.Command{
+	Use:   "delete <id>...",
+	Short: "Delete event subscriptions",
+	Long: `Delete event subscriptions by the Id shown by events list (or their resource path).
+
+Example:
+  bmc-cli events delete 3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, cleanup, err := newEventService()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		for _, id := range args {
+			if err := service.delete(cmd.Context(), id); err != nil {
+				return err
+			}
+			fmt.Printf("Deleted subscription %s\n", id)
+		}
+		return nil
+	},
+}
+
+var eventsListenCmd = &cobra.Command{
+	Use:   "listen",
+	Short: "Receive Redfish events over HTTPS",
+	Long: `Run an HTTPS receiver for the events BMCs post to their subscriptions, on any path. Each
+event record is printed to standard output as a JSON line with the time it was received,
+the address of the BMC that sent it and the subscription's context:
+
+  {"received":"2026-10-18T09:30:00Z","source":"10.0.0.21","context":"r740-01","event":{"EventType":"Alert",...}}
+
+With --forward each line is posted to a URL instead, such as a chat or alerting webhook.
+When forwarding fails the BMC is answered with an error, so it retries the event later.
+
+Without --cert and --key a self-signed certificate is generated, which BMCs accept as
+they do not verify the destination's certificate by default.
+
+Example:
+  bmc-cli events listen --listen :8443
+  bmc-cli events listen --listen :8443 --cert hook.crt --key hook.key --forward http://alerts.lab:9000/redfish`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{skipConfigAnnotation: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if (eventsCert == "") != (eventsKey == "") {
+			return fmt.Errorf("--cert and --key must be given together")
+		}
+
+		var tlsConfig *tls.Config
+		if eventsCert == "" {
+			var err error
+			if tlsConfig, err = selfSignedTLSConfig(eventsListen, "bmc-cli events"); err != nil {
+				return fmt.Errorf("failed to create TLS certificate: %w", err)
+			}
+		}
+
+		server := &http.Server{
+			Addr:              eventsListen,
+			Handler:           newEventReceiver(os.Stdout, eventsForward),
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		context.AfterFunc(cmd.Context(), func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = server.Shutdown(ctx)
+		})
+
+		// Standard output carries the events, so the status goes to standard error
+		fmt.Fprintf(os.Stderr, "Listening for Redfish events on https://%s\n", eventsListen)
+		err := server.ListenAndServeTLS(eventsCert, eventsKey)
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	},
+}
+
+// newEventService creates an event service client for the configured BMC and returns a
+// function releasing its session
+func newEventService() (*eventService, func(), error) {
+	client, err := NewBMCClient()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create BMC client: %w", err)
+	}
+
+	requester, ok := client.(RedfishRequester)
+	if !ok {
+		closeClient(client)
+		return nil, nil, fmt.Errorf("event subscriptions are not supported for BMC type %s", config.BMCType)
+	}
+	return &eventService{requester: requester}, func() { closeClient(client) }, nil
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+	eventsCmd.AddCommand(eventsSubscribeCmd, eventsListCmd, eventsDeleteCmd, eventsListenCmd)
+	eventsSubscribeCmd.Flags().StringVar(&eventsDestination, "destination", "", "URL the BMC posts events to")
+	eventsSubscribeCmd.Flags().StringSliceVar(&eventsTypes, "types", []string{"Alert"}, "event types to subscribe to (such as Alert,StatusChange)")
+	eventsSubscribeCmd.Flags().StringVar(&eventsContext, "event-context", "", "opaque string the BMC includes in every event, such as the host name")
+	eventsListenCmd.Flags().StringVar(&eventsListen, "listen", ":8443", "address to listen on")
+	eventsListenCmd.Flags().StringVar(&eventsCert, "cert", "", "TLS certificate file (default: a generated self-signed certificate)")
+	eventsListenCmd.Flags().StringVar(&eventsKey, "key", "", "TLS private key file")
+	eventsListenCmd.Flags().StringVar(&eventsForward, "forward", "", "post each event as JSON to this URL instead of printing it")
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	hostsSelector    string
+	importFormat     string
+	importMappings   []string
+	importLabels     []string
+	importType       string
+	importReplace    bool
+	importDryRun     bool
+	addHostAddress   string
+	addHostType      string
+	addHostPort      int
+	addHostUsername  string
+	addHostGroups    []string
+	addHostLabels    []string
+	addHostPasswdEnv string
+)
+
+// Inventory commands read the configuration themselves, like the config commands, so the
+// inventory can be built before any BMC is configured
+var hostsCmd = &cobra.Command{
+	Use:   "hosts",
+	Short: "Manage the host inventory",
+	Long: `Manage the inventory of BMCs kept under hosts: in the config file. Settings a host does
+not set come from the vendor section of its BMC type, so credentials shared by many BMCs
+are configured once:
+
+  idrac:
+    username: root
+    password_cmd: pass show bmc/idrac
+  hosts:
+    - name: r740-01
+      bmc_type: idrac
+      address: 10.6.75.19
+      groups: [compute]
+      labels:
+        rack: r12
+
+Commands select a host with --host; 'check' and 'hosts list' select hosts with a selector.`,
+	Annotations: map[string]string{skipConfigAnnotation: "true"},
+}
+
+var hostsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List inventory hosts",
+	Long: `List the inventory hosts, optionally filtered by a selector: comma-separated terms
+key=value, key!=value, key or !key. The keys name (a glob pattern), bmc_type and group match
+the host itself; other keys match labels. The active context's selector is the default.
+
+Example:
+  bmc-cli hosts list
+  bmc-cli hosts list -l rack=r12,group=gpu
+  bmc-cli hosts list -l 'name=r740-*' --output json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := readConfig(); err != nil {
+			return err
+		}
+		hosts, err := config.selectHosts(hostsSelector)
+		if err != nil {
+			return err
+		}
+		views := make([]hostView, 0, len(hosts))
+		for _, h := range hosts {
+			views = append(views, h.view())
+		}
+		if ok, err := printStructured(views); ok {
+			return err
+		}
+
+		if len(hosts) == 0 {
+			fmt.Println("No hosts")
+			return nil
+		}
+		fmt.Printf("%-20s %-10s %-22s %-20s %s\n", "Name", "Type", "Address", "Groups", "Labels")
+		for _, h := range hosts {
+			bmcType := h.BMCType
+			if bmcType == "" {
+				bmcType = baseConfig.BMCType
+			}
+			address := h.Address
+			if h.Port != 0 {
+				address = bmcAddress(h.Address, h.Port)
+			}
+			row := fmt.Sprintf("%-20s %-10s %-22s %-20s %s", h.Name, bmcType, address, strings.Join(h.Groups, ","), sortedLabels(h.Labels))
+			fmt.Println(strings.TrimRight(row, " "))
+		}
+		return nil
+	},
+}
+
+var hostsAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a host to the inventory",
+	Long: `Add a BMC to the inventory of the config file in use (or the per-user config file).
+
+Example:
+  bmc-cli hosts add r740-01 --address 10.6.75.19 --type idrac --group compute --label rack=r12`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if addHostAddress == "" {
+			return fmt.Errorf("--address is required")
+		}
+		bmcType, ok := normalizeBMCType(addHostType)
+		if !ok {
+			return fmt.Errorf("unsupported BMC type: %s (supported types: ilo, idrac, supermicro, xcc, openbmc, ipmi)", addHostType)
+		}
+		if addHostPort < 0 || addHostPort > 65535 {
+			return fmt.Errorf("port %d is out of range (1-65535)", addHostPort)
+		}
+		host := HostConfig{
+			Name:     args[0],
+			BMCType:  bmcType,
+			Address:  addHostAddress,
+			Port:     addHostPort,
+			Username: addHostUsername,
+			Groups:   addHostGroups,
+		}
+		host.PasswordEnv = addHostPasswdEnv
+		for _, label := range addHostLabels {
+			key, value, ok := strings.Cut(label, "=")
+			if !ok || key == "" {
+				return fmt.Errorf("invalid label %q (expected key=value)", label)
+			}
+			setLabel(&host, key, value)
+		}
+
+		path, err := writableConfigFile()
+		if err != nil {
+			return err
+		}
+		if err := addInventoryHosts(path, []HostConfig{host}, inventoryAdd); err != nil {
+			return err
+		}
+		fmt.Printf("Added host %s to %s\n", host.Name, path)
+		return nil
+	},
+}
+
+var hostsRemoveCmd = &cobra.Command{
+	Use:   "remove <name>...",
+	Short: "Remove hosts from the inventory",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := writableConfigFile()
+		if err != nil {
+			return err
+		}
+		if err := removeInventoryHosts(path, args); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s from %s\n", strings.Join(args, ", "), path)
+		return nil
+	},
+}
+
+var hostsImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import hosts from an Ansible inventory, CSV file or NetBox export",
+	Long: `Import BMCs into the inventory from another source of truth. Hosts are merged by name:
+imported hosts replace entries of the same name and other entries are kept, unless
+--replace is given.
+
+Formats and the fields each host setting is read from by default:
+  ansible      INI inventory: inventory_hostname, bmc_address, bmc_type, bmc_port and
+               bmc_username host or group variables; groups (and their parents) become
+               groups
+  csv          header row: name, address, bmc_type, port, username, password_env, groups
+               (separated by ';'); other columns become labels
+  netbox-json  devices from /api/dcim/devices/: name, oob_ip.address and
+               device_type.manufacturer.slug; site, rack and role become labels and tags
+               become groups
+
+--map setting=field reads a setting from another field; --label field[=label] adds a
+label. Vendor names such as dell, hpe or lenovo are accepted as BMC types.
+
+Example:
+  bmc-cli hosts import --from ansible inventory.ini --map address=ansible_host --type idrac
+  bmc-cli hosts import --from csv hosts.csv --dry-run
+  bmc-cli hosts import --from netbox-json devices.json --label platform.slug=platform`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if importFormat == "" {
+			return fmt.Errorf("--from is required (ansible, csv or netbox-json)")
+		}
+		mappings, err := parseFieldMappings(importMappings)
+		if err != nil {
+			return err
+		}
+		labels, err := parseLabelMappings(importLabels)
+		if err != nil {
+			return err
+		}
+		bmcType, ok := normalizeBMCType(importType)
+		if !ok {
+			return fmt.Errorf("unsupported BMC type: %s (supported types: ilo, idrac, supermicro, xcc, openbmc, ipmi)", importType)
+		}
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("error opening inventory: %w", err)
+		}
+		defer file.Close()
+		records, err := readInventory(importFormat, file)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", args[0], err)
+		}
+
+		hosts, warnings := importHosts(records, importOptions{Format: importFormat, Mappings: mappings, Labels: labels, BMCType: bmcType})
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		if len(hosts) == 0 {
+			return fmt.Errorf("no hosts imported from %s", args[0])
+		}
+
+		if importDryRun {
+			return printYAML(map[string][]HostConfig{"hosts": hosts})
+		}
+		path, err := writableConfigFile()
+		if err != nil {
+			return err
+		}
+		mode := inventoryMerge
+		if importReplace {
+			mode = inventoryReplace
+		}
+		if err := addInventoryHosts(path, hosts, mode); err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d hosts into %s\n", len(hosts), path)
+		return nil
+	},
+}
+
+// How addInventoryHosts treats the existing inventory
+const (
+	// inventoryAdd fails if a host of the same name exists
+	inventoryAdd = iota
+	// inventoryMerge replaces hosts of the same name and keeps the others
+	inventoryMerge
+	// inventoryReplace replaces the whole inventory
+	inventoryReplace
+)
+
+// addInventoryHosts adds hosts to the inventory of a config file
+func addInventoryHosts(path string, hosts []HostConfig, mode int) error {
+	return updateConfigFile(path, func(root *yaml.Node) error {
+		inventory := inventoryNode(root)
+		if mode == inventoryReplace {
+			inventory.Content = nil
+		}
+		for _, h := range hosts {
+			var node yaml.Node
+			if err := node.Encode(h); err != nil {
+				return fmt.Errorf("error encoding host %s: %w", h.Name, err)
+			}
+			i := slices.IndexFunc(inventory.Content, func(n *yaml.Node) bool { return inventoryEntryName(n, h.Name) })
+			switch {
+			case i < 0:
+				inventory.Content = append(inventory.Content, &node)
+			case mode != inventoryAdd:
+				inventory.Content[i] = &node
+			default:
+				return fmt.Errorf("host %s already exists (remove it first)", h.Name)
+			}
+		}
+		return nil
+	})
+}
+
+// removeInventoryHosts removes the named hosts from the inventory of a config file; no host
+// is removed unless all of them exist
+func removeInventoryHosts(path string, names []string) error {
+	return updateConfigFile(path, func(root *yaml.Node) error {
+		inventory := inventoryNode(root)
+		for _, name := range names {
+			if !slices.ContainsFunc(inventory.Content, func(n *yaml.Node) bool { return inventoryEntryName(n, name) }) {
+				return fmt.Errorf("host %s not found in %s", name, path)
+			}
+		}
+		inventory.Content = slices.DeleteFunc(inventory.Content, func(n *yaml.Node) bool {
+			return slices.ContainsFunc(names, func(name string) bool { return inventoryEntryName(n, name) })
+		})
+		return nil
+	})
+}
+
+// inventoryNode returns the hosts sequence of a config file, creating it if needed
+func inventoryNode(root *yaml.Node) *yaml.Node {
+	node := mappingValue(root, "hosts")
+	if node == nil || node.Kind != yaml.SequenceNode {
+		node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(root, "hosts", node)
+		node = mappingValue(root, "hosts")
+	}
+	return node
+}
+
+// inventoryEntryName reports whether an inventory entry has the given (case-insensitive) name
+func inventoryEntryName(entry *yaml.Node, name string) bool {
+	value := mappingValue(entry, "name")
+	return value != nil && strings.EqualFold(value.Value, name)
+}
+
+func init() {
+	rootCmd.AddCommand(hostsCmd)
+	hostsCmd.AddCommand(hostsListCmd, hostsAddCmd, hostsRemoveCmd, hostsImportCmd)
+
+	hostsListCmd.Flags().StringVarP(&hostsSelector, "selector", "l", "", "only list hosts matching a selector (such as rack=r12,group=gpu)")
+
+	hostsAddCmd.Flags().StringVar(&addHostAddress, "address", "", "BMC address (hostname or IP)")
+	hostsAddCmd.Flags().StringVar(&addHostType, "type", "", "BMC type (default is bmc_type)")
+	hostsAddCmd.Flags().IntVar(&addHostPort, "port", 0, "BMC port (default from the vendor section)")
+	hostsAddCmd.Flags().StringVar(&addHostUsername, "username", "", "username (default from the vendor section)")
+	hostsAddCmd.Flags().StringVar(&addHostPasswdEnv, "password-env", "", "environment variable holding the password")
+	hostsAddCmd.Flags().StringArrayVar(&addHostGroups, "group", nil, "group the host belongs to (repeatable)")
+	hostsAddCmd.Flags().StringArrayVar(&addHostLabels, "label", nil, "label as key=value (repeatable)")
+
+	hostsImportCmd.Flags().StringVar(&importFormat, "from", "", "inventory format: ansible, csv or netbox-json")
+	hostsImportCmd.Flags().StringArrayVar(&importMappings, "map", nil, "read a host setting from another field, as setting=field (repeatable)")
+	hostsImportCmd.Flags().StringArrayVar(&importLabels, "label", nil, "add a label from a field, as field or field=label (repeatable)")
+	hostsImportCmd.Flags().StringVar(&importType, "type", "", "BMC type for hosts without one")
+	hostsImportCmd.Flags().BoolVar(&importReplace, "replace", false, "replace the whole inventory instead of merging by name")
+	hostsImportCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "print the imported hosts instead of writing them")
+}
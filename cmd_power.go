@@ -9,7 +9,7 @@ import (
 var powerCmd = &cobra.Command{
 	Use:   "power",
 	Short: "Power management commands",
-	Long:  `Commands for managing server power state (on, off, status)`,
+	Long:  `Commands for managing server power state (on, off, cycle, status)`,
 }
 
 var powerOnCmd = &cobra.Command{
@@ -21,9 +21,10 @@ var powerOnCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to create BMC client: %w", err)
 		}
+		defer closeClient(client)
 
 		fmt.Println("Powering on server...")
-		if err := client.SetPowerState(PowerStateOn); err != nil {
+		if err := client.SetPowerState(cmd.Context(), PowerStateOn); err != nil {
 			return fmt.Errorf("failed to power on server: %w", err)
 		}
 
@@ -41,9 +42,10 @@ var powerOffCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to create BMC client: %w", err)
 		}
+		defer closeClient(client)
 
 		fmt.Println("Powering off server...")
-		if err := client.SetPowerState(PowerStateOff); err != nil {
+		if err := client.SetPowerState(cmd.Context(), PowerStateOff); err != nil {
 			return fmt.Errorf("failed to power off server: %w", err)
 		}
 
@@ -52,6 +54,34 @@ var powerOffCmd = &cobra.Command{
 	},
 }
 
+var powerCycleCmd = &cobra.Command{
+	Use:   "cycle",
+	Short: "Power cycle the server",
+	Long:  `Turns the server off and back on via the BMC`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := NewBMCClient()
+		if err != nil {
+			return fmt.Errorf("failed to create BMC client: %w", err)
+		}
+		defer closeClient(client)
+
+		fmt.Println("Power cycling server...")
+		if err := client.SetPowerState(cmd.Context(), PowerStateCycle); err != nil {
+			return fmt.Errorf("failed to power cycle server: %w", err)
+		}
+
+		fmt.Println("Server power cycle command sent successfully")
+		return nil
+	},
+}
+
+// powerStatus is the output of 'power status'
+type powerStatus struct {
+	PowerState string `json:"power_state" yaml:"power_state"`
+	Health     string `json:"health" yaml:"health"`
+	State      string `json:"state" yaml:"state"`
+}
+
 var powerStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Check server power status",
@@ -61,13 +91,19 @@ var powerStatusCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to create BMC client: %w", err)
 		}
+		defer closeClient(client)
 
-		fmt.Println("Checking server status...")
-		systemInfo, err := client.GetSystemInfo()
+		progressf("Checking server status...\n")
+		systemInfo, err := client.GetSystemInfo(cmd.Context())
 		if err != nil {
 			return fmt.Errorf("failed to get system info: %w", err)
 		}
 
+		status := powerStatus{PowerState: systemInfo.PowerState, Health: systemInfo.Status.Health, State: systemInfo.Status.State}
+		if printed, err := printStructured(status); printed {
+			return err
+		}
+
 		fmt.Printf("Power State: %s\n", systemInfo.PowerState)
 		fmt.Printf("Health: %s\n", systemInfo.Status.Health)
 		fmt.Printf("State: %s\n", systemInfo.Status.State)
@@ -79,5 +115,6 @@ func init() {
 	rootCmd.AddCommand(powerCmd)
 	powerCmd.AddCommand(powerOnCmd)
 	powerCmd.AddCommand(powerOffCmd)
+	powerCmd.AddCommand(powerCycleCmd)
 	powerCmd.AddCommand(powerStatusCmd)
 }
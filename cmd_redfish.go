@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	redfishData    string
+	redfishInclude bool
+	redfishETag    string
+)
+
+var redfishCmd = &cobra.Command{
+	Use:   "redfish",
+	Short: "Raw Redfish API commands",
+	Long: `Send raw requests to the Redfish API of the configured BMC, reusing its host,
+credentials, session handling and TLS settings. Paths are relative to /redfish/v1
+unless they start with a slash.`,
+}
+
+// newRedfishMethodCmd creates the subcommand for one HTTP method
+func newRedfishMethodCmd(method string) *cobra.Command {
+	name := strings.ToLower(method)
+	cmd := &cobra.Command{
+		Use:   name + " [path]",
+		Short: fmt.Sprintf("Send a %s request to the Redfish API", method),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRedfishRequest(cmd.Context(), method, args[0])
+		},
+	}
+
+	switch method {
+	case http.MethodGet:
+		cmd.Example = "  bmc-cli redfish get Systems/1\n  bmc-cli redfish get /redfish/v1/Managers -i"
+	case http.MethodDelete:
+		cmd.Example = "  bmc-cli redfish delete SessionService/Sessions/12"
+	default:
+		cmd.Example = fmt.Sprintf("  bmc-cli redfish %s Systems/1 --data '{\"AssetTag\":\"rack-42\"}'\n  bmc-cli redfish %s Systems/1 --data @body.json --etag auto", name, name)
+		cmd.Flags().StringVarP(&redfishData, "data", "d", "", "JSON request body, @file to read it from a file or @- for stdin")
+		cmd.Flags().StringVar(&redfishETag, "etag", "", "send If-Match with this ETag, or 'auto' to use the resource's current ETag")
+	}
+	cmd.Flags().BoolVarP(&redfishInclude, "include", "i", false, "show the response status and headers")
+
+	return cmd
+}
+
+func runRedfishRequest(ctx context.Context, method, path string) error {
+	body, err := readRedfishData(redfishData)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewBMCClient()
+	if err != nil {
+		return fmt.Errorf("failed to create BMC client: %w", err)
+	}
+	defer closeClient(client)
+
+	requester, ok := client.(RedfishRequester)
+	if !ok {
+		return fmt.Errorf("raw Redfish requests are not supported for BMC type %s", config.BMCType)
+	}
+
+	endpoint := redfishPath(path)
+	header := http.Header{}
+	if redfishETag != "" {
+		etag := redfishETag
+		if etag == "auto" {
+			if etag, err = currentETag(ctx, requester, endpoint); err != nil {
+				return err
+			}
+		}
+		header.Set("If-Match", etag)
+	}
+
+	resp, err := requester.RedfishRequest(ctx, method, endpoint, body, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+
+	if redfishInclude {
+		writeResponseHeader(os.Stdout, resp)
+	}
+	if len(respBody) > 0 {
+		fmt.Println(prettyJSON(respBody))
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("request failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// redfishPath turns a path relative to the service root into an absolute endpoint
+func redfishPath(path string) string {
+	if strings.HasPrefix(path, "/") {
+		return path
+	}
+	return "/redfish/v1/" + path
+}
+
+// readRedfishData returns the request body given with --data: literal JSON, @file or @- for stdin
+func readRedfishData(data string) ([]byte, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	body := []byte(data)
+	if strings.HasPrefix(data, "@") {
+		var err error
+		if data == "@-" {
+			body, err = io.ReadAll(os.Stdin)
+		} else {
+			body, err = os.ReadFile(data[1:])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading request body: %w", err)
+		}
+	}
+
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("request body is not valid JSON")
+	}
+	return body, nil
+}
+
+// currentETag fetches a resource and returns its ETag header, falling back to @odata.etag
+func currentETag(ctx context.Context, requester RedfishRequester, endpoint string) (string, error) {
+	resp, err := requester.RedfishRequest(ctx, http.MethodGet, endpoint, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error reading ETag of %s: status %d", endpoint, resp.StatusCode)
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return etag, nil
+	}
+
+	var resource struct {
+		ETag string `json:"@odata.etag"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&resource); err != nil || resource.ETag == "" {
+		return "", fmt.Errorf("%s did not return an ETag", endpoint)
+	}
+	return resource.ETag, nil
+}
+
+// redfishLink is an @odata.id reference to another resource
+type redfishLink struct {
+	OdataID string `json:"@odata.id"`
+}
+
+// redfishJSON sends a request with an optional JSON body and decodes the JSON response into
+// result when it is not nil. Responses outside 2xx are returned as errors.
+func redfishJSON(ctx context.Context, requester RedfishRequester, method, endpoint string, body, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("error marshaling request: %w", err)
+		}
+	}
+
+	resp, err := requester.RedfishRequest(ctx, method, endpoint, data, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s failed with status %d: %s", method, endpoint, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	if result != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return fmt.Errorf("error decoding response: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeResponseHeader prints the status line and headers in sorted order
+func writeResponseHeader(w io.Writer, resp *http.Response) {
+	fmt.Fprintf(w, "%s %s\n", resp.Proto, resp.Status)
+
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range resp.Header[name] {
+			fmt.Fprintf(w, "%s: %s\n", name, value)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// prettyJSON indents a JSON body, returning other bodies unchanged
+func prettyJSON(body []byte) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		return string(body)
+	}
+	return out.String()
+}
+
+func init() {
+	rootCmd.AddCommand(redfishCmd)
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete} {
+		redfishCmd.AddCommand(newRedfishMethodCmd(method))
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRedfishPath(t *testing.T) {
+	if path := redfishPath("Systems/1"); path != "/redfish/v1/Systems/1" {
+		t.Errorf("Expected '/redfish/v1/Systems/1', got: %s", path)
+	}
+	if path := redfishPath("/redfish/v1/Managers"); path != "/redfish/v1/Managers" {
+		t.Errorf("Expected '/redfish/v1/Managers', got: %s", path)
+	}
+}
+
+func TestReadRedfishData(t *testing.T) {
+	body, err := readRedfishData(`{"AssetTag":"rack-42"}`)
+	if err != nil || string(body) != `{"AssetTag":"rack-42"}` {
+		t.Errorf("Unexpected literal body %q: %v", body, err)
+	}
+
+	file := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(file, []byte(`{"Boot":{}}`), 0600); err != nil {
+		t.Fatalf("Failed to write body: %v", err)
+	}
+	body, err = readRedfishData("@" + file)
+	if err != nil || string(body) != `{"Boot":{}}` {
+		t.Errorf("Unexpected file body %q: %v", body, err)
+	}
+
+	if _, err := readRedfishData("{not json"); err == nil {
+		t.Error("Expected error for invalid JSON, got nil")
+	}
+	if body, err := readRedfishData(""); err != nil || body != nil {
+		t.Errorf("Expected no body, got %q: %v", body, err)
+	}
+}
+
+func TestRedfishRequest_ConditionalPatch(t *testing.T) {
+	var ifMatch string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "admin" || password != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"@odata.etag":"W/\"abc\"","AssetTag":""}`))
+		case "PATCH":
+			ifMatch = r.Header.Get("If-Match")
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &IDRACClient{
+		baseURL:    server.URL,
+		username:   "admin",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	etag, err := currentETag(context.Background(), client, "/redfish/v1/Systems/System.Embedded.1")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if etag != `W/"abc"` {
+		t.Errorf("Expected ETag 'W/\"abc\"', got: %s", etag)
+	}
+
+	header := http.Header{}
+	header.Set("If-Match", etag)
+	resp, err := client.RedfishRequest(context.Background(), "PATCH", "/redfish/v1/Systems/System.Embedded.1", []byte(`{"AssetTag":"rack-42"}`), header)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status 204, got: %d", resp.StatusCode)
+	}
+	if ifMatch != `W/"abc"` {
+		t.Errorf("Expected If-Match 'W/\"abc\"', got: %s", ifMatch)
+	}
+}
+
+func TestRedfishRequest_OpenBMCSession(t *testing.T) {
+	server, logins := newOpenBMCTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"Id":"bmc"}`))
+	})
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &OpenBMCClient{
+		baseURL:    server.URL,
+		username:   "root",
+		password:   "0penBmc",
+		httpClient: server.Client(),
+	}
+
+	resp, err := client.RedfishRequest(context.Background(), "GET", "/redfish/v1/Managers/bmc", nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got: %d", resp.StatusCode)
+	}
+	if *logins != 1 {
+		t.Errorf("Expected raw request to use a session, got %d logins", *logins)
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	if out := prettyJSON([]byte(`{"a":1}`)); out != "{\n  \"a\": 1\n}" {
+		t.Errorf("Unexpected indented JSON: %q", out)
+	}
+	if out := prettyJSON([]byte("not json")); out != "not json" {
+		t.Errorf("Expected non-JSON body unchanged, got: %q", out)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	runSelector string
+	runVars     []string
+	runState    string
+	runFresh    bool
+	runDryRun   bool
+	runWorkers  int
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run <recipe.yaml>",
+	Short: "Run a multi-step provisioning recipe on one or more hosts",
+	Long: `Run the steps of a recipe in order on the active BMC, or on every inventory host matching
+--selector, several hosts at a time. Each step does one thing:
+
+  power: on | off | cycle          wait: {power: on, interval: 5s}
+  mount: <image url>               unmount: true
+  boot: {target: cd, persistent: false}
+  bios: {BootMode: Uefi}           sleep: 30s
+  http: {url: ..., method: GET, status: 200, contains: ..., insecure: false}
+  shell: <command>                 (run with sh -c; BMC_HOST_NAME, BMC_ADDRESS and
+                                    BMC_TYPE are set; quote template values with
+                                    shellquote)
+
+and takes name, when (skip the step unless the template renders to a true value), timeout
+(per attempt; wait steps default to 10m), retries and retry_delay (default 10s). String
+values are Go templates rendered per host with .Host (the inventory entry) and .Vars (the
+recipe's vars, overridden by --var):
+
+  name: reinstall
+  vars:
+    image: http://10.0.0.5/images/{{ index .Host.Labels "os" }}.iso
+  steps:
+    - mount: "{{ .Vars.image }}"
+    - boot: {target: cd}
+    - power: cycle
+    - wait: {power: on}
+    - name: installer finished
+      http: {url: "http://{{ .Host.Name }}.lab:9100/metrics"}
+      retries: 60
+      retry_delay: 30s
+    - unmount: true
+    - shell: ./register.sh "$BMC_HOST_NAME" {{ shellquote .Host.Labels.rack }}
+      when: '{{ ne (index .Host.Labels "rack") "" }}'
+
+Progress is recorded per host in a state file (by default next to the recipe, as
+<recipe>.state.json). Running the recipe again skips hosts that completed and resumes the
+others at the step that failed; --fresh starts over.
+
+Example:
+  bmc-cli run reinstall.yaml --host r740-01
+  bmc-cli run reinstall.yaml --selector rack=r12 --workers 8
+  bmc-cli run reinstall.yaml --selector group=gpu --var os=rocky9 --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recipe, err := loadRecipe(args[0])
+		if err != nil {
+			return err
+		}
+		vars, err := parseRecipeVars(runVars)
+		if err != nil {
+			return err
+		}
+		targets, err := recipeTargets(runSelector)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("no hosts match %q", runSelector)
+		}
+
+		statePath := runState
+		if statePath == "" {
+			statePath = recipeStatePath(args[0])
+		}
+		state, err := loadRecipeState(statePath, recipe, runFresh)
+		if err != nil {
+			return err
+		}
+
+		if runDryRun {
+			return printRecipePlan(recipe, targets, vars, state)
+		}
+
+		var mu sync.Mutex
+		runner := &recipeRunner{recipe: recipe, vars: vars, state: state, report: func(host, format string, args ...interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			progressf("[%s] %s\n", host, fmt.Sprintf(format, args...))
+		}}
+		results := runner.run(cmd.Context(), targets, runWorkers)
+
+		failed := 0
+		for _, result := range results {
+			if result.Status != recipeCompleted {
+				failed++
+			}
+		}
+
+		if printed, err := printStructured(results); !printed {
+			fmt.Println()
+			for _, result := range results {
+				line := fmt.Sprintf("%-20s  %-9s  %d/%d steps  %s", result.Host, result.Status, result.Completed, result.Steps, result.Error)
+				fmt.Println(strings.TrimRight(line, " "))
+			}
+			fmt.Printf("\nRecipe %s: %d host(s): %d completed, %d failed\n", recipe.Name, len(results), len(results)-failed, failed)
+		} else if err != nil {
+			return err
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d host(s) failed; run the recipe again to resume (state in %s)", failed, len(results), statePath)
+		}
+		return nil
+	},
+}
+
+// recipeTargets returns the inventory hosts matching selector, or the active BMC
+func recipeTargets(selector string) ([]recipeTarget, error) {
+	checks, err := checkTargets(false, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]recipeTarget, 0, len(checks))
+	for _, check := range checks {
+		host, ok := baseConfig.findHost(check.Name)
+		if !ok {
+			// The active BMC comes from a config section or context rather than the inventory
+			section, _ := check.Config.activeSection()
+			host = HostConfig{Name: check.Name, BMCType: check.Config.BMCType, Address: *section.Host, Port: *section.Port}
+		}
+		targets = append(targets, recipeTarget{Host: host, Config: check.Config})
+	}
+	return targets, nil
+}
+
+// parseRecipeVars parses --var name=value flags
+func parseRecipeVars(flags []string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, flag := range flags {
+		name, value, ok := strings.Cut(flag, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var %q (use name=value)", flag)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// printRecipePlan prints the rendered steps each host would run, without running them
+func printRecipePlan(recipe *Recipe, targets []recipeTarget, vars map[string]string, state *recipeState) error {
+	for _, target := range targets {
+		name := target.Host.Name
+		progress := state.progress(name)
+		fmt.Printf("%s:\n", name)
+		if progress.Status == recipeCompleted {
+			fmt.Println("  already completed")
+			continue
+		}
+
+		data, err := newRecipeData(recipe, target.Host, vars)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for i, step := range recipe.Steps {
+			prefix := fmt.Sprintf("  %d/%d", i+1, len(recipe.Steps))
+			if i < progress.Completed {
+				fmt.Printf("%s %s: done\n", prefix, step.describe())
+				continue
+			}
+			enabled, err := step.enabled(data)
+			if err != nil {
+				return fmt.Errorf("%s: step %d: %w", name, i+1, err)
+			}
+			if !enabled {
+				fmt.Printf("%s %s: skipped\n", prefix, step.describe())
+				continue
+			}
+			rendered, err := step.render(data)
+			if err != nil {
+				return fmt.Errorf("%s: step %d: %w", name, i+1, err)
+			}
+			fmt.Printf("%s %s\n", prefix, rendered.describe())
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().StringVarP(&runSelector, "selector", "l", "", "run on the inventory hosts matching a selector (such as rack=r12,group=gpu)")
+	runCmd.Flags().StringArrayVar(&runVars, "var", nil, "set a recipe variable (name=value, repeatable)")
+	runCmd.Flags().StringVar(&runState, "state", "", "state file recording progress (default <recipe>.state.json)")
+	runCmd.Flags().BoolVar(&runFresh, "fresh", false, "ignore the state file and start every host from the first step")
+	runCmd.Flags().BoolVar(&runDryRun, "dry-run", false, "print the rendered steps for each host without running them")
+	runCmd.Flags().IntVar(&runWorkers, "workers", 4, "number of hosts run concurrently")
+}
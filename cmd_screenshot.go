@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var screenshotOut string
+
+var screenshotCmd = &cobra.Command{
+	Use:   "screenshot",
+	Short: "Save a picture of the host's screen",
+	Long: `Capture the host's screen through the BMC and save it as a PNG image, without opening
+the remote console. This is useful for recording where a host hangs during POST.
+
+iDRAC exports the screenshot through the Lifecycle Controller. iLO serves the screen
+thumbnail shown on its overview page, which is smaller than the real screen.
+
+Example:
+  bmc-cli screenshot -o screen.png
+  bmc-cli --host r740-01 screenshot -o r740-01.png
+  bmc-cli screenshot -o - | display`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := NewBMCClient()
+		if err != nil {
+			return fmt.Errorf("failed to create BMC client: %w", err)
+		}
+		defer closeClient(client)
+
+		capturer, ok := client.(ScreenCapturer)
+		if !ok {
+			return fmt.Errorf("screen capture is not supported for BMC type %s", config.BMCType)
+		}
+
+		// The image itself may be going to standard output
+		if screenshotOut != "-" {
+			progressf("Capturing the screen...\n")
+		}
+		data, err := capturer.CaptureScreen(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to capture the screen: %w", err)
+		}
+
+		if screenshotOut == "-" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(screenshotOut, data, 0644); err != nil {
+			return fmt.Errorf("error writing screenshot: %w", err)
+		}
+		progressf("Saved the screenshot to %s\n", screenshotOut)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(screenshotCmd)
+	screenshotCmd.Flags().StringVarP(&screenshotOut, "out", "o", "screenshot.png", "file to save the PNG image to, or - for standard output")
+}
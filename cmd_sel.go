@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var selCmd = &cobra.Command{
+	Use:   "sel",
+	Short: "System event log commands",
+	Long:  `Commands for reading the BMC system event log`,
+}
+
+var selListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List system event log entries",
+	Long:  `Lists all entries of the system event log`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := NewBMCClient()
+		if err != nil {
+			return fmt.Errorf("failed to create BMC client: %w", err)
+		}
+		defer closeClient(client)
+
+		reader, ok := client.(EventLogReader)
+		if !ok {
+			return fmt.Errorf("reading the event log is not supported for BMC type %s", config.BMCType)
+		}
+
+		progressf("Retrieving system event log...\n")
+		entries, err := reader.GetEventLog(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to read event log: %w", err)
+		}
+		if printed, err := printStructured(entries); printed {
+			return err
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("System event log is empty")
+			return nil
+		}
+
+		fmt.Printf("%-6s %-20s %-9s %s\n", "ID", "Created", "Severity", "Message")
+		fmt.Println("---------------------------------------------------------------------------------")
+
+		for _, entry := range entries {
+			created := "-"
+			if !entry.Created.IsZero() {
+				created = entry.Created.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-6s %-20s %-9s %s\n", entry.ID, created, entry.Severity, entry.Message)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(selCmd)
+	selCmd.AddCommand(selListCmd)
+}
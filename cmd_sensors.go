@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var sensorsCmd = &cobra.Command{
+	Use:   "sensors",
+	Short: "Sensor commands",
+	Long:  `Commands for reading temperature, voltage, fan and other sensors`,
+}
+
+var sensorsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List sensor readings",
+	Long:  `Lists the current reading and status of every sensor`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := NewBMCClient()
+		if err != nil {
+			return fmt.Errorf("failed to create BMC client: %w", err)
+		}
+		defer closeClient(client)
+
+		reader, ok := client.(SensorReader)
+		if !ok {
+			return fmt.Errorf("reading sensors is not supported for BMC type %s", config.BMCType)
+		}
+
+		progressf("Retrieving sensor readings...\n")
+		readings, err := reader.GetSensors(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to read sensors: %w", err)
+		}
+		if printed, err := printStructured(readings); printed {
+			return err
+		}
+
+		if len(readings) == 0 {
+			fmt.Println("No sensors found")
+			return nil
+		}
+
+		fmt.Printf("%-20s %-12s %-10s %s\n", "Name", "Reading", "Units", "Status")
+		fmt.Println("---------------------------------------------------------------------------------")
+
+		for _, reading := range readings {
+			value := "na"
+			if reading.Available && reading.Units != "discrete" {
+				value = fmt.Sprintf("%.2f", reading.Value)
+			} else if reading.Available {
+				value = "-"
+			}
+			fmt.Printf("%-20s %-12s %-10s %s\n", reading.Name, value, reading.Units, reading.Status)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sensorsCmd)
+	sensorsCmd.AddCommand(sensorsListCmd)
+}
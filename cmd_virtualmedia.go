@@ -29,9 +29,10 @@ Example:
 		if err != nil {
 			return fmt.Errorf("failed to create BMC client: %w", err)
 		}
+		defer closeClient(client)
 
 		fmt.Printf("Mounting virtual media: %s\n", imageURL)
-		if err := client.MountVirtualMedia(imageURL); err != nil {
+		if err := client.MountVirtualMedia(cmd.Context(), imageURL); err != nil {
 			return fmt.Errorf("failed to mount virtual media: %w", err)
 		}
 
@@ -49,9 +50,10 @@ var unmountCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to create BMC client: %w", err)
 		}
+		defer closeClient(client)
 
 		fmt.Println("Unmounting virtual media...")
-		if err := client.UnmountVirtualMedia(); err != nil {
+		if err := client.UnmountVirtualMedia(cmd.Context()); err != nil {
 			return fmt.Errorf("failed to unmount virtual media: %w", err)
 		}
 
@@ -69,9 +71,10 @@ var listMediaCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to create BMC client: %w", err)
 		}
+		defer closeClient(client)
 
 		fmt.Println("Retrieving virtual media information...")
-		vmList, err := client.GetVirtualMedia()
+		vmList, err := client.GetVirtualMedia(cmd.Context())
 		if err != nil {
 			return fmt.Errorf("failed to get virtual media info: %w", err)
 		}
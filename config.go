@@ -1,90 +1,262 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
 // Config represents the application configuration
 type Config struct {
-	BMCType BMCType     `yaml:"bmc_type" mapstructure:"bmc_type"`
-	ILO     ILOConfig   `yaml:"ilo" mapstructure:"ilo"`
-	IDRAC   IDRACConfig `yaml:"idrac" mapstructure:"idrac"`
+	CurrentContext string                   `yaml:"current_context" mapstructure:"current_context"`
+	Contexts       map[string]ContextConfig `yaml:"contexts" mapstructure:"contexts"`
+	Hosts          []HostConfig             `yaml:"hosts" mapstructure:"hosts"`
+
+	BMCType    BMCType          `yaml:"bmc_type" mapstructure:"bmc_type"`
+	ILO        ILOConfig        `yaml:"ilo" mapstructure:"ilo"`
+	IDRAC      IDRACConfig      `yaml:"idrac" mapstructure:"idrac"`
+	Supermicro SupermicroConfig `yaml:"supermicro" mapstructure:"supermicro"`
+	XCC        XCCConfig        `yaml:"xcc" mapstructure:"xcc"`
+	OpenBMC    OpenBMCConfig    `yaml:"openbmc" mapstructure:"openbmc"`
+	IPMI       IPMIConfig       `yaml:"ipmi" mapstructure:"ipmi"`
 }
 
 // ILOConfig represents iLO connection configuration
 type ILOConfig struct {
-	Host     string `yaml:"host" mapstructure:"host"`
-	Username string `yaml:"username" mapstructure:"username"`
-	Password string `yaml:"password" mapstructure:"password"`
-	Port     int    `yaml:"port" mapstructure:"port"`
-	UseHTTPS bool   `yaml:"use_https" mapstructure:"use_https"`
+	Host           string `yaml:"host" mapstructure:"host"`
+	Username       string `yaml:"username" mapstructure:"username"`
+	Password       string `yaml:"password" mapstructure:"password"`
+	PasswordSource `yaml:",inline" mapstructure:",squash"`
+	Port           int         `yaml:"port" mapstructure:"port"`
+	UseHTTPS       bool        `yaml:"use_https" mapstructure:"use_https"`
+	TLS            TLSConfig   `yaml:"tls" mapstructure:"tls"`
+	Proxy          ProxyConfig `yaml:"proxy" mapstructure:"proxy"`
 }
 
 // IDRACConfig represents iDRAC connection configuration
 type IDRACConfig struct {
-	Host     string `yaml:"host" mapstructure:"host"`
-	Username string `yaml:"username" mapstructure:"username"`
-	Password string `yaml:"password" mapstructure:"password"`
-	Port     int    `yaml:"port" mapstructure:"port"`
-	UseHTTPS bool   `yaml:"use_https" mapstructure:"use_https"`
+	Host           string `yaml:"host" mapstructure:"host"`
+	Username       string `yaml:"username" mapstructure:"username"`
+	Password       string `yaml:"password" mapstructure:"password"`
+	PasswordSource `yaml:",inline" mapstructure:",squash"`
+	Port           int         `yaml:"port" mapstructure:"port"`
+	UseHTTPS       bool        `yaml:"use_https" mapstructure:"use_https"`
+	TLS            TLSConfig   `yaml:"tls" mapstructure:"tls"`
+	Proxy          ProxyConfig `yaml:"proxy" mapstructure:"proxy"`
+}
+
+// SupermicroConfig represents Supermicro BMC connection configuration
+type SupermicroConfig struct {
+	Host           string `yaml:"host" mapstructure:"host"`
+	Username       string `yaml:"username" mapstructure:"username"`
+	Password       string `yaml:"password" mapstructure:"password"`
+	PasswordSource `yaml:",inline" mapstructure:",squash"`
+	Port           int         `yaml:"port" mapstructure:"port"`
+	UseHTTPS       bool        `yaml:"use_https" mapstructure:"use_https"`
+	TLS            TLSConfig   `yaml:"tls" mapstructure:"tls"`
+	Proxy          ProxyConfig `yaml:"proxy" mapstructure:"proxy"`
+}
+
+// XCCConfig represents Lenovo XClarity Controller connection configuration
+type XCCConfig struct {
+	Host           string `yaml:"host" mapstructure:"host"`
+	Username       string `yaml:"username" mapstructure:"username"`
+	Password       string `yaml:"password" mapstructure:"password"`
+	PasswordSource `yaml:",inline" mapstructure:",squash"`
+	Port           int         `yaml:"port" mapstructure:"port"`
+	UseHTTPS       bool        `yaml:"use_https" mapstructure:"use_https"`
+	TLS            TLSConfig   `yaml:"tls" mapstructure:"tls"`
+	Proxy          ProxyConfig `yaml:"proxy" mapstructure:"proxy"`
+}
+
+// OpenBMCConfig represents OpenBMC connection configuration
+type OpenBMCConfig struct {
+	Host           string `yaml:"host" mapstructure:"host"`
+	Username       string `yaml:"username" mapstructure:"username"`
+	Password       string `yaml:"password" mapstructure:"password"`
+	PasswordSource `yaml:",inline" mapstructure:",squash"`
+	Port           int         `yaml:"port" mapstructure:"port"`
+	UseHTTPS       bool        `yaml:"use_https" mapstructure:"use_https"`
+	TLS            TLSConfig   `yaml:"tls" mapstructure:"tls"`
+	Proxy          ProxyConfig `yaml:"proxy" mapstructure:"proxy"`
+}
+
+// IPMIConfig represents IPMI-over-LAN (RMCP+) connection configuration
+type IPMIConfig struct {
+	Host           string `yaml:"host" mapstructure:"host"`
+	Username       string `yaml:"username" mapstructure:"username"`
+	Password       string `yaml:"password" mapstructure:"password"`
+	PasswordSource `yaml:",inline" mapstructure:",squash"`
+	Port           int `yaml:"port" mapstructure:"port"`
 }
 
 var config Config
 
+// baseConfig is the configuration before a context is applied, from which 'check --all'
+// enumerates every configured BMC
+var baseConfig Config
+
+// loadConfig reads the configuration and checks that the selected BMC can be contacted
 func loadConfig() error {
+	if err := readConfig(); err != nil {
+		return err
+	}
+	return validateConfig()
+}
+
+// readConfig reads the configuration from the config file and the environment and applies
+// the selected context, without requiring a complete BMC configuration
+func readConfig() error {
 	// Set default values
 	viper.SetDefault("bmc_type", "ilo")
 	viper.SetDefault("ilo.port", 443)
 	viper.SetDefault("ilo.use_https", true)
 	viper.SetDefault("idrac.port", 443)
 	viper.SetDefault("idrac.use_https", true)
+	viper.SetDefault("supermicro.port", 443)
+	viper.SetDefault("supermicro.use_https", true)
+	viper.SetDefault("xcc.port", 443)
+	viper.SetDefault("xcc.use_https", true)
+	viper.SetDefault("openbmc.port", 443)
+	viper.SetDefault("openbmc.use_https", true)
+	viper.SetDefault("ipmi.port", 623)
 
 	// Environment variable bindings
 	viper.AutomaticEnv()
 
-	// Bind BMC type environment variable
+	// Bind BMC type and context environment variables
 	_ = viper.BindEnv("bmc_type", "BMC_TYPE")
+	_ = viper.BindEnv("current_context", "BMC_CONTEXT")
 
 	// Bind iLO specific environment variables
 	_ = viper.BindEnv("ilo.host", "ILO_HOST")
 	_ = viper.BindEnv("ilo.username", "ILO_USERNAME")
 	_ = viper.BindEnv("ilo.password", "ILO_PASSWORD")
+	_ = viper.BindEnv("ilo.password_file", "ILO_PASSWORD_FILE")
+	_ = viper.BindEnv("ilo.password_cmd", "ILO_PASSWORD_CMD")
+	_ = viper.BindEnv("ilo.password_env", "ILO_PASSWORD_ENV")
 	_ = viper.BindEnv("ilo.port", "ILO_PORT")
 	_ = viper.BindEnv("ilo.use_https", "ILO_USE_HTTPS")
+	_ = viper.BindEnv("ilo.tls.ca_file", "ILO_TLS_CA_FILE")
+	_ = viper.BindEnv("ilo.tls.server_name", "ILO_TLS_SERVER_NAME")
+	_ = viper.BindEnv("ilo.tls.insecure", "ILO_TLS_INSECURE")
+	_ = viper.BindEnv("ilo.proxy.url", "ILO_PROXY_URL")
+	_ = viper.BindEnv("ilo.proxy.jump", "ILO_PROXY_JUMP")
+	_ = viper.BindEnv("ilo.proxy.identity_file", "ILO_PROXY_IDENTITY_FILE")
+	_ = viper.BindEnv("ilo.proxy.known_hosts_file", "ILO_PROXY_KNOWN_HOSTS_FILE")
 
 	// Bind iDRAC specific environment variables
 	_ = viper.BindEnv("idrac.host", "IDRAC_HOST")
 	_ = viper.BindEnv("idrac.username", "IDRAC_USERNAME")
 	_ = viper.BindEnv("idrac.password", "IDRAC_PASSWORD")
+	_ = viper.BindEnv("idrac.password_file", "IDRAC_PASSWORD_FILE")
+	_ = viper.BindEnv("idrac.password_cmd", "IDRAC_PASSWORD_CMD")
+	_ = viper.BindEnv("idrac.password_env", "IDRAC_PASSWORD_ENV")
 	_ = viper.BindEnv("idrac.port", "IDRAC_PORT")
 	_ = viper.BindEnv("idrac.use_https", "IDRAC_USE_HTTPS")
+	_ = viper.BindEnv("idrac.tls.ca_file", "IDRAC_TLS_CA_FILE")
+	_ = viper.BindEnv("idrac.tls.server_name", "IDRAC_TLS_SERVER_NAME")
+	_ = viper.BindEnv("idrac.tls.insecure", "IDRAC_TLS_INSECURE")
+	_ = viper.BindEnv("idrac.proxy.url", "IDRAC_PROXY_URL")
+	_ = viper.BindEnv("idrac.proxy.jump", "IDRAC_PROXY_JUMP")
+	_ = viper.BindEnv("idrac.proxy.identity_file", "IDRAC_PROXY_IDENTITY_FILE")
+	_ = viper.BindEnv("idrac.proxy.known_hosts_file", "IDRAC_PROXY_KNOWN_HOSTS_FILE")
 
-	// Configuration file handling
-	if cfgFile != "" {
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Look for config in current directory
-		viper.AddConfigPath(".")
-		viper.SetConfigName("config")
-		viper.SetConfigType("yaml")
-	}
+	// Bind Supermicro specific environment variables
+	_ = viper.BindEnv("supermicro.host", "SUPERMICRO_HOST")
+	_ = viper.BindEnv("supermicro.username", "SUPERMICRO_USERNAME")
+	_ = viper.BindEnv("supermicro.password", "SUPERMICRO_PASSWORD")
+	_ = viper.BindEnv("supermicro.password_file", "SUPERMICRO_PASSWORD_FILE")
+	_ = viper.BindEnv("supermicro.password_cmd", "SUPERMICRO_PASSWORD_CMD")
+	_ = viper.BindEnv("supermicro.password_env", "SUPERMICRO_PASSWORD_ENV")
+	_ = viper.BindEnv("supermicro.port", "SUPERMICRO_PORT")
+	_ = viper.BindEnv("supermicro.use_https", "SUPERMICRO_USE_HTTPS")
+	_ = viper.BindEnv("supermicro.tls.ca_file", "SUPERMICRO_TLS_CA_FILE")
+	_ = viper.BindEnv("supermicro.tls.server_name", "SUPERMICRO_TLS_SERVER_NAME")
+	_ = viper.BindEnv("supermicro.tls.insecure", "SUPERMICRO_TLS_INSECURE")
+	_ = viper.BindEnv("supermicro.proxy.url", "SUPERMICRO_PROXY_URL")
+	_ = viper.BindEnv("supermicro.proxy.jump", "SUPERMICRO_PROXY_JUMP")
+	_ = viper.BindEnv("supermicro.proxy.identity_file", "SUPERMICRO_PROXY_IDENTITY_FILE")
+	_ = viper.BindEnv("supermicro.proxy.known_hosts_file", "SUPERMICRO_PROXY_KNOWN_HOSTS_FILE")
 
-	// Read config file if it exists
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return fmt.Errorf("error reading config file: %w", err)
-		}
+	// Bind Lenovo XCC specific environment variables
+	_ = viper.BindEnv("xcc.host", "XCC_HOST")
+	_ = viper.BindEnv("xcc.username", "XCC_USERNAME")
+	_ = viper.BindEnv("xcc.password", "XCC_PASSWORD")
+	_ = viper.BindEnv("xcc.password_file", "XCC_PASSWORD_FILE")
+	_ = viper.BindEnv("xcc.password_cmd", "XCC_PASSWORD_CMD")
+	_ = viper.BindEnv("xcc.password_env", "XCC_PASSWORD_ENV")
+	_ = viper.BindEnv("xcc.port", "XCC_PORT")
+	_ = viper.BindEnv("xcc.use_https", "XCC_USE_HTTPS")
+	_ = viper.BindEnv("xcc.tls.ca_file", "XCC_TLS_CA_FILE")
+	_ = viper.BindEnv("xcc.tls.server_name", "XCC_TLS_SERVER_NAME")
+	_ = viper.BindEnv("xcc.tls.insecure", "XCC_TLS_INSECURE")
+	_ = viper.BindEnv("xcc.proxy.url", "XCC_PROXY_URL")
+	_ = viper.BindEnv("xcc.proxy.jump", "XCC_PROXY_JUMP")
+	_ = viper.BindEnv("xcc.proxy.identity_file", "XCC_PROXY_IDENTITY_FILE")
+	_ = viper.BindEnv("xcc.proxy.known_hosts_file", "XCC_PROXY_KNOWN_HOSTS_FILE")
+
+	// Bind OpenBMC specific environment variables
+	_ = viper.BindEnv("openbmc.host", "OPENBMC_HOST")
+	_ = viper.BindEnv("openbmc.username", "OPENBMC_USERNAME")
+	_ = viper.BindEnv("openbmc.password", "OPENBMC_PASSWORD")
+	_ = viper.BindEnv("openbmc.password_file", "OPENBMC_PASSWORD_FILE")
+	_ = viper.BindEnv("openbmc.password_cmd", "OPENBMC_PASSWORD_CMD")
+	_ = viper.BindEnv("openbmc.password_env", "OPENBMC_PASSWORD_ENV")
+	_ = viper.BindEnv("openbmc.port", "OPENBMC_PORT")
+	_ = viper.BindEnv("openbmc.use_https", "OPENBMC_USE_HTTPS")
+	_ = viper.BindEnv("openbmc.tls.ca_file", "OPENBMC_TLS_CA_FILE")
+	_ = viper.BindEnv("openbmc.tls.server_name", "OPENBMC_TLS_SERVER_NAME")
+	_ = viper.BindEnv("openbmc.tls.insecure", "OPENBMC_TLS_INSECURE")
+	_ = viper.BindEnv("openbmc.proxy.url", "OPENBMC_PROXY_URL")
+	_ = viper.BindEnv("openbmc.proxy.jump", "OPENBMC_PROXY_JUMP")
+	_ = viper.BindEnv("openbmc.proxy.identity_file", "OPENBMC_PROXY_IDENTITY_FILE")
+	_ = viper.BindEnv("openbmc.proxy.known_hosts_file", "OPENBMC_PROXY_KNOWN_HOSTS_FILE")
+
+	// Bind IPMI specific environment variables
+	_ = viper.BindEnv("ipmi.host", "IPMI_HOST")
+	_ = viper.BindEnv("ipmi.username", "IPMI_USERNAME")
+	_ = viper.BindEnv("ipmi.password", "IPMI_PASSWORD")
+	_ = viper.BindEnv("ipmi.password_file", "IPMI_PASSWORD_FILE")
+	_ = viper.BindEnv("ipmi.password_cmd", "IPMI_PASSWORD_CMD")
+	_ = viper.BindEnv("ipmi.password_env", "IPMI_PASSWORD_ENV")
+	_ = viper.BindEnv("ipmi.port", "IPMI_PORT")
+
+	// Read config file if it exists, decrypting it if it is age or SOPS encrypted
+	path := configFilePath()
+	if path == "" {
 		// Config file not found is okay, we can work with env vars
 		if verbose {
 			fmt.Printf("Config file not found, using environment variables and defaults\n")
 		}
 	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading config file: %w", err)
+		}
+		data, encryption, err := decryptConfig(data)
+		if err != nil {
+			return fmt.Errorf("error reading config file %s: %w", path, err)
+		}
+		// Decrypted SOPS values are re-encoded as YAML whatever the file's format
+		configType := configFileType(path)
+		if encryption != nil && !encryption.WholeFile {
+			configType = "yaml"
+		}
+		viper.SetConfigType(configType)
+		if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+			return fmt.Errorf("error reading config file: %w", err)
+		}
 		if verbose {
-			fmt.Printf("Using config file: %s\n", viper.ConfigFileUsed())
+			if encryption != nil {
+				fmt.Printf("Using encrypted config file: %s\n", path)
+			} else {
+				fmt.Printf("Using config file: %s\n", path)
+			}
 		}
 	}
 
@@ -93,7 +265,62 @@ func loadConfig() error {
 		return fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
-	return validateConfig()
+	baseConfig = config
+	if err := config.applyContext(); err != nil {
+		return err
+	}
+	if err := config.applyHost(); err != nil {
+		return err
+	}
+	// Mirror the context's overrides so 'config get' and 'config view' show effective values
+	if config.CurrentContext != "" {
+		viper.Set("current_context", config.CurrentContext)
+	}
+	viper.Set("bmc_type", string(config.BMCType))
+	if section, ok := config.activeSection(); ok && *section.Host != "" {
+		viper.Set(string(config.BMCType)+".host", *section.Host)
+	}
+	return nil
+}
+
+// configFilePath returns the --config file, or the first config file found in the current
+// directory and then in the per-user config directory. Any format viper reads is accepted
+// (config.json, config.toml, ...), with config.yaml and config.yml preferred.
+func configFilePath() string {
+	if cfgFile != "" {
+		return cfgFile
+	}
+	dirs := []string{"."}
+	if path, err := userConfigFile(); err == nil {
+		dirs = append(dirs, filepath.Dir(path))
+	}
+	exts := []string{"yaml", "yml"}
+	for _, ext := range viper.SupportedExts {
+		if !containsString(exts, ext) {
+			exts = append(exts, ext)
+		}
+	}
+	for _, dir := range dirs {
+		for _, ext := range exts {
+			name := filepath.Join(dir, "config."+ext)
+			if _, err := os.Stat(name); err == nil {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// configFileType returns the format of a config file from its extension, looking past an
+// extension such as .age added by encryption. Files without a known extension are YAML.
+func configFileType(path string) string {
+	for name := path; filepath.Ext(name) != ""; name = strings.TrimSuffix(name, filepath.Ext(name)) {
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+		if containsString(viper.SupportedExts, ext) {
+			return ext
+		}
+	}
+	return "yaml"
 }
 
 func validateConfig() error {
@@ -105,9 +332,6 @@ func validateConfig() error {
 		if config.ILO.Username == "" {
 			return fmt.Errorf("iLO username is required (set ILO_USERNAME environment variable or username in config file)")
 		}
-		if config.ILO.Password == "" {
-			return fmt.Errorf("iLO password is required (set ILO_PASSWORD environment variable or password in config file)")
-		}
 	case BMCTypeIDRAC:
 		if config.IDRAC.Host == "" {
 			return fmt.Errorf("iDRAC host is required (set IDRAC_HOST environment variable or host in config file)")
@@ -115,20 +339,67 @@ func validateConfig() error {
 		if config.IDRAC.Username == "" {
 			return fmt.Errorf("iDRAC username is required (set IDRAC_USERNAME environment variable or username in config file)")
 		}
-		if config.IDRAC.Password == "" {
-			return fmt.Errorf("iDRAC password is required (set IDRAC_PASSWORD environment variable or password in config file)")
+	case BMCTypeSupermicro:
+		if config.Supermicro.Host == "" {
+			return fmt.Errorf("Supermicro host is required (set SUPERMICRO_HOST environment variable or host in config file)")
+		}
+		if config.Supermicro.Username == "" {
+			return fmt.Errorf("Supermicro username is required (set SUPERMICRO_USERNAME environment variable or username in config file)")
+		}
+	case BMCTypeXCC:
+		if config.XCC.Host == "" {
+			return fmt.Errorf("XCC host is required (set XCC_HOST environment variable or host in config file)")
+		}
+		if config.XCC.Username == "" {
+			return fmt.Errorf("XCC username is required (set XCC_USERNAME environment variable or username in config file)")
+		}
+	case BMCTypeOpenBMC:
+		if config.OpenBMC.Host == "" {
+			return fmt.Errorf("OpenBMC host is required (set OPENBMC_HOST environment variable or host in config file)")
+		}
+		if config.OpenBMC.Username == "" {
+			return fmt.Errorf("OpenBMC username is required (set OPENBMC_USERNAME environment variable or username in config file)")
+		}
+	case BMCTypeIPMI:
+		if config.IPMI.Host == "" {
+			return fmt.Errorf("IPMI host is required (set IPMI_HOST environment variable or host in config file)")
+		}
+		if config.IPMI.Username == "" {
+			return fmt.Errorf("IPMI username is required (set IPMI_USERNAME environment variable or username in config file)")
 		}
 	default:
-		return fmt.Errorf("unsupported BMC type: %s (supported types: ilo, idrac)", config.BMCType)
+		return fmt.Errorf("unsupported BMC type: %s (supported types: ilo, idrac, supermicro, xcc, openbmc, ipmi)", config.BMCType)
 	}
 	return nil
 }
 
 func createSampleConfig() error {
 	sampleConfig := `# BMC CLI Configuration File
-# Specify the BMC type: 'ilo' for HPE iLO or 'idrac' for DELL iDRAC
+# Specify the BMC type: 'ilo' for HPE iLO, 'idrac' for DELL iDRAC, 'supermicro' for Supermicro,
+# 'xcc' for Lenovo XClarity Controller, 'openbmc' for OpenBMC or 'ipmi' for IPMI-over-LAN
 bmc_type: "ilo"
 
+# Optional contexts, switched with 'bmc-cli config use <name>' or --context. A context
+# overrides bmc_type and the host of that BMC type's section, and sets the default output
+# format (text, json or yaml).
+# current_context: "lab"
+# contexts:
+#   lab:
+#     bmc_type: "idrac"
+#     host: "192.168.1.101"
+#     output: "json"
+
+# Optional host inventory, selected with --host <name> and filtered with selectors such as
+# 'check --selector rack=r12'. Settings a host leaves out come from its BMC type's section.
+# Edit it with 'bmc-cli hosts add/remove' or fill it with 'bmc-cli hosts import'.
+# hosts:
+#   - name: "r740-01"
+#     bmc_type: "idrac"
+#     address: "192.168.1.111"
+#     groups: ["compute"]
+#     labels:
+#       rack: "r12"
+
 # HPE iLO Configuration
 ilo:
   host: "192.168.1.100"          # iLO IP address or hostname
@@ -136,14 +407,67 @@ ilo:
   password: "password"           # iLO password  
   port: 443                      # iLO port (default: 443)
   use_https: true                # Use HTTPS (default: true)
+  tls:
+    ca_file: ""                  # PEM CA bundle to verify the certificate (default: pin on first use)
+    server_name: ""              # Name to verify when the certificate does not match the host
+    insecure: false              # Skip certificate verification (not recommended)
 
 # DELL iDRAC Configuration
 idrac:
   host: "192.168.1.101"          # iDRAC IP address or hostname
   username: "root"               # iDRAC username
-  password: "calvin"             # iDRAC password
+  password_cmd: "pass show bmc/idrac"  # Command printing the password; or use password,
+                                       # password_file, password_env, or leave all unset to
+                                       # use the keyring (bmc-cli creds set 192.168.1.101)
   port: 443                      # iDRAC port (default: 443)
   use_https: true                # Use HTTPS (default: true)
+  tls:
+    ca_file: ""                  # PEM CA bundle to verify the certificate (default: pin on first use)
+    server_name: ""              # Name to verify when the certificate does not match the host
+    insecure: false              # Skip certificate verification (not recommended)
+
+# Supermicro BMC Configuration
+supermicro:
+  host: "192.168.1.102"          # BMC IP address or hostname
+  username: "ADMIN"              # BMC username
+  password: "password"           # BMC password
+  port: 443                      # BMC port (default: 443)
+  use_https: true                # Use HTTPS (default: true)
+  tls:
+    ca_file: ""                  # PEM CA bundle to verify the certificate (default: pin on first use)
+    server_name: ""              # Name to verify when the certificate does not match the host
+    insecure: false              # Skip certificate verification (not recommended)
+
+# Lenovo XClarity Controller Configuration
+xcc:
+  host: "192.168.1.103"          # XCC IP address or hostname
+  username: "USERID"             # XCC username
+  password: "PASSW0RD"           # XCC password
+  port: 443                      # XCC port (default: 443)
+  use_https: true                # Use HTTPS (default: true)
+  tls:
+    ca_file: ""                  # PEM CA bundle to verify the certificate (default: pin on first use)
+    server_name: ""              # Name to verify when the certificate does not match the host
+    insecure: false              # Skip certificate verification (not recommended)
+
+# OpenBMC Configuration
+openbmc:
+  host: "192.168.1.104"          # BMC IP address or hostname
+  username: "root"               # BMC username
+  password: "0penBmc"            # BMC password
+  port: 443                      # BMC port (default: 443)
+  use_https: true                # Use HTTPS (default: true)
+  tls:
+    ca_file: ""                  # PEM CA bundle to verify the certificate (default: pin on first use)
+    server_name: ""              # Name to verify when the certificate does not match the host
+    insecure: false              # Skip certificate verification (not recommended)
+
+# IPMI-over-LAN (RMCP+) Configuration for BMCs without Redfish
+ipmi:
+  host: "192.168.1.105"          # BMC IP address or hostname
+  username: "admin"              # IPMI username
+  password: "password"           # IPMI password
+  port: 623                      # IPMI port (default: 623)
 `
 
 	configPath := filepath.Join(".", "config.yaml")
@@ -162,24 +486,109 @@ idrac:
 
 // NewBMCClient creates a BMC client based on the configuration
 func NewBMCClient() (BMCClient, error) {
-	switch config.BMCType {
+	return config.newClient()
+}
+
+// newClient creates a client for the BMC selected by this configuration
+func (c *Config) newClient() (BMCClient, error) {
+	switch c.BMCType {
 	case BMCTypeILO:
+		password, err := resolvePassword(c.ILO.Host, c.ILO.Password, c.ILO.PasswordSource)
+		if err != nil {
+			return nil, err
+		}
+		transport, err := newBMCTransport(c.ILO.Host, c.ILO.Port, c.ILO.TLS, c.ILO.Proxy)
+		if err != nil {
+			return nil, err
+		}
 		return NewILOClient(
-			config.ILO.Host,
-			config.ILO.Username,
-			config.ILO.Password,
-			config.ILO.Port,
-			config.ILO.UseHTTPS,
+			c.ILO.Host,
+			c.ILO.Username,
+			password,
+			c.ILO.Port,
+			c.ILO.UseHTTPS,
+			transport,
 		), nil
 	case BMCTypeIDRAC:
+		password, err := resolvePassword(c.IDRAC.Host, c.IDRAC.Password, c.IDRAC.PasswordSource)
+		if err != nil {
+			return nil, err
+		}
+		transport, err := newBMCTransport(c.IDRAC.Host, c.IDRAC.Port, c.IDRAC.TLS, c.IDRAC.Proxy)
+		if err != nil {
+			return nil, err
+		}
 		return NewIDRACClient(
-			config.IDRAC.Host,
-			config.IDRAC.Username,
-			config.IDRAC.Password,
-			config.IDRAC.Port,
-			config.IDRAC.UseHTTPS,
+			c.IDRAC.Host,
+			c.IDRAC.Username,
+			password,
+			c.IDRAC.Port,
+			c.IDRAC.UseHTTPS,
+			transport,
+		), nil
+	case BMCTypeSupermicro:
+		password, err := resolvePassword(c.Supermicro.Host, c.Supermicro.Password, c.Supermicro.PasswordSource)
+		if err != nil {
+			return nil, err
+		}
+		transport, err := newBMCTransport(c.Supermicro.Host, c.Supermicro.Port, c.Supermicro.TLS, c.Supermicro.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		return NewSupermicroClient(
+			c.Supermicro.Host,
+			c.Supermicro.Username,
+			password,
+			c.Supermicro.Port,
+			c.Supermicro.UseHTTPS,
+			transport,
+		), nil
+	case BMCTypeXCC:
+		password, err := resolvePassword(c.XCC.Host, c.XCC.Password, c.XCC.PasswordSource)
+		if err != nil {
+			return nil, err
+		}
+		transport, err := newBMCTransport(c.XCC.Host, c.XCC.Port, c.XCC.TLS, c.XCC.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		return NewXCCClient(
+			c.XCC.Host,
+			c.XCC.Username,
+			password,
+			c.XCC.Port,
+			c.XCC.UseHTTPS,
+			transport,
+		), nil
+	case BMCTypeOpenBMC:
+		password, err := resolvePassword(c.OpenBMC.Host, c.OpenBMC.Password, c.OpenBMC.PasswordSource)
+		if err != nil {
+			return nil, err
+		}
+		transport, err := newBMCTransport(c.OpenBMC.Host, c.OpenBMC.Port, c.OpenBMC.TLS, c.OpenBMC.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		return NewOpenBMCClient(
+			c.OpenBMC.Host,
+			c.OpenBMC.Username,
+			password,
+			c.OpenBMC.Port,
+			c.OpenBMC.UseHTTPS,
+			transport,
+		), nil
+	case BMCTypeIPMI:
+		password, err := resolvePassword(c.IPMI.Host, c.IPMI.Password, c.IPMI.PasswordSource)
+		if err != nil {
+			return nil, err
+		}
+		return NewIPMIClient(
+			c.IPMI.Host,
+			c.IPMI.Username,
+			password,
+			c.IPMI.Port,
 		), nil
 	default:
-		return nil, fmt.Errorf("unsupported BMC type: %s", config.BMCType)
+		return nil, fmt.Errorf("unsupported BMC type: %s", c.BMCType)
 	}
 }
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/spf13/viper"
@@ -45,6 +48,25 @@ func TestValidateConfig_IDRAC(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_Supermicro(t *testing.T) {
+	// Test valid Supermicro configuration
+	config = Config{
+		BMCType: BMCTypeSupermicro,
+		Supermicro: SupermicroConfig{
+			Host:     "192.168.1.102",
+			Username: "ADMIN",
+			Password: "password",
+			Port:     443,
+			UseHTTPS: true,
+		},
+	}
+
+	err := validateConfig()
+	if err != nil {
+		t.Errorf("Expected no error for valid Supermicro config, got: %v", err)
+	}
+}
+
 func TestValidateConfig_MissingILOHost(t *testing.T) {
 	config = Config{
 		BMCType: BMCTypeILO,
@@ -130,6 +152,89 @@ func TestNewBMCClient_IDRAC(t *testing.T) {
 	}
 }
 
+func TestNewBMCClient_Supermicro(t *testing.T) {
+	config = Config{
+		BMCType: BMCTypeSupermicro,
+		Supermicro: SupermicroConfig{
+			Host:     "192.168.1.102",
+			Username: "ADMIN",
+			Password: "password",
+			Port:     443,
+			UseHTTPS: true,
+		},
+	}
+
+	client, err := NewBMCClient()
+	if err != nil {
+		t.Errorf("Expected no error creating Supermicro client, got: %v", err)
+	}
+	if _, ok := client.(*SupermicroClient); !ok {
+		t.Errorf("Expected *SupermicroClient, got: %T", client)
+	}
+}
+
+func TestNewBMCClient_XCC(t *testing.T) {
+	config = Config{
+		BMCType: BMCTypeXCC,
+		XCC: XCCConfig{
+			Host:     "192.168.1.103",
+			Username: "USERID",
+			Password: "PASSW0RD",
+			Port:     443,
+			UseHTTPS: true,
+		},
+	}
+
+	client, err := NewBMCClient()
+	if err != nil {
+		t.Errorf("Expected no error creating XCC client, got: %v", err)
+	}
+	if _, ok := client.(*XCCClient); !ok {
+		t.Errorf("Expected *XCCClient, got: %T", client)
+	}
+}
+
+func TestNewBMCClient_OpenBMC(t *testing.T) {
+	config = Config{
+		BMCType: BMCTypeOpenBMC,
+		OpenBMC: OpenBMCConfig{
+			Host:     "192.168.1.104",
+			Username: "root",
+			Password: "0penBmc",
+			Port:     443,
+			UseHTTPS: true,
+		},
+	}
+
+	client, err := NewBMCClient()
+	if err != nil {
+		t.Errorf("Expected no error creating OpenBMC client, got: %v", err)
+	}
+	if _, ok := client.(*OpenBMCClient); !ok {
+		t.Errorf("Expected *OpenBMCClient, got: %T", client)
+	}
+}
+
+func TestNewBMCClient_IPMI(t *testing.T) {
+	config = Config{
+		BMCType: BMCTypeIPMI,
+		IPMI: IPMIConfig{
+			Host:     "192.168.1.105",
+			Username: "ADMIN",
+			Password: "password",
+			Port:     623,
+		},
+	}
+
+	client, err := NewBMCClient()
+	if err != nil {
+		t.Errorf("Expected no error creating IPMI client, got: %v", err)
+	}
+	if _, ok := client.(*IPMIClient); !ok {
+		t.Errorf("Expected *IPMIClient, got: %T", client)
+	}
+}
+
 func TestNewBMCClient_UnsupportedType(t *testing.T) {
 	config = Config{
 		BMCType: "unsupported",
@@ -227,3 +332,142 @@ func TestLoadConfigFromEnvironment_IDRAC(t *testing.T) {
 	os.Unsetenv("IDRAC_USE_HTTPS")
 	config = originalConfig
 }
+
+func TestLoadConfigFromEnvironment_PasswordCmd(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+
+	t.Setenv("BMC_TYPE", "idrac")
+	t.Setenv("IDRAC_HOST", "192.168.1.101")
+	t.Setenv("IDRAC_USERNAME", "root")
+	t.Setenv("IDRAC_PASSWORD_CMD", "echo calvin")
+	viper.Reset()
+
+	if err := loadConfig(); err != nil {
+		t.Fatalf("Expected no error loading config from environment, got: %v", err)
+	}
+	if config.IDRAC.PasswordCmd != "echo calvin" {
+		t.Errorf("Expected password_cmd 'echo calvin', got: %q", config.IDRAC.PasswordCmd)
+	}
+
+	// The command only runs when the client is created
+	client, err := NewBMCClient()
+	if err != nil {
+		t.Fatalf("Expected no error creating client, got: %v", err)
+	}
+	if password := client.(*IDRACClient).password; password != "calvin" {
+		t.Errorf("Expected password 'calvin', got: %q", password)
+	}
+}
+
+func TestValidateConfigFile(t *testing.T) {
+	data := []byte(`bmc_type: idarc
+current_context: prod
+idrac:
+  host: 10.0.0.1
+  prot: 443
+  port: 70000
+  password: 12345
+  tls:
+    insecure: "yes"
+ilo:
+  host: 10.0.0.1
+hosts:
+  - name: r740-01
+    address: 10.0.0.2
+    port: 0
+  - name: R740-01
+    labels: [gpu]
+`)
+	problems, err := validateConfigFile(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var got []string
+	for _, problem := range problems {
+		got = append(got, fmt.Sprintf("%d %s", problem.Line, problem.Key))
+	}
+	expected := []string{
+		"1 bmc_type",
+		"2 current_context",
+		"4 idrac.host",
+		"5 idrac.prot",
+		"6 idrac.port",
+		"7 idrac.password",
+		"9 idrac.tls.insecure",
+		"15 hosts[0].port",
+		"16 hosts[1].name",
+		"16 hosts[1]",
+		"17 hosts[1].labels",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected problems:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if !strings.Contains(problems[3].Message, `did you mean "port"`) {
+		t.Errorf("Expected a suggestion for the misspelt key, got: %s", problems[3].Message)
+	}
+
+	problems, err = validateConfigFile([]byte(testPlainConfig))
+	if err != nil || len(problems) != 0 {
+		t.Errorf("Expected a valid config, got %v: %v", problems, err)
+	}
+}
+
+func TestConfigFileType(t *testing.T) {
+	tests := map[string]string{
+		"config.yaml":     "yaml",
+		"bmc.yml":         "yml",
+		"config.json":     "json",
+		"CONFIG.TOML":     "toml",
+		"config.yaml.age": "yaml",
+		"config.json.age": "json",
+		"secrets":         "yaml",
+	}
+	for path, expected := range tests {
+		if got := configFileType(path); got != expected {
+			t.Errorf("%s: expected %s, got %s", path, expected, got)
+		}
+	}
+}
+
+func TestLoadConfig_Formats(t *testing.T) {
+	originalConfig, originalFile := config, cfgFile
+	defer func() { config, cfgFile = originalConfig, originalFile }()
+
+	// A --config file in another format is read according to its extension
+	dir := t.TempDir()
+	cfgFile = filepath.Join(dir, "bmc.toml")
+	if err := os.WriteFile(cfgFile, []byte("bmc_type = \"idrac\"\n\n[idrac]\nhost = \"10.0.0.21\"\nusername = \"root\"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	viper.Reset()
+	if err := loadConfig(); err != nil {
+		t.Fatalf("Expected no error loading a TOML config, got: %v", err)
+	}
+	if config.BMCType != BMCTypeIDRAC || config.IDRAC.Host != "10.0.0.21" {
+		t.Errorf("Unexpected config: %s %+v", config.BMCType, config.IDRAC)
+	}
+
+	// Without --config, config.json in the working directory is found
+	cfgFile = ""
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, ".config"))
+	t.Chdir(dir)
+	if err := os.WriteFile("config.json", []byte(`{"bmc_type": "ilo", "ilo": {"host": "10.0.0.31", "username": "admin"}}`), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if path := configFilePath(); path != "config.json" {
+		t.Errorf("Expected config.json to be found, got %q", path)
+	}
+	if _, err := writableConfigFile(); err == nil {
+		t.Error("Expected error modifying a JSON config file")
+	}
+	viper.Reset()
+	if err := loadConfig(); err != nil {
+		t.Fatalf("Expected no error loading a JSON config, got: %v", err)
+	}
+	if config.BMCType != BMCTypeILO || config.ILO.Host != "10.0.0.31" {
+		t.Errorf("Unexpected config: %s %+v", config.BMCType, config.ILO)
+	}
+}
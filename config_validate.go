@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// supportedBMCTypes lists the values accepted for bmc_type
+var supportedBMCTypes = []BMCType{BMCTypeILO, BMCTypeIDRAC, BMCTypeSupermicro, BMCTypeXCC, BMCTypeOpenBMC, BMCTypeIPMI}
+
+// configProblem is one finding of 'config validate'
+type configProblem struct {
+	Line    int    `json:"line" yaml:"line"`
+	Key     string `json:"key" yaml:"key"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// validateConfigFile checks a configuration file against the Config schema (unknown keys,
+// wrong value types) and for values that cannot work (bad ports, unsupported BMC types,
+// hosts configured twice, a missing current context). Encrypted files are decrypted first.
+func validateConfigFile(data []byte) ([]configProblem, error) {
+	plaintext, _, err := decryptConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(plaintext, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing config: %w", err)
+	}
+	root := documentRoot(&doc)
+	if root == nil {
+		return nil, nil
+	}
+
+	var problems []configProblem
+	checkConfigNode(root, reflect.TypeOf(Config{}), "", &problems)
+	if root.Kind == yaml.MappingNode {
+		checkConfigConsistency(root, &problems)
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems, nil
+}
+
+// checkConfigNode checks that node has the shape of t, descending into mappings
+func checkConfigNode(node *yaml.Node, t reflect.Type, path string, problems *[]configProblem) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			addConfigProblem(problems, node, path, "expected a mapping")
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := joinConfigPath(path, key.Value)
+			// Viper matches keys case-insensitively
+			field, ok := fields[strings.ToLower(key.Value)]
+			if !ok {
+				if path == "" && key.Value == sopsMetadataKey {
+					continue
+				}
+				addConfigProblem(problems, key, childPath, "unknown key"+suggestKey(key.Value, fields))
+				continue
+			}
+			checkConfigNode(value, field, childPath, problems)
+			if value.Kind == yaml.ScalarNode {
+				checkConfigValue(value, strings.ToLower(key.Value), childPath, problems)
+			}
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			addConfigProblem(problems, node, path, "expected a mapping")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkConfigNode(node.Content[i+1], t.Elem(), joinConfigPath(path, node.Content[i].Value), problems)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			addConfigProblem(problems, node, path, "expected a list")
+			return
+		}
+		for i, item := range node.Content {
+			checkConfigNode(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!str" {
+			addConfigProblem(problems, node, path, "expected a string"+quoteHint(node))
+		}
+	case reflect.Int:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!int" {
+			addConfigProblem(problems, node, path, "expected an integer")
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!bool" {
+			addConfigProblem(problems, node, path, "expected true or false")
+		}
+	}
+}
+
+// checkConfigValue checks the values of keys with a restricted range
+func checkConfigValue(node *yaml.Node, key, path string, problems *[]configProblem) {
+	switch key {
+	case "port":
+		var port int
+		if node.Decode(&port) == nil && (port < 1 || port > 65535) {
+			addConfigProblem(problems, node, path, fmt.Sprintf("port %d is out of range (1-65535)", port))
+		}
+	case "bmc_type":
+		if !slices.Contains(supportedBMCTypes, BMCType(node.Value)) {
+			addConfigProblem(problems, node, path, fmt.Sprintf("unsupported BMC type %q (supported types: ilo, idrac, supermicro, xcc, openbmc, ipmi)", node.Value))
+		}
+	case "output":
+		if err := validateOutputFormat(node.Value); err != nil {
+			addConfigProblem(problems, node, path, err.Error())
+		}
+	}
+}
+
+// checkConfigConsistency checks relations between settings: the current context exists,
+// context and inventory host names are unique, inventory hosts have an address and no host
+// is configured in two BMC sections
+func checkConfigConsistency(root *yaml.Node, problems *[]configProblem) {
+	contexts := map[string]bool{}
+	if node := mappingValue(root, "contexts"); node != nil && node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			name := strings.ToLower(key.Value)
+			if contexts[name] {
+				addConfigProblem(problems, key, "contexts."+key.Value, "duplicate context name (context names are case-insensitive)")
+			}
+			contexts[name] = true
+		}
+	}
+
+	if node := mappingValue(root, "current_context"); node != nil && node.Kind == yaml.ScalarNode && node.Value != "" {
+		if !contexts[strings.ToLower(node.Value)] {
+			addConfigProblem(problems, node, "current_context", fmt.Sprintf("context %q is not defined under contexts", node.Value))
+		}
+	}
+
+	hosts := map[string]string{}
+	for _, bmcType := range supportedBMCTypes {
+		section := mappingValue(root, string(bmcType))
+		host := mappingValue(section, "host")
+		if host == nil || host.Kind != yaml.ScalarNode || host.Value == "" {
+			continue
+		}
+		name := strings.ToLower(host.Value)
+		if other, ok := hosts[name]; ok {
+			addConfigProblem(problems, host, string(bmcType)+".host", fmt.Sprintf("host %s is also configured in the %s section", host.Value, other))
+			continue
+		}
+		hosts[name] = string(bmcType)
+	}
+
+	names := map[string]bool{}
+	if node := mappingValue(root, "hosts"); node != nil && node.Kind == yaml.SequenceNode {
+		for i, entry := range node.Content {
+			if entry.Kind != yaml.MappingNode {
+				continue
+			}
+			path := fmt.Sprintf("hosts[%d]", i)
+			name := mappingValue(entry, "name")
+			if name == nil || name.Value == "" {
+				addConfigProblem(problems, entry, path, "host has no name")
+			} else if names[strings.ToLower(name.Value)] {
+				addConfigProblem(problems, name, path+".name", fmt.Sprintf("duplicate host name %q (host names are case-insensitive)", name.Value))
+			} else {
+				names[strings.ToLower(name.Value)] = true
+			}
+			if address := mappingValue(entry, "address"); address == nil || address.Value == "" {
+				addConfigProblem(problems, entry, path, "host has no address")
+			}
+		}
+	}
+}
+
+// yamlFields maps the lower-cased yaml keys of a struct to their field types, including the
+// fields of inlined structs
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if options == "inline" {
+			for key, value := range yamlFields(field.Type) {
+				fields[key] = value
+			}
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[strings.ToLower(name)] = field.Type
+	}
+	return fields
+}
+
+func addConfigProblem(problems *[]configProblem, node *yaml.Node, key, message string) {
+	if key == "" {
+		key = "(root)"
+	}
+	*problems = append(*problems, configProblem{Line: node.Line, Key: key, Message: message})
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// quoteHint suggests quoting scalars YAML reads as another type, such as a numeric password
+func quoteHint(node *yaml.Node) string {
+	if node.Kind == yaml.ScalarNode {
+		return fmt.Sprintf(" (quote the value: %q)", node.Value)
+	}
+	return ""
+}
+
+// suggestKey proposes the known key closest to an unknown one, if any is close
+func suggestKey(key string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", 3
+	for candidate := range fields {
+		if d := editDistance(strings.ToLower(key), candidate); d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
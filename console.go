@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// consoleDefaults are the SSH port of each BMC type and the command of its CLI that
+// attaches to the host serial console; OpenBMC serves the console directly on its own port
+var consoleDefaults = map[BMCType]struct {
+	Port    int
+	Command string
+}{
+	BMCTypeILO:        {22, "vsp"},
+	BMCTypeIDRAC:      {22, "console com2"},
+	BMCTypeSupermicro: {22, "start /system1/sol1"},
+	BMCTypeXCC:        {22, "console 1"},
+	BMCTypeOpenBMC:    {2200, ""},
+}
+
+// consoleOptions selects how the serial console is reached; zero values use the BMC
+// type's defaults
+type consoleOptions struct {
+	Port    int
+	Command string
+	// Width and Height are the size of the terminal requested for the session
+	Width  int
+	Height int
+}
+
+// consoleSession is an SSH session attached to the host serial console
+type consoleSession struct {
+	Address string
+	Command string
+	Stdin   io.WriteCloser
+	Stdout  io.Reader
+
+	client  *ssh.Client
+	session *ssh.Session
+}
+
+// Close ends the session and the SSH connection
+func (c *consoleSession) Close() error {
+	c.session.Close()
+	return c.client.Close()
+}
+
+// openConsole connects to the BMC over SSH with the configured credentials and starts the
+// command that attaches to the host serial console. The BMC's host key is pinned in the
+// known hosts file on first use, like its certificate.
+func (c *Config) openConsole(ctx context.Context, opts consoleOptions) (*consoleSession, error) {
+	section, ok := c.activeSection()
+	if !ok {
+		return nil, fmt.Errorf("unsupported BMC type: %s", c.BMCType)
+	}
+	defaults, ok := consoleDefaults[c.BMCType]
+	if !ok {
+		return nil, fmt.Errorf("the serial console is not supported for BMC type %s", c.BMCType)
+	}
+	if *section.Host == "" {
+		return nil, fmt.Errorf("no host configured for BMC type %s", c.BMCType)
+	}
+	if section.Proxy.URL != "" {
+		return nil, fmt.Errorf("the serial console cannot use a proxy url; use a jump host instead")
+	}
+
+	port, command := opts.Port, opts.Command
+	if port == 0 {
+		port = defaults.Port
+	}
+	if command == "" {
+		command = defaults.Command
+	}
+	address := net.JoinHostPort(*section.Host, strconv.Itoa(port))
+
+	hosts, err := newKnownHosts()
+	if err != nil {
+		return nil, err
+	}
+	password, err := resolvePassword(*section.Host, *section.Password, *section.Source)
+	if err != nil {
+		return nil, err
+	}
+	config := &ssh.ClientConfig{
+		User: *section.Username,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(password),
+			// Some BMCs only offer keyboard-interactive, prompting for the password
+			ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = password
+				}
+				return answers, nil
+			}),
+		},
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			fingerprint := ssh.FingerprintSHA256(key)
+			pinned, err := hosts.pin(address, fingerprint, "SSH host key")
+			if err != nil || pinned == fingerprint {
+				return err
+			}
+			return fmt.Errorf("SSH HOST KEY OF %s HAS CHANGED: expected fingerprint %s, got %s. "+
+				"If the change is expected, remove the entry for %s from %s", address, pinned, fingerprint, address, hosts.path)
+		},
+		Timeout: requestTimeout,
+	}
+
+	conn, err := dialConsole(ctx, *section.Proxy, address)
+	if err != nil {
+		return nil, err
+	}
+	// The handshake is not context aware, so cancellation closes the socket under it
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, address, config)
+	stop()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error opening SSH session to %s: %w", address, err)
+	}
+	client := ssh.NewClient(sshConn, channels, requests)
+
+	console, err := startConsole(client, command, opts)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	console.Address = address
+	return console, nil
+}
+
+// dialConsole connects to the BMC's SSH port, through the jump host if one is configured
+func dialConsole(ctx context.Context, proxy ProxyConfig, address string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	if proxy.Jump != "" {
+		jump, err := newSSHJump(proxy)
+		if err != nil {
+			return nil, err
+		}
+		return jump.DialContext(ctx, "tcp", address)
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", address, err)
+	}
+	return conn, nil
+}
+
+// startConsole opens a session with a terminal, which BMC CLIs need, and runs the console
+// command, or the login shell when there is none
+func startConsole(client *ssh.Client, command string, opts consoleOptions) (*consoleSession, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("error opening SSH session: %w", err)
+	}
+
+	width, height := opts.Width, opts.Height
+	if width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+	modes := ssh.TerminalModes{ssh.ECHO: 0, ssh.TTY_OP_ISPEED: 115200, ssh.TTY_OP_OSPEED: 115200}
+	if err := session.RequestPty("vt100", height, width, modes); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("error requesting a terminal: %w", err)
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	if command == "" {
+		err = session.Shell()
+	} else {
+		err = session.Start(command)
+	}
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("error starting %q: %w", command, err)
+	}
+	return &consoleSession{Command: command, Stdin: stdin, Stdout: stdout, client: client, session: session}, nil
+}
+
+// escapeFilter scans keyboard input for the detach sequence: the escape character at the
+// start of a line followed by a dot. The escape character typed twice sends it once.
+type escapeFilter struct {
+	escape byte
+	// midLine is set once a character other than a line break was sent
+	midLine bool
+	// pending is set after the escape character was typed at the start of a line
+	pending bool
+}
+
+// filter returns the input to send to the console and whether the detach sequence was typed
+func (f *escapeFilter) filter(in []byte) ([]byte, bool) {
+	if f.escape == 0 {
+		return in, false
+	}
+
+	out := make([]byte, 0, len(in))
+	for _, b := range in {
+		if f.pending {
+			f.pending = false
+			switch b {
+			case '.':
+				return out, true
+			case f.escape:
+				out = append(out, b)
+				f.midLine = true
+				continue
+			}
+			out = append(out, f.escape)
+		} else if b == f.escape && !f.midLine {
+			f.pending = true
+			continue
+		}
+		out = append(out, b)
+		f.midLine = b != '\r' && b != '\n'
+	}
+	return out, false
+}
+
+// attachConsole relays input to the console and its output to out until the console
+// command exits, the detach sequence is typed or ctx is canceled
+func attachConsole(ctx context.Context, console *consoleSession, in io.Reader, out io.Writer, escape byte) error {
+	detached := make(chan struct{})
+	go func() {
+		filter := &escapeFilter{escape: escape}
+		buf := make([]byte, 256)
+		for {
+			n, err := in.Read(buf)
+			if n > 0 {
+				data, detach := filter.filter(buf[:n])
+				if len(data) > 0 {
+					if _, err := console.Stdin.Write(data); err != nil {
+						return
+					}
+				}
+				if detach {
+					close(detached)
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(out, console.Stdout)
+		copied <- err
+	}()
+
+	select {
+	case err := <-copied:
+		return err
+	case <-detached:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ansiSequence matches the terminal control sequences BMC consoles emit, such as colors and
+// cursor movement, which are left out of console logs
+var ansiSequence = regexp.MustCompile(`\x1b(\[[0-9;?]*[ -/]*[@-~]|[()][0-9A-Za-z]|[=>78cDEHMZ])`)
+
+// consoleLogger writes console output to a log one timestamped line at a time, and signals
+// when until matches a line, including the partial last line a login prompt leaves
+type consoleLogger struct {
+	out   io.Writer
+	until *regexp.Regexp
+	now   func() time.Time
+
+	line    []byte
+	started time.Time
+	// matched is closed when until matches
+	matched   chan struct{}
+	closeOnce sync.Once
+}
+
+func newConsoleLogger(out io.Writer, until *regexp.Regexp) *consoleLogger {
+	return &consoleLogger{out: out, until: until, now: time.Now, matched: make(chan struct{})}
+}
+
+func (l *consoleLogger) Write(p []byte) (int, error) {
+	for _, b := range p {
+		switch b {
+		case '\r':
+			// Consoles end lines with CRLF
+		case '\n':
+			if err := l.flush(); err != nil {
+				return 0, err
+			}
+		default:
+			if len(l.line) == 0 {
+				l.started = l.now()
+			}
+			l.line = append(l.line, b)
+		}
+	}
+
+	if l.until != nil && len(l.line) > 0 && l.until.MatchString(cleanConsoleLine(l.line)) {
+		if err := l.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// flush writes the current line, if any, and checks it against until
+func (l *consoleLogger) flush() error {
+	if len(l.line) == 0 {
+		return nil
+	}
+	text := cleanConsoleLine(l.line)
+	l.line = l.line[:0]
+	if _, err := fmt.Fprintf(l.out, "%s %s\n", l.started.Format("2006-01-02T15:04:05.000Z07:00"), text); err != nil {
+		return err
+	}
+	if l.until != nil && l.until.MatchString(text) {
+		l.closeOnce.Do(func() { close(l.matched) })
+	}
+	return nil
+}
+
+func cleanConsoleLine(line []byte) string {
+	return ansiSequence.ReplaceAllString(string(line), "")
+}
+
+// logConsole streams the console into logger until its pattern matches, the console
+// command exits or ctx is done. It keeps reading the console output until the console is
+// closed, so the console is not reused afterwards.
+func logConsole(ctx context.Context, console *consoleSession, logger *consoleLogger) error {
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(logger, console.Stdout)
+		copied <- err
+	}()
+
+	select {
+	case <-logger.matched:
+		return nil
+	case err := <-copied:
+		// The pattern may have matched in the last output
+		select {
+		case <-logger.matched:
+			return nil
+		default:
+		}
+		if err == nil {
+			err = logger.flush()
+		}
+		if err != nil {
+			return err
+		}
+		return io.EOF
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
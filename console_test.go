@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/crypto/ssh"
+)
+
+// startTestConsole runs an SSH server accepting admin/password that answers the console
+// command with a banner and echoes input back, returning its address
+func startTestConsole(t *testing.T) string {
+	_, hostPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate host key: %v", err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPrivate)
+	if err != nil {
+		t.Fatalf("Failed to create host signer: %v", err)
+	}
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "admin" && string(password) == "password" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("access denied")
+		},
+	}
+	serverConfig.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestConsole(conn, serverConfig)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveTestConsole(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer channel.Close()
+			for request := range channelRequests {
+				switch request.Type {
+				case "pty-req":
+					_ = request.Reply(true, nil)
+				case "exec":
+					var payload struct{ Command string }
+					_ = ssh.Unmarshal(request.Payload, &payload)
+					_ = request.Reply(true, nil)
+					fmt.Fprintf(channel, "[%s]\r\n", payload.Command)
+					// Echo until the host "powers off"
+					buf := make([]byte, 256)
+					for {
+						n, err := channel.Read(buf)
+						_, _ = channel.Write(buf[:n])
+						if err != nil || bytes.Contains(buf[:n], []byte("poweroff")) {
+							_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+							return
+						}
+					}
+				default:
+					_ = request.Reply(false, nil)
+				}
+			}
+		}()
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for a writer and a reader in different goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newTestConsoleConfig(t *testing.T, password string) (Config, int) {
+	host, portText, _ := net.SplitHostPort(startTestConsole(t))
+	port, _ := strconv.Atoi(portText)
+	knownHostsFile = filepath.Join(t.TempDir(), "known_hosts")
+	t.Cleanup(func() { knownHostsFile = "" })
+	return Config{
+		BMCType: BMCTypeIDRAC,
+		IDRAC:   IDRACConfig{Host: host, Port: 443, Username: "admin", Password: password, UseHTTPS: true},
+	}, port
+}
+
+func TestEscapeFilter(t *testing.T) {
+	tests := []struct {
+		input  []string
+		output string
+		detach bool
+	}{
+		{[]string{"ls\r"}, "ls\r", false},
+		{[]string{"~."}, "", true},
+		{[]string{"ls\r~.more"}, "ls\r", true},
+		{[]string{"ls\r~", "."}, "ls\r", true},
+		{[]string{"a~.b"}, "a~.b", false},
+		{[]string{"~~.\r"}, "~.\r", false},
+		{[]string{"~x"}, "~x", false},
+	}
+	for _, test := range tests {
+		filter := &escapeFilter{escape: '~'}
+		var output []byte
+		detach := false
+		for _, chunk := range test.input {
+			out, d := filter.filter([]byte(chunk))
+			output = append(output, out...)
+			if d {
+				detach = true
+				break
+			}
+		}
+		if string(output) != test.output || detach != test.detach {
+			t.Errorf("filter(%q) = %q, %v; want %q, %v", test.input, output, detach, test.output, test.detach)
+		}
+	}
+
+	if out, detach := (&escapeFilter{}).filter([]byte("~.")); string(out) != "~." || detach {
+		t.Errorf("Expected no escape handling when disabled, got %q %v", out, detach)
+	}
+}
+
+func TestParseEscapeChar(t *testing.T) {
+	for value, want := range map[string]byte{"~": '~', "^]": 0x1d, "^A": 1, "none": 0} {
+		if got, err := parseEscapeChar(value); err != nil || got != want {
+			t.Errorf("parseEscapeChar(%q) = %d, %v; want %d", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "ab", "^a"} {
+		if _, err := parseEscapeChar(value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
+
+func TestConsole(t *testing.T) {
+	cfg, port := newTestConsoleConfig(t, "password")
+	ctx := context.Background()
+
+	console, err := cfg.openConsole(ctx, consoleOptions{Port: port})
+	if err != nil {
+		t.Fatalf("Failed to open console: %v", err)
+	}
+	defer console.Close()
+	if console.Command != "console com2" {
+		t.Errorf("Expected the iDRAC console command, got %q", console.Command)
+	}
+
+	// Detaching leaves the console command running
+	in, input := io.Pipe()
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() { done <- attachConsole(ctx, console, in, out, '~') }()
+	_, _ = input.Write([]byte("hello\r"))
+	_, _ = input.Write([]byte("~."))
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected a clean detach, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the detach")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "hello") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := out.String(); !strings.HasPrefix(got, "[console com2]\r\n") || !strings.Contains(got, "hello\r") {
+		t.Errorf("Unexpected console output: %q", got)
+	}
+
+	// The session ends when the console command exits, and the pinned host key is reused
+	console, err = cfg.openConsole(ctx, consoleOptions{Port: port, Command: "connect com2"})
+	if err != nil {
+		t.Fatalf("Failed to reopen console: %v", err)
+	}
+	defer console.Close()
+	in, input = io.Pipe()
+	go func() { _, _ = input.Write([]byte("poweroff\r")) }()
+	out = &syncBuffer{}
+	if err := attachConsole(ctx, console, in, out, '~'); err != nil {
+		t.Errorf("Expected the console to end cleanly, got: %v", err)
+	}
+	if got := out.String(); !strings.HasPrefix(got, "[connect com2]\r\n") {
+		t.Errorf("Unexpected console output: %q", got)
+	}
+}
+
+func TestConsole_PasswordEnv(t *testing.T) {
+	cfg, port := newTestConsoleConfig(t, "")
+	cfg.IDRAC.PasswordEnv = "CONSOLE_TEST_PASSWORD"
+	t.Setenv("CONSOLE_TEST_PASSWORD", "password")
+
+	console, err := cfg.openConsole(context.Background(), consoleOptions{Port: port})
+	if err != nil {
+		t.Fatalf("Expected the password from the environment to be used, got: %v", err)
+	}
+	console.Close()
+
+	t.Setenv("CONSOLE_TEST_PASSWORD", "")
+	if _, err := cfg.openConsole(context.Background(), consoleOptions{Port: port}); err == nil {
+		t.Error("Expected error without a password")
+	}
+}
+
+func TestConsole_Errors(t *testing.T) {
+	cfg, port := newTestConsoleConfig(t, "wrong")
+	if _, err := cfg.openConsole(context.Background(), consoleOptions{Port: port}); err == nil || !strings.Contains(err.Error(), "unable to authenticate") {
+		t.Errorf("Expected authentication error, got: %v", err)
+	}
+
+	cfg.BMCType = BMCTypeIPMI
+	cfg.IPMI = IPMIConfig{Host: "10.0.0.1", Username: "admin", Password: "password"}
+	if _, err := cfg.openConsole(context.Background(), consoleOptions{}); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("Expected unsupported error for IPMI, got: %v", err)
+	}
+}
+
+func TestConsoleLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger := newConsoleLogger(&out, regexp.MustCompile(`login:\s*$`))
+	logger.now = func() time.Time { return time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC) }
+
+	_, _ = logger.Write([]byte("\x1b[2J\x1b[1;32mBooting\x1b[0m Linux\r\nStarting sshd"))
+	_, _ = logger.Write([]byte("...\r\n\r\nrocky9"))
+	select {
+	case <-logger.matched:
+		t.Fatal("Expected no match yet")
+	default:
+	}
+	_, _ = logger.Write([]byte(" login: "))
+	select {
+	case <-logger.matched:
+	default:
+		t.Fatal("Expected the login prompt to match")
+	}
+
+	expected := "2026-10-18T09:30:00.000Z Booting Linux\n" +
+		"2026-10-18T09:30:00.000Z Starting sshd...\n" +
+		"2026-10-18T09:30:00.000Z rocky9 login: \n"
+	if out.String() != expected {
+		t.Errorf("Expected log:\n%q\ngot:\n%q", expected, out.String())
+	}
+}
+
+func TestConsoleLog(t *testing.T) {
+	cfg, port := newTestConsoleConfig(t, "password")
+	ctx := context.Background()
+
+	// The test console echoes input, standing in for the host's boot output
+	logOutput := func(ctx context.Context, output string, until string, out io.Writer) error {
+		console, err := cfg.openConsole(context.Background(), consoleOptions{Port: port})
+		if err != nil {
+			t.Fatalf("Failed to open console: %v", err)
+		}
+		defer console.Close()
+		_, _ = console.Stdin.Write([]byte(output))
+		return logConsole(ctx, console, newConsoleLogger(out, regexp.MustCompile(until)))
+	}
+
+	out := &syncBuffer{}
+	if err := logOutput(ctx, "Installing packages\r\nInstallation complete\r\n", "Installation complete", out); err != nil {
+		t.Fatalf("Expected the pattern to match, got: %v", err)
+	}
+	if got := out.String(); !strings.Contains(got, " [console com2]\n") || !strings.HasSuffix(got, " Installation complete\n") {
+		t.Errorf("Unexpected log: %q", got)
+	}
+
+	// Without a match the timeout ends logging
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := logOutput(timeoutCtx, "Installing packages\r\n", "never", io.Discard); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a timeout, got: %v", err)
+	}
+
+	// The console closing first is reported as EOF
+	if err := logOutput(ctx, "poweroff\r\n", "never", io.Discard); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected EOF when the console closes, got: %v", err)
+	}
+}
+
+// TestLocalFlagsDoNotShadowGlobalFlags guards against commands like console log defining a
+// flag of their own that hides a global flag such as --timeout or --context
+func TestLocalFlagsDoNotShadowGlobalFlags(t *testing.T) {
+	global := rootCmd.PersistentFlags()
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		cmd.LocalNonPersistentFlags().VisitAll(func(flag *pflag.Flag) {
+			if global.Lookup(flag.Name) != nil || (flag.Shorthand != "" && global.ShorthandLookup(flag.Shorthand) != nil) {
+				t.Errorf("%s: --%s shadows a global flag", cmd.CommandPath(), flag.Name)
+			}
+		})
+		for _, child := range cmd.Commands() {
+			walk(child)
+		}
+	}
+	walk(rootCmd)
+}
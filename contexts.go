@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// contextName selects a context for a single command, overriding current_context
+var contextName string
+
+// ContextConfig represents a named set of defaults, like a kubectl context: which BMC the
+// commands talk to and how their output is presented
+type ContextConfig struct {
+	// BMCType selects the vendor section used by this context
+	BMCType BMCType `yaml:"bmc_type" mapstructure:"bmc_type"`
+	// Host overrides the host of that vendor section, or names an inventory host
+	Host string `yaml:"host" mapstructure:"host"`
+	// Selector is the default selector for commands working on the host inventory
+	Selector string `yaml:"selector" mapstructure:"selector"`
+	// Output is the default output format (text, json or yaml)
+	Output string `yaml:"output" mapstructure:"output"`
+}
+
+// bmcSection points at the settings of the vendor section selected by bmc_type
+type bmcSection struct {
+	Host     *string
+	Username *string
+	Password *string
+	Source   *PasswordSource
+	Port     *int
+	// UseHTTPS, TLS and Proxy are nil for IPMI, which does not use HTTP
+	UseHTTPS *bool
+	TLS      *TLSConfig
+	Proxy    *ProxyConfig
+}
+
+// activeSection returns the vendor section for the configured BMC type
+func (c *Config) activeSection() (bmcSection, bool) {
+	switch c.BMCType {
+	case BMCTypeILO:
+		return bmcSection{&c.ILO.Host, &c.ILO.Username, &c.ILO.Password, &c.ILO.PasswordSource, &c.ILO.Port, &c.ILO.UseHTTPS, &c.ILO.TLS, &c.ILO.Proxy}, true
+	case BMCTypeIDRAC:
+		return bmcSection{&c.IDRAC.Host, &c.IDRAC.Username, &c.IDRAC.Password, &c.IDRAC.PasswordSource, &c.IDRAC.Port, &c.IDRAC.UseHTTPS, &c.IDRAC.TLS, &c.IDRAC.Proxy}, true
+	case BMCTypeSupermicro:
+		return bmcSection{&c.Supermicro.Host, &c.Supermicro.Username, &c.Supermicro.Password, &c.Supermicro.PasswordSource, &c.Supermicro.Port, &c.Supermicro.UseHTTPS, &c.Supermicro.TLS, &c.Supermicro.Proxy}, true
+	case BMCTypeXCC:
+		return bmcSection{&c.XCC.Host, &c.XCC.Username, &c.XCC.Password, &c.XCC.PasswordSource, &c.XCC.Port, &c.XCC.UseHTTPS, &c.XCC.TLS, &c.XCC.Proxy}, true
+	case BMCTypeOpenBMC:
+		return bmcSection{&c.OpenBMC.Host, &c.OpenBMC.Username, &c.OpenBMC.Password, &c.OpenBMC.PasswordSource, &c.OpenBMC.Port, &c.OpenBMC.UseHTTPS, &c.OpenBMC.TLS, &c.OpenBMC.Proxy}, true
+	case BMCTypeIPMI:
+		return bmcSection{&c.IPMI.Host, &c.IPMI.Username, &c.IPMI.Password, &c.IPMI.PasswordSource, &c.IPMI.Port, nil, nil, nil}, true
+	}
+	return bmcSection{}, false
+}
+
+// activeContext returns the selected context, if any
+func (c *Config) activeContext() (ContextConfig, bool) {
+	if c.CurrentContext == "" {
+		return ContextConfig{}, false
+	}
+	ctx, ok := c.Contexts[strings.ToLower(c.CurrentContext)]
+	return ctx, ok
+}
+
+// applyContext overlays the selected context (--context, or current_context) on the
+// configuration
+func (c *Config) applyContext() error {
+	if contextName != "" {
+		c.CurrentContext = contextName
+	}
+	if c.CurrentContext == "" {
+		return nil
+	}
+
+	ctx, ok := c.activeContext()
+	if !ok {
+		return fmt.Errorf("context %q not found (available: %s)", c.CurrentContext, strings.Join(c.contextNames(), ", "))
+	}
+	if ctx.Output != "" {
+		if err := validateOutputFormat(ctx.Output); err != nil {
+			return fmt.Errorf("context %q: %w", c.CurrentContext, err)
+		}
+	}
+
+	if err := c.overlay(ctx); err != nil {
+		return fmt.Errorf("context %q: %w", c.CurrentContext, err)
+	}
+	return nil
+}
+
+// overlay applies a context's BMC type and host to the configuration. A host naming an
+// inventory entry selects that entry.
+func (c *Config) overlay(ctx ContextConfig) error {
+	if ctx.BMCType != "" {
+		c.BMCType = ctx.BMCType
+	}
+	if ctx.Host == "" {
+		return nil
+	}
+	if h, ok := c.findHost(ctx.Host); ok {
+		return c.useHost(h)
+	}
+	if section, ok := c.activeSection(); ok {
+		*section.Host = ctx.Host
+	}
+	return nil
+}
+
+// contextNames returns the configured context names in order
+func (c *Config) contextNames() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// userConfigFile is the per-user configuration file, used when there is no config.yaml in
+// the current directory: $XDG_CONFIG_HOME/bmc-cli/config.yaml on Linux
+func userConfigFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating user config directory: %w", err)
+	}
+	return filepath.Join(dir, "bmc-cli", "config.yaml"), nil
+}
+
+// writableConfigFile returns the file 'config set' and 'config use' modify: the config file
+// in use, or the per-user file when there is none yet. Only YAML files are edited, since
+// other formats would be rewritten as YAML.
+func writableConfigFile() (string, error) {
+	path := configFilePath()
+	if path == "" {
+		return userConfigFile()
+	}
+	if configType := configFileType(path); configType != "yaml" && configType != "yml" {
+		return "", fmt.Errorf("%s is a %s file; only YAML config files can be modified", path, configType)
+	}
+	return path, nil
+}
+
+// setConfigValue sets a dotted key (such as "idrac.host" or "contexts.lab.output") in a
+// config file. The value is parsed as YAML so numbers and booleans keep their type.
+func setConfigValue(path, key, value string) error {
+	keys := strings.Split(key, ".")
+	for _, k := range keys {
+		if k == "" {
+			return fmt.Errorf("invalid key %q", key)
+		}
+	}
+
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	var parsed yaml.Node
+	if value != "" && yaml.Unmarshal([]byte(value), &parsed) == nil && len(parsed.Content) == 1 {
+		valueNode = parsed.Content[0]
+	}
+
+	return updateConfigFile(path, func(root *yaml.Node) error {
+		node := root
+		for i, k := range keys[:len(keys)-1] {
+			child := mappingValue(node, k)
+			if child == nil {
+				child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, child)
+			} else if child.Kind != yaml.MappingNode {
+				return fmt.Errorf("cannot set %s: %s is not a mapping", key, strings.Join(keys[:i+1], "."))
+			}
+			node = child
+		}
+		setMappingValue(node, keys[len(keys)-1], valueNode)
+		return nil
+	})
+}
+
+// updateConfigFile applies edit to the top-level mapping of a config file, creating the file
+// if needed and keeping its comments and key order. Encrypted files are decrypted and
+// encrypted again for the same recipients.
+func updateConfigFile(path string, edit func(root *yaml.Node) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+	plaintext, encryption, err := decryptConfig(data)
+	if err != nil {
+		return fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(plaintext, &doc); err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := documentRoot(&doc)
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s is not a YAML mapping", path)
+	}
+	if err := edit(root); err != nil {
+		return err
+	}
+
+	out, err := encodeYAML(&doc)
+	if err != nil {
+		return err
+	}
+	if encryption != nil {
+		if out, err = encryptConfig(out, *encryption); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("error creating config directory: %w", err)
+	}
+	return writeFilePreservingMode(path, out)
+}
+
+// setMappingValue sets key in a mapping node, keeping the line comment of a replaced value
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	if existing := mappingValue(mapping, key); existing != nil {
+		value.LineComment = existing.LineComment
+		*existing = *value
+		return
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// configFileContexts returns the context names defined in a config file and its
+// current_context
+func configFileContexts(path string) ([]string, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("error reading config file: %w", err)
+	}
+	plaintext, _, err := decryptConfig(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+
+	var file struct {
+		CurrentContext string                   `yaml:"current_context"`
+		Contexts       map[string]ContextConfig `yaml:"contexts"`
+	}
+	if err := yaml.Unmarshal(plaintext, &file); err != nil {
+		return nil, "", fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	names := make([]string, 0, len(file.Contexts))
+	for name := range file.Contexts {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	return names, strings.ToLower(file.CurrentContext), nil
+}
+
+// redactSettings replaces passwords, and credentials in proxy URLs, in a settings tree,
+// including lists of settings such as the host inventory
+func redactSettings(settings map[string]interface{}) {
+	for key, value := range settings {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			redactSettings(v)
+		case []interface{}:
+			redactSettingsList(v)
+		case string:
+			if key == "password" && v != "" {
+				settings[key] = "REDACTED"
+			} else if key == "url" {
+				if u, err := url.Parse(v); err == nil && u.User != nil {
+					settings[key] = u.Redacted()
+				}
+			}
+		}
+	}
+}
+
+func redactSettingsList(list []interface{}) {
+	for _, item := range list {
+		switch v := item.(type) {
+		case map[string]interface{}:
+			redactSettings(v)
+		case []interface{}:
+			redactSettingsList(v)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService is the service name BMC passwords are stored under in the OS keyring
+const keyringService = "bmc-cli"
+
+// PasswordSource represents where a BMC password comes from when it is not written in the
+// configuration. Without any source the OS keyring is consulted.
+type PasswordSource struct {
+	// PasswordFile is a file holding the password, such as a mounted secret
+	PasswordFile string `yaml:"password_file,omitempty" mapstructure:"password_file" json:"password_file,omitempty"`
+	// PasswordCmd is a shell command printing the password, such as "pass show bmc/r740"
+	PasswordCmd string `yaml:"password_cmd,omitempty" mapstructure:"password_cmd" json:"password_cmd,omitempty"`
+	// PasswordEnv is the name of an environment variable holding the password
+	PasswordEnv string `yaml:"password_env,omitempty" mapstructure:"password_env" json:"password_env,omitempty"`
+}
+
+// resolvePassword returns the password for host: the literal password if set, otherwise the
+// value from the configured source, otherwise the one stored with 'creds set'. It is only
+// called for BMCs that are about to be contacted, so helpers prompting for a master
+// password or touching a hardware token do not run needlessly.
+func resolvePassword(host, password string, source PasswordSource) (string, error) {
+	sources := 0
+	for _, value := range []string{password, source.PasswordFile, source.PasswordCmd, source.PasswordEnv} {
+		if value != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return "", fmt.Errorf("only one of password, password_file, password_cmd and password_env may be set for %s", host)
+	}
+
+	switch {
+	case password != "":
+		return password, nil
+	case source.PasswordFile != "":
+		data, err := os.ReadFile(expandHome(source.PasswordFile))
+		if err != nil {
+			return "", fmt.Errorf("error reading password file: %w", err)
+		}
+		return nonEmptyPassword(host, strings.TrimRight(string(data), "\r\n"), "password_file")
+	case source.PasswordCmd != "":
+		return runPasswordCommand(host, source.PasswordCmd)
+	case source.PasswordEnv != "":
+		return nonEmptyPassword(host, os.Getenv(source.PasswordEnv), "$"+source.PasswordEnv)
+	}
+
+	stored, err := keyring.Get(keyringService, host)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("no password configured for %s (set password, password_file, password_cmd or password_env, or run 'bmc-cli creds set %s')", host, host)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading password for %s from the keyring: %w", host, err)
+	}
+	return stored, nil
+}
+
+// runPasswordCommand runs a password helper through the shell and returns the first line
+// it prints. The helper's stderr and stdin stay attached so it can prompt.
+func runPasswordCommand(host, command string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("password_cmd for %s failed: %w", host, err)
+	}
+
+	line, _, _ := strings.Cut(stdout.String(), "\n")
+	return nonEmptyPassword(host, strings.TrimRight(line, "\r"), "password_cmd")
+}
+
+func nonEmptyPassword(host, password, source string) (string, error) {
+	if password == "" {
+		return "", fmt.Errorf("%s returned an empty password for %s", source, host)
+	}
+	return password, nil
+}
+
+// storePassword saves a password for host in the OS keyring
+func storePassword(host, password string) error {
+	if err := keyring.Set(keyringService, host, password); err != nil {
+		return fmt.Errorf("error storing password in the keyring: %w", err)
+	}
+	return nil
+}
+
+// deletePassword removes the password for host from the OS keyring
+func deletePassword(host string) error {
+	err := keyring.Delete(keyringService, host)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("no password stored for %s", host)
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting password from the keyring: %w", err)
+	}
+	return nil
+}
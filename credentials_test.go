@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestResolvePassword_Sources(t *testing.T) {
+	keyring.MockInit()
+
+	if password, err := resolvePassword("bmc1", "literal", PasswordSource{}); err != nil || password != "literal" {
+		t.Errorf("Expected literal password, got %q: %v", password, err)
+	}
+
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+	if password, err := resolvePassword("bmc1", "", PasswordSource{PasswordFile: file}); err != nil || password != "from-file" {
+		t.Errorf("Expected password from file, got %q: %v", password, err)
+	}
+
+	if password, err := resolvePassword("bmc1", "", PasswordSource{PasswordCmd: "printf 'from-cmd\\nignored'"}); err != nil || password != "from-cmd" {
+		t.Errorf("Expected password from command, got %q: %v", password, err)
+	}
+	if _, err := resolvePassword("bmc1", "", PasswordSource{PasswordCmd: "exit 3"}); err == nil {
+		t.Error("Expected error for a failing password command")
+	}
+
+	t.Setenv("BMC1_SECRET", "from-env")
+	if password, err := resolvePassword("bmc1", "", PasswordSource{PasswordEnv: "BMC1_SECRET"}); err != nil || password != "from-env" {
+		t.Errorf("Expected password from environment, got %q: %v", password, err)
+	}
+	if _, err := resolvePassword("bmc1", "", PasswordSource{PasswordEnv: "BMC1_UNSET"}); err == nil {
+		t.Error("Expected error for an unset password variable")
+	}
+
+	if _, err := resolvePassword("bmc1", "literal", PasswordSource{PasswordEnv: "BMC1_SECRET"}); err == nil {
+		t.Error("Expected error when more than one source is set")
+	}
+}
+
+func TestResolvePassword_Keyring(t *testing.T) {
+	keyring.MockInit()
+
+	_, err := resolvePassword("192.168.1.101", "", PasswordSource{})
+	if err == nil || !strings.Contains(err.Error(), "bmc-cli creds set 192.168.1.101") {
+		t.Errorf("Expected hint to store a password, got: %v", err)
+	}
+
+	if err := storePassword("192.168.1.101", "calvin"); err != nil {
+		t.Fatalf("Expected no error storing password, got: %v", err)
+	}
+	if password, err := resolvePassword("192.168.1.101", "", PasswordSource{}); err != nil || password != "calvin" {
+		t.Errorf("Expected password from keyring, got %q: %v", password, err)
+	}
+
+	if err := deletePassword("192.168.1.101"); err != nil {
+		t.Fatalf("Expected no error deleting password, got: %v", err)
+	}
+	if err := deletePassword("192.168.1.101"); err == nil {
+		t.Error("Expected error deleting a password that is not stored")
+	}
+}
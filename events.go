@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	eventServicePath  = "/redfish/v1/EventService"
+	subscriptionsPath = eventServicePath + "/Subscriptions"
+
+	// maxEventSize bounds the body of an incoming event
+	maxEventSize = 1 << 20
+)
+
+// eventSubscription is a Redfish event destination registered on the BMC
+type eventSubscription struct {
+	ID          string   `json:"id" yaml:"id"`
+	Destination string   `json:"destination" yaml:"destination"`
+	EventTypes  []string `json:"event_types,omitempty" yaml:"event_types,omitempty"`
+	Context     string   `json:"context,omitempty" yaml:"context,omitempty"`
+	Protocol    string   `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+}
+
+// redfishSubscription is the EventDestination resource
+type redfishSubscription struct {
+	ID          string   `json:"Id"`
+	Destination string   `json:"Destination"`
+	EventTypes  []string `json:"EventTypes"`
+	Context     string   `json:"Context"`
+	Protocol    string   `json:"Protocol"`
+}
+
+func (s redfishSubscription) summary() eventSubscription {
+	return eventSubscription{ID: s.ID, Destination: s.Destination, EventTypes: s.EventTypes, Context: s.Context, Protocol: s.Protocol}
+}
+
+// eventService manages the event subscriptions of a BMC's Redfish EventService
+type eventService struct {
+	requester RedfishRequester
+}
+
+// list returns the subscriptions registered on the BMC
+func (s *eventService) list(ctx context.Context) ([]eventSubscription, error) {
+	var collection struct {
+		Members []redfishLink `json:"Members"`
+	}
+	if err := redfishJSON(ctx, s.requester, http.MethodGet, subscriptionsPath, nil, &collection); err != nil {
+		return nil, err
+	}
+
+	subscriptions := []eventSubscription{}
+	for _, member := range collection.Members {
+		var subscription redfishSubscription
+		if err := redfishJSON(ctx, s.requester, http.MethodGet, member.OdataID, nil, &subscription); err != nil {
+			return nil, err
+		}
+		if subscription.ID == "" {
+			subscription.ID = path.Base(member.OdataID)
+		}
+		subscriptions = append(subscriptions, subscription.summary())
+	}
+	return subscriptions, nil
+}
+
+// subscribe registers destination for the given event types, after checking that the
+// event service is enabled and supports them
+func (s *eventService) subscribe(ctx context.Context, destination string, eventTypes []string, eventContext string) (*eventSubscription, error) {
+	if u, err := url.Parse(destination); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid destination %q (use an http:// or https:// URL)", destination)
+	}
+
+	var service struct {
+		ServiceEnabled            *bool    `json:"ServiceEnabled"`
+		EventTypesForSubscription []string `json:"EventTypesForSubscription"`
+	}
+	if err := redfishJSON(ctx, s.requester, http.MethodGet, eventServicePath, nil, &service); err != nil {
+		return nil, err
+	}
+	if service.ServiceEnabled != nil && !*service.ServiceEnabled {
+		return nil, fmt.Errorf("the event service is disabled on the BMC")
+	}
+	if len(service.EventTypesForSubscription) > 0 {
+		for _, eventType := range eventTypes {
+			if !containsString(service.EventTypesForSubscription, eventType) {
+				return nil, fmt.Errorf("event type %s is not supported (supported types: %s)", eventType, strings.Join(service.EventTypesForSubscription, ", "))
+			}
+		}
+	}
+
+	request := redfishSubscription{Destination: destination, EventTypes: eventTypes, Context: eventContext, Protocol: "Redfish"}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+	resp, err := s.requester.RedfishRequest(ctx, http.MethodPost, subscriptionsPath, body, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("creating the subscription failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	// The new subscription is identified by its Location, which some BMCs return without a body
+	var created redfishSubscription
+	_ = json.Unmarshal(respBody, &created)
+	if created.ID == "" {
+		created.ID = path.Base(strings.TrimSuffix(resp.Header.Get("Location"), "/"))
+	}
+	if created.Destination == "" {
+		created = redfishSubscription{ID: created.ID, Destination: destination, EventTypes: eventTypes, Context: eventContext, Protocol: "Redfish"}
+	}
+	subscription := created.summary()
+	return &subscription, nil
+}
+
+// delete removes a subscription, given its Id or its resource path
+func (s *eventService) delete(ctx context.Context, id string) error {
+	endpoint := id
+	if !strings.HasPrefix(id, "/") {
+		endpoint = subscriptionsPath + "/" + id
+	}
+	resp, err := s.requester.RedfishRequest(ctx, http.MethodDelete, endpoint, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("no subscription %s", id)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("deleting subscription %s failed with status %d: %s", id, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// receivedEvent is one event record received from a BMC, written as a JSON line
+type receivedEvent struct {
+	Received time.Time       `json:"received"`
+	Source   string          `json:"source"`
+	Context  string          `json:"context,omitempty"`
+	Event    json.RawMessage `json:"event"`
+}
+
+// eventReceiver accepts the events BMCs post to a subscription's destination and writes each
+// event record as a JSON line to out, or posts it to forward
+type eventReceiver struct {
+	out     io.Writer
+	forward string
+	client  *http.Client
+	now     func() time.Time
+	// errOut receives delivery failures, which are otherwise only reported to the BMC
+	errOut io.Writer
+
+	mu sync.Mutex
+}
+
+func newEventReceiver(out io.Writer, forward string) *eventReceiver {
+	return &eventReceiver{out: out, forward: forward, client: &http.Client{Timeout: requestTimeout}, now: time.Now, errOut: os.Stderr}
+}
+
+func (e *eventReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "events must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxEventSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	lines, err := e.parse(r, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := e.deliver(r.Context(), lines); err != nil {
+		// A failed delivery makes the BMC retry the event later
+		fmt.Fprintf(e.errOut, "Failed to deliver event from %s: %v\n", r.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parse splits an Event payload into its records. Older firmware posts a single record
+// rather than an Event with an Events array.
+func (e *eventReceiver) parse(r *http.Request, body []byte) ([][]byte, error) {
+	var payload struct {
+		Context string            `json:"Context"`
+		Events  []json.RawMessage `json:"Events"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+	records := payload.Events
+	if records == nil {
+		records = []json.RawMessage{body}
+	}
+
+	source := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(source); err == nil {
+		source = host
+	}
+	received := e.now().UTC()
+
+	var lines [][]byte
+	for _, record := range records {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, record); err != nil {
+			return nil, fmt.Errorf("invalid event: %w", err)
+		}
+		line, err := json.Marshal(receivedEvent{Received: received, Source: source, Context: payload.Context, Event: compact.Bytes()})
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// deliver writes the lines to out, or posts each one to the forward URL
+func (e *eventReceiver) deliver(ctx context.Context, lines [][]byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, line := range lines {
+		if e.forward == "" {
+			if _, err := fmt.Fprintf(e.out, "%s\n", line); err != nil {
+				return err
+			}
+			continue
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.forward, bytes.NewReader(line))
+		if err != nil {
+			return fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := e.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("error forwarding event: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("forwarding the event failed with status %d", resp.StatusCode)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEventSubscriptions(t *testing.T) {
+	check := newCheckTarget(t, "password")
+	client, err := check.Config.newClient()
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(client)
+	service := &eventService{requester: client.(RedfishRequester)}
+	ctx := context.Background()
+
+	subscription, err := service.subscribe(ctx, "https://10.0.0.5:8443/hook", []string{"Alert", "StatusChange"}, "r740-01")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if subscription.ID != "1" || subscription.Destination != "https://10.0.0.5:8443/hook" {
+		t.Errorf("Unexpected subscription: %+v", subscription)
+	}
+
+	subscriptions, err := service.list(ctx)
+	if err != nil || len(subscriptions) != 1 {
+		t.Fatalf("Expected one subscription, got: %+v (%v)", subscriptions, err)
+	}
+	if got := subscriptions[0]; got.ID != "1" || strings.Join(got.EventTypes, ",") != "Alert,StatusChange" || got.Context != "r740-01" || got.Protocol != "Redfish" {
+		t.Errorf("Unexpected subscription: %+v", got)
+	}
+
+	for _, invalid := range []struct {
+		destination string
+		eventTypes  []string
+		err         string
+	}{
+		{"10.0.0.5:8443", []string{"Alert"}, "invalid destination"},
+		{"https://10.0.0.5:8443/hook", []string{"Alert", "Telemetry"}, "event type Telemetry is not supported"},
+		{"http://10.0.0.5:8443/hook", []string{"Alert"}, "must be an HTTPS URI"},
+	} {
+		if _, err := service.subscribe(ctx, invalid.destination, invalid.eventTypes, ""); err == nil || !strings.Contains(err.Error(), invalid.err) {
+			t.Errorf("Expected %q error for %s %v, got: %v", invalid.err, invalid.destination, invalid.eventTypes, err)
+		}
+	}
+
+	if err := service.delete(ctx, "1"); err != nil {
+		t.Fatalf("Failed to delete subscription: %v", err)
+	}
+	if subscriptions, err := service.list(ctx); err != nil || len(subscriptions) != 0 {
+		t.Errorf("Expected no subscriptions, got: %+v (%v)", subscriptions, err)
+	}
+	if err := service.delete(ctx, "1"); err == nil || !strings.Contains(err.Error(), "no subscription 1") {
+		t.Errorf("Expected not found error, got: %v", err)
+	}
+}
+
+func TestEventReceiver(t *testing.T) {
+	var out syncBuffer
+	receiver := newEventReceiver(&out, "")
+	receiver.now = func() time.Time { return time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC) }
+	server := httptest.NewTLSServer(receiver)
+	defer server.Close()
+
+	post := func(url, body string) int {
+		resp, err := server.Client().Post(url, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to post event: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	event := `{"@odata.type": "#Event.v1_4_0.Event", "Context": "r740-01", "Events": [
+		{"EventType": "Alert", "MessageId": "PSU0003", "Severity": "Critical"},
+		{"EventType": "StatusChange", "MessageId": "SYS1003"}]}`
+	if status := post(server.URL+"/hook", event); status != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", status)
+	}
+	// Older firmware posts the record itself
+	if status := post(server.URL, `{"EventType": "Alert", "MessageId": "TMP0120"}`); status != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", status)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	expected := []string{
+		`{"received":"2026-10-18T09:30:00Z","source":"127.0.0.1","context":"r740-01","event":{"EventType":"Alert","MessageId":"PSU0003","Severity":"Critical"}}`,
+		`{"received":"2026-10-18T09:30:00Z","source":"127.0.0.1","context":"r740-01","event":{"EventType":"StatusChange","MessageId":"SYS1003"}}`,
+		`{"received":"2026-10-18T09:30:00Z","source":"127.0.0.1","event":{"EventType":"Alert","MessageId":"TMP0120"}}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected lines:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+
+	if status := post(server.URL, "not json"); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid event, got %d", status)
+	}
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", resp.StatusCode)
+	}
+}
+
+func TestEventReceiver_Forward(t *testing.T) {
+	var mu sync.Mutex
+	var forwarded []receivedEvent
+	fail := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var event receivedEvent
+		if err := json.Unmarshal(body, &event); err != nil || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected forwarded event %q: %v", body, err)
+		}
+		forwarded = append(forwarded, event)
+	}))
+	defer target.Close()
+
+	receiver := newEventReceiver(nil, target.URL)
+	receiver.errOut = io.Discard
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	post := func() int {
+		resp, err := http.Post(server.URL, "application/json", strings.NewReader(`{"Events": [{"EventType": "Alert"}, {"EventType": "Alert"}]}`))
+		if err != nil {
+			t.Fatalf("Failed to post event: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post(); status != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", status)
+	}
+	mu.Lock()
+	if len(forwarded) != 2 || string(forwarded[0].Event) != `{"EventType":"Alert"}` {
+		t.Errorf("Unexpected forwarded events: %+v", forwarded)
+	}
+	fail = true
+	mu.Unlock()
+
+	// The BMC is told to retry when forwarding fails
+	if status := post(); status != http.StatusBadGateway {
+		t.Errorf("Expected 502 when forwarding fails, got %d", status)
+	}
+}
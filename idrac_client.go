@@ -2,13 +2,13 @@ package main
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
-	"time"
 )
 
 // IDRACClient represents an iDRAC API client
@@ -20,7 +20,7 @@ type IDRACClient struct {
 }
 
 // NewIDRACClient creates a new iDRAC client
-func NewIDRACClient(host, username, password string, port int, useHTTPS bool) BMCClient {
+func NewIDRACClient(host, username, password string, port int, useHTTPS bool, transport *http.Transport) BMCClient {
 	scheme := "http"
 	if useHTTPS {
 		scheme = "https"
@@ -28,28 +28,16 @@ func NewIDRACClient(host, username, password string, port int, useHTTPS bool) BM
 
 	baseURL := fmt.Sprintf("%s://%s:%d", scheme, host, port)
 
-	// Create HTTP client with custom transport for SSL/TLS
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true, // For self-signed certificates
-		},
-	}
-
-	client := &http.Client{
-		Timeout:   30 * time.Second,
-		Transport: transport,
-	}
-
 	return &IDRACClient{
 		baseURL:    baseURL,
 		username:   username,
 		password:   password,
-		httpClient: client,
+		httpClient: newHTTPClient(transport),
 	}
 }
 
 // makeRequest makes an authenticated HTTP request to the iDRAC API
-func (c *IDRACClient) makeRequest(method, endpoint string, body interface{}) (*http.Response, error) {
+func (c *IDRACClient) makeRequest(ctx context.Context, method, endpoint string, body interface{}) (*http.Response, error) {
 	var bodyReader io.Reader
 	if body != nil {
 		jsonBody, err := json.Marshal(body)
@@ -60,7 +48,7 @@ func (c *IDRACClient) makeRequest(method, endpoint string, body interface{}) (*h
 	}
 
 	url := c.baseURL + endpoint
-	req, err := http.NewRequest(method, url, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
@@ -82,8 +70,8 @@ func (c *IDRACClient) makeRequest(method, endpoint string, body interface{}) (*h
 }
 
 // GetSystemInfo retrieves basic system information
-func (c *IDRACClient) GetSystemInfo() (*SystemInfo, error) {
-	resp, err := c.makeRequest("GET", "/redfish/v1/Systems/System.Embedded.1", nil)
+func (c *IDRACClient) GetSystemInfo(ctx context.Context) (*SystemInfo, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/redfish/v1/Systems/System.Embedded.1", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -102,12 +90,16 @@ func (c *IDRACClient) GetSystemInfo() (*SystemInfo, error) {
 }
 
 // SetPowerState changes the server power state
-func (c *IDRACClient) SetPowerState(state PowerState) error {
+func (c *IDRACClient) SetPowerState(ctx context.Context, state PowerState) error {
+	resetType, err := redfishResetType(ctx, c, "/redfish/v1/Systems/System.Embedded.1", state)
+	if err != nil {
+		return err
+	}
 	powerRequest := PowerRequest{
-		ResetType: string(state),
+		ResetType: resetType,
 	}
 
-	resp, err := c.makeRequest("POST", "/redfish/v1/Systems/System.Embedded.1/Actions/ComputerSystem.Reset", powerRequest)
+	resp, err := c.makeRequest(ctx, "POST", "/redfish/v1/Systems/System.Embedded.1/Actions/ComputerSystem.Reset", powerRequest)
 	if err != nil {
 		return err
 	}
@@ -122,8 +114,8 @@ func (c *IDRACClient) SetPowerState(state PowerState) error {
 }
 
 // GetVirtualMedia lists available virtual media slots
-func (c *IDRACClient) GetVirtualMedia() ([]VirtualMediaInfo, error) {
-	resp, err := c.makeRequest("GET", "/redfish/v1/Managers/iDRAC.Embedded.1/VirtualMedia", nil)
+func (c *IDRACClient) GetVirtualMedia(ctx context.Context) ([]VirtualMediaInfo, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/redfish/v1/Managers/iDRAC.Embedded.1/VirtualMedia", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -145,8 +137,11 @@ func (c *IDRACClient) GetVirtualMedia() ([]VirtualMediaInfo, error) {
 
 	var virtualMediaList []VirtualMediaInfo
 	for _, member := range result.Members {
-		vmInfo, err := c.getVirtualMediaInfo(member.OdataID)
+		vmInfo, err := c.getVirtualMediaInfo(ctx, member.OdataID)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			continue // Skip this media slot if we can't get info
 		}
 		virtualMediaList = append(virtualMediaList, *vmInfo)
@@ -156,8 +151,8 @@ func (c *IDRACClient) GetVirtualMedia() ([]VirtualMediaInfo, error) {
 }
 
 // getVirtualMediaInfo gets information about a specific virtual media slot
-func (c *IDRACClient) getVirtualMediaInfo(odataID string) (*VirtualMediaInfo, error) {
-	resp, err := c.makeRequest("GET", odataID, nil)
+func (c *IDRACClient) getVirtualMediaInfo(ctx context.Context, odataID string) (*VirtualMediaInfo, error) {
+	resp, err := c.makeRequest(ctx, "GET", odataID, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -176,9 +171,9 @@ func (c *IDRACClient) getVirtualMediaInfo(odataID string) (*VirtualMediaInfo, er
 }
 
 // MountVirtualMedia mounts an image to the first available CD/DVD virtual media slot
-func (c *IDRACClient) MountVirtualMedia(imageURL string) error {
+func (c *IDRACClient) MountVirtualMedia(ctx context.Context, imageURL string) error {
 	// Get available virtual media slots
-	vmList, err := c.GetVirtualMedia()
+	vmList, err := c.GetVirtualMedia(ctx)
 	if err != nil {
 		return fmt.Errorf("error getting virtual media info: %w", err)
 	}
@@ -210,7 +205,7 @@ func (c *IDRACClient) MountVirtualMedia(imageURL string) error {
 		"Inserted": false,
 	}
 
-	resp, err := c.makeRequest("PATCH", targetSlot, ejectRequest)
+	resp, err := c.makeRequest(ctx, "PATCH", targetSlot, ejectRequest)
 	if err == nil {
 		resp.Body.Close()
 	}
@@ -221,7 +216,7 @@ func (c *IDRACClient) MountVirtualMedia(imageURL string) error {
 		"Inserted": true,
 	}
 
-	resp, err = c.makeRequest("PATCH", targetSlot, mountRequest)
+	resp, err = c.makeRequest(ctx, "PATCH", targetSlot, mountRequest)
 	if err != nil {
 		return err
 	}
@@ -236,8 +231,8 @@ func (c *IDRACClient) MountVirtualMedia(imageURL string) error {
 }
 
 // UnmountVirtualMedia unmounts virtual media from all slots
-func (c *IDRACClient) UnmountVirtualMedia() error {
-	vmList, err := c.GetVirtualMedia()
+func (c *IDRACClient) UnmountVirtualMedia(ctx context.Context) error {
+	vmList, err := c.GetVirtualMedia(ctx)
 	if err != nil {
 		return fmt.Errorf("error getting virtual media info: %w", err)
 	}
@@ -249,8 +244,11 @@ func (c *IDRACClient) UnmountVirtualMedia() error {
 			}
 
 			endpoint := fmt.Sprintf("/redfish/v1/Managers/iDRAC.Embedded.1/VirtualMedia/%s", vm.Name)
-			resp, err := c.makeRequest("PATCH", endpoint, unmountRequest)
+			resp, err := c.makeRequest(ctx, "PATCH", endpoint, unmountRequest)
 			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
 				continue // Continue with other slots
 			}
 			resp.Body.Close()
@@ -259,3 +257,55 @@ func (c *IDRACClient) UnmountVirtualMedia() error {
 
 	return nil
 }
+
+// SetBootOverride sets the boot source override for the next boot (or every boot when persistent)
+func (c *IDRACClient) SetBootOverride(ctx context.Context, target BootTarget, persistent bool) error {
+	resp, err := c.makeRequest(ctx, "PATCH", "/redfish/v1/Systems/System.Embedded.1", newBootOverrideRequest(target, persistent))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusAccepted {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("boot override failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// CaptureScreen exports a screenshot of the host's screen through the Lifecycle Controller,
+// which returns it base64 encoded in the action's response
+func (c *IDRACClient) CaptureScreen(ctx context.Context) ([]byte, error) {
+	endpoint := "/redfish/v1/Dell/Managers/iDRAC.Embedded.1/DellLCService/Actions/DellLCService.ExportServerScreenShot"
+	resp, err := c.makeRequest(ctx, "POST", endpoint, map[string]string{"FileType": "ServerScreenShot"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("screenshot export failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var result struct {
+		ServerScreenShotFile string `json:"ServerScreenShotFile"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	if result.ServerScreenShotFile == "" {
+		return nil, fmt.Errorf("the iDRAC returned no screenshot; the host may be powered off")
+	}
+	data, err := base64.StdEncoding.DecodeString(result.ServerScreenShotFile)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding screenshot: %w", err)
+	}
+	return screenshotPNG(data)
+}
+
+// RedfishRequest sends a raw request to the iDRAC Redfish API
+func (c *IDRACClient) RedfishRequest(ctx context.Context, method, endpoint string, body []byte, header http.Header) (*http.Response, error) {
+	return basicAuthRequest(ctx, c.httpClient, c.baseURL, c.username, c.password, method, endpoint, body, header)
+}
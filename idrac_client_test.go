@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -46,7 +47,7 @@ func TestIDRACClient_GetSystemInfo(t *testing.T) {
 	}
 
 	// Test GetSystemInfo
-	systemInfo, err := client.GetSystemInfo()
+	systemInfo, err := client.GetSystemInfo(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -90,7 +91,7 @@ func TestIDRACClient_SetPowerState_On(t *testing.T) {
 	}
 
 	// Test SetPowerState
-	err := client.SetPowerState(PowerStateOn)
+	err := client.SetPowerState(context.Background(), PowerStateOn)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -128,7 +129,7 @@ func TestIDRACClient_SetPowerState_Off(t *testing.T) {
 	}
 
 	// Test SetPowerState
-	err := client.SetPowerState(PowerStateOff)
+	err := client.SetPowerState(context.Background(), PowerStateOff)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -197,7 +198,7 @@ func TestIDRACClient_GetVirtualMedia(t *testing.T) {
 	}
 
 	// Test GetVirtualMedia
-	vmList, err := client.GetVirtualMedia()
+	vmList, err := client.GetVirtualMedia(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -282,7 +283,7 @@ func TestIDRACClient_MountVirtualMedia(t *testing.T) {
 	}
 
 	// Test MountVirtualMedia
-	err := client.MountVirtualMedia("http://example.com/image.iso")
+	err := client.MountVirtualMedia(context.Background(), "http://example.com/image.iso")
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -350,7 +351,7 @@ func TestIDRACClient_UnmountVirtualMedia(t *testing.T) {
 	}
 
 	// Test UnmountVirtualMedia
-	err := client.UnmountVirtualMedia()
+	err := client.UnmountVirtualMedia(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -375,13 +376,13 @@ func TestIDRACClient_ErrorHandling(t *testing.T) {
 	}
 
 	// Test error handling for GetSystemInfo
-	_, err := client.GetSystemInfo()
+	_, err := client.GetSystemInfo(context.Background())
 	if err == nil {
 		t.Error("Expected error for 401 response, got nil")
 	}
 
 	// Test error handling for SetPowerState
-	err = client.SetPowerState(PowerStateOn)
+	err = client.SetPowerState(context.Background(), PowerStateOn)
 	if err == nil {
 		t.Error("Expected error for 401 response, got nil")
 	}
@@ -434,7 +435,7 @@ func TestIDRACClient_NoVirtualMediaFound(t *testing.T) {
 	}
 
 	// Test MountVirtualMedia when no CD/DVD slot is available
-	err := client.MountVirtualMedia("http://example.com/image.iso")
+	err := client.MountVirtualMedia(context.Background(), "http://example.com/image.iso")
 	if err == nil {
 		t.Error("Expected error when no CD/DVD slot found, got nil")
 	}
@@ -442,3 +443,44 @@ func TestIDRACClient_NoVirtualMediaFound(t *testing.T) {
 		t.Errorf("Expected error about no CD/DVD slot, got: %v", err)
 	}
 }
+
+func TestIDRACClient_SetBootOverride(t *testing.T) {
+	// Create test server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/redfish/v1/Systems/System.Embedded.1" {
+			t.Errorf("Expected path '/redfish/v1/Systems/System.Embedded.1', got: %s", r.URL.Path)
+		}
+		if r.Method != "PATCH" {
+			t.Errorf("Expected PATCH request, got: %s", r.Method)
+		}
+
+		// Verify request body
+		var bootRequest BootOverrideRequest
+		if err := json.NewDecoder(r.Body).Decode(&bootRequest); err != nil {
+			t.Errorf("Error decoding request body: %v", err)
+		}
+		if bootRequest.Boot.BootSourceOverrideTarget != "Pxe" {
+			t.Errorf("Expected BootSourceOverrideTarget 'Pxe', got: %s", bootRequest.Boot.BootSourceOverrideTarget)
+		}
+		if bootRequest.Boot.BootSourceOverrideEnabled != "Continuous" {
+			t.Errorf("Expected BootSourceOverrideEnabled 'Continuous', got: %s", bootRequest.Boot.BootSourceOverrideEnabled)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &IDRACClient{
+		baseURL:    server.URL,
+		username:   "root",
+		password:   "calvin",
+		httpClient: server.Client(),
+	}
+
+	// Test SetBootOverride
+	err := client.SetBootOverride(context.Background(), BootTargetPXE, true)
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
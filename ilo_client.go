@@ -2,12 +2,12 @@ package main
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
+	"net/url"
 )
 
 // ILOClient represents an iLO API client
@@ -22,8 +22,9 @@ type ILOClient struct {
 type PowerState string
 
 const (
-	PowerStateOn  PowerState = "On"
-	PowerStateOff PowerState = "ForceOff"
+	PowerStateOn    PowerState = "On"
+	PowerStateOff   PowerState = "ForceOff"
+	PowerStateCycle PowerState = "PowerCycle"
 )
 
 // SystemInfo represents basic system information
@@ -33,6 +34,8 @@ type SystemInfo struct {
 		Health string `json:"Health"`
 		State  string `json:"State"`
 	} `json:"Status"`
+	// Boot is the boot source override; IPMI does not report it
+	Boot BootOverride `json:"Boot"`
 }
 
 // VirtualMediaInfo represents virtual media information
@@ -56,7 +59,7 @@ type VirtualMediaRequest struct {
 }
 
 // NewILOClient creates a new iLO client
-func NewILOClient(host, username, password string, port int, useHTTPS bool) BMCClient {
+func NewILOClient(host, username, password string, port int, useHTTPS bool, transport *http.Transport) BMCClient {
 	scheme := "http"
 	if useHTTPS {
 		scheme = "https"
@@ -64,28 +67,16 @@ func NewILOClient(host, username, password string, port int, useHTTPS bool) BMCC
 
 	baseURL := fmt.Sprintf("%s://%s:%d", scheme, host, port)
 
-	// Create HTTP client with custom transport for SSL/TLS
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true, // For self-signed certificates
-		},
-	}
-
-	client := &http.Client{
-		Timeout:   30 * time.Second,
-		Transport: transport,
-	}
-
 	return &ILOClient{
 		baseURL:    baseURL,
 		username:   username,
 		password:   password,
-		httpClient: client,
+		httpClient: newHTTPClient(transport),
 	}
 }
 
 // makeRequest makes an authenticated HTTP request to the iLO API
-func (c *ILOClient) makeRequest(method, endpoint string, body interface{}) (*http.Response, error) {
+func (c *ILOClient) makeRequest(ctx context.Context, method, endpoint string, body interface{}) (*http.Response, error) {
 	var bodyReader io.Reader
 	if body != nil {
 		jsonBody, err := json.Marshal(body)
@@ -96,7 +87,7 @@ func (c *ILOClient) makeRequest(method, endpoint string, body interface{}) (*htt
 	}
 
 	url := c.baseURL + endpoint
-	req, err := http.NewRequest(method, url, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
@@ -118,8 +109,8 @@ func (c *ILOClient) makeRequest(method, endpoint string, body interface{}) (*htt
 }
 
 // GetSystemInfo retrieves basic system information
-func (c *ILOClient) GetSystemInfo() (*SystemInfo, error) {
-	resp, err := c.makeRequest("GET", "/redfish/v1/Systems/1", nil)
+func (c *ILOClient) GetSystemInfo(ctx context.Context) (*SystemInfo, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/redfish/v1/Systems/1", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -138,12 +129,16 @@ func (c *ILOClient) GetSystemInfo() (*SystemInfo, error) {
 }
 
 // SetPowerState changes the server power state
-func (c *ILOClient) SetPowerState(state PowerState) error {
+func (c *ILOClient) SetPowerState(ctx context.Context, state PowerState) error {
+	resetType, err := redfishResetType(ctx, c, "/redfish/v1/Systems/1", state)
+	if err != nil {
+		return err
+	}
 	powerRequest := PowerRequest{
-		ResetType: string(state),
+		ResetType: resetType,
 	}
 
-	resp, err := c.makeRequest("POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", powerRequest)
+	resp, err := c.makeRequest(ctx, "POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", powerRequest)
 	if err != nil {
 		return err
 	}
@@ -158,8 +153,8 @@ func (c *ILOClient) SetPowerState(state PowerState) error {
 }
 
 // GetVirtualMedia lists available virtual media slots
-func (c *ILOClient) GetVirtualMedia() ([]VirtualMediaInfo, error) {
-	resp, err := c.makeRequest("GET", "/redfish/v1/Managers/1/VirtualMedia", nil)
+func (c *ILOClient) GetVirtualMedia(ctx context.Context) ([]VirtualMediaInfo, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/redfish/v1/Managers/1/VirtualMedia", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -181,8 +176,11 @@ func (c *ILOClient) GetVirtualMedia() ([]VirtualMediaInfo, error) {
 
 	var virtualMediaList []VirtualMediaInfo
 	for _, member := range result.Members {
-		vmInfo, err := c.getVirtualMediaInfo(member.OdataID)
+		vmInfo, err := c.getVirtualMediaInfo(ctx, member.OdataID)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			continue // Skip this media slot if we can't get info
 		}
 		virtualMediaList = append(virtualMediaList, *vmInfo)
@@ -192,8 +190,8 @@ func (c *ILOClient) GetVirtualMedia() ([]VirtualMediaInfo, error) {
 }
 
 // getVirtualMediaInfo gets information about a specific virtual media slot
-func (c *ILOClient) getVirtualMediaInfo(odataID string) (*VirtualMediaInfo, error) {
-	resp, err := c.makeRequest("GET", odataID, nil)
+func (c *ILOClient) getVirtualMediaInfo(ctx context.Context, odataID string) (*VirtualMediaInfo, error) {
+	resp, err := c.makeRequest(ctx, "GET", odataID, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -212,9 +210,9 @@ func (c *ILOClient) getVirtualMediaInfo(odataID string) (*VirtualMediaInfo, erro
 }
 
 // MountVirtualMedia mounts an image to the first available CD/DVD virtual media slot
-func (c *ILOClient) MountVirtualMedia(imageURL string) error {
+func (c *ILOClient) MountVirtualMedia(ctx context.Context, imageURL string) error {
 	// Get available virtual media slots
-	vmList, err := c.GetVirtualMedia()
+	vmList, err := c.GetVirtualMedia(ctx)
 	if err != nil {
 		return fmt.Errorf("error getting virtual media info: %w", err)
 	}
@@ -243,7 +241,7 @@ func (c *ILOClient) MountVirtualMedia(imageURL string) error {
 		Inserted: true,
 	}
 
-	resp, err := c.makeRequest("PATCH", targetSlot, mountRequest)
+	resp, err := c.makeRequest(ctx, "PATCH", targetSlot, mountRequest)
 	if err != nil {
 		return err
 	}
@@ -258,8 +256,8 @@ func (c *ILOClient) MountVirtualMedia(imageURL string) error {
 }
 
 // UnmountVirtualMedia unmounts virtual media from all slots
-func (c *ILOClient) UnmountVirtualMedia() error {
-	vmList, err := c.GetVirtualMedia()
+func (c *ILOClient) UnmountVirtualMedia(ctx context.Context) error {
+	vmList, err := c.GetVirtualMedia(ctx)
 	if err != nil {
 		return fmt.Errorf("error getting virtual media info: %w", err)
 	}
@@ -271,8 +269,11 @@ func (c *ILOClient) UnmountVirtualMedia() error {
 			}
 
 			endpoint := fmt.Sprintf("/redfish/v1/Managers/1/VirtualMedia/%d", i+1)
-			resp, err := c.makeRequest("PATCH", endpoint, unmountRequest)
+			resp, err := c.makeRequest(ctx, "PATCH", endpoint, unmountRequest)
 			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
 				continue // Continue with other slots
 			}
 			resp.Body.Close()
@@ -281,3 +282,77 @@ func (c *ILOClient) UnmountVirtualMedia() error {
 
 	return nil
 }
+
+// SetBootOverride sets the boot source override for the next boot (or every boot when persistent)
+func (c *ILOClient) SetBootOverride(ctx context.Context, target BootTarget, persistent bool) error {
+	resp, err := c.makeRequest(ctx, "PATCH", "/redfish/v1/Systems/1", newBootOverrideRequest(target, persistent))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("boot override failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// CaptureScreen downloads the screen thumbnail the iLO web interface shows on its overview
+// page. It is served outside Redfish and needs a web session, so a Redfish session is
+// created for the download and deleted afterwards.
+func (c *ILOClient) CaptureScreen(ctx context.Context) ([]byte, error) {
+	resp, err := c.makeRequest(ctx, "POST", "/redfish/v1/SessionService/Sessions", map[string]string{
+		"UserName": c.username,
+		"Password": c.password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("session login failed with status %d", resp.StatusCode)
+	}
+	token := resp.Header.Get("X-Auth-Token")
+	if token == "" {
+		return nil, fmt.Errorf("session login succeeded but no X-Auth-Token was returned")
+	}
+	if location := resp.Header.Get("Location"); location != "" {
+		endpoint := location
+		if u, err := url.Parse(location); err == nil && u.IsAbs() {
+			endpoint = u.Path
+		}
+		defer func() {
+			if resp, err := c.makeRequest(context.WithoutCancel(ctx), "DELETE", endpoint, nil); err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/images/thumbnail.bmp", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Cookie", "sessionKey="+token)
+	req.Header.Set("X-Auth-Token", token)
+	resp, err = c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading screenshot: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("screen capture failed with status %d: %s", resp.StatusCode, string(data))
+	}
+	return screenshotPNG(data)
+}
+
+// RedfishRequest sends a raw request to the iLO Redfish API
+func (c *ILOClient) RedfishRequest(ctx context.Context, method, endpoint string, body []byte, header http.Header) (*http.Response, error) {
+	return basicAuthRequest(ctx, c.httpClient, c.baseURL, c.username, c.password, method, endpoint, body, header)
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestILOClient_GetSystemInfo(t *testing.T) {
@@ -46,7 +49,7 @@ func TestILOClient_GetSystemInfo(t *testing.T) {
 	}
 
 	// Test GetSystemInfo
-	systemInfo, err := client.GetSystemInfo()
+	systemInfo, err := client.GetSystemInfo(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -90,7 +93,7 @@ func TestILOClient_SetPowerState_On(t *testing.T) {
 	}
 
 	// Test SetPowerState
-	err := client.SetPowerState(PowerStateOn)
+	err := client.SetPowerState(context.Background(), PowerStateOn)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -128,7 +131,7 @@ func TestILOClient_SetPowerState_Off(t *testing.T) {
 	}
 
 	// Test SetPowerState
-	err := client.SetPowerState(PowerStateOff)
+	err := client.SetPowerState(context.Background(), PowerStateOff)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -185,7 +188,7 @@ func TestILOClient_GetVirtualMedia(t *testing.T) {
 	}
 
 	// Test GetVirtualMedia
-	vmList, err := client.GetVirtualMedia()
+	vmList, err := client.GetVirtualMedia(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -262,7 +265,7 @@ func TestILOClient_MountVirtualMedia(t *testing.T) {
 	}
 
 	// Test MountVirtualMedia
-	err := client.MountVirtualMedia("http://example.com/image.iso")
+	err := client.MountVirtualMedia(context.Background(), "http://example.com/image.iso")
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -287,14 +290,82 @@ func TestILOClient_ErrorHandling(t *testing.T) {
 	}
 
 	// Test error handling for GetSystemInfo
-	_, err := client.GetSystemInfo()
+	_, err := client.GetSystemInfo(context.Background())
 	if err == nil {
 		t.Error("Expected error for 500 response, got nil")
 	}
 
 	// Test error handling for SetPowerState
-	err = client.SetPowerState(PowerStateOn)
+	err = client.SetPowerState(context.Background(), PowerStateOn)
 	if err == nil {
 		t.Error("Expected error for 500 response, got nil")
 	}
 }
+
+func TestILOClient_SetBootOverride(t *testing.T) {
+	// Create test server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/redfish/v1/Systems/1" {
+			t.Errorf("Expected path '/redfish/v1/Systems/1', got: %s", r.URL.Path)
+		}
+		if r.Method != "PATCH" {
+			t.Errorf("Expected PATCH request, got: %s", r.Method)
+		}
+
+		// Verify request body
+		var bootRequest BootOverrideRequest
+		if err := json.NewDecoder(r.Body).Decode(&bootRequest); err != nil {
+			t.Errorf("Error decoding request body: %v", err)
+		}
+		if bootRequest.Boot.BootSourceOverrideTarget != "Pxe" {
+			t.Errorf("Expected BootSourceOverrideTarget 'Pxe', got: %s", bootRequest.Boot.BootSourceOverrideTarget)
+		}
+		if bootRequest.Boot.BootSourceOverrideEnabled != "Continuous" {
+			t.Errorf("Expected BootSourceOverrideEnabled 'Continuous', got: %s", bootRequest.Boot.BootSourceOverrideEnabled)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &ILOClient{
+		baseURL:    server.URL,
+		username:   "admin",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	// Test SetBootOverride
+	err := client.SetBootOverride(context.Background(), BootTargetPXE, true)
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestILOClient_Canceled(t *testing.T) {
+	// Create test server that never answers until the client goes away
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := &ILOClient{
+		baseURL:    server.URL,
+		username:   "admin",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetVirtualMedia(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected request to stop promptly, took %s", elapsed)
+	}
+}
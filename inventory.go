@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// hostName selects an inventory host for a single command
+var hostName string
+
+// HostConfig represents one BMC of the host inventory. Settings left empty come from the
+// vendor section of its BMC type, so shared credentials are configured once per vendor.
+type HostConfig struct {
+	Name           string  `yaml:"name" mapstructure:"name" json:"name"`
+	BMCType        BMCType `yaml:"bmc_type,omitempty" mapstructure:"bmc_type" json:"bmc_type,omitempty"`
+	Address        string  `yaml:"address" mapstructure:"address" json:"address"`
+	Port           int     `yaml:"port,omitempty" mapstructure:"port" json:"port,omitempty"`
+	Username       string  `yaml:"username,omitempty" mapstructure:"username" json:"username,omitempty"`
+	Password       string  `yaml:"password,omitempty" mapstructure:"password" json:"-"`
+	PasswordSource `yaml:",inline" mapstructure:",squash"`
+	Groups         []string          `yaml:"groups,omitempty" mapstructure:"groups" json:"groups,omitempty"`
+	Labels         map[string]string `yaml:"labels,omitempty" mapstructure:"labels" json:"labels,omitempty"`
+}
+
+// hostView is an inventory host as listed by hosts list: where its password comes from,
+// but not a literal password, which HostConfig only carries for the config file
+type hostView struct {
+	Name           string  `yaml:"name" json:"name"`
+	BMCType        BMCType `yaml:"bmc_type,omitempty" json:"bmc_type,omitempty"`
+	Address        string  `yaml:"address" json:"address"`
+	Port           int     `yaml:"port,omitempty" json:"port,omitempty"`
+	Username       string  `yaml:"username,omitempty" json:"username,omitempty"`
+	PasswordSource `yaml:",inline"`
+	Groups         []string          `yaml:"groups,omitempty" json:"groups,omitempty"`
+	Labels         map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+func (h HostConfig) view() hostView {
+	return hostView{
+		Name:           h.Name,
+		BMCType:        h.BMCType,
+		Address:        h.Address,
+		Port:           h.Port,
+		Username:       h.Username,
+		PasswordSource: h.PasswordSource,
+		Groups:         h.Groups,
+		Labels:         h.Labels,
+	}
+}
+
+// findHost returns the inventory host with the given name; names are case-insensitive
+func (c *Config) findHost(name string) (HostConfig, bool) {
+	for _, h := range c.Hosts {
+		if strings.EqualFold(h.Name, name) {
+			return h, true
+		}
+	}
+	return HostConfig{}, false
+}
+
+// useHost points the configuration at an inventory host
+func (c *Config) useHost(h HostConfig) error {
+	if h.BMCType != "" {
+		c.BMCType = h.BMCType
+	}
+	section, ok := c.activeSection()
+	if !ok {
+		return fmt.Errorf("host %s: unsupported BMC type %q", h.Name, c.BMCType)
+	}
+	*section.Host = h.Address
+	if h.Port != 0 {
+		*section.Port = h.Port
+	}
+	if h.Username != "" {
+		*section.Username = h.Username
+	}
+	if h.Password != "" || h.PasswordSource != (PasswordSource{}) {
+		*section.Password = h.Password
+		*section.Source = h.PasswordSource
+	}
+	return nil
+}
+
+// selectorTerm is one comma-separated condition of a selector
+type selectorTerm struct {
+	Key    string
+	Value  string
+	Negate bool
+	// Exists matches hosts having the key at all, for a bare "key" term
+	Exists bool
+}
+
+// hostSelector selects inventory hosts, in the style of Kubernetes label selectors:
+// "rack=r12,env!=prod,gpu". The keys name (a glob pattern), bmc_type and group match the
+// host itself; any other key matches a label.
+type hostSelector []selectorTerm
+
+func parseSelector(s string) (hostSelector, error) {
+	var selector hostSelector
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		var t selectorTerm
+		if key, value, ok := strings.Cut(term, "!="); ok {
+			t = selectorTerm{Key: key, Value: value, Negate: true}
+		} else if key, value, ok := strings.Cut(term, "="); ok {
+			t = selectorTerm{Key: key, Value: strings.TrimPrefix(value, "=")}
+		} else if key, ok := strings.CutPrefix(term, "!"); ok {
+			t = selectorTerm{Key: key, Exists: true, Negate: true}
+		} else {
+			t = selectorTerm{Key: term, Exists: true}
+		}
+		t.Key, t.Value = strings.TrimSpace(t.Key), strings.TrimSpace(t.Value)
+		if t.Key == "" {
+			return nil, fmt.Errorf("invalid selector term %q", term)
+		}
+		if t.Key == "name" && !t.Exists {
+			if _, err := path.Match(t.Value, ""); err != nil {
+				return nil, fmt.Errorf("invalid name pattern %q: %w", t.Value, err)
+			}
+		}
+		selector = append(selector, t)
+	}
+	return selector, nil
+}
+
+// matches reports whether the host satisfies every term of the selector
+func (s hostSelector) matches(h HostConfig) bool {
+	for _, t := range s {
+		if t.matches(h) == t.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (t selectorTerm) matches(h HostConfig) bool {
+	switch t.Key {
+	case "name":
+		if t.Exists {
+			return true
+		}
+		ok, _ := path.Match(strings.ToLower(t.Value), strings.ToLower(h.Name))
+		return ok
+	case "bmc_type":
+		if t.Exists {
+			return h.BMCType != ""
+		}
+		return BMCType(t.Value) == h.BMCType
+	case "group":
+		if t.Exists {
+			return len(h.Groups) > 0
+		}
+		return slices.Contains(h.Groups, t.Value)
+	}
+	value, ok := h.Labels[t.Key]
+	if t.Exists {
+		return ok
+	}
+	return ok && value == t.Value
+}
+
+// selectHosts returns the inventory hosts matching selector, or the default selector of
+// the active context when selector is empty
+func (c *Config) selectHosts(selector string) ([]HostConfig, error) {
+	if selector == "" {
+		if ctx, ok := c.activeContext(); ok {
+			selector = ctx.Selector
+		}
+	}
+	s, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	var hosts []HostConfig
+	for _, h := range c.Hosts {
+		if s.matches(h) {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts, nil
+}
+
+// applyHost points the configuration at the --host inventory entry, if one was given
+func (c *Config) applyHost() error {
+	if hostName == "" {
+		return nil
+	}
+	h, ok := c.findHost(hostName)
+	if !ok {
+		names := make([]string, 0, len(c.Hosts))
+		for _, h := range c.Hosts {
+			names = append(names, h.Name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("host %q not found: the inventory is empty (see 'bmc-cli hosts add')", hostName)
+		}
+		return fmt.Errorf("host %q not found in the inventory (hosts: %s)", hostName, strings.Join(names, ", "))
+	}
+	return c.useHost(h)
+}
@@ -0,0 +1,549 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Inventory formats 'hosts import' reads
+const (
+	importAnsible = "ansible"
+	importCSV     = "csv"
+	importNetBox  = "netbox-json"
+)
+
+// importFields are the host settings an import can map source fields to
+var importFields = []string{"name", "address", "bmc_type", "port", "username", "password_env", "groups"}
+
+// defaultImportMappings maps host settings to the source fields each format provides them in
+var defaultImportMappings = map[string]map[string]string{
+	importAnsible: {"name": "inventory_hostname", "address": "bmc_address", "bmc_type": "bmc_type", "port": "bmc_port", "username": "bmc_username"},
+	importCSV:     {"name": "name", "address": "address", "bmc_type": "bmc_type", "port": "port", "username": "username", "password_env": "password_env", "groups": "groups"},
+	importNetBox:  {"name": "name", "address": "oob_ip.address", "bmc_type": "device_type.manufacturer.slug"},
+}
+
+// defaultImportLabels maps source fields to labels for each format; CSV columns that are not
+// mapped to a setting become labels as well
+var defaultImportLabels = map[string]map[string]string{
+	importNetBox: {"site.slug": "site", "rack.name": "rack", "role.slug": "role"},
+}
+
+// bmcTypeAliases maps vendor names found in inventories to BMC types
+var bmcTypeAliases = map[string]BMCType{
+	"hp":          BMCTypeILO,
+	"hpe":         BMCTypeILO,
+	"dell":        BMCTypeIDRAC,
+	"dell-emc":    BMCTypeIDRAC,
+	"lenovo":      BMCTypeXCC,
+	"smc":         BMCTypeSupermicro,
+	"super-micro": BMCTypeSupermicro,
+}
+
+// importOptions control how source records become inventory hosts
+type importOptions struct {
+	Format string
+	// Mappings override the source field of host settings ("address" → "ansible_host")
+	Mappings map[string]string
+	// Labels adds labels from source fields (source field → label name)
+	Labels map[string]string
+	// BMCType is used for records without a BMC type
+	BMCType BMCType
+}
+
+// inventoryRecord is one host read from an inventory source, as flat fields
+type inventoryRecord struct {
+	Fields map[string]string
+	Groups []string
+	// Line locates the record in the source for warnings
+	Line int
+}
+
+// readInventory reads the records of an inventory in the given format
+func readInventory(format string, r io.Reader) ([]inventoryRecord, error) {
+	switch format {
+	case importAnsible:
+		return readAnsibleInventory(r)
+	case importCSV:
+		return readCSVInventory(r)
+	case importNetBox:
+		return readNetBoxExport(r)
+	}
+	return nil, fmt.Errorf("unsupported inventory format %q (use ansible, csv or netbox-json)", format)
+}
+
+// ansibleSection is a [group], [group:vars] or [group:children] header
+var ansibleSection = regexp.MustCompile(`^\[([^\]:]+)(?::(vars|children))?\]$`)
+
+// ansibleRange is a numeric host range such as web[01:10]
+var ansibleRange = regexp.MustCompile(`\[(\d+):(\d+)\]`)
+
+// readAnsibleInventory reads an Ansible INI inventory. Host fields are the inventory_hostname
+// and the host's variables, including those inherited from its groups; the host's groups
+// and their parent groups become inventory groups.
+func readAnsibleInventory(r io.Reader) ([]inventoryRecord, error) {
+	type ansibleHost struct {
+		vars   map[string]string
+		groups []string
+		line   int
+	}
+	var order []string
+	hosts := map[string]*ansibleHost{}
+	groupVars := map[string]map[string]string{}
+	parents := map[string][]string{}
+
+	group, kind := "ungrouped", ""
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if m := ansibleSection.FindStringSubmatch(line); m != nil {
+			group, kind = m[1], m[2]
+			continue
+		}
+
+		fields, err := splitAnsibleLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		switch kind {
+		case "vars":
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key=value in [%s:vars]", lineNo, group)
+			}
+			if groupVars[group] == nil {
+				groupVars[group] = map[string]string{}
+			}
+			groupVars[group][strings.TrimSpace(key)] = unquoteAnsible(strings.TrimSpace(value))
+		case "children":
+			parents[fields[0]] = append(parents[fields[0]], group)
+		default:
+			vars := map[string]string{}
+			for _, field := range fields[1:] {
+				key, value, ok := strings.Cut(field, "=")
+				if !ok {
+					return nil, fmt.Errorf("line %d: expected key=value, got %q", lineNo, field)
+				}
+				vars[key] = value
+			}
+			names, err := expandAnsibleHost(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			for _, name := range names {
+				h, ok := hosts[name]
+				if !ok {
+					h = &ansibleHost{vars: map[string]string{}, line: lineNo}
+					hosts[name] = h
+					order = append(order, name)
+				}
+				for key, value := range vars {
+					h.vars[key] = value
+				}
+				if !slices.Contains(h.groups, group) {
+					h.groups = append(h.groups, group)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading inventory: %w", err)
+	}
+
+	// ancestors lists a group's parents, nearest first
+	var ancestors func(group string, seen map[string]bool) []string
+	ancestors = func(group string, seen map[string]bool) []string {
+		var result []string
+		for _, parent := range parents[group] {
+			if seen[parent] {
+				continue
+			}
+			seen[parent] = true
+			result = append(result, parent)
+			result = append(result, ancestors(parent, seen)...)
+		}
+		return result
+	}
+
+	records := make([]inventoryRecord, 0, len(order))
+	for _, name := range order {
+		h := hosts[name]
+		var groups []string
+		for _, g := range h.groups {
+			for _, candidate := range append([]string{g}, ancestors(g, map[string]bool{g: true})...) {
+				if candidate != "all" && candidate != "ungrouped" && !slices.Contains(groups, candidate) {
+					groups = append(groups, candidate)
+				}
+			}
+		}
+
+		// Variables of more specific groups win: all, then ancestors, then the host's groups,
+		// then the host line itself
+		fields := map[string]string{}
+		for key, value := range groupVars["all"] {
+			fields[key] = value
+		}
+		for i := len(groups) - 1; i >= 0; i-- {
+			for key, value := range groupVars[groups[i]] {
+				fields[key] = value
+			}
+		}
+		for key, value := range h.vars {
+			fields[key] = value
+		}
+		fields["inventory_hostname"] = name
+		records = append(records, inventoryRecord{Fields: fields, Groups: groups, Line: h.line})
+	}
+	return records, nil
+}
+
+// splitAnsibleLine splits a host line on whitespace, keeping quoted values together
+func splitAnsibleLine(line string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ' ' || r == '\t':
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		case r == '#' && current.Len() == 0:
+			return fields, nil
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
+
+func unquoteAnsible(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// expandAnsibleHost expands a numeric range pattern such as "web[01:03]" into host names,
+// keeping the width of zero-padded bounds
+func expandAnsibleHost(pattern string) ([]string, error) {
+	m := ansibleRange.FindStringSubmatchIndex(pattern)
+	if m == nil {
+		return []string{pattern}, nil
+	}
+	startText, endText := pattern[m[2]:m[3]], pattern[m[4]:m[5]]
+	start, _ := strconv.Atoi(startText)
+	end, _ := strconv.Atoi(endText)
+	if end < start {
+		return nil, fmt.Errorf("invalid host range %q", pattern)
+	}
+	width := 0
+	if len(startText) > 1 && startText[0] == '0' {
+		width = len(startText)
+	}
+
+	var names []string
+	for i := start; i <= end; i++ {
+		rest, err := expandAnsibleHost(pattern[m[1]:])
+		if err != nil {
+			return nil, err
+		}
+		for _, suffix := range rest {
+			names = append(names, fmt.Sprintf("%s%0*d%s", pattern[:m[0]], width, i, suffix))
+		}
+	}
+	return names, nil
+}
+
+// readCSVInventory reads a CSV file with a header row; each column is a field. The groups
+// column holds groups separated by semicolons or spaces.
+func readCSVInventory(r io.Reader) ([]inventoryRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	var records []inventoryRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(row) > len(header) {
+			return nil, fmt.Errorf("line %d: %d fields, but the header has %d", line, len(row), len(header))
+		}
+		fields := map[string]string{}
+		for i, value := range row {
+			if value = strings.TrimSpace(value); value != "" {
+				fields[header[i]] = value
+			}
+		}
+		records = append(records, inventoryRecord{Fields: fields, Line: line})
+	}
+	return records, nil
+}
+
+// readNetBoxExport reads devices exported from the NetBox API (/api/dcim/devices/), either a
+// paginated response or a plain array. Nested objects become dotted fields such as
+// "site.slug"; tags become groups.
+func readNetBoxExport(r io.Reader) ([]inventoryRecord, error) {
+	var doc interface{}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("error parsing NetBox export: %w", err)
+	}
+	if page, ok := doc.(map[string]interface{}); ok {
+		doc = page["results"]
+	}
+	devices, ok := doc.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("NetBox export is neither a device list nor an API response with results")
+	}
+
+	records := make([]inventoryRecord, 0, len(devices))
+	for i, item := range devices {
+		device, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("device %d is not an object", i+1)
+		}
+		record := inventoryRecord{Fields: map[string]string{}, Line: i + 1}
+		flattenJSON("", device, record.Fields)
+		if tags, ok := device["tags"].([]interface{}); ok {
+			for _, tag := range tags {
+				if t, ok := tag.(map[string]interface{}); ok {
+					if slug, ok := t["slug"].(string); ok && slug != "" {
+						record.Groups = append(record.Groups, slug)
+					}
+				}
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// flattenJSON stores the scalars of a decoded JSON object under dotted keys
+func flattenJSON(prefix string, value interface{}, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			flattenJSON(joinConfigPath(prefix, key), child, fields)
+		}
+	case string:
+		if v != "" {
+			fields[prefix] = v
+		}
+	case json.Number:
+		fields[prefix] = v.String()
+	case bool:
+		fields[prefix] = strconv.FormatBool(v)
+	}
+}
+
+// importHosts turns records into inventory hosts. Records that cannot be used are skipped
+// with a warning, as are all but the last of records sharing a name.
+func importHosts(records []inventoryRecord, opts importOptions) ([]HostConfig, []string) {
+	mappings := map[string]string{}
+	for field, source := range defaultImportMappings[opts.Format] {
+		mappings[field] = source
+	}
+	for field, source := range opts.Mappings {
+		mappings[field] = source
+	}
+	labels := map[string]string{}
+	for source, label := range defaultImportLabels[opts.Format] {
+		labels[source] = label
+	}
+	for source, label := range opts.Labels {
+		labels[source] = label
+	}
+	mapped := map[string]bool{}
+	for _, source := range mappings {
+		mapped[source] = true
+	}
+
+	var hosts []HostConfig
+	var warnings []string
+	index := map[string]int{}
+	for _, record := range records {
+		get := func(field string) string { return record.Fields[mappings[field]] }
+		warn := func(format string, args ...interface{}) {
+			warnings = append(warnings, fmt.Sprintf("record %d: ", record.Line)+fmt.Sprintf(format, args...))
+		}
+
+		h := HostConfig{Name: get("name"), Address: normalizeAddress(get("address")), Username: get("username")}
+		h.PasswordEnv = get("password_env")
+		if h.Name == "" {
+			warn("no %s, skipped", mappings["name"])
+			continue
+		}
+		if h.Address == "" {
+			warn("%s has no %s, skipped", h.Name, mappings["address"])
+			continue
+		}
+
+		bmcType, ok := normalizeBMCType(get("bmc_type"))
+		if !ok {
+			warn("%s has unsupported BMC type %q, skipped", h.Name, get("bmc_type"))
+			continue
+		}
+		if bmcType == "" {
+			bmcType = opts.BMCType
+		}
+		h.BMCType = bmcType
+
+		if port := get("port"); port != "" {
+			n, err := strconv.Atoi(port)
+			if err != nil || n < 1 || n > 65535 {
+				warn("%s has invalid port %q, skipped", h.Name, port)
+				continue
+			}
+			h.Port = n
+		}
+
+		h.Groups = record.Groups
+		if groups := get("groups"); groups != "" {
+			for _, g := range strings.FieldsFunc(groups, func(r rune) bool { return r == ';' || r == ' ' }) {
+				if !slices.Contains(h.Groups, g) {
+					h.Groups = append(h.Groups, g)
+				}
+			}
+		}
+
+		for source, label := range labels {
+			if value := record.Fields[source]; value != "" {
+				setLabel(&h, label, value)
+			}
+		}
+		if opts.Format == importCSV {
+			for column, value := range record.Fields {
+				if !mapped[column] && labels[column] == "" {
+					setLabel(&h, column, value)
+				}
+			}
+		}
+
+		key := strings.ToLower(h.Name)
+		if i, ok := index[key]; ok {
+			warn("%s is defined more than once, the last definition is used", h.Name)
+			hosts[i] = h
+			continue
+		}
+		index[key] = len(hosts)
+		hosts = append(hosts, h)
+	}
+	return hosts, warnings
+}
+
+func setLabel(h *HostConfig, key, value string) {
+	if h.Labels == nil {
+		h.Labels = map[string]string{}
+	}
+	h.Labels[key] = value
+}
+
+// normalizeAddress strips the prefix length from addresses such as NetBox's "10.0.0.5/24"
+func normalizeAddress(address string) string {
+	if ip, _, err := net.ParseCIDR(address); err == nil {
+		return ip.String()
+	}
+	return address
+}
+
+// normalizeBMCType maps a BMC type or vendor name to a BMC type; an empty value is valid
+// and leaves the type to the default
+func normalizeBMCType(value string) (BMCType, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return "", true
+	}
+	if slices.Contains(supportedBMCTypes, BMCType(value)) {
+		return BMCType(value), true
+	}
+	bmcType, ok := bmcTypeAliases[value]
+	return bmcType, ok
+}
+
+// parseFieldMappings parses --map values of the form setting=source-field
+func parseFieldMappings(values []string) (map[string]string, error) {
+	mappings := map[string]string{}
+	for _, value := range values {
+		field, source, ok := strings.Cut(value, "=")
+		if !ok || source == "" {
+			return nil, fmt.Errorf("invalid mapping %q (expected setting=field, such as address=ansible_host)", value)
+		}
+		if !slices.Contains(importFields, field) {
+			return nil, fmt.Errorf("unknown setting %q in mapping (settings: %s)", field, strings.Join(importFields, ", "))
+		}
+		mappings[field] = source
+	}
+	return mappings, nil
+}
+
+// parseLabelMappings parses --label values of the form field or field=label
+func parseLabelMappings(values []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, value := range values {
+		source, label, ok := strings.Cut(value, "=")
+		if !ok {
+			label, _, _ = strings.Cut(source, ".")
+		}
+		if source == "" || label == "" {
+			return nil, fmt.Errorf("invalid label mapping %q (expected field or field=label)", value)
+		}
+		labels[source] = label
+	}
+	return labels, nil
+}
+
+// sortedLabels formats labels as key=value pairs in key order
+func sortedLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestHostSelector(t *testing.T) {
+	hosts := []HostConfig{
+		{Name: "r740-01", BMCType: BMCTypeIDRAC, Groups: []string{"compute", "gpu"}, Labels: map[string]string{"rack": "r12", "env": "prod"}},
+		{Name: "r740-02", BMCType: BMCTypeIDRAC, Groups: []string{"compute"}, Labels: map[string]string{"rack": "r12"}},
+		{Name: "dl380-01", BMCType: BMCTypeILO, Labels: map[string]string{"rack": "r13"}},
+	}
+
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"", "r740-01 r740-02 dl380-01"},
+		{"rack=r12", "r740-01 r740-02"},
+		{"rack=r12,env!=prod", "r740-02"},
+		{"env", "r740-01"},
+		{"!env", "r740-02 dl380-01"},
+		{"group=gpu", "r740-01"},
+		{"bmc_type=ilo", "dl380-01"},
+		{"name=R740-*", "r740-01 r740-02"},
+		{"rack==r13", "dl380-01"},
+	}
+	for _, tt := range tests {
+		selector, err := parseSelector(tt.selector)
+		if err != nil {
+			t.Fatalf("parseSelector(%q) failed: %v", tt.selector, err)
+		}
+		var names []string
+		for _, h := range hosts {
+			if selector.matches(h) {
+				names = append(names, h.Name)
+			}
+		}
+		if got := strings.Join(names, " "); got != tt.want {
+			t.Errorf("Selector %q matched %q, expected %q", tt.selector, got, tt.want)
+		}
+	}
+
+	if _, err := parseSelector("=r12"); err == nil {
+		t.Error("Expected error for a term without a key")
+	}
+	if _, err := parseSelector("name=[r"); err == nil {
+		t.Error("Expected error for an invalid name pattern")
+	}
+}
+
+func TestApplyHost(t *testing.T) {
+	defer func() { hostName = "" }()
+
+	cfg := Config{
+		BMCType: BMCTypeILO,
+		IDRAC:   IDRACConfig{Host: "192.168.1.101", Username: "root", Password: "calvin", Port: 443},
+		Hosts: []HostConfig{
+			{Name: "r740-01", BMCType: BMCTypeIDRAC, Address: "10.0.0.9", Port: 8443},
+		},
+		Contexts: map[string]ContextConfig{"rack": {Host: "R740-01"}},
+	}
+
+	hostName = "r740-01"
+	if err := cfg.applyHost(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.BMCType != BMCTypeIDRAC || cfg.IDRAC.Host != "10.0.0.9" || cfg.IDRAC.Port != 8443 {
+		t.Errorf("Expected iDRAC at 10.0.0.9:8443, got %s at %s:%d", cfg.BMCType, cfg.IDRAC.Host, cfg.IDRAC.Port)
+	}
+	// Settings the host does not set come from the vendor section
+	if cfg.IDRAC.Username != "root" || cfg.IDRAC.Password != "calvin" {
+		t.Errorf("Expected credentials of the idrac section, got %s/%s", cfg.IDRAC.Username, cfg.IDRAC.Password)
+	}
+
+	hostName = "r740-99"
+	if err := cfg.applyHost(); err == nil || !strings.Contains(err.Error(), "hosts: r740-01") {
+		t.Errorf("Expected error listing the inventory, got: %v", err)
+	}
+
+	// A context host naming an inventory entry selects that entry
+	hostName = ""
+	cfg = Config{BMCType: BMCTypeILO, CurrentContext: "rack", Contexts: cfg.Contexts, Hosts: cfg.Hosts}
+	if err := cfg.applyContext(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.BMCType != BMCTypeIDRAC || cfg.IDRAC.Host != "10.0.0.9" {
+		t.Errorf("Expected the context to select r740-01, got %s at %s", cfg.BMCType, cfg.IDRAC.Host)
+	}
+}
+
+func TestImportAnsibleInventory(t *testing.T) {
+	inventory := `[all:vars]
+bmc_username=admin
+
+[compute]
+r740-[01:02] bmc_address=10.0.0.1
+dl380-01 ansible_host=10.0.1.1 bmc_type=hpe bmc_port=8443 # iLO 5
+
+[gpu]
+r740-02 bmc_address=10.0.0.2
+
+[datacenter:children]
+compute
+
+[datacenter:vars]
+bmc_type=dell
+bmc_username=root
+`
+	records, err := readAnsibleInventory(strings.NewReader(inventory))
+	if err != nil {
+		t.Fatalf("readAnsibleInventory failed: %v", err)
+	}
+	hosts, warnings := importHosts(records, importOptions{Format: importAnsible})
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "dl380-01 has no bmc_address") {
+		t.Errorf("Expected a warning for the host without a BMC address, got: %v", warnings)
+	}
+
+	want := []HostConfig{
+		{Name: "r740-01", BMCType: BMCTypeIDRAC, Address: "10.0.0.1", Username: "root", Groups: []string{"compute", "datacenter"}},
+		{Name: "r740-02", BMCType: BMCTypeIDRAC, Address: "10.0.0.2", Username: "root", Groups: []string{"compute", "datacenter", "gpu"}},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("Unexpected hosts:\n got: %+v\nwant: %+v", hosts, want)
+	}
+
+	hosts, _ = importHosts(records, importOptions{Format: importAnsible, Mappings: map[string]string{"address": "ansible_host"}})
+	if len(hosts) != 1 || hosts[0].Name != "dl380-01" || hosts[0].BMCType != BMCTypeILO || hosts[0].Address != "10.0.1.1" || hosts[0].Port != 8443 {
+		t.Errorf("Expected dl380-01 as an iLO with --map address=ansible_host, got: %+v", hosts)
+	}
+}
+
+func TestImportCSVInventory(t *testing.T) {
+	data := `name,address,bmc_type,port,groups,rack
+r740-01,10.0.0.1,idrac,,compute;gpu,r12
+dl380-01,10.0.1.1,hp,8443,,r13
+,10.0.2.1,idrac,,,
+sm-01,10.0.3.1,quanta,,,
+`
+	records, err := readCSVInventory(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("readCSVInventory failed: %v", err)
+	}
+	hosts, warnings := importHosts(records, importOptions{Format: importCSV})
+	if len(warnings) != 2 {
+		t.Errorf("Expected warnings for the records without a name and with an unknown type, got: %v", warnings)
+	}
+
+	want := []HostConfig{
+		{Name: "r740-01", BMCType: BMCTypeIDRAC, Address: "10.0.0.1", Groups: []string{"compute", "gpu"}, Labels: map[string]string{"rack": "r12"}},
+		{Name: "dl380-01", BMCType: BMCTypeILO, Address: "10.0.1.1", Port: 8443, Labels: map[string]string{"rack": "r13"}},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("Unexpected hosts:\n got: %+v\nwant: %+v", hosts, want)
+	}
+}
+
+func TestImportNetBoxExport(t *testing.T) {
+	data := `{"count": 2, "results": [
+  {"id": 1, "name": "r740-01", "oob_ip": {"address": "10.0.0.1/24"},
+   "device_type": {"manufacturer": {"slug": "dell"}}, "site": {"slug": "ams1"},
+   "rack": {"name": "R12"}, "role": {"slug": "compute"}, "platform": {"slug": "ubuntu"},
+   "tags": [{"slug": "gpu"}]},
+  {"id": 2, "name": "switch-01", "oob_ip": null, "device_type": {"manufacturer": {"slug": "arista"}}}
+]}`
+	records, err := readNetBoxExport(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("readNetBoxExport failed: %v", err)
+	}
+	hosts, warnings := importHosts(records, importOptions{Format: importNetBox, Labels: map[string]string{"platform.slug": "os"}})
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "switch-01 has no oob_ip.address") {
+		t.Errorf("Expected a warning for the device without an OOB address, got: %v", warnings)
+	}
+
+	want := []HostConfig{{
+		Name: "r740-01", BMCType: BMCTypeIDRAC, Address: "10.0.0.1", Groups: []string{"gpu"},
+		Labels: map[string]string{"site": "ams1", "rack": "R12", "role": "compute", "os": "ubuntu"},
+	}}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("Unexpected hosts:\n got: %+v\nwant: %+v", hosts, want)
+	}
+
+	if _, err := readNetBoxExport(strings.NewReader(`{"detail": "Invalid token."}`)); err == nil {
+		t.Error("Expected error for an API error response")
+	}
+}
+
+func TestInventoryHostsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	original := `bmc_type: idrac
+hosts:
+  # rack 12
+  - name: r740-01
+    address: 10.0.0.1
+`
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if err := addInventoryHosts(path, []HostConfig{{Name: "R740-01", Address: "10.0.0.9"}}, inventoryAdd); err == nil {
+		t.Error("Expected error adding an existing host")
+	}
+	imported := []HostConfig{
+		{Name: "r740-01", Address: "10.0.0.9"},
+		{Name: "dl380-01", BMCType: BMCTypeILO, Address: "10.0.1.1", Labels: map[string]string{"rack": "r13"}},
+	}
+	if err := addInventoryHosts(path, imported, inventoryMerge); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := removeInventoryHosts(path, []string{"dl380-01", "missing"}); err == nil {
+		t.Error("Expected error removing a missing host")
+	}
+	if err := addInventoryHosts(path, []HostConfig{{Name: "r740-02", Address: "10.0.0.2"}}, inventoryAdd); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := removeInventoryHosts(path, []string{"R740-01"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	want := `bmc_type: idrac
+hosts:
+  - name: dl380-01
+    bmc_type: ilo
+    address: 10.0.1.1
+    labels:
+      rack: r13
+  - name: r740-02
+    address: 10.0.0.2
+`
+	if string(data) != want {
+		t.Errorf("Unexpected config file:\n%s", data)
+	}
+}
+
+func TestHostView(t *testing.T) {
+	host := HostConfig{
+		Name:           "r740-01",
+		Address:        "10.0.0.11",
+		Password:       "s3cret",
+		PasswordSource: PasswordSource{PasswordEnv: "R740_PASSWORD"},
+	}
+
+	yamlData, err := yaml.Marshal(host.view())
+	if err != nil {
+		t.Fatalf("Failed to marshal YAML: %v", err)
+	}
+	jsonData, err := json.Marshal(host.view())
+	if err != nil {
+		t.Fatalf("Failed to marshal JSON: %v", err)
+	}
+	for format, data := range map[string][]byte{"yaml": yamlData, "json": jsonData} {
+		if strings.Contains(string(data), "s3cret") {
+			t.Errorf("%s: expected no password, got:\n%s", format, data)
+		}
+		if !strings.Contains(string(data), "R740_PASSWORD") {
+			t.Errorf("%s: expected the password source, got:\n%s", format, data)
+		}
+	}
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IPMIClient represents an IPMI v2.0 over LAN (lanplus) client for BMCs without Redfish
+type IPMIClient struct {
+	address  string
+	username string
+	password string
+	// timeout and retries apply to each UDP packet; commandTimeout bounds a whole command,
+	// retransmissions included
+	timeout        time.Duration
+	retries        int
+	commandTimeout time.Duration
+	session        *ipmiSession
+}
+
+// ipmiBootDevices maps boot targets to the boot device selector of boot option parameter 5
+var ipmiBootDevices = map[BootTarget]byte{
+	BootTargetPXE:  0x04,
+	BootTargetHDD:  0x08,
+	BootTargetCD:   0x14,
+	BootTargetBIOS: 0x18,
+	BootTargetUSB:  0x3C,
+}
+
+// ipmiSensorUnits names the most common IPMI base unit codes
+var ipmiSensorUnits = map[byte]string{
+	1:  "degrees C",
+	2:  "degrees F",
+	3:  "degrees K",
+	4:  "Volts",
+	5:  "Amps",
+	6:  "Watts",
+	7:  "Joules",
+	17: "CFM",
+	18: "RPM",
+	19: "Hz",
+}
+
+// ipmiSensorTypes names the most common IPMI sensor type codes
+var ipmiSensorTypes = map[byte]string{
+	0x01: "Temperature",
+	0x02: "Voltage",
+	0x03: "Current",
+	0x04: "Fan",
+	0x05: "Physical Security",
+	0x07: "Processor",
+	0x08: "Power Supply",
+	0x09: "Power Unit",
+	0x0C: "Memory",
+	0x0D: "Drive Slot",
+	0x0F: "System Firmware Progress",
+	0x10: "Event Logging Disabled",
+	0x12: "System Event",
+	0x13: "Critical Interrupt",
+	0x14: "Button / Switch",
+	0x1D: "System Boot Initiated",
+	0x20: "OS Stop / Shutdown",
+	0x23: "Watchdog",
+}
+
+// ipmiThresholdEvents describes the offsets of threshold based events
+var ipmiThresholdEvents = []string{
+	"Lower Non-critical going low",
+	"Lower Non-critical going high",
+	"Lower Critical going low",
+	"Lower Critical going high",
+	"Lower Non-recoverable going low",
+	"Lower Non-recoverable going high",
+	"Upper Non-critical going low",
+	"Upper Non-critical going high",
+	"Upper Critical going low",
+	"Upper Critical going high",
+	"Upper Non-recoverable going low",
+	"Upper Non-recoverable going high",
+}
+
+// NewIPMIClient creates a new IPMI-over-LAN client. A lost UDP packet is retransmitted after
+// two seconds, twice; like an HTTP request, each command gives up after --timeout.
+func NewIPMIClient(host, username, password string, port int) BMCClient {
+	return &IPMIClient{
+		address:        net.JoinHostPort(host, strconv.Itoa(port)),
+		username:       username,
+		password:       password,
+		timeout:        2 * time.Second,
+		retries:        2,
+		commandTimeout: requestTimeout,
+	}
+}
+
+// command runs an IPMI command, opening the session on first use
+func (c *IPMIClient) command(ctx context.Context, netFn, cmd byte, data []byte) ([]byte, error) {
+	if c.commandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.commandTimeout)
+		defer cancel()
+	}
+
+	if c.session == nil {
+		if verbose {
+			fmt.Printf("Opening IPMI session to %s\n", c.address)
+		}
+		session, err := openIPMISession(ctx, c.address, c.username, c.password, c.timeout, c.retries)
+		if err != nil {
+			return nil, err
+		}
+		c.session = session
+	}
+
+	if verbose {
+		fmt.Printf("Sending IPMI command netfn=0x%02x cmd=0x%02x\n", netFn, cmd)
+	}
+	return c.session.command(ctx, netFn, cmd, data)
+}
+
+// Close ends the IPMI session
+func (c *IPMIClient) Close() error {
+	if c.session == nil {
+		return nil
+	}
+	err := c.session.close()
+	c.session = nil
+	return err
+}
+
+// GetSystemInfo retrieves the chassis power state and reports faults as health
+func (c *IPMIClient) GetSystemInfo(ctx context.Context) (*SystemInfo, error) {
+	resp, err := c.command(ctx, ipmiNetFnChassis, ipmiCmdGetChassisStatus, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 3 {
+		return nil, fmt.Errorf("short chassis status response")
+	}
+
+	systemInfo := &SystemInfo{PowerState: "Off"}
+	if resp[0]&0x01 != 0 {
+		systemInfo.PowerState = "On"
+	}
+
+	// Power overload, power fault and power control fault, then cooling and drive faults
+	systemInfo.Status.Health = "OK"
+	if resp[0]&0x1A != 0 {
+		systemInfo.Status.Health = "Critical"
+	} else if resp[2]&0x0C != 0 {
+		systemInfo.Status.Health = "Warning"
+	}
+	systemInfo.Status.State = "Enabled"
+
+	return systemInfo, nil
+}
+
+// SetPowerState changes the server power state with a chassis control command
+func (c *IPMIClient) SetPowerState(ctx context.Context, state PowerState) error {
+	controls := map[PowerState]byte{
+		PowerStateOff:   0x00,
+		PowerStateOn:    0x01,
+		PowerStateCycle: 0x02,
+	}
+	control, ok := controls[state]
+	if !ok {
+		return fmt.Errorf("unsupported IPMI power state: %s", state)
+	}
+
+	_, err := c.command(ctx, ipmiNetFnChassis, ipmiCmdChassisControl, []byte{control})
+	if err != nil {
+		return fmt.Errorf("power operation failed: %w", err)
+	}
+	return nil
+}
+
+// GetVirtualMedia is not available over IPMI
+func (c *IPMIClient) GetVirtualMedia(ctx context.Context) ([]VirtualMediaInfo, error) {
+	return nil, fmt.Errorf("virtual media is not supported over IPMI")
+}
+
+// MountVirtualMedia is not available over IPMI
+func (c *IPMIClient) MountVirtualMedia(ctx context.Context, imageURL string) error {
+	return fmt.Errorf("virtual media is not supported over IPMI")
+}
+
+// UnmountVirtualMedia is not available over IPMI
+func (c *IPMIClient) UnmountVirtualMedia(ctx context.Context) error {
+	return fmt.Errorf("virtual media is not supported over IPMI")
+}
+
+// SetBootOverride sets the boot flags (boot option parameter 5)
+func (c *IPMIClient) SetBootOverride(ctx context.Context, target BootTarget, persistent bool) error {
+	flags := []byte{0x05, 0x00, 0x00, 0x00, 0x00, 0x00}
+	if target != BootTargetNone {
+		device, ok := ipmiBootDevices[target]
+		if !ok {
+			return fmt.Errorf("unsupported IPMI boot target: %s", target)
+		}
+		flags[1] = 0x80 // boot flags valid
+		if persistent {
+			flags[1] |= 0x40
+		}
+		flags[2] = device
+	}
+
+	if _, err := c.command(ctx, ipmiNetFnChassis, ipmiCmdSetSystemBootOptions, flags); err != nil {
+		return fmt.Errorf("boot override failed: %w", err)
+	}
+	return nil
+}
+
+// GetEventLog reads all entries of the System Event Log
+func (c *IPMIClient) GetEventLog(ctx context.Context) ([]EventLogEntry, error) {
+	info, err := c.command(ctx, ipmiNetFnStorage, ipmiCmdGetSELInfo, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(info) < 3 {
+		return nil, fmt.Errorf("short SEL info response")
+	}
+	count := int(binary.LittleEndian.Uint16(info[1:3]))
+	if count == 0 {
+		return nil, nil
+	}
+
+	resv, err := c.command(ctx, ipmiNetFnStorage, ipmiCmdReserveSEL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resv) < 2 {
+		return nil, fmt.Errorf("short SEL reservation response")
+	}
+
+	var entries []EventLogEntry
+	recordID := uint16(0x0000)
+	for i := 0; i < count && recordID != 0xFFFF; i++ {
+		req := append([]byte{}, resv[:2]...)
+		req = binary.LittleEndian.AppendUint16(req, recordID)
+		req = append(req, 0x00, 0xFF)
+
+		resp, err := c.command(ctx, ipmiNetFnStorage, ipmiCmdGetSELEntry, req)
+		if err != nil {
+			return entries, err
+		}
+		if len(resp) < 18 {
+			return entries, fmt.Errorf("short SEL entry response")
+		}
+
+		entries = append(entries, decodeSELRecord(resp[2:18]))
+		recordID = binary.LittleEndian.Uint16(resp[0:2])
+	}
+
+	return entries, nil
+}
+
+// decodeSELRecord turns a 16 byte SEL record into an event log entry
+func decodeSELRecord(record []byte) EventLogEntry {
+	entry := EventLogEntry{
+		ID:       strconv.Itoa(int(binary.LittleEndian.Uint16(record[0:2]))),
+		Severity: "OK",
+	}
+
+	recordType := record[2]
+	if recordType < 0xE0 {
+		entry.Created = time.Unix(int64(binary.LittleEndian.Uint32(record[3:7])), 0).UTC()
+	}
+	if recordType != 0x02 {
+		entry.Message = fmt.Sprintf("OEM record type 0x%02x: % x", recordType, record[7:])
+		return entry
+	}
+
+	sensorType := ipmiSensorTypes[record[10]]
+	if sensorType == "" {
+		sensorType = fmt.Sprintf("Sensor type 0x%02x", record[10])
+	}
+
+	direction := "Asserted"
+	if record[12]&0x80 != 0 {
+		direction = "Deasserted"
+	}
+
+	eventType := record[12] & 0x7F
+	offset := record[13] & 0x0F
+	event := fmt.Sprintf("event offset 0x%x", offset)
+	if eventType == 0x01 && int(offset) < len(ipmiThresholdEvents) {
+		event = ipmiThresholdEvents[offset]
+		switch {
+		case strings.Contains(event, "Non-critical"):
+			entry.Severity = "Warning"
+		default:
+			entry.Severity = "Critical"
+		}
+	}
+
+	entry.Message = fmt.Sprintf("%s #0x%02x %s %s", sensorType, record[11], event, direction)
+	return entry
+}
+
+// GetSensors reads every sensor listed in the SDR repository
+func (c *IPMIClient) GetSensors(ctx context.Context) ([]SensorReading, error) {
+	resv, err := c.reserveSDR(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var readings []SensorReading
+	recordID := uint16(0x0000)
+	for i := 0; recordID != 0xFFFF; i++ {
+		if i > 0xFFFF {
+			return readings, fmt.Errorf("SDR repository does not terminate")
+		}
+
+		record, next, err := c.getSDRRecord(ctx, resv, recordID)
+		var ipmiErr *IPMIError
+		if errors.As(err, &ipmiErr) && ipmiErr.CompletionCode == 0xC5 {
+			// The reservation was lost, take a new one and read the record again
+			if resv, err = c.reserveSDR(ctx); err != nil {
+				return readings, err
+			}
+			record, next, err = c.getSDRRecord(ctx, resv, recordID)
+		}
+		if err != nil {
+			return readings, err
+		}
+
+		if reading, ok := c.readSensor(ctx, record); ok {
+			readings = append(readings, reading)
+		}
+		if next == recordID {
+			break
+		}
+		recordID = next
+	}
+
+	return readings, nil
+}
+
+// reserveSDR reserves the SDR repository for partial reads
+func (c *IPMIClient) reserveSDR(ctx context.Context) ([]byte, error) {
+	resv, err := c.command(ctx, ipmiNetFnStorage, ipmiCmdReserveSDR, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resv) < 2 {
+		return nil, fmt.Errorf("short SDR reservation response")
+	}
+	return resv[:2], nil
+}
+
+// getSDRRecord reads a full SDR record in small chunks, since many BMCs cannot return a whole
+// record in one response
+func (c *IPMIClient) getSDRRecord(ctx context.Context, resv []byte, recordID uint16) ([]byte, uint16, error) {
+	read := func(offset, count int) ([]byte, uint16, error) {
+		req := append([]byte{}, resv...)
+		req = binary.LittleEndian.AppendUint16(req, recordID)
+		req = append(req, byte(offset), byte(count))
+
+		resp, err := c.command(ctx, ipmiNetFnStorage, ipmiCmdGetSDR, req)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(resp) < 2+count {
+			return nil, 0, fmt.Errorf("short SDR response")
+		}
+		return resp[2 : 2+count], binary.LittleEndian.Uint16(resp[0:2]), nil
+	}
+
+	header, next, err := read(0, 5)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	record := header
+	total := 5 + int(header[4])
+	for offset := 5; offset < total; offset += 16 {
+		count := total - offset
+		if count > 16 {
+			count = 16
+		}
+		chunk, _, err := read(offset, count)
+		if err != nil {
+			return nil, 0, err
+		}
+		record = append(record, chunk...)
+	}
+
+	return record, next, nil
+}
+
+// readSensor reads the sensor described by a full or compact SDR record. Records for other
+// record types or sensors owned by satellite controllers are skipped.
+func (c *IPMIClient) readSensor(ctx context.Context, record []byte) (SensorReading, bool) {
+	recordType := record[3]
+	if recordType != 0x01 && recordType != 0x02 {
+		return SensorReading{}, false
+	}
+	if len(record) < 32 || record[5] != ipmiBMCAddress || record[6]&0x03 != 0 {
+		return SensorReading{}, false
+	}
+
+	reading := SensorReading{}
+	if recordType == 0x01 && len(record) >= 48 {
+		reading.Name = ipmiIDString(record[47:])
+	} else {
+		reading.Name = ipmiIDString(record[31:])
+	}
+	if reading.Name == "" {
+		reading.Name = fmt.Sprintf("Sensor 0x%02x", record[7])
+	}
+
+	resp, err := c.command(ctx, ipmiNetFnSensor, ipmiCmdGetSensorReading, []byte{record[7]})
+	if err != nil || len(resp) < 2 {
+		reading.Status = "na"
+		return reading, true
+	}
+
+	// Bit 6 means scanning is enabled, bit 5 that the reading is unavailable
+	if resp[1]&0x40 == 0 || resp[1]&0x20 != 0 {
+		reading.Status = "na"
+		return reading, true
+	}
+
+	analog := recordType == 0x01 && record[20]>>6 != 0x03
+	if !analog {
+		var states uint16
+		if len(resp) >= 3 {
+			states = uint16(resp[2])
+		}
+		if len(resp) >= 4 {
+			states |= uint16(resp[3]&0x7F) << 8
+		}
+		reading.Available = true
+		reading.Units = "discrete"
+		reading.Status = fmt.Sprintf("0x%04x", states)
+		return reading, true
+	}
+
+	reading.Available = true
+	reading.Value = ipmiConvertReading(resp[0], record)
+	reading.Units = ipmiSensorUnits[record[21]]
+	if record[20]&0x01 != 0 {
+		reading.Units = "%"
+	}
+
+	reading.Status = "ok"
+	if len(resp) >= 3 {
+		switch {
+		case resp[2]&0x24 != 0:
+			reading.Status = "non-recoverable"
+		case resp[2]&0x12 != 0:
+			reading.Status = "critical"
+		case resp[2]&0x09 != 0:
+			reading.Status = "warning"
+		}
+	}
+
+	return reading, true
+}
+
+// ipmiConvertReading applies the linear conversion y = (M*x + B*10^Bexp) * 10^Rexp from a full SDR record
+func ipmiConvertReading(raw byte, record []byte) float64 {
+	m := ipmiSignExtend(int(record[24])|int(record[25]&0xC0)<<2, 10)
+	b := ipmiSignExtend(int(record[26])|int(record[27]&0xC0)<<2, 10)
+	rexp := ipmiSignExtend(int(record[29]>>4), 4)
+	bexp := ipmiSignExtend(int(record[29]&0x0F), 4)
+
+	var x float64
+	switch record[20] >> 6 {
+	case 0x01: // one's complement
+		if raw&0x80 != 0 {
+			x = -float64(^raw)
+		} else {
+			x = float64(raw)
+		}
+	case 0x02: // two's complement
+		x = float64(int8(raw))
+	default:
+		x = float64(raw)
+	}
+
+	return (float64(m)*x + float64(b)*math.Pow10(bexp)) * math.Pow10(rexp)
+}
+
+// ipmiSignExtend interprets the low bits of value as a two's complement number
+func ipmiSignExtend(value, bits int) int {
+	if value&(1<<(bits-1)) != 0 {
+		return value - (1 << bits)
+	}
+	return value
+}
+
+// ipmiIDString decodes an SDR ID string (type/length byte followed by the string)
+func ipmiIDString(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	length := int(data[0] & 0x1F)
+	if length > len(data)-1 {
+		length = len(data) - 1
+	}
+	return strings.TrimRight(string(data[1:1+length]), "\x00 ")
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestIPMIClient creates a client for an in-process responder with short timeouts
+func newTestIPMIClient(responder *ipmiTestResponder, password string) *IPMIClient {
+	client := NewIPMIClient("127.0.0.1", "admin", password, responder.port()).(*IPMIClient)
+	client.timeout = 500 * time.Millisecond
+	client.retries = 1
+	return client
+}
+
+func TestIPMIClient_CommandTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { requestTimeout = timeout }(requestTimeout)
+	requestTimeout = 300 * time.Millisecond
+
+	// A socket that never answers stands in for an unreachable BMC
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	client := NewIPMIClient("127.0.0.1", "admin", "password", conn.LocalAddr().(*net.UDPAddr).Port).(*IPMIClient)
+	if client.timeout != 2*time.Second {
+		t.Errorf("Expected a short per-packet timeout, got %s", client.timeout)
+	}
+	start := time.Now()
+	if _, err := client.GetSystemInfo(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the command to time out, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected --timeout to bound the command, took %s", elapsed)
+	}
+}
+
+func TestIPMIClient_PowerAndStatus(t *testing.T) {
+	responder := newIPMITestResponder(t, "admin", "password")
+	client := newTestIPMIClient(responder, "password")
+
+	systemInfo, err := client.GetSystemInfo(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if systemInfo.PowerState != "Off" {
+		t.Errorf("Expected PowerState 'Off', got: %s", systemInfo.PowerState)
+	}
+
+	for _, state := range []PowerState{PowerStateOn, PowerStateCycle, PowerStateOff} {
+		if err := client.SetPowerState(context.Background(), state); err != nil {
+			t.Fatalf("Expected no error for %s, got: %v", state, err)
+		}
+	}
+
+	responder.mu.Lock()
+	controls := responder.chassisControl
+	responder.mu.Unlock()
+	if !bytes.Equal(controls, []byte{0x01, 0x02, 0x00}) {
+		t.Errorf("Expected chassis controls [1 2 0], got: %v", controls)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Errorf("Expected no error closing session, got: %v", err)
+	}
+	responder.mu.Lock()
+	closed := responder.closed
+	responder.mu.Unlock()
+	if !closed {
+		t.Error("Expected session to be closed on the BMC")
+	}
+}
+
+func TestIPMIClient_WrongPassword(t *testing.T) {
+	responder := newIPMITestResponder(t, "admin", "password")
+	client := newTestIPMIClient(responder, "wrong")
+
+	_, err := client.GetSystemInfo(context.Background())
+	if err == nil {
+		t.Fatal("Expected authentication error, got nil")
+	}
+	if !strings.Contains(err.Error(), "authentication failed") {
+		t.Errorf("Expected authentication failure, got: %v", err)
+	}
+}
+
+func TestIPMIClient_SetBootOverride(t *testing.T) {
+	responder := newIPMITestResponder(t, "admin", "password")
+	client := newTestIPMIClient(responder, "password")
+	defer client.Close()
+
+	if err := client.SetBootOverride(context.Background(), BootTargetPXE, true); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	responder.mu.Lock()
+	flags := responder.bootFlags
+	responder.mu.Unlock()
+	if len(flags) < 2 || flags[0] != 0xC0 || flags[1] != 0x04 {
+		t.Errorf("Expected persistent PXE boot flags [c0 04 ...], got: % x", flags)
+	}
+
+	if err := client.SetBootOverride(context.Background(), BootTarget("Floppy"), false); err == nil {
+		t.Error("Expected error for unsupported boot target, got nil")
+	}
+}
+
+func TestIPMIClient_VirtualMediaUnsupported(t *testing.T) {
+	client := NewIPMIClient("127.0.0.1", "admin", "password", 623)
+
+	if err := client.MountVirtualMedia(context.Background(), "http://example.com/image.iso"); err == nil {
+		t.Error("Expected error for virtual media over IPMI, got nil")
+	}
+}
+
+func TestIPMIClient_GetEventLog(t *testing.T) {
+	responder := newIPMITestResponder(t, "admin", "password")
+
+	// Upper critical temperature event followed by a power supply event
+	record1 := []byte{0x01, 0x00, 0x02, 0, 0, 0, 0, 0x20, 0x00, 0x04, 0x01, 0x30, 0x01, 0x59, 0x00, 0x00}
+	binary.LittleEndian.PutUint32(record1[3:7], 1700000000)
+	record2 := []byte{0x02, 0x00, 0x02, 0, 0, 0, 0, 0x20, 0x00, 0x04, 0x08, 0x40, 0xEF, 0x01, 0x00, 0x00}
+	responder.mu.Lock()
+	responder.sel = [][]byte{record1, record2}
+	responder.mu.Unlock()
+
+	client := newTestIPMIClient(responder, "password")
+	defer client.Close()
+
+	entries, err := client.GetEventLog(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got: %d", len(entries))
+	}
+	if entries[0].Severity != "Critical" || !strings.Contains(entries[0].Message, "Temperature") {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if !entries[0].Created.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Unexpected timestamp: %v", entries[0].Created)
+	}
+	if !strings.Contains(entries[1].Message, "Power Supply") || !strings.Contains(entries[1].Message, "Deasserted") {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+}
+
+func TestIPMIClient_GetSensors(t *testing.T) {
+	responder := newIPMITestResponder(t, "admin", "password")
+
+	// CPU temperature reported 1:1 and a fan with a 100x multiplier
+	responder.mu.Lock()
+	responder.sdrs = [][]byte{
+		fullSensorRecord(0x0001, 0x30, "CPU Temp", 1, 1, 0, 0),
+		fullSensorRecord(0x0002, 0x41, "FAN1", 18, 100, 0, 0),
+		fullSensorRecord(0x0003, 0x50, "PSU1 Power", 6, 1, 0, 0),
+	}
+	responder.readings[0x30] = []byte{45, 0x40, 0x00}
+	responder.readings[0x41] = []byte{54, 0x40, 0x10}
+	responder.readings[0x50] = []byte{0, 0x60, 0x00}
+	responder.mu.Unlock()
+
+	client := newTestIPMIClient(responder, "password")
+	defer client.Close()
+
+	readings, err := client.GetSensors(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(readings) != 3 {
+		t.Fatalf("Expected 3 readings, got: %d", len(readings))
+	}
+
+	if readings[0].Name != "CPU Temp" || readings[0].Value != 45 || readings[0].Units != "degrees C" || readings[0].Status != "ok" {
+		t.Errorf("Unexpected CPU reading: %+v", readings[0])
+	}
+	if readings[1].Value != 5400 || readings[1].Units != "RPM" || readings[1].Status != "critical" {
+		t.Errorf("Unexpected fan reading: %+v", readings[1])
+	}
+	if readings[2].Available || readings[2].Status != "na" {
+		t.Errorf("Expected unavailable PSU reading, got: %+v", readings[2])
+	}
+}
+
+func TestIPMIConvertReading(t *testing.T) {
+	// Voltage sensor with M=2, B=0, Rexp=-2 (reading * 0.02)
+	record := fullSensorRecord(1, 1, "12V", 4, 2, 0, -2)
+	if value := ipmiConvertReading(100, record); math.Abs(value-2.0) > 1e-9 {
+		t.Errorf("Expected 2.0, got: %v", value)
+	}
+
+	// Two's complement temperature
+	record = fullSensorRecord(1, 1, "Inlet", 1, 1, 0, 0)
+	record[20] = 0x80
+	if value := ipmiConvertReading(0xFB, record); value != -5 {
+		t.Errorf("Expected -5, got: %v", value)
+	}
+}
+
+func TestIPMIClient_Canceled(t *testing.T) {
+	// A BMC that never answers would otherwise hold the client for timeout × retries
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	client := NewIPMIClient("127.0.0.1", "admin", "password", conn.LocalAddr().(*net.UDPAddr).Port).(*IPMIClient)
+	client.timeout = 10 * time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = client.GetSystemInfo(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context canceled, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected command to stop promptly, took %s", elapsed)
+	}
+}
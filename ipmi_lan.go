@@ -0,0 +1,614 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// RMCP+ payload types
+const (
+	ipmiPayloadIPMI            byte = 0x00
+	ipmiPayloadOpenSessionReq  byte = 0x10
+	ipmiPayloadOpenSessionResp byte = 0x11
+	ipmiPayloadRAKP1           byte = 0x12
+	ipmiPayloadRAKP2           byte = 0x13
+	ipmiPayloadRAKP3           byte = 0x14
+	ipmiPayloadRAKP4           byte = 0x15
+
+	ipmiPayloadEncrypted     byte = 0x80
+	ipmiPayloadAuthenticated byte = 0x40
+)
+
+const (
+	ipmiAuthTypeRMCPPlus       byte = 0x06
+	ipmiBMCAddress             byte = 0x20
+	ipmiConsoleAddress         byte = 0x81
+	ipmiPrivilegeAdministrator byte = 0x04
+	ipmiNameOnlyLookup         byte = 0x10
+	ipmiAuthCodeLength              = 12
+	ipmiMaxUsernameLength           = 16
+)
+
+// IPMI network functions
+const (
+	ipmiNetFnChassis byte = 0x00
+	ipmiNetFnSensor  byte = 0x04
+	ipmiNetFnApp     byte = 0x06
+	ipmiNetFnStorage byte = 0x0A
+)
+
+// IPMI commands
+const (
+	ipmiCmdGetChassisStatus     byte = 0x01
+	ipmiCmdChassisControl       byte = 0x02
+	ipmiCmdSetSystemBootOptions byte = 0x08
+	ipmiCmdGetSensorReading     byte = 0x2D
+	ipmiCmdSetSessionPrivilege  byte = 0x3B
+	ipmiCmdCloseSession         byte = 0x3C
+	ipmiCmdReserveSDR           byte = 0x22
+	ipmiCmdGetSDR               byte = 0x23
+	ipmiCmdGetSELInfo           byte = 0x40
+	ipmiCmdReserveSEL           byte = 0x42
+	ipmiCmdGetSELEntry          byte = 0x43
+)
+
+// rmcpHeader is the RMCP header of every IPMI-over-LAN packet: version 1.0, no ACK, class IPMI
+var rmcpHeader = []byte{0x06, 0x00, 0xFF, 0x07}
+
+// IPMIError is returned when the BMC answers a command with a non-zero completion code
+type IPMIError struct {
+	NetFn          byte
+	Command        byte
+	CompletionCode byte
+}
+
+func (e *IPMIError) Error() string {
+	descriptions := map[byte]string{
+		0xC0: "node busy",
+		0xC1: "invalid command",
+		0xC3: "timeout while processing command",
+		0xC5: "reservation canceled or invalid",
+		0xC7: "request data length invalid",
+		0xC9: "parameter out of range",
+		0xCB: "requested sensor, data or record not present",
+		0xCC: "invalid data field in request",
+		0xD4: "insufficient privilege level",
+		0xD5: "command not supported in present state",
+	}
+	msg := fmt.Sprintf("IPMI command 0x%02x (netfn 0x%02x) failed with completion code 0x%02x", e.Command, e.NetFn, e.CompletionCode)
+	if desc, ok := descriptions[e.CompletionCode]; ok {
+		msg += ": " + desc
+	}
+	return msg
+}
+
+// ipmiRMCPPlusStatus describes the status codes of the RMCP+ session establishment messages
+func ipmiRMCPPlusStatus(code byte) string {
+	descriptions := map[byte]string{
+		0x01: "insufficient resources to create a session",
+		0x02: "invalid session ID",
+		0x03: "invalid payload type",
+		0x04: "invalid authentication algorithm",
+		0x05: "invalid integrity algorithm",
+		0x06: "no matching authentication payload",
+		0x07: "no matching integrity payload",
+		0x08: "inactive session ID",
+		0x09: "invalid role",
+		0x0A: "unauthorized role or privilege level requested",
+		0x0B: "insufficient resources to create a session at the requested role",
+		0x0C: "invalid name length",
+		0x0D: "unauthorized name",
+		0x0E: "unauthorized GUID",
+		0x0F: "invalid integrity check value",
+		0x10: "invalid confidentiality algorithm",
+		0x11: "no cipher suite match with proposed security algorithms",
+		0x12: "illegal or unrecognized parameter",
+	}
+	if desc, ok := descriptions[code]; ok {
+		return desc
+	}
+	return fmt.Sprintf("status 0x%02x", code)
+}
+
+// ipmiPacket is a decoded RMCP+ session packet
+type ipmiPacket struct {
+	payloadType   byte
+	authenticated bool
+	encrypted     bool
+	sessionID     uint32
+	seq           uint32
+	payload       []byte
+}
+
+// encodeIPMIPacket wraps a payload in the RMCP and RMCP+ session headers. With k1 set the packet
+// is authenticated with HMAC-SHA1-96, and with k2 set the payload is encrypted with AES-CBC-128.
+func encodeIPMIPacket(payloadType byte, sessionID, seq uint32, payload, k1, k2 []byte) ([]byte, error) {
+	if k2 != nil {
+		encrypted, err := ipmiEncrypt(k2, payload)
+		if err != nil {
+			return nil, err
+		}
+		payload = encrypted
+		payloadType |= ipmiPayloadEncrypted
+	}
+	if k1 != nil {
+		payloadType |= ipmiPayloadAuthenticated
+	}
+
+	packet := append([]byte{}, rmcpHeader...)
+	packet = append(packet, ipmiAuthTypeRMCPPlus, payloadType)
+	packet = binary.LittleEndian.AppendUint32(packet, sessionID)
+	packet = binary.LittleEndian.AppendUint32(packet, seq)
+	packet = binary.LittleEndian.AppendUint16(packet, uint16(len(payload)))
+	packet = append(packet, payload...)
+
+	if k1 != nil {
+		// The authenticated part runs from the auth type to the next header byte and must be
+		// a multiple of four bytes long
+		authLen := len(packet) - len(rmcpHeader) + 2
+		padLen := (4 - authLen%4) % 4
+		packet = append(packet, bytes.Repeat([]byte{0xFF}, padLen)...)
+		packet = append(packet, byte(padLen), 0x07)
+
+		mac := hmac.New(sha1.New, k1)
+		mac.Write(packet[len(rmcpHeader):])
+		packet = append(packet, mac.Sum(nil)[:ipmiAuthCodeLength]...)
+	}
+
+	return packet, nil
+}
+
+// decodeIPMIPacket parses an RMCP+ session packet, verifying its integrity with k1 and decrypting
+// its payload with k2 when the packet says it is authenticated or encrypted
+func decodeIPMIPacket(data, k1, k2 []byte) (*ipmiPacket, error) {
+	if len(data) < 16 || !bytes.Equal(data[:4], rmcpHeader) {
+		return nil, fmt.Errorf("not an RMCP IPMI packet")
+	}
+	if data[4] != ipmiAuthTypeRMCPPlus {
+		return nil, fmt.Errorf("unsupported IPMI session auth type 0x%02x (only RMCP+ is supported)", data[4])
+	}
+
+	packet := &ipmiPacket{
+		payloadType:   data[5] &^ (ipmiPayloadEncrypted | ipmiPayloadAuthenticated),
+		authenticated: data[5]&ipmiPayloadAuthenticated != 0,
+		encrypted:     data[5]&ipmiPayloadEncrypted != 0,
+		sessionID:     binary.LittleEndian.Uint32(data[6:10]),
+		seq:           binary.LittleEndian.Uint32(data[10:14]),
+	}
+
+	payloadLen := int(binary.LittleEndian.Uint16(data[14:16]))
+	if len(data) < 16+payloadLen {
+		return nil, fmt.Errorf("truncated IPMI packet")
+	}
+	payload := data[16 : 16+payloadLen]
+
+	if packet.authenticated {
+		if k1 == nil {
+			return nil, fmt.Errorf("authenticated IPMI packet outside of a session")
+		}
+		if len(data) < 16+payloadLen+2+ipmiAuthCodeLength {
+			return nil, fmt.Errorf("truncated IPMI packet trailer")
+		}
+		authEnd := len(data) - ipmiAuthCodeLength
+		mac := hmac.New(sha1.New, k1)
+		mac.Write(data[len(rmcpHeader):authEnd])
+		if !hmac.Equal(mac.Sum(nil)[:ipmiAuthCodeLength], data[authEnd:]) {
+			return nil, fmt.Errorf("IPMI packet integrity check failed")
+		}
+	}
+
+	if packet.encrypted {
+		if k2 == nil {
+			return nil, fmt.Errorf("encrypted IPMI packet outside of a session")
+		}
+		decrypted, err := ipmiDecrypt(k2, payload)
+		if err != nil {
+			return nil, err
+		}
+		payload = decrypted
+	}
+
+	packet.payload = append([]byte{}, payload...)
+	return packet, nil
+}
+
+// ipmiEncrypt encrypts a payload with AES-CBC-128 as IV | ciphertext(data | pad | pad length)
+func ipmiEncrypt(k2, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(k2[:16])
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+
+	padLen := (aes.BlockSize - (len(data)+1)%aes.BlockSize) % aes.BlockSize
+	plain := append([]byte{}, data...)
+	for i := 1; i <= padLen; i++ {
+		plain = append(plain, byte(i))
+	}
+	plain = append(plain, byte(padLen))
+
+	out := make([]byte, aes.BlockSize+len(plain))
+	iv := out[:aes.BlockSize]
+	if _, err := rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("error generating IV: %w", err)
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out[aes.BlockSize:], plain)
+	return out, nil
+}
+
+// ipmiDecrypt reverses ipmiEncrypt
+func ipmiDecrypt(k2, data []byte) ([]byte, error) {
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid encrypted IPMI payload length %d", len(data))
+	}
+
+	block, err := aes.NewCipher(k2[:16])
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+
+	plain := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).CryptBlocks(plain, data[aes.BlockSize:])
+
+	padLen := int(plain[len(plain)-1])
+	if padLen >= aes.BlockSize || padLen+1 > len(plain) {
+		return nil, fmt.Errorf("invalid IPMI payload padding")
+	}
+	return plain[:len(plain)-1-padLen], nil
+}
+
+// ipmiChecksum computes the two's complement checksum used in IPMI messages
+func ipmiChecksum(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	return -sum
+}
+
+// ipmiMessage is a decoded IPMI LAN message. For responses the first data byte is the
+// completion code.
+type ipmiMessage struct {
+	netFn byte
+	rqSeq byte
+	cmd   byte
+	data  []byte
+}
+
+// encodeIPMIMessage builds an IPMI LAN message from responder address rsAddr to requester rqAddr
+// (or the reverse for responses)
+func encodeIPMIMessage(rsAddr, netFn, rqAddr, rqSeq, cmd byte, data []byte) []byte {
+	msg := []byte{rsAddr, netFn << 2}
+	msg = append(msg, ipmiChecksum(msg))
+
+	body := []byte{rqAddr, rqSeq << 2, cmd}
+	body = append(body, data...)
+	msg = append(msg, body...)
+	return append(msg, ipmiChecksum(body))
+}
+
+// decodeIPMIMessage parses and validates an IPMI LAN message
+func decodeIPMIMessage(msg []byte) (*ipmiMessage, error) {
+	if len(msg) < 7 {
+		return nil, fmt.Errorf("IPMI message too short")
+	}
+	if ipmiChecksum(msg[:2]) != msg[2] || ipmiChecksum(msg[3:len(msg)-1]) != msg[len(msg)-1] {
+		return nil, fmt.Errorf("IPMI message checksum mismatch")
+	}
+
+	return &ipmiMessage{
+		netFn: msg[1] >> 2,
+		rqSeq: msg[4] >> 2,
+		cmd:   msg[5],
+		data:  append([]byte{}, msg[6:len(msg)-1]...),
+	}, nil
+}
+
+// ipmiSIK derives the session integrity key from the RAKP exchange (RAKP-HMAC-SHA1)
+func ipmiSIK(password string, rm, rc []byte, role byte, username string) []byte {
+	mac := hmac.New(sha1.New, []byte(password))
+	mac.Write(rm)
+	mac.Write(rc)
+	mac.Write([]byte{role, byte(len(username))})
+	mac.Write([]byte(username))
+	return mac.Sum(nil)
+}
+
+// ipmiDeriveKey derives the additional keying material K1 (constant 0x01) and K2 (constant 0x02)
+func ipmiDeriveKey(sik []byte, constant byte) []byte {
+	mac := hmac.New(sha1.New, sik)
+	mac.Write(bytes.Repeat([]byte{constant}, sha1.Size))
+	return mac.Sum(nil)
+}
+
+// ipmiRAKP2AuthCode computes the key exchange authentication code the BMC sends in RAKP message 2
+func ipmiRAKP2AuthCode(password string, consoleSID, bmcSID uint32, rm, rc, guid []byte, role byte, username string) []byte {
+	mac := hmac.New(sha1.New, []byte(password))
+	mac.Write(binary.LittleEndian.AppendUint32(nil, consoleSID))
+	mac.Write(binary.LittleEndian.AppendUint32(nil, bmcSID))
+	mac.Write(rm)
+	mac.Write(rc)
+	mac.Write(guid)
+	mac.Write([]byte{role, byte(len(username))})
+	mac.Write([]byte(username))
+	return mac.Sum(nil)
+}
+
+// ipmiRAKP3AuthCode computes the key exchange authentication code the console sends in RAKP message 3
+func ipmiRAKP3AuthCode(password string, rc []byte, consoleSID uint32, role byte, username string) []byte {
+	mac := hmac.New(sha1.New, []byte(password))
+	mac.Write(rc)
+	mac.Write(binary.LittleEndian.AppendUint32(nil, consoleSID))
+	mac.Write([]byte{role, byte(len(username))})
+	mac.Write([]byte(username))
+	return mac.Sum(nil)
+}
+
+// ipmiRAKP4ICV computes the integrity check value the BMC sends in RAKP message 4
+func ipmiRAKP4ICV(sik, rm []byte, bmcSID uint32, guid []byte) []byte {
+	mac := hmac.New(sha1.New, sik)
+	mac.Write(rm)
+	mac.Write(binary.LittleEndian.AppendUint32(nil, bmcSID))
+	mac.Write(guid)
+	return mac.Sum(nil)[:ipmiAuthCodeLength]
+}
+
+// ipmiSession is an established IPMI v2.0 RMCP+ session using cipher suite 3
+// (RAKP-HMAC-SHA1, HMAC-SHA1-96, AES-CBC-128)
+type ipmiSession struct {
+	conn      net.Conn
+	timeout   time.Duration
+	retries   int
+	username  string
+	password  string
+	privilege byte
+
+	consoleSessionID uint32
+	bmcSessionID     uint32
+	seq              uint32
+	rqSeq            byte
+	tag              byte
+	k1               []byte
+	k2               []byte
+}
+
+// openIPMISession dials the BMC and establishes an authenticated, encrypted session at
+// administrator privilege
+func openIPMISession(ctx context.Context, address, username, password string, timeout time.Duration, retries int) (*ipmiSession, error) {
+	if len(username) > ipmiMaxUsernameLength {
+		return nil, fmt.Errorf("IPMI username must be at most %d characters", ipmiMaxUsernameLength)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", address, err)
+	}
+
+	s := &ipmiSession{
+		conn:      conn,
+		timeout:   timeout,
+		retries:   retries,
+		username:  username,
+		password:  password,
+		privilege: ipmiPrivilegeAdministrator,
+	}
+
+	if err := s.establish(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// RMCP+ sessions start at user privilege; raise it to the level negotiated above
+	if _, err := s.command(ctx, ipmiNetFnApp, ipmiCmdSetSessionPrivilege, []byte{s.privilege}); err != nil {
+		s.conn.Close()
+		return nil, fmt.Errorf("error setting session privilege: %w", err)
+	}
+
+	return s, nil
+}
+
+// establish runs the Open Session and RAKP 1-4 exchange and derives the session keys
+func (s *ipmiSession) establish(ctx context.Context) error {
+	consoleSID := make([]byte, 4)
+	if _, err := rand.Read(consoleSID); err != nil {
+		return fmt.Errorf("error generating session ID: %w", err)
+	}
+	s.consoleSessionID = binary.LittleEndian.Uint32(consoleSID) | 1
+
+	// Open Session Request proposing cipher suite 3
+	s.tag++
+	openReq := []byte{s.tag, s.privilege, 0x00, 0x00}
+	openReq = binary.LittleEndian.AppendUint32(openReq, s.consoleSessionID)
+	openReq = append(openReq, 0x00, 0x00, 0x00, 0x08, 0x01, 0x00, 0x00, 0x00) // RAKP-HMAC-SHA1
+	openReq = append(openReq, 0x01, 0x00, 0x00, 0x08, 0x01, 0x00, 0x00, 0x00) // HMAC-SHA1-96
+	openReq = append(openReq, 0x02, 0x00, 0x00, 0x08, 0x01, 0x00, 0x00, 0x00) // AES-CBC-128
+
+	resp, err := s.handshake(ctx, ipmiPayloadOpenSessionReq, openReq, ipmiPayloadOpenSessionResp, 36)
+	if err != nil {
+		return fmt.Errorf("error opening IPMI session: %w", err)
+	}
+	if binary.LittleEndian.Uint32(resp[4:8]) != s.consoleSessionID {
+		return fmt.Errorf("error opening IPMI session: BMC answered for another session")
+	}
+	s.bmcSessionID = binary.LittleEndian.Uint32(resp[8:12])
+
+	// RAKP Message 1
+	rm := make([]byte, 16)
+	if _, err := rand.Read(rm); err != nil {
+		return fmt.Errorf("error generating random number: %w", err)
+	}
+	role := s.privilege | ipmiNameOnlyLookup
+
+	s.tag++
+	rakp1 := []byte{s.tag, 0x00, 0x00, 0x00}
+	rakp1 = binary.LittleEndian.AppendUint32(rakp1, s.bmcSessionID)
+	rakp1 = append(rakp1, rm...)
+	rakp1 = append(rakp1, role, 0x00, 0x00, byte(len(s.username)))
+	rakp1 = append(rakp1, s.username...)
+
+	resp, err = s.handshake(ctx, ipmiPayloadRAKP1, rakp1, ipmiPayloadRAKP2, 60)
+	if err != nil {
+		return fmt.Errorf("IPMI authentication failed: %w", err)
+	}
+	rc := resp[8:24]
+	guid := resp[24:40]
+	expected := ipmiRAKP2AuthCode(s.password, s.consoleSessionID, s.bmcSessionID, rm, rc, guid, role, s.username)
+	if !hmac.Equal(resp[40:60], expected) {
+		return fmt.Errorf("IPMI authentication failed: BMC key exchange code mismatch (wrong password?)")
+	}
+
+	sik := ipmiSIK(s.password, rm, rc, role, s.username)
+
+	// RAKP Message 3
+	s.tag++
+	rakp3 := []byte{s.tag, 0x00, 0x00, 0x00}
+	rakp3 = binary.LittleEndian.AppendUint32(rakp3, s.bmcSessionID)
+	rakp3 = append(rakp3, ipmiRAKP3AuthCode(s.password, rc, s.consoleSessionID, role, s.username)...)
+
+	resp, err = s.handshake(ctx, ipmiPayloadRAKP3, rakp3, ipmiPayloadRAKP4, 8+ipmiAuthCodeLength)
+	if err != nil {
+		return fmt.Errorf("IPMI authentication failed: %w", err)
+	}
+	if !hmac.Equal(resp[8:8+ipmiAuthCodeLength], ipmiRAKP4ICV(sik, rm, s.bmcSessionID, guid)) {
+		return fmt.Errorf("IPMI authentication failed: BMC integrity check value mismatch")
+	}
+
+	s.k1 = ipmiDeriveKey(sik, 0x01)
+	s.k2 = ipmiDeriveKey(sik, 0x02)[:16]
+	return nil
+}
+
+// handshake sends an unauthenticated session setup message and returns the matching reply
+// payload after checking its status code
+func (s *ipmiSession) handshake(ctx context.Context, reqType byte, req []byte, respType byte, minLen int) ([]byte, error) {
+	packet, err := encodeIPMIPacket(reqType, 0, 0, req, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := req[0]
+	reply, err := s.roundTrip(ctx, packet, func(p *ipmiPacket) bool {
+		return p.payloadType == respType && len(p.payload) >= 2 && p.payload[0] == tag
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if status := reply.payload[1]; status != 0 {
+		return nil, fmt.Errorf("%s", ipmiRMCPPlusStatus(status))
+	}
+	if len(reply.payload) < minLen {
+		return nil, fmt.Errorf("short reply from BMC (%d bytes)", len(reply.payload))
+	}
+	return reply.payload, nil
+}
+
+// roundTrip sends a packet and waits for a reply accepted by the filter, retransmitting on timeout
+func (s *ipmiSession) roundTrip(ctx context.Context, packet []byte, accept func(*ipmiPacket) bool) (*ipmiPacket, error) {
+	// Cancellation expires the read deadline so a blocked Read returns immediately
+	stop := context.AfterFunc(ctx, func() { _ = s.conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	buf := make([]byte, 1024)
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, err := s.conn.Write(packet); err != nil {
+			return nil, fmt.Errorf("error sending IPMI packet: %w", err)
+		}
+
+		deadline, ctxLimited := time.Now().Add(s.timeout), false
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline, ctxLimited = ctxDeadline, true
+		}
+		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if err := s.conn.SetReadDeadline(deadline); err != nil {
+				return nil, err
+			}
+			n, err := s.conn.Read(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					}
+					// The context's timer may fire just after the read deadline
+					if ctxLimited {
+						return nil, context.DeadlineExceeded
+					}
+					break
+				}
+				return nil, fmt.Errorf("error receiving IPMI packet: %w", err)
+			}
+
+			reply, err := decodeIPMIPacket(buf[:n], s.k1, s.k2)
+			if err != nil || !accept(reply) {
+				continue // Ignore stray or corrupted packets
+			}
+			return reply, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no response from BMC after %d attempts", s.retries+1)
+}
+
+// command sends an IPMI request inside the session and returns the response data without
+// the completion code
+func (s *ipmiSession) command(ctx context.Context, netFn, cmd byte, data []byte) ([]byte, error) {
+	s.seq++
+	s.rqSeq = (s.rqSeq + 1) & 0x3F
+	rqSeq := s.rqSeq
+
+	msg := encodeIPMIMessage(ipmiBMCAddress, netFn, ipmiConsoleAddress, rqSeq, cmd, data)
+	packet, err := encodeIPMIPacket(ipmiPayloadIPMI, s.bmcSessionID, s.seq, msg, s.k1, s.k2)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *ipmiMessage
+	_, err = s.roundTrip(ctx, packet, func(p *ipmiPacket) bool {
+		if p.payloadType != ipmiPayloadIPMI || !p.authenticated || p.sessionID != s.consoleSessionID {
+			return false
+		}
+		m, err := decodeIPMIMessage(p.payload)
+		if err != nil || m.rqSeq != rqSeq || m.cmd != cmd {
+			return false
+		}
+		resp = m
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.data) == 0 {
+		return nil, fmt.Errorf("IPMI response without completion code")
+	}
+	if cc := resp.data[0]; cc != 0 {
+		return nil, &IPMIError{NetFn: netFn, Command: cmd, CompletionCode: cc}
+	}
+	return resp.data[1:], nil
+}
+
+// close ends the session on the BMC and releases the socket
+func (s *ipmiSession) close() error {
+	_, err := s.command(context.Background(), ipmiNetFnApp, ipmiCmdCloseSession, binary.LittleEndian.AppendUint32(nil, s.bmcSessionID))
+	if closeErr := s.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
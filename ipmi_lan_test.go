@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+)
+
+// ipmiTestResponder is an in-process BMC that speaks IPMI v2.0 RMCP+ (cipher suite 3) over UDP
+type ipmiTestResponder struct {
+	conn     *net.UDPConn
+	username string
+	password string
+
+	mu             sync.Mutex
+	powerOn        bool
+	chassisControl []byte
+	bootFlags      []byte
+	sel            [][]byte
+	sdrs           [][]byte
+	readings       map[byte][]byte
+	closed         bool
+
+	consoleSID uint32
+	bmcSID     uint32
+	rm         []byte
+	rc         []byte
+	guid       []byte
+	role       byte
+	k1         []byte
+	k2         []byte
+	seq        uint32
+}
+
+// newIPMITestResponder starts a responder on a random localhost UDP port
+func newIPMITestResponder(t *testing.T, username, password string) *ipmiTestResponder {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	r := &ipmiTestResponder{
+		conn:     conn,
+		username: username,
+		password: password,
+		readings: map[byte][]byte{},
+		guid:     bytes.Repeat([]byte{0xAB}, 16),
+	}
+	go r.serve()
+	t.Cleanup(func() { conn.Close() })
+	return r
+}
+
+// port returns the UDP port the responder listens on
+func (r *ipmiTestResponder) port() int {
+	return r.conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func (r *ipmiTestResponder) serve() {
+	buf := make([]byte, 1024)
+	for {
+		n, addr, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if reply := r.handle(buf[:n]); reply != nil {
+			_, _ = r.conn.WriteToUDP(reply, addr)
+		}
+	}
+}
+
+// handle processes one packet and returns the reply, or nil to drop it
+func (r *ipmiTestResponder) handle(data []byte) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	packet, err := decodeIPMIPacket(data, r.k1, r.k2)
+	if err != nil {
+		return nil
+	}
+	p := packet.payload
+
+	switch packet.payloadType {
+	case ipmiPayloadOpenSessionReq:
+		r.consoleSID = binary.LittleEndian.Uint32(p[4:8])
+		r.bmcSID = 0x0200AB01
+		r.k1, r.k2 = nil, nil
+
+		resp := []byte{p[0], 0x00, ipmiPrivilegeAdministrator, 0x00}
+		resp = binary.LittleEndian.AppendUint32(resp, r.consoleSID)
+		resp = binary.LittleEndian.AppendUint32(resp, r.bmcSID)
+		resp = append(resp, p[8:32]...)
+		reply, _ := encodeIPMIPacket(ipmiPayloadOpenSessionResp, 0, 0, resp, nil, nil)
+		return reply
+
+	case ipmiPayloadRAKP1:
+		r.rm = append([]byte{}, p[8:24]...)
+		r.role = p[24]
+		username := string(p[28 : 28+int(p[27])])
+
+		resp := []byte{p[0], 0x00, 0x00, 0x00}
+		if username != r.username {
+			resp[1] = 0x0D // unauthorized name
+		}
+		resp = binary.LittleEndian.AppendUint32(resp, r.consoleSID)
+		if resp[1] == 0x00 {
+			r.rc = make([]byte, 16)
+			_, _ = rand.Read(r.rc)
+			resp = append(resp, r.rc...)
+			resp = append(resp, r.guid...)
+			resp = append(resp, ipmiRAKP2AuthCode(r.password, r.consoleSID, r.bmcSID, r.rm, r.rc, r.guid, r.role, r.username)...)
+		}
+		reply, _ := encodeIPMIPacket(ipmiPayloadRAKP2, 0, 0, resp, nil, nil)
+		return reply
+
+	case ipmiPayloadRAKP3:
+		resp := []byte{p[0], 0x00, 0x00, 0x00}
+		if !hmac.Equal(p[8:28], ipmiRAKP3AuthCode(r.password, r.rc, r.consoleSID, r.role, r.username)) {
+			resp[1] = 0x0F // invalid integrity check value
+		}
+		resp = binary.LittleEndian.AppendUint32(resp, r.consoleSID)
+
+		sik := ipmiSIK(r.password, r.rm, r.rc, r.role, r.username)
+		if resp[1] == 0x00 {
+			resp = append(resp, ipmiRAKP4ICV(sik, r.rm, r.bmcSID, r.guid)...)
+		}
+		reply, _ := encodeIPMIPacket(ipmiPayloadRAKP4, 0, 0, resp, nil, nil)
+		if resp[1] == 0x00 {
+			r.k1 = ipmiDeriveKey(sik, 0x01)
+			r.k2 = ipmiDeriveKey(sik, 0x02)[:16]
+		}
+		return reply
+
+	case ipmiPayloadIPMI:
+		if !packet.authenticated || !packet.encrypted || packet.sessionID != r.bmcSID {
+			return nil
+		}
+		msg, err := decodeIPMIMessage(p)
+		if err != nil {
+			return nil
+		}
+
+		cc, data := r.execute(msg.netFn, msg.cmd, msg.data)
+		resp := encodeIPMIMessage(ipmiConsoleAddress, msg.netFn|0x01, ipmiBMCAddress, msg.rqSeq, msg.cmd, append([]byte{cc}, data...))
+		r.seq++
+		reply, _ := encodeIPMIPacket(ipmiPayloadIPMI, r.consoleSID, r.seq, resp, r.k1, r.k2)
+		return reply
+	}
+
+	return nil
+}
+
+// execute runs an IPMI command against the responder state
+func (r *ipmiTestResponder) execute(netFn, cmd byte, data []byte) (byte, []byte) {
+	switch {
+	case netFn == ipmiNetFnApp && cmd == ipmiCmdSetSessionPrivilege:
+		return 0x00, []byte{data[0]}
+	case netFn == ipmiNetFnApp && cmd == ipmiCmdCloseSession:
+		r.closed = true
+		return 0x00, nil
+
+	case netFn == ipmiNetFnChassis && cmd == ipmiCmdGetChassisStatus:
+		var state byte
+		if r.powerOn {
+			state = 0x01
+		}
+		return 0x00, []byte{state, 0x00, 0x00, 0x00}
+	case netFn == ipmiNetFnChassis && cmd == ipmiCmdChassisControl:
+		r.chassisControl = append(r.chassisControl, data[0])
+		r.powerOn = data[0] != 0x00
+		return 0x00, nil
+	case netFn == ipmiNetFnChassis && cmd == ipmiCmdSetSystemBootOptions:
+		if data[0]&0x7F == 0x05 {
+			r.bootFlags = append([]byte{}, data[1:]...)
+		}
+		return 0x00, nil
+
+	case netFn == ipmiNetFnStorage && cmd == ipmiCmdGetSELInfo:
+		resp := []byte{0x51}
+		resp = binary.LittleEndian.AppendUint16(resp, uint16(len(r.sel)))
+		resp = append(resp, make([]byte, 11)...)
+		return 0x00, resp
+	case netFn == ipmiNetFnStorage && cmd == ipmiCmdReserveSEL:
+		return 0x00, []byte{0x01, 0x00}
+	case netFn == ipmiNetFnStorage && cmd == ipmiCmdGetSELEntry:
+		return r.getRecord(r.sel, binary.LittleEndian.Uint16(data[2:4]), 0, 16)
+
+	case netFn == ipmiNetFnStorage && cmd == ipmiCmdReserveSDR:
+		return 0x00, []byte{0x02, 0x00}
+	case netFn == ipmiNetFnStorage && cmd == ipmiCmdGetSDR:
+		return r.getRecord(r.sdrs, binary.LittleEndian.Uint16(data[2:4]), int(data[4]), int(data[5]))
+
+	case netFn == ipmiNetFnSensor && cmd == ipmiCmdGetSensorReading:
+		if reading, ok := r.readings[data[0]]; ok {
+			return 0x00, reading
+		}
+		return 0xCB, nil
+	}
+
+	return 0xC1, nil
+}
+
+// getRecord serves SEL and SDR records, whose first two bytes are the record ID
+func (r *ipmiTestResponder) getRecord(records [][]byte, id uint16, offset, count int) (byte, []byte) {
+	for i, record := range records {
+		if id != 0x0000 && binary.LittleEndian.Uint16(record[0:2]) != id {
+			continue
+		}
+
+		next := uint16(0xFFFF)
+		if i+1 < len(records) {
+			next = binary.LittleEndian.Uint16(records[i+1][0:2])
+		}
+		end := offset + count
+		if end > len(record) {
+			end = len(record)
+		}
+		return 0x00, append(binary.LittleEndian.AppendUint16(nil, next), record[offset:end]...)
+	}
+	return 0xCB, nil
+}
+
+// fullSensorRecord builds a type 0x01 SDR with the given conversion factors
+func fullSensorRecord(id uint16, number byte, name string, units byte, m, b int, rexp int) []byte {
+	record := make([]byte, 48)
+	binary.LittleEndian.PutUint16(record[0:2], id)
+	record[2] = 0x51
+	record[3] = 0x01
+	record[5] = ipmiBMCAddress
+	record[7] = number
+	record[21] = units
+	record[24] = byte(m)
+	record[25] = byte(m>>2) & 0xC0
+	record[26] = byte(b)
+	record[27] = byte(b>>2) & 0xC0
+	record[29] = byte(rexp&0x0F) << 4
+	record[47] = 0xC0 | byte(len(name))
+	record = append(record, name...)
+	record[4] = byte(len(record) - 5)
+	return record
+}
+
+func TestIPMIPacket_RoundTrip(t *testing.T) {
+	k1 := bytes.Repeat([]byte{0x11}, 20)
+	k2 := bytes.Repeat([]byte{0x22}, 16)
+	payload := []byte("chassis status request")
+
+	packet, err := encodeIPMIPacket(ipmiPayloadIPMI, 42, 7, payload, k1, k2)
+	if err != nil {
+		t.Fatalf("Expected no error encoding packet, got: %v", err)
+	}
+
+	decoded, err := decodeIPMIPacket(packet, k1, k2)
+	if err != nil {
+		t.Fatalf("Expected no error decoding packet, got: %v", err)
+	}
+	if !decoded.authenticated || !decoded.encrypted {
+		t.Error("Expected packet to be authenticated and encrypted")
+	}
+	if decoded.sessionID != 42 || decoded.seq != 7 {
+		t.Errorf("Expected session 42 seq 7, got session %d seq %d", decoded.sessionID, decoded.seq)
+	}
+	if !bytes.Equal(decoded.payload, payload) {
+		t.Errorf("Expected payload %q, got %q", payload, decoded.payload)
+	}
+
+	// A tampered packet must fail the integrity check
+	packet[20] ^= 0xFF
+	if _, err := decodeIPMIPacket(packet, k1, k2); err == nil {
+		t.Error("Expected integrity check error for tampered packet, got nil")
+	}
+}
+
+func TestIPMIMessage_RoundTrip(t *testing.T) {
+	msg := encodeIPMIMessage(ipmiBMCAddress, ipmiNetFnChassis, ipmiConsoleAddress, 5, ipmiCmdChassisControl, []byte{0x01})
+
+	decoded, err := decodeIPMIMessage(msg)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if decoded.netFn != ipmiNetFnChassis || decoded.cmd != ipmiCmdChassisControl || decoded.rqSeq != 5 {
+		t.Errorf("Unexpected decoded message: %+v", decoded)
+	}
+
+	msg[len(msg)-1]++
+	if _, err := decodeIPMIMessage(msg); err == nil {
+		t.Error("Expected checksum error, got nil")
+	}
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 )
@@ -13,23 +16,55 @@ var (
 	verbose bool
 )
 
+// skipConfigAnnotation marks commands (and their subcommands) that run without a BMC configuration
+const skipConfigAnnotation = "skip-config"
+
 var rootCmd = &cobra.Command{
 	Use:   "bmc-cli",
 	Short: "A CLI tool to manage BMC operations",
 	Long: `bmc-cli is a command-line tool for managing Baseboard Management Controllers (BMCs)
-including HP iLO (Integrated Lights-Out) and DELL iDRAC systems. It provides functionality to:
+including HP iLO (Integrated Lights-Out), DELL iDRAC, Supermicro, Lenovo XClarity
+Controller (XCC) and OpenBMC systems. It provides functionality to:
 - Power on/off servers
 - Mount virtual media
+- Override the boot device
+- Read the system event log and sensors
 - Manage server configurations
 
-Supports both HPE iLO and DELL iDRAC BMCs via Redfish API.
+Supports HPE iLO, DELL iDRAC, Supermicro, Lenovo XCC and OpenBMC BMCs via Redfish API,
+and older BMCs without Redfish via IPMI v2.0 over LAN.
 Configuration can be provided via YAML file or environment variables.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if recordDir != "" && replayDir != "" {
+			return fmt.Errorf("--record and --replay cannot be used together")
+		}
+		if outputFlag != "" {
+			if err := validateOutputFormat(outputFlag); err != nil {
+				return err
+			}
+		}
+
+		for c := cmd; c != nil; c = c.Parent() {
+			if c.Annotations[skipConfigAnnotation] == "true" {
+				return nil
+			}
+		}
+		initConfig()
+		return nil
+	},
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./config.yaml, then <user config dir>/bmc-cli/config.yaml; config.json, config.toml and other formats are found too)")
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "configuration context to use instead of current_context")
+	rootCmd.PersistentFlags().StringVar(&hostName, "host", "", "inventory host to use (see 'bmc-cli hosts list')")
+	rootCmd.PersistentFlags().StringVar(&outputFlag, "output", "", "output format: text, json or yaml (default from the context, else text)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().DurationVar(&requestTimeout, "timeout", requestTimeout, "timeout for each request to the BMC")
+	rootCmd.PersistentFlags().IntVar(&requestRetries, "retries", requestRetries, "number of times a failed request is retried")
+	rootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "record BMC HTTP traffic to a cassette directory (credentials are redacted)")
+	rootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "replay BMC HTTP traffic from a cassette directory instead of contacting the BMC")
+	rootCmd.PersistentFlags().StringVar(&knownHostsFile, "known-hosts", "", "file of pinned BMC certificate fingerprints (default is <user config dir>/bmc-cli/known_hosts)")
 }
 
 func initConfig() {
@@ -45,12 +80,26 @@ func initConfig() {
 			fmt.Printf("Connected to iLO at %s:%d\n", config.ILO.Host, config.ILO.Port)
 		case BMCTypeIDRAC:
 			fmt.Printf("Connected to iDRAC at %s:%d\n", config.IDRAC.Host, config.IDRAC.Port)
+		case BMCTypeSupermicro:
+			fmt.Printf("Connected to Supermicro BMC at %s:%d\n", config.Supermicro.Host, config.Supermicro.Port)
+		case BMCTypeXCC:
+			fmt.Printf("Connected to XCC at %s:%d\n", config.XCC.Host, config.XCC.Port)
+		case BMCTypeOpenBMC:
+			fmt.Printf("Connected to OpenBMC at %s:%d\n", config.OpenBMC.Host, config.OpenBMC.Port)
+		case BMCTypeIPMI:
+			fmt.Printf("Connected to IPMI BMC at %s:%d\n", config.IPMI.Host, config.IPMI.Port)
 		}
 	}
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	// The first SIGINT or SIGTERM cancels in-flight requests; a second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ServerManifest declares the desired state of a server for 'plan' and 'apply'. Settings
+// left out of the manifest are not managed.
+type ServerManifest struct {
+	// Power is the desired power state: on or off
+	Power string `yaml:"power"`
+	// Reboot restarts a running server when staged BIOS settings or a boot override need a
+	// restart to take effect
+	Reboot       bool                   `yaml:"reboot"`
+	Boot         *BootManifest          `yaml:"boot"`
+	VirtualMedia *VirtualMediaManifest  `yaml:"virtual_media"`
+	BIOS         map[string]interface{} `yaml:"bios"`
+	NTP          *NTPManifest           `yaml:"ntp"`
+	DNS          *DNSManifest           `yaml:"dns"`
+	Users        []UserManifest         `yaml:"users"`
+}
+
+// BootManifest is the desired boot source override
+type BootManifest struct {
+	// Target is none, pxe, cd, hdd, bios or usb
+	Target     string `yaml:"target"`
+	Persistent bool   `yaml:"persistent"`
+}
+
+// VirtualMediaManifest is the desired virtual media image; an empty image means ejected
+type VirtualMediaManifest struct {
+	Image string `yaml:"image"`
+}
+
+// NTPManifest is the desired NTP configuration of the BMC
+type NTPManifest struct {
+	Enabled *bool    `yaml:"enabled"`
+	Servers []string `yaml:"servers"`
+}
+
+// DNSManifest is the desired static DNS servers of the BMC
+type DNSManifest struct {
+	Servers []string `yaml:"servers"`
+}
+
+// UserManifest is a desired BMC account. The password is only set when the account is
+// created, since BMCs do not reveal it for comparison.
+type UserManifest struct {
+	Username       string `yaml:"username"`
+	Role           string `yaml:"role"`
+	Enabled        *bool  `yaml:"enabled"`
+	Absent         bool   `yaml:"absent"`
+	Password       string `yaml:"password"`
+	PasswordSource `yaml:",inline"`
+}
+
+// loadManifest reads and validates a manifest file; "-" reads standard input
+func loadManifest(path string) (*ServerManifest, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest: %w", err)
+	}
+	manifest, err := parseManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// parseManifest decodes a manifest, rejecting unknown keys, and validates its values
+func parseManifest(data []byte) (*ServerManifest, error) {
+	var manifest ServerManifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing manifest: %w", err)
+	}
+
+	manifest.Power = strings.ToLower(manifest.Power)
+	if manifest.Power != "" && manifest.Power != "on" && manifest.Power != "off" {
+		return nil, fmt.Errorf("power: unsupported state %q (use on or off)", manifest.Power)
+	}
+	if manifest.Boot != nil {
+		if _, ok := bootTargets[strings.ToLower(manifest.Boot.Target)]; !ok {
+			return nil, fmt.Errorf("boot.target: unknown boot target %q (valid targets: none, pxe, cd, hdd, bios, usb)", manifest.Boot.Target)
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, user := range manifest.Users {
+		if user.Username == "" {
+			return nil, fmt.Errorf("users[%d]: username is required", i)
+		}
+		if seen[user.Username] {
+			return nil, fmt.Errorf("users[%d]: user %s is declared twice", i, user.Username)
+		}
+		seen[user.Username] = true
+
+		sources := 0
+		for _, value := range []string{user.Password, user.PasswordFile, user.PasswordCmd, user.PasswordEnv} {
+			if value != "" {
+				sources++
+			}
+		}
+		if sources > 1 {
+			return nil, fmt.Errorf("users[%d]: only one of password, password_file, password_cmd and password_env may be set", i)
+		}
+		if user.Absent && (user.Role != "" || user.Enabled != nil || sources > 0) {
+			return nil, fmt.Errorf("users[%d]: an absent user takes no other settings", i)
+		}
+	}
+	return &manifest, nil
+}
+
+// managesRedfishSettings reports whether the manifest declares settings that are only
+// reachable through Redfish
+func (m *ServerManifest) managesRedfishSettings() bool {
+	return len(m.BIOS) > 0 || m.NTP != nil || m.DNS != nil || len(m.Users) > 0
+}
@@ -0,0 +1,1141 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MockProfile describes the vendor-specific resource layout and quirks emulated by MockBMC
+type MockProfile struct {
+	Name            string
+	Manufacturer    string
+	Model           string
+	SystemID        string
+	ManagerID       string
+	ManagerModel    string
+	FirmwareVersion string
+	ResetTypes      []string
+	BootTargets     []string
+	Slots           []MockSlotProfile
+	BiosAttributes  map[string]interface{}
+
+	// TaskIDFormat formats the sequence number of a new task
+	TaskIDFormat string
+	// PowerConflictStatus is returned when a reset asks for the power state the system is already in
+	PowerConflictStatus int
+	// PowerConflictMessageID is the MessageId reported alongside PowerConflictStatus
+	PowerConflictMessageID string
+	// MediaInUseMessageID is reported when inserting into a slot that already holds an image
+	MediaInUseMessageID string
+	// BiosRequiresJob means pending BIOS settings are only applied by a configuration job
+	// created through the manager's Jobs collection (iDRAC), rather than on the next reset (iLO)
+	BiosRequiresJob bool
+	// AccountSlots is the fixed number of account slots (iDRAC), which are assigned with PATCH
+	// rather than created with POST and deleted (iLO); zero means accounts are created freely
+	AccountSlots int
+	// ScreenshotExport serves screenshots base64 encoded from the Lifecycle Controller's
+	// ExportServerScreenShot action (iDRAC)
+	ScreenshotExport bool
+	// ScreenshotThumbnail serves the screen as a BMP thumbnail for web sessions (iLO)
+	ScreenshotThumbnail bool
+}
+
+// MockSlotProfile describes a virtual media slot
+type MockSlotProfile struct {
+	ID         string
+	Name       string
+	MediaTypes []string
+}
+
+// mockProfiles holds the built-in emulation profiles
+var mockProfiles = map[string]MockProfile{
+	"ilo5": {
+		Name:            "ilo5",
+		Manufacturer:    "HPE",
+		Model:           "ProLiant DL380 Gen10",
+		SystemID:        "1",
+		ManagerID:       "1",
+		ManagerModel:    "iLO 5",
+		FirmwareVersion: "iLO 5 v2.72",
+		ResetTypes:      []string{"On", "ForceOff", "GracefulShutdown", "ForceRestart", "Nmi", "PushPowerButton"},
+		BootTargets:     []string{"None", "Cd", "Hdd", "Usb", "SDCard", "Utilities", "Diags", "BiosSetup", "Pxe", "UefiShell", "UefiHttp", "UefiTarget"},
+		Slots: []MockSlotProfile{
+			{ID: "1", Name: "VirtualMedia", MediaTypes: []string{"Floppy", "USBStick"}},
+			{ID: "2", Name: "VirtualMedia", MediaTypes: []string{"CD", "DVD"}},
+		},
+		BiosAttributes: map[string]interface{}{
+			"BootMode":           "Uefi",
+			"ProcHyperthreading": "Enabled",
+			"UsbControl":         "UsbEnabled",
+			"WorkloadProfile":    "GeneralPowerEfficientCompute",
+		},
+		TaskIDFormat:           "%d",
+		PowerConflictStatus:    http.StatusBadRequest,
+		PowerConflictMessageID: "iLO.2.14.InvalidOperationForSystemState",
+		MediaInUseMessageID:    "iLO.2.14.UnableModifyDuringSessionInProgress",
+		ScreenshotThumbnail:    true,
+	},
+	"idrac9": {
+		Name:            "idrac9",
+		Manufacturer:    "Dell Inc.",
+		Model:           "PowerEdge R740",
+		SystemID:        "System.Embedded.1",
+		ManagerID:       "iDRAC.Embedded.1",
+		ManagerModel:    "14G Monolithic",
+		FirmwareVersion: "6.10.30.00",
+		ResetTypes:      []string{"On", "ForceOff", "ForceRestart", "GracefulRestart", "GracefulShutdown", "PushPowerButton", "Nmi", "PowerCycle"},
+		BootTargets:     []string{"None", "Pxe", "Floppy", "Cd", "Hdd", "BiosSetup", "Utilities", "UefiTarget", "SDCard", "UefiHttp"},
+		Slots: []MockSlotProfile{
+			{ID: "CD", Name: "CD", MediaTypes: []string{"CD", "DVD"}},
+			{ID: "RemovableDisk", Name: "RemovableDisk", MediaTypes: []string{"USBStick"}},
+		},
+		BiosAttributes: map[string]interface{}{
+			"BootMode":          "Uefi",
+			"LogicalProc":       "Enabled",
+			"SriovGlobalEnable": "Disabled",
+			"SysProfile":        "PerfPerWattOptimizedDapc",
+		},
+		TaskIDFormat:           "JID_%012d",
+		PowerConflictStatus:    http.StatusConflict,
+		PowerConflictMessageID: "IDRAC.2.8.PSU501",
+		MediaInUseMessageID:    "IDRAC.2.8.VRM0012",
+		BiosRequiresJob:        true,
+		AccountSlots:           16,
+		ScreenshotExport:       true,
+	},
+}
+
+// mockProfileNames returns the names of the built-in profiles
+func mockProfileNames() []string {
+	names := make([]string, 0, len(mockProfiles))
+	for name := range mockProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mockVirtualMedia is the state of an emulated virtual media slot
+type mockVirtualMedia struct {
+	MockSlotProfile
+	image    string
+	inserted bool
+}
+
+// mockAccount is an emulated BMC account other than the one tests log in with
+type mockAccount struct {
+	id       string
+	userName string
+	password string
+	roleID   string
+	enabled  bool
+}
+
+// mockSubscription is an emulated event subscription
+type mockSubscription struct {
+	id          string
+	destination string
+	eventTypes  []string
+	context     string
+}
+
+// mockEventTypes are the event types the emulated EventService accepts
+var mockEventTypes = []string{"StatusChange", "ResourceUpdated", "ResourceAdded", "ResourceRemoved", "Alert"}
+
+// mockTask is an emulated Redfish task (or iDRAC job)
+type mockTask struct {
+	id         string
+	name       string
+	state      string
+	percent    int
+	pollsLeft  int
+	onComplete func()
+}
+
+// MockBMC is an in-process, stateful Redfish emulator for tests and demos
+type MockBMC struct {
+	Username string
+	Password string
+	// TransitionReads is how many reads of the system report PoweringOn/PoweringOff before settling
+	TransitionReads int
+	// TaskPolls is how many reads of a running task report progress before it completes
+	TaskPolls int
+
+	profile MockProfile
+
+	mu              sync.Mutex
+	powerState      string
+	transitionLeft  int
+	bootTarget      string
+	bootEnabled     string
+	media           []*mockVirtualMedia
+	bios            map[string]interface{}
+	pendingBios     map[string]interface{}
+	pendingBiosTask *mockTask
+	tasks           map[string]*mockTask
+	taskOrder       []string
+	accounts        []*mockAccount
+	accountSeq      int
+	subscriptions   []*mockSubscription
+	subscriptionSeq int
+	ntpEnabled      bool
+	ntpServers      []string
+	dnsServers      []string
+	sessions        map[string]string
+	sessionSeq      int
+}
+
+// NewMockBMC creates an emulator for the named profile with the given credentials
+func NewMockBMC(profile, username, password string) (*MockBMC, error) {
+	p, ok := mockProfiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown mock profile: %s (available profiles: %s)", profile, strings.Join(mockProfileNames(), ", "))
+	}
+
+	m := &MockBMC{
+		Username:        username,
+		Password:        password,
+		TransitionReads: 1,
+		TaskPolls:       2,
+		profile:         p,
+		powerState:      "On",
+		bootTarget:      "None",
+		bootEnabled:     "Disabled",
+		bios:            map[string]interface{}{},
+		pendingBios:     map[string]interface{}{},
+		tasks:           map[string]*mockTask{},
+		sessions:        map[string]string{},
+	}
+	for name, value := range p.BiosAttributes {
+		m.bios[name] = value
+	}
+	for _, slot := range p.Slots {
+		m.media = append(m.media, &mockVirtualMedia{MockSlotProfile: slot})
+	}
+	for m.accountSeq = 2; m.accountSeq <= p.AccountSlots; m.accountSeq++ {
+		m.accounts = append(m.accounts, &mockAccount{id: fmt.Sprint(m.accountSeq), roleID: "None"})
+	}
+
+	return m, nil
+}
+
+func (m *MockBMC) systemPath() string {
+	return "/redfish/v1/Systems/" + m.profile.SystemID
+}
+
+func (m *MockBMC) managerPath() string {
+	return "/redfish/v1/Managers/" + m.profile.ManagerID
+}
+
+// ServeHTTP routes Redfish requests to the emulated resources
+func (m *MockBMC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	sys := m.systemPath()
+	mgr := m.managerPath()
+
+	// The service root and session login are reachable without credentials
+	switch {
+	case path == "/redfish/v1" && r.Method == http.MethodGet:
+		m.writeServiceRoot(w)
+		return
+	case path == "/redfish/v1/SessionService/Sessions" && r.Method == http.MethodPost:
+		m.createSession(w, r)
+		return
+	case m.profile.ScreenshotThumbnail && path == "/images/thumbnail.bmp" && r.Method == http.MethodGet:
+		// The web interface authenticates with the session cookie
+		cookie, err := r.Cookie("sessionKey")
+		if err != nil || !m.validSession(cookie.Value) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "image/bmp")
+		_, _ = w.Write(mockBMP(m.screen()))
+		return
+	}
+
+	if !m.authorized(r) {
+		writeMockError(w, http.StatusUnauthorized, "Base.1.8.NoValidSession", "There is no valid session established with the implementation.")
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(path, "/redfish/v1/SessionService/Sessions/") && r.Method == http.MethodDelete:
+		delete(m.sessions, strings.TrimPrefix(path, "/redfish/v1/SessionService/Sessions/"))
+		w.WriteHeader(http.StatusNoContent)
+
+	case path == "/redfish/v1/Systems" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, mockCollection(path, "ComputerSystemCollection", sys))
+	case path == sys && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, m.system())
+	case path == sys && r.Method == http.MethodPatch:
+		m.patchSystem(w, r)
+	case path == sys+"/Actions/ComputerSystem.Reset" && r.Method == http.MethodPost:
+		m.resetSystem(w, r)
+	case path == sys+"/Bios" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, m.biosResource(path, m.bios))
+	case path == sys+"/Bios/Settings" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, m.biosResource(path, m.pendingBios))
+	case path == sys+"/Bios/Settings" && r.Method == http.MethodPatch:
+		m.patchBiosSettings(w, r)
+
+	case path == "/redfish/v1/Managers" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, mockCollection(path, "ManagerCollection", mgr))
+	case path == mgr && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, m.manager())
+	case path == mgr+"/VirtualMedia" && r.Method == http.MethodGet:
+		var members []string
+		for _, vm := range m.media {
+			members = append(members, path+"/"+vm.ID)
+		}
+		writeMockJSON(w, http.StatusOK, mockCollection(path, "VirtualMediaCollection", members...))
+	case strings.HasPrefix(path, mgr+"/VirtualMedia/"):
+		m.serveVirtualMedia(w, r, strings.TrimPrefix(path, mgr+"/VirtualMedia/"))
+	case path == mgr+"/NetworkProtocol" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id": path,
+			"Id":        "NetworkProtocol",
+			"NTP":       map[string]interface{}{"ProtocolEnabled": m.ntpEnabled, "NTPServers": mockStrings(m.ntpServers)},
+		})
+	case path == mgr+"/NetworkProtocol" && r.Method == http.MethodPatch:
+		m.patchNetworkProtocol(w, r)
+	case path == mgr+"/EthernetInterfaces" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, mockCollection(path, "EthernetInterfaceCollection", path+"/1"))
+	case path == mgr+"/EthernetInterfaces/1" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id": path,
+			"Id":        "1",
+			// The servers in use include one learned through DHCP
+			"NameServers":       append(mockStrings(m.dnsServers), "10.0.0.254"),
+			"StaticNameServers": mockStrings(m.dnsServers),
+		})
+	case path == mgr+"/EthernetInterfaces/1" && r.Method == http.MethodPatch:
+		var request struct {
+			StaticNameServers []string `json:"StaticNameServers"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeMockError(w, http.StatusBadRequest, "Base.1.8.MalformedJSON", "The request body submitted was malformed JSON.")
+			return
+		}
+		m.dnsServers = request.StaticNameServers
+		w.WriteHeader(http.StatusNoContent)
+	case m.profile.ScreenshotExport && path == "/redfish/v1/Dell/Managers/"+m.profile.ManagerID+"/DellLCService/Actions/DellLCService.ExportServerScreenShot" && r.Method == http.MethodPost:
+		m.exportScreenshot(w, r)
+	case m.profile.BiosRequiresJob && path == mgr+"/Jobs" && r.Method == http.MethodPost:
+		m.createBiosJob(w, r)
+	case m.profile.BiosRequiresJob && strings.HasPrefix(path, mgr+"/Jobs/") && r.Method == http.MethodGet:
+		m.serveTask(w, strings.TrimPrefix(path, mgr+"/Jobs/"), true)
+
+	case path == "/redfish/v1/AccountService" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id": path,
+			"Id":        "AccountService",
+			"Accounts":  map[string]string{"@odata.id": path + "/Accounts"},
+			"Roles":     map[string]string{"@odata.id": path + "/Roles"},
+		})
+	case path == "/redfish/v1/AccountService/Accounts" && r.Method == http.MethodGet:
+		members := []string{path + "/1"}
+		for _, account := range m.accounts {
+			members = append(members, path+"/"+account.id)
+		}
+		writeMockJSON(w, http.StatusOK, mockCollection(path, "ManagerAccountCollection", members...))
+	case path == "/redfish/v1/AccountService/Accounts" && r.Method == http.MethodPost:
+		m.createAccount(w, r)
+	case path == "/redfish/v1/AccountService/Accounts/1" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id": path,
+			"Id":        "1",
+			"UserName":  m.Username,
+			"RoleId":    "Administrator",
+			"Enabled":   true,
+		})
+	case strings.HasPrefix(path, "/redfish/v1/AccountService/Accounts/"):
+		m.serveAccount(w, r, strings.TrimPrefix(path, "/redfish/v1/AccountService/Accounts/"))
+	case path == "/redfish/v1/AccountService/Roles" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, mockCollection(path, "RoleCollection", path+"/Administrator"))
+	case path == "/redfish/v1/AccountService/Roles/Administrator" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id":          path,
+			"Id":                 "Administrator",
+			"RoleId":             "Administrator",
+			"IsPredefined":       true,
+			"AssignedPrivileges": []string{"Login", "ConfigureManager", "ConfigureUsers", "ConfigureSelf", "ConfigureComponents"},
+		})
+
+	case path == "/redfish/v1/EventService" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id":                 path,
+			"Id":                        "EventService",
+			"ServiceEnabled":            true,
+			"EventTypesForSubscription": mockEventTypes,
+			"Subscriptions":             map[string]string{"@odata.id": path + "/Subscriptions"},
+		})
+	case path == "/redfish/v1/EventService/Subscriptions" && r.Method == http.MethodGet:
+		var members []string
+		for _, subscription := range m.subscriptions {
+			members = append(members, path+"/"+subscription.id)
+		}
+		writeMockJSON(w, http.StatusOK, mockCollection(path, "EventDestinationCollection", members...))
+	case path == "/redfish/v1/EventService/Subscriptions" && r.Method == http.MethodPost:
+		m.createSubscription(w, r)
+	case strings.HasPrefix(path, "/redfish/v1/EventService/Subscriptions/"):
+		m.serveSubscription(w, r, strings.TrimPrefix(path, "/redfish/v1/EventService/Subscriptions/"))
+
+	case path == "/redfish/v1/TaskService" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id":      path,
+			"Id":             "TaskService",
+			"ServiceEnabled": true,
+			"Tasks":          map[string]string{"@odata.id": path + "/Tasks"},
+		})
+	case path == "/redfish/v1/TaskService/Tasks" && r.Method == http.MethodGet:
+		var members []string
+		for _, id := range m.taskOrder {
+			members = append(members, path+"/"+id)
+		}
+		writeMockJSON(w, http.StatusOK, mockCollection(path, "TaskCollection", members...))
+	case strings.HasPrefix(path, "/redfish/v1/TaskService/Tasks/") && r.Method == http.MethodGet:
+		m.serveTask(w, strings.TrimPrefix(path, "/redfish/v1/TaskService/Tasks/"), false)
+
+	default:
+		writeMockError(w, http.StatusNotFound, "Base.1.8.ResourceMissingAtURI", fmt.Sprintf("The resource at the URI %s was not found.", r.URL.Path))
+	}
+}
+
+// authorized accepts either basic auth or a session token
+func (m *MockBMC) authorized(r *http.Request) bool {
+	if token := r.Header.Get("X-Auth-Token"); token != "" {
+		return m.validSession(token)
+	}
+
+	username, password, ok := r.BasicAuth()
+	return ok && username == m.Username && password == m.Password
+}
+
+func (m *MockBMC) validSession(token string) bool {
+	for _, t := range m.sessions {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MockBMC) createSession(w http.ResponseWriter, r *http.Request) {
+	var credentials struct {
+		UserName string `json:"UserName"`
+		Password string `json:"Password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		writeMockError(w, http.StatusBadRequest, "Base.1.8.MalformedJSON", "The request body submitted was malformed JSON.")
+		return
+	}
+	if credentials.UserName != m.Username || credentials.Password != m.Password {
+		writeMockError(w, http.StatusUnauthorized, "Base.1.8.ResourceAtUriUnauthorized", "Invalid username or password.")
+		return
+	}
+
+	m.sessionSeq++
+	id := fmt.Sprintf("%d", m.sessionSeq)
+	m.sessions[id] = mockToken()
+
+	location := "/redfish/v1/SessionService/Sessions/" + id
+	w.Header().Set("X-Auth-Token", m.sessions[id])
+	w.Header().Set("Location", location)
+	writeMockJSON(w, http.StatusCreated, map[string]string{"@odata.id": location, "Id": id, "UserName": m.Username})
+}
+
+func (m *MockBMC) writeServiceRoot(w http.ResponseWriter) {
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"@odata.id":      "/redfish/v1",
+		"Id":             "RootService",
+		"RedfishVersion": "1.6.0",
+		"Vendor":         m.profile.Manufacturer,
+		"Systems":        map[string]string{"@odata.id": "/redfish/v1/Systems"},
+		"Managers":       map[string]string{"@odata.id": "/redfish/v1/Managers"},
+		"TaskService":    map[string]string{"@odata.id": "/redfish/v1/TaskService"},
+		"EventService":   map[string]string{"@odata.id": "/redfish/v1/EventService"},
+		"SessionService": map[string]string{"@odata.id": "/redfish/v1/SessionService"},
+		"AccountService": map[string]string{"@odata.id": "/redfish/v1/AccountService"},
+	})
+}
+
+// currentPowerState reports the power state, counting down any transition in progress
+func (m *MockBMC) currentPowerState() string {
+	if m.transitionLeft > 0 {
+		m.transitionLeft--
+		if m.powerState == "On" {
+			return "PoweringOn"
+		}
+		return "PoweringOff"
+	}
+	return m.powerState
+}
+
+func (m *MockBMC) system() map[string]interface{} {
+	sys := m.systemPath()
+	return map[string]interface{}{
+		"@odata.id":    sys,
+		"Id":           m.profile.SystemID,
+		"Manufacturer": m.profile.Manufacturer,
+		"Model":        m.profile.Model,
+		"PowerState":   m.currentPowerState(),
+		"Status":       map[string]string{"Health": "OK", "State": "Enabled"},
+		"Boot": map[string]interface{}{
+			"BootSourceOverrideTarget":                         m.bootTarget,
+			"BootSourceOverrideEnabled":                        m.bootEnabled,
+			"BootSourceOverrideMode":                           m.bios["BootMode"],
+			"BootSourceOverrideTarget@Redfish.AllowableValues": m.profile.BootTargets,
+		},
+		"Bios": map[string]string{"@odata.id": sys + "/Bios"},
+		"Actions": map[string]interface{}{
+			"#ComputerSystem.Reset": map[string]interface{}{
+				"target":                            sys + "/Actions/ComputerSystem.Reset",
+				"ResetType@Redfish.AllowableValues": m.profile.ResetTypes,
+			},
+		},
+	}
+}
+
+func (m *MockBMC) patchSystem(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Boot *struct {
+			BootSourceOverrideTarget  *string `json:"BootSourceOverrideTarget"`
+			BootSourceOverrideEnabled *string `json:"BootSourceOverrideEnabled"`
+		} `json:"Boot"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeMockError(w, http.StatusBadRequest, "Base.1.8.MalformedJSON", "The request body submitted was malformed JSON.")
+		return
+	}
+	if request.Boot == nil {
+		writeMockError(w, http.StatusBadRequest, "Base.1.8.PropertyNotWritable", "Only the Boot property can be modified.")
+		return
+	}
+
+	if target := request.Boot.BootSourceOverrideTarget; target != nil {
+		if !containsString(m.profile.BootTargets, *target) {
+			writeMockError(w, http.StatusBadRequest, "Base.1.8.PropertyValueNotInList",
+				fmt.Sprintf("The value %s for the property BootSourceOverrideTarget is not in the list of acceptable values.", *target))
+			return
+		}
+		m.bootTarget = *target
+	}
+	if enabled := request.Boot.BootSourceOverrideEnabled; enabled != nil {
+		if !containsString([]string{"Disabled", "Once", "Continuous"}, *enabled) {
+			writeMockError(w, http.StatusBadRequest, "Base.1.8.PropertyValueNotInList",
+				fmt.Sprintf("The value %s for the property BootSourceOverrideEnabled is not in the list of acceptable values.", *enabled))
+			return
+		}
+		m.bootEnabled = *enabled
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *MockBMC) resetSystem(w http.ResponseWriter, r *http.Request) {
+	var request PowerRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeMockError(w, http.StatusBadRequest, "Base.1.8.MalformedJSON", "The request body submitted was malformed JSON.")
+		return
+	}
+	if !containsString(m.profile.ResetTypes, request.ResetType) {
+		writeMockError(w, http.StatusBadRequest, "Base.1.8.ActionParameterNotSupported",
+			fmt.Sprintf("The parameter ResetType with value %s is not supported by this action.", request.ResetType))
+		return
+	}
+
+	switch request.ResetType {
+	case "On":
+		if m.powerState == "On" {
+			writeMockError(w, m.profile.PowerConflictStatus, m.profile.PowerConflictMessageID, "The server is already powered on.")
+			return
+		}
+		m.powerOn()
+	case "ForceOff", "GracefulShutdown":
+		if m.powerState == "Off" {
+			writeMockError(w, m.profile.PowerConflictStatus, m.profile.PowerConflictMessageID, "The server is already powered off.")
+			return
+		}
+		m.powerState = "Off"
+		m.transitionLeft = m.TransitionReads
+	case "PushPowerButton":
+		if m.powerState == "On" {
+			m.powerState = "Off"
+			m.transitionLeft = m.TransitionReads
+		} else {
+			m.powerOn()
+		}
+	case "ForceRestart", "GracefulRestart", "PowerCycle":
+		m.powerOn()
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// powerOn boots the system, consuming a one-time boot override and applying pending BIOS settings
+func (m *MockBMC) powerOn() {
+	m.powerState = "On"
+	m.transitionLeft = m.TransitionReads
+
+	if m.bootEnabled == "Once" {
+		m.bootEnabled = "Disabled"
+		m.bootTarget = "None"
+	}
+
+	if len(m.pendingBios) == 0 {
+		return
+	}
+	if m.pendingBiosTask == nil {
+		if m.profile.BiosRequiresJob {
+			return
+		}
+		m.pendingBiosTask = m.newTask("BIOS Configuration")
+	}
+	m.pendingBiosTask.state = "Running"
+}
+
+func (m *MockBMC) biosResource(path string, attributes map[string]interface{}) map[string]interface{} {
+	resource := map[string]interface{}{
+		"@odata.id":  path,
+		"Id":         "Bios",
+		"Attributes": attributes,
+	}
+	if !strings.HasSuffix(path, "/Settings") {
+		resource["@Redfish.Settings"] = map[string]interface{}{
+			"SettingsObject": map[string]string{"@odata.id": path + "/Settings"},
+		}
+	}
+	return resource
+}
+
+func (m *MockBMC) patchBiosSettings(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Attributes map[string]interface{} `json:"Attributes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeMockError(w, http.StatusBadRequest, "Base.1.8.MalformedJSON", "The request body submitted was malformed JSON.")
+		return
+	}
+
+	for name := range request.Attributes {
+		if _, ok := m.bios[name]; !ok {
+			writeMockError(w, http.StatusBadRequest, "Base.1.8.PropertyUnknown", fmt.Sprintf("The property %s is not in the list of valid properties for the resource.", name))
+			return
+		}
+	}
+	for name, value := range request.Attributes {
+		m.pendingBios[name] = value
+	}
+
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"@Message.ExtendedInfo": []map[string]string{{
+			"MessageId": "Base.1.8.SystemResetRequired",
+			"Message":   "The settings will be applied on the next system reset.",
+		}},
+	})
+}
+
+// createBiosJob schedules the iDRAC configuration job that applies pending BIOS settings
+func (m *MockBMC) createBiosJob(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		TargetSettingsURI string `json:"TargetSettingsURI"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeMockError(w, http.StatusBadRequest, "Base.1.8.MalformedJSON", "The request body submitted was malformed JSON.")
+		return
+	}
+	if request.TargetSettingsURI != m.systemPath()+"/Bios/Settings" {
+		writeMockError(w, http.StatusBadRequest, "IDRAC.2.8.SYS046", "Unable to create the job because the TargetSettingsURI is invalid.")
+		return
+	}
+	if len(m.pendingBios) == 0 {
+		writeMockError(w, http.StatusBadRequest, "IDRAC.2.8.SYS011", "Pending configuration values are not available.")
+		return
+	}
+	if m.pendingBiosTask != nil {
+		writeMockError(w, http.StatusBadRequest, "IDRAC.2.8.SYS010", "A configuration job is already scheduled.")
+		return
+	}
+
+	m.pendingBiosTask = m.newTask("Configure: BIOS.Setup.1-1")
+	m.pendingBiosTask.state = "Scheduled"
+
+	w.Header().Set("Location", m.managerPath()+"/Jobs/"+m.pendingBiosTask.id)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *MockBMC) newTask(name string) *mockTask {
+	task := &mockTask{
+		id:        fmt.Sprintf(m.profile.TaskIDFormat, len(m.taskOrder)+1),
+		name:      name,
+		state:     "New",
+		pollsLeft: m.TaskPolls,
+	}
+	task.onComplete = func() {
+		for attribute, value := range m.pendingBios {
+			m.bios[attribute] = value
+		}
+		m.pendingBios = map[string]interface{}{}
+		m.pendingBiosTask = nil
+	}
+
+	m.tasks[task.id] = task
+	m.taskOrder = append(m.taskOrder, task.id)
+	return task
+}
+
+// serveTask reports a task, advancing it one step when running. iDRAC jobs use the Job
+// schema (JobState) while the TaskService uses TaskState.
+func (m *MockBMC) serveTask(w http.ResponseWriter, id string, job bool) {
+	task, ok := m.tasks[id]
+	if !ok {
+		writeMockError(w, http.StatusNotFound, "Base.1.8.ResourceMissingAtURI", fmt.Sprintf("The task %s was not found.", id))
+		return
+	}
+
+	if task.state == "Running" {
+		if task.pollsLeft > 0 {
+			task.pollsLeft--
+			task.percent = 100 * (m.TaskPolls - task.pollsLeft) / (m.TaskPolls + 1)
+		} else {
+			task.state = "Completed"
+			task.percent = 100
+			task.onComplete()
+		}
+	}
+
+	if job {
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id":       m.managerPath() + "/Jobs/" + task.id,
+			"Id":              task.id,
+			"Name":            task.name,
+			"JobState":        task.state,
+			"PercentComplete": task.percent,
+		})
+		return
+	}
+
+	taskState := task.state
+	if taskState == "Scheduled" {
+		taskState = "Pending"
+	}
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"@odata.id":       "/redfish/v1/TaskService/Tasks/" + task.id,
+		"Id":              task.id,
+		"Name":            task.name,
+		"TaskState":       taskState,
+		"PercentComplete": task.percent,
+	})
+}
+
+func (m *MockBMC) manager() map[string]interface{} {
+	mgr := m.managerPath()
+	return map[string]interface{}{
+		"@odata.id":          mgr,
+		"Id":                 m.profile.ManagerID,
+		"Model":              m.profile.ManagerModel,
+		"FirmwareVersion":    m.profile.FirmwareVersion,
+		"Status":             map[string]string{"Health": "OK", "State": "Enabled"},
+		"VirtualMedia":       map[string]string{"@odata.id": mgr + "/VirtualMedia"},
+		"NetworkProtocol":    map[string]string{"@odata.id": mgr + "/NetworkProtocol"},
+		"EthernetInterfaces": map[string]string{"@odata.id": mgr + "/EthernetInterfaces"},
+	}
+}
+
+func (m *MockBMC) patchNetworkProtocol(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		NTP *struct {
+			ProtocolEnabled *bool    `json:"ProtocolEnabled"`
+			NTPServers      []string `json:"NTPServers"`
+		} `json:"NTP"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeMockError(w, http.StatusBadRequest, "Base.1.8.MalformedJSON", "The request body submitted was malformed JSON.")
+		return
+	}
+	if request.NTP != nil {
+		if request.NTP.ProtocolEnabled != nil {
+			m.ntpEnabled = *request.NTP.ProtocolEnabled
+		}
+		if request.NTP.NTPServers != nil {
+			m.ntpServers = request.NTP.NTPServers
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mockAccountRequest is the writable part of a ManagerAccount
+type mockAccountRequest struct {
+	UserName *string `json:"UserName"`
+	Password *string `json:"Password"`
+	RoleID   *string `json:"RoleId"`
+	Enabled  *bool   `json:"Enabled"`
+}
+
+func (a *mockAccount) update(request mockAccountRequest) {
+	if request.UserName != nil {
+		a.userName = *request.UserName
+	}
+	if request.Password != nil {
+		a.password = *request.Password
+	}
+	if request.RoleID != nil {
+		a.roleID = *request.RoleID
+	}
+	if request.Enabled != nil {
+		a.enabled = *request.Enabled
+	}
+}
+
+// createAccount adds an account; BMCs with fixed slots do not support creating one
+func (m *MockBMC) createAccount(w http.ResponseWriter, r *http.Request) {
+	if m.profile.AccountSlots > 0 {
+		writeMockError(w, http.StatusMethodNotAllowed, "Base.1.8.OperationNotAllowed", "Accounts are assigned by modifying an unused account slot.")
+		return
+	}
+	var request mockAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.UserName == nil || request.Password == nil {
+		writeMockError(w, http.StatusBadRequest, "Base.1.8.PropertyMissing", "UserName and Password are required to create an account.")
+		return
+	}
+	if *request.UserName == m.Username || m.findAccount(*request.UserName) != nil {
+		writeMockError(w, http.StatusConflict, "Base.1.8.ResourceAlreadyExists", fmt.Sprintf("The account %s already exists.", *request.UserName))
+		return
+	}
+
+	m.accountSeq++
+	account := &mockAccount{id: fmt.Sprint(m.accountSeq), roleID: "ReadOnly", enabled: true}
+	account.update(request)
+	m.accounts = append(m.accounts, account)
+	w.Header().Set("Location", "/redfish/v1/AccountService/Accounts/"+account.id)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// createSubscription registers an event destination, which like on iLO and iDRAC must use
+// HTTPS
+func (m *MockBMC) createSubscription(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Destination string   `json:"Destination"`
+		EventTypes  []string `json:"EventTypes"`
+		Context     string   `json:"Context"`
+		Protocol    string   `json:"Protocol"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Destination == "" {
+		writeMockError(w, http.StatusBadRequest, "Base.1.8.PropertyMissing", "Destination is required to create a subscription.")
+		return
+	}
+	if !strings.HasPrefix(request.Destination, "https://") {
+		writeMockError(w, http.StatusBadRequest, "Base.1.8.PropertyValueFormatError", "The Destination must be an HTTPS URI.")
+		return
+	}
+	for _, eventType := range request.EventTypes {
+		if !containsString(mockEventTypes, eventType) {
+			writeMockError(w, http.StatusBadRequest, "Base.1.8.PropertyValueNotInList", fmt.Sprintf("The value %s for EventTypes is not in the list of acceptable values.", eventType))
+			return
+		}
+	}
+
+	m.subscriptionSeq++
+	subscription := &mockSubscription{id: fmt.Sprint(m.subscriptionSeq), destination: request.Destination, eventTypes: request.EventTypes, context: request.Context}
+	m.subscriptions = append(m.subscriptions, subscription)
+	w.Header().Set("Location", "/redfish/v1/EventService/Subscriptions/"+subscription.id)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (m *MockBMC) serveSubscription(w http.ResponseWriter, r *http.Request, id string) {
+	for i, subscription := range m.subscriptions {
+		if subscription.id != id {
+			continue
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeMockJSON(w, http.StatusOK, map[string]interface{}{
+				"@odata.id":   r.URL.Path,
+				"Id":          subscription.id,
+				"Destination": subscription.destination,
+				"EventTypes":  mockStrings(subscription.eventTypes),
+				"Context":     subscription.context,
+				"Protocol":    "Redfish",
+			})
+		case http.MethodDelete:
+			m.subscriptions = append(m.subscriptions[:i], m.subscriptions[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeMockError(w, http.StatusMethodNotAllowed, "Base.1.8.OperationNotAllowed", "The operation is not allowed on a subscription.")
+		}
+		return
+	}
+	writeMockError(w, http.StatusNotFound, "Base.1.8.ResourceMissingAtURI", fmt.Sprintf("The resource at the URI %s was not found.", r.URL.Path))
+}
+
+func (m *MockBMC) findAccount(userName string) *mockAccount {
+	for _, account := range m.accounts {
+		if account.userName == userName {
+			return account
+		}
+	}
+	return nil
+}
+
+func (m *MockBMC) serveAccount(w http.ResponseWriter, r *http.Request, id string) {
+	index := -1
+	for i, account := range m.accounts {
+		if account.id == id {
+			index = i
+		}
+	}
+	if index < 0 {
+		writeMockError(w, http.StatusNotFound, "Base.1.8.ResourceMissingAtURI", fmt.Sprintf("The account %s was not found.", id))
+		return
+	}
+	account := m.accounts[index]
+
+	switch r.Method {
+	case http.MethodGet:
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id": "/redfish/v1/AccountService/Accounts/" + account.id,
+			"Id":        account.id,
+			"UserName":  account.userName,
+			"RoleId":    account.roleID,
+			"Enabled":   account.enabled,
+		})
+	case http.MethodPatch:
+		var request mockAccountRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeMockError(w, http.StatusBadRequest, "Base.1.8.MalformedJSON", "The request body submitted was malformed JSON.")
+			return
+		}
+		account.update(request)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if m.profile.AccountSlots > 0 {
+			writeMockError(w, http.StatusMethodNotAllowed, "Base.1.8.OperationNotAllowed", "Account slots cannot be deleted.")
+			return
+		}
+		m.accounts = append(m.accounts[:index], m.accounts[index+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeMockError(w, http.StatusMethodNotAllowed, "Base.1.8.OperationNotAllowed", "The operation is not allowed on this resource.")
+	}
+}
+
+// mockStrings returns an empty list rather than null for unset string lists
+func mockStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func (m *MockBMC) serveVirtualMedia(w http.ResponseWriter, r *http.Request, rest string) {
+	id, action, _ := strings.Cut(rest, "/Actions/")
+
+	var slot *mockVirtualMedia
+	for _, vm := range m.media {
+		if vm.ID == id {
+			slot = vm
+		}
+	}
+	if slot == nil {
+		writeMockError(w, http.StatusNotFound, "Base.1.8.ResourceMissingAtURI", fmt.Sprintf("The resource at the URI %s was not found.", r.URL.Path))
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeMockJSON(w, http.StatusOK, m.virtualMedia(slot))
+	case action == "" && r.Method == http.MethodPatch:
+		var request struct {
+			Image    *string `json:"Image"`
+			Inserted *bool   `json:"Inserted"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeMockError(w, http.StatusBadRequest, "Base.1.8.MalformedJSON", "The request body submitted was malformed JSON.")
+			return
+		}
+		if request.Inserted != nil && !*request.Inserted {
+			slot.image, slot.inserted = "", false
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if request.Image == nil {
+			writeMockError(w, http.StatusBadRequest, "Base.1.8.PropertyMissing", "The property Image is a required property and must be included in the request.")
+			return
+		}
+		m.insertMedia(w, slot, *request.Image)
+	case action == "VirtualMedia.InsertMedia" && r.Method == http.MethodPost:
+		var request struct {
+			Image string `json:"Image"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeMockError(w, http.StatusBadRequest, "Base.1.8.MalformedJSON", "The request body submitted was malformed JSON.")
+			return
+		}
+		m.insertMedia(w, slot, request.Image)
+	case action == "VirtualMedia.EjectMedia" && r.Method == http.MethodPost:
+		slot.image, slot.inserted = "", false
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeMockError(w, http.StatusMethodNotAllowed, "Base.1.8.OperationNotAllowed", fmt.Sprintf("%s is not allowed on %s.", r.Method, r.URL.Path))
+	}
+}
+
+func (m *MockBMC) insertMedia(w http.ResponseWriter, slot *mockVirtualMedia, image string) {
+	if slot.inserted {
+		writeMockError(w, http.StatusBadRequest, m.profile.MediaInUseMessageID, "The virtual media slot already has an image inserted. Eject it first.")
+		return
+	}
+	if u, err := url.Parse(image); err != nil || u.Scheme == "" || u.Host == "" {
+		writeMockError(w, http.StatusBadRequest, "Base.1.8.PropertyValueFormatError", fmt.Sprintf("The value %s for the property Image is of a different format than the property can accept.", image))
+		return
+	}
+
+	slot.image, slot.inserted = image, true
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *MockBMC) virtualMedia(slot *mockVirtualMedia) map[string]interface{} {
+	path := m.managerPath() + "/VirtualMedia/" + slot.ID
+	connectedVia := "NotConnected"
+	if slot.inserted {
+		connectedVia = "URI"
+	}
+	return map[string]interface{}{
+		"@odata.id":      path,
+		"Id":             slot.ID,
+		"Name":           slot.Name,
+		"MediaTypes":     slot.MediaTypes,
+		"Image":          slot.image,
+		"Inserted":       slot.inserted,
+		"Connected":      slot.inserted,
+		"ConnectedVia":   connectedVia,
+		"WriteProtected": true,
+		"Actions": map[string]interface{}{
+			"#VirtualMedia.InsertMedia": map[string]string{"target": path + "/Actions/VirtualMedia.InsertMedia"},
+			"#VirtualMedia.EjectMedia":  map[string]string{"target": path + "/Actions/VirtualMedia.EjectMedia"},
+		},
+	}
+}
+
+// mockCollection builds a Redfish resource collection
+// screen renders the emulated host screen: a POST screen with a progress bar while the
+// host is on, and black while it is off
+func (m *MockBMC) screen() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	if m.powerState != "On" {
+		draw.Draw(img, img.Bounds(), image.Black, image.Point{}, draw.Src)
+		return img
+	}
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0, 0, 0xaa, 0xff}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(8, 40, 40, 44), image.White, image.Point{}, draw.Src)
+	return img
+}
+
+func (m *MockBMC) exportScreenshot(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		FileType string `json:"FileType"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.FileType != "ServerScreenShot" {
+		writeMockError(w, http.StatusBadRequest, "Base.1.8.ActionParameterUnknown", "FileType must be ServerScreenShot.")
+		return
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, m.screen())
+	writeMockJSON(w, http.StatusOK, map[string]string{"ServerScreenShotFile": base64.StdEncoding.EncodeToString(buf.Bytes())})
+}
+
+// mockBMP encodes an image as a 24-bit bottom-up BMP, as iLO serves its screen thumbnail
+func mockBMP(img image.Image) []byte {
+	bounds := img.Bounds()
+	rowSize := (bounds.Dx()*3 + 3) &^ 3
+	pixels := rowSize * bounds.Dy()
+
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, bmpHeader{
+		Magic:       [2]byte{'B', 'M'},
+		FileSize:    uint32(54 + pixels),
+		PixelOffset: 54,
+		HeaderSize:  40,
+		Width:       int32(bounds.Dx()),
+		Height:      int32(bounds.Dy()),
+		Planes:      1,
+		BitCount:    24,
+	})
+	// The remaining BITMAPINFOHEADER fields: image size, resolution and palette
+	_ = binary.Write(&buf, binary.LittleEndian, [5]uint32{uint32(pixels)})
+	row := make([]byte, rowSize)
+	for y := bounds.Max.Y - 1; y >= bounds.Min.Y; y-- {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			i := (x - bounds.Min.X) * 3
+			row[i], row[i+1], row[i+2] = c.B, c.G, c.R
+		}
+		buf.Write(row)
+	}
+	return buf.Bytes()
+}
+
+func mockCollection(path, name string, members ...string) map[string]interface{} {
+	links := []map[string]string{}
+	for _, member := range members {
+		links = append(links, map[string]string{"@odata.id": member})
+	}
+	return map[string]interface{}{
+		"@odata.id":           path,
+		"Name":                name,
+		"Members":             links,
+		"Members@odata.count": len(links),
+	}
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeMockError writes a Redfish error response
+func writeMockError(w http.ResponseWriter, status int, messageID, message string) {
+	writeMockJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    messageID,
+			"message": message,
+			"@Message.ExtendedInfo": []map[string]string{{
+				"MessageId": messageID,
+				"Message":   message,
+			}},
+		},
+	})
+}
+
+func mockToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
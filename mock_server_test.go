@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newMockBMCServer starts an emulator for the profile and returns it with its test server
+func newMockBMCServer(t *testing.T, profile string) (*MockBMC, *httptest.Server) {
+	bmc, err := NewMockBMC(profile, "admin", "password")
+	if err != nil {
+		t.Fatalf("Failed to create mock BMC: %v", err)
+	}
+	server := httptest.NewServer(bmc)
+	t.Cleanup(server.Close)
+	return bmc, server
+}
+
+// mockRequest sends an authenticated request to the emulator and decodes any JSON response
+func mockRequest(t *testing.T, server *httptest.Server, method, path string, body interface{}, result interface{}) *http.Response {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+
+	req, _ := http.NewRequest(method, server.URL+path, bytes.NewReader(data))
+	req.SetBasicAuth("admin", "password")
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			t.Fatalf("Error decoding %s: %v", path, err)
+		}
+	}
+	return resp
+}
+
+func TestMockBMC_ILOClientEndToEnd(t *testing.T) {
+	_, server := newMockBMCServer(t, "ilo5")
+
+	client := &ILOClient{
+		baseURL:    server.URL,
+		username:   "admin",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	// Power off passes through PoweringOff before settling
+	if err := client.SetPowerState(context.Background(), PowerStateOff); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for _, expected := range []string{"PoweringOff", "Off"} {
+		systemInfo, err := client.GetSystemInfo(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if systemInfo.PowerState != expected {
+			t.Errorf("Expected PowerState '%s', got: %s", expected, systemInfo.PowerState)
+		}
+	}
+
+	// Powering off twice is rejected like on real hardware
+	if err := client.SetPowerState(context.Background(), PowerStateOff); err == nil {
+		t.Error("Expected error powering off a system that is off, got nil")
+	}
+
+	// iLO 5 does not implement the PowerCycle reset type, so a power cycle is a ForceRestart
+	resp, err := client.RedfishRequest(context.Background(), http.MethodPost, "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", []byte(`{"ResetType":"PowerCycle"}`), nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected the PowerCycle reset type to be rejected, got status %d", resp.StatusCode)
+	}
+	if err := client.SetPowerState(context.Background(), PowerStateCycle); err != nil {
+		t.Fatalf("Expected no error power cycling, got: %v", err)
+	}
+	for _, expected := range []string{"PoweringOn", "On"} {
+		systemInfo, err := client.GetSystemInfo(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if systemInfo.PowerState != expected {
+			t.Errorf("Expected PowerState '%s', got: %s", expected, systemInfo.PowerState)
+		}
+	}
+
+	if err := client.MountVirtualMedia(context.Background(), "http://example.com/image.iso"); err != nil {
+		t.Fatalf("Expected no error mounting, got: %v", err)
+	}
+	vmList, err := client.GetVirtualMedia(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(vmList) != 2 || !vmList[1].Inserted || vmList[1].Image != "http://example.com/image.iso" {
+		t.Errorf("Expected image inserted in the CD/DVD slot, got: %+v", vmList)
+	}
+
+	// A second mount needs the slot to be ejected first
+	if err := client.MountVirtualMedia(context.Background(), "http://example.com/other.iso"); err == nil {
+		t.Error("Expected error mounting into an occupied slot, got nil")
+	}
+	if err := client.UnmountVirtualMedia(context.Background()); err != nil {
+		t.Fatalf("Expected no error unmounting, got: %v", err)
+	}
+	if err := client.MountVirtualMedia(context.Background(), "http://example.com/other.iso"); err != nil {
+		t.Errorf("Expected no error mounting after eject, got: %v", err)
+	}
+}
+
+func TestMockBMC_BootOverrideConsumedOnBoot(t *testing.T) {
+	_, server := newMockBMCServer(t, "ilo5")
+
+	client := &ILOClient{
+		baseURL:    server.URL,
+		username:   "admin",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	if err := client.SetBootOverride(context.Background(), BootTargetCD, false); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var system struct {
+		Boot struct {
+			BootSourceOverrideTarget  string
+			BootSourceOverrideEnabled string
+		}
+	}
+	mockRequest(t, server, "GET", "/redfish/v1/Systems/1", nil, &system)
+	if system.Boot.BootSourceOverrideTarget != "Cd" || system.Boot.BootSourceOverrideEnabled != "Once" {
+		t.Errorf("Expected one-time Cd override, got: %+v", system.Boot)
+	}
+
+	// The one-time override is cleared by the next boot
+	mockRequest(t, server, "POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", PowerRequest{ResetType: "ForceRestart"}, nil)
+	mockRequest(t, server, "GET", "/redfish/v1/Systems/1", nil, &system)
+	if system.Boot.BootSourceOverrideEnabled != "Disabled" {
+		t.Errorf("Expected override to be consumed, got: %+v", system.Boot)
+	}
+}
+
+func TestMockBMC_IDRACClientEndToEnd(t *testing.T) {
+	_, server := newMockBMCServer(t, "idrac9")
+
+	client := &IDRACClient{
+		baseURL:    server.URL,
+		username:   "admin",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	// iDRAC reports an already-on system as a conflict
+	if err := client.SetPowerState(context.Background(), PowerStateOn); err == nil {
+		t.Error("Expected error powering on a running system, got nil")
+	}
+	if err := client.SetPowerState(context.Background(), PowerStateCycle); err != nil {
+		t.Errorf("Expected no error for PowerCycle, got: %v", err)
+	}
+
+	// The iDRAC client ejects before inserting, so repeated mounts succeed
+	for _, image := range []string{"http://example.com/a.iso", "http://example.com/b.iso"} {
+		if err := client.MountVirtualMedia(context.Background(), image); err != nil {
+			t.Fatalf("Expected no error mounting %s, got: %v", image, err)
+		}
+	}
+
+	// USB is not a valid boot target on iDRAC 9
+	if err := client.SetBootOverride(context.Background(), BootTargetUSB, false); err == nil {
+		t.Error("Expected error for unsupported boot target, got nil")
+	}
+}
+
+func TestMockBMC_BiosSettingsThroughTask(t *testing.T) {
+	bmc, server := newMockBMCServer(t, "ilo5")
+
+	patch := map[string]interface{}{"Attributes": map[string]string{"WorkloadProfile": "HighPerformanceCompute"}}
+	if resp := mockRequest(t, server, "PATCH", "/redfish/v1/Systems/1/Bios/Settings", patch, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", resp.StatusCode)
+	}
+
+	unknown := map[string]interface{}{"Attributes": map[string]string{"NoSuchAttribute": "x"}}
+	if resp := mockRequest(t, server, "PATCH", "/redfish/v1/Systems/1/Bios/Settings", unknown, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown attribute, got: %d", resp.StatusCode)
+	}
+
+	// Settings are applied by a task started on the next reset
+	mockRequest(t, server, "POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", PowerRequest{ResetType: "ForceRestart"}, nil)
+
+	var tasks struct {
+		Members []struct {
+			OdataID string `json:"@odata.id"`
+		}
+	}
+	mockRequest(t, server, "GET", "/redfish/v1/TaskService/Tasks", nil, &tasks)
+	if len(tasks.Members) != 1 {
+		t.Fatalf("Expected 1 task, got: %d", len(tasks.Members))
+	}
+
+	var task struct {
+		TaskState       string
+		PercentComplete int
+	}
+	for i := 0; i <= bmc.TaskPolls; i++ {
+		mockRequest(t, server, "GET", tasks.Members[0].OdataID, nil, &task)
+	}
+	if task.TaskState != "Completed" || task.PercentComplete != 100 {
+		t.Errorf("Expected completed task, got: %+v", task)
+	}
+
+	var bios struct {
+		Attributes map[string]interface{}
+	}
+	mockRequest(t, server, "GET", "/redfish/v1/Systems/1/Bios", nil, &bios)
+	if bios.Attributes["WorkloadProfile"] != "HighPerformanceCompute" {
+		t.Errorf("Expected BIOS attribute to be applied, got: %v", bios.Attributes["WorkloadProfile"])
+	}
+}
+
+func TestMockBMC_IDRACBiosRequiresJob(t *testing.T) {
+	bmc, server := newMockBMCServer(t, "idrac9")
+	bmc.TaskPolls = 0
+
+	patch := map[string]interface{}{"Attributes": map[string]string{"LogicalProc": "Disabled"}}
+	mockRequest(t, server, "PATCH", "/redfish/v1/Systems/System.Embedded.1/Bios/Settings", patch, nil)
+
+	// Without a configuration job a reset leaves the settings pending
+	mockRequest(t, server, "POST", "/redfish/v1/Systems/System.Embedded.1/Actions/ComputerSystem.Reset", PowerRequest{ResetType: "ForceRestart"}, nil)
+	if bmc.bios["LogicalProc"] != "Enabled" || len(bmc.tasks) != 0 {
+		t.Fatalf("Expected settings to stay pending without a job")
+	}
+
+	job := map[string]string{"TargetSettingsURI": "/redfish/v1/Systems/System.Embedded.1/Bios/Settings"}
+	resp := mockRequest(t, server, "POST", "/redfish/v1/Managers/iDRAC.Embedded.1/Jobs", job, nil)
+	location := resp.Header.Get("Location")
+	if location != "/redfish/v1/Managers/iDRAC.Embedded.1/Jobs/JID_000000000001" {
+		t.Fatalf("Unexpected job location: %s", location)
+	}
+
+	var state struct{ JobState string }
+	mockRequest(t, server, "GET", location, nil, &state)
+	if state.JobState != "Scheduled" {
+		t.Errorf("Expected job to be scheduled until reboot, got: %s", state.JobState)
+	}
+
+	mockRequest(t, server, "POST", "/redfish/v1/Systems/System.Embedded.1/Actions/ComputerSystem.Reset", PowerRequest{ResetType: "ForceRestart"}, nil)
+	mockRequest(t, server, "GET", location, nil, &state)
+	if state.JobState != "Completed" || bmc.bios["LogicalProc"] != "Disabled" {
+		t.Errorf("Expected job to complete and apply settings, got state %s", state.JobState)
+	}
+}
+
+func TestMockBMC_Authentication(t *testing.T) {
+	_, server := newMockBMCServer(t, "ilo5")
+
+	// The service root is readable without credentials
+	resp, err := server.Client().Get(server.URL + "/redfish/v1")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 for service root, got: %d", resp.StatusCode)
+	}
+
+	resp, err = server.Client().Get(server.URL + "/redfish/v1/Systems/1")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without credentials, got: %d", resp.StatusCode)
+	}
+
+	// Session tokens are accepted in place of basic auth
+	login, _ := json.Marshal(map[string]string{"UserName": "admin", "Password": "password"})
+	resp, err = server.Client().Post(server.URL+"/redfish/v1/SessionService/Sessions", "application/json", bytes.NewReader(login))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	token := resp.Header.Get("X-Auth-Token")
+	if resp.StatusCode != http.StatusCreated || token == "" {
+		t.Fatalf("Expected session to be created, got status %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest("GET", server.URL+"/redfish/v1/Systems/1", nil)
+	req.Header.Set("X-Auth-Token", token)
+	resp, err = server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 with session token, got: %d", resp.StatusCode)
+	}
+}
+
+func TestNewMockBMC_UnknownProfile(t *testing.T) {
+	if _, err := NewMockBMC("ilo2", "admin", "password"); err == nil {
+		t.Error("Expected error for unknown profile, got nil")
+	}
+}
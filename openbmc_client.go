@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// OpenBMCClient represents an OpenBMC (bmcweb) Redfish API client. OpenBMC deployments
+// commonly disable basic auth, so the client always authenticates with a Redfish session.
+type OpenBMCClient struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+
+	// mu guards the session, which concurrent requests (such as dump's workers) share
+	mu              sync.Mutex
+	sessionToken    string
+	sessionLocation string
+}
+
+// openBMCVirtualMedia is a virtual media slot as exposed by bmcweb
+type openBMCVirtualMedia struct {
+	VirtualMediaInfo
+	OdataID string `json:"@odata.id"`
+	Actions struct {
+		InsertMedia struct {
+			Target string `json:"target"`
+		} `json:"#VirtualMedia.InsertMedia"`
+		EjectMedia struct {
+			Target string `json:"target"`
+		} `json:"#VirtualMedia.EjectMedia"`
+	} `json:"Actions"`
+}
+
+// NewOpenBMCClient creates a new OpenBMC client
+func NewOpenBMCClient(host, username, password string, port int, useHTTPS bool, transport *http.Transport) BMCClient {
+	scheme := "http"
+	if useHTTPS {
+		scheme = "https"
+	}
+
+	baseURL := fmt.Sprintf("%s://%s:%d", scheme, host, port)
+
+	return &OpenBMCClient{
+		baseURL:    baseURL,
+		username:   username,
+		password:   password,
+		httpClient: newHTTPClient(transport),
+	}
+}
+
+// login creates a Redfish session and stores its token; the caller holds c.mu
+func (c *OpenBMCClient) login(ctx context.Context) error {
+	credentials := map[string]string{
+		"UserName": c.username,
+		"Password": c.password,
+	}
+	jsonBody, err := json.Marshal(credentials)
+	if err != nil {
+		return fmt.Errorf("error marshaling request body: %w", err)
+	}
+
+	url := c.baseURL + "/redfish/v1/SessionService/Sessions"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	if verbose {
+		fmt.Printf("Creating session at %s\n", url)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error creating session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("session login failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	token := resp.Header.Get("X-Auth-Token")
+	if token == "" {
+		return fmt.Errorf("session login succeeded but no X-Auth-Token was returned")
+	}
+
+	c.sessionToken = token
+	c.sessionLocation = resp.Header.Get("Location")
+	return nil
+}
+
+// makeRequest makes an HTTP request to the OpenBMC API using the session token, logging in
+// first if needed and once more if the session has expired
+func (c *OpenBMCClient) makeRequest(ctx context.Context, method, endpoint string, body interface{}) (*http.Response, error) {
+	var jsonBody []byte
+	if body != nil {
+		var err error
+		jsonBody, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling request body: %w", err)
+		}
+	}
+
+	return c.RedfishRequest(ctx, method, endpoint, jsonBody, nil)
+}
+
+// RedfishRequest sends a raw request to the OpenBMC Redfish API using the session token,
+// logging in first if needed and once more if the session has expired
+func (c *OpenBMCClient) RedfishRequest(ctx context.Context, method, endpoint string, body []byte, header http.Header) (*http.Response, error) {
+	token, err := c.session(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRequest(ctx, token, method, endpoint, body, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		if token, err = c.session(ctx, token); err != nil {
+			return nil, err
+		}
+		return c.doRequest(ctx, token, method, endpoint, body, header)
+	}
+
+	return resp, nil
+}
+
+// session returns the session token, logging in if there is none yet or if the current
+// token is still the expired one. Requests that find the session expired at the same time
+// then log in only once.
+func (c *OpenBMCClient) session(ctx context.Context, expired string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sessionToken == "" || c.sessionToken == expired {
+		if err := c.login(ctx); err != nil {
+			return "", err
+		}
+	}
+	return c.sessionToken, nil
+}
+
+// doRequest sends a single request with a session token and any extra headers
+func (c *OpenBMCClient) doRequest(ctx context.Context, token, method, endpoint string, jsonBody []byte, header http.Header) (*http.Response, error) {
+	var bodyReader io.Reader
+	if jsonBody != nil {
+		bodyReader = bytes.NewReader(jsonBody)
+	}
+
+	url := c.baseURL + endpoint
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("X-Auth-Token", token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	if verbose {
+		fmt.Printf("Making %s request to %s\n", method, url)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+
+	return resp, nil
+}
+
+// Close deletes the Redfish session so it does not count against the BMC's session limit
+func (c *OpenBMCClient) Close() error {
+	c.mu.Lock()
+	token, location := c.sessionToken, c.sessionLocation
+	c.sessionToken = ""
+	c.sessionLocation = ""
+	c.mu.Unlock()
+	if token == "" || location == "" {
+		return nil
+	}
+
+	endpoint := location
+	if u, err := url.Parse(location); err == nil && u.IsAbs() {
+		endpoint = u.Path
+	}
+
+	resp, err := c.doRequest(context.Background(), token, "DELETE", endpoint, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// checkOpenBMCResponse turns a failed OpenBMC response into an error
+func checkOpenBMCResponse(resp *http.Response, operation string) error {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return nil
+	}
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("%s failed with status %d: %s", operation, resp.StatusCode, string(bodyBytes))
+}
+
+// getJSON retrieves a resource and decodes it into out
+func (c *OpenBMCClient) getJSON(ctx context.Context, endpoint string, out interface{}) error {
+	resp, err := c.makeRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return nil
+}
+
+// GetSystemInfo retrieves basic system information
+func (c *OpenBMCClient) GetSystemInfo(ctx context.Context) (*SystemInfo, error) {
+	var systemInfo SystemInfo
+	if err := c.getJSON(ctx, "/redfish/v1/Systems/system", &systemInfo); err != nil {
+		return nil, err
+	}
+	return &systemInfo, nil
+}
+
+// SetPowerState changes the server power state
+func (c *OpenBMCClient) SetPowerState(ctx context.Context, state PowerState) error {
+	resetType, err := redfishResetType(ctx, c, "/redfish/v1/Systems/system", state)
+	if err != nil {
+		return err
+	}
+	powerRequest := PowerRequest{
+		ResetType: resetType,
+	}
+
+	resp, err := c.makeRequest(ctx, "POST", "/redfish/v1/Systems/system/Actions/ComputerSystem.Reset", powerRequest)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkOpenBMCResponse(resp, "power operation")
+}
+
+// getVirtualMediaSlots lists the virtual media slots of the BMC
+func (c *OpenBMCClient) getVirtualMediaSlots(ctx context.Context) ([]openBMCVirtualMedia, error) {
+	var result struct {
+		Members []struct {
+			OdataID string `json:"@odata.id"`
+		} `json:"Members"`
+	}
+	if err := c.getJSON(ctx, "/redfish/v1/Managers/bmc/VirtualMedia", &result); err != nil {
+		return nil, err
+	}
+
+	var slots []openBMCVirtualMedia
+	for _, member := range result.Members {
+		var slot openBMCVirtualMedia
+		if err := c.getJSON(ctx, member.OdataID, &slot); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue // Skip this media slot if we can't get info
+		}
+		if slot.OdataID == "" {
+			slot.OdataID = member.OdataID
+		}
+		slots = append(slots, slot)
+	}
+
+	return slots, nil
+}
+
+// GetVirtualMedia lists available virtual media slots
+func (c *OpenBMCClient) GetVirtualMedia(ctx context.Context) ([]VirtualMediaInfo, error) {
+	slots, err := c.getVirtualMediaSlots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var virtualMediaList []VirtualMediaInfo
+	for _, slot := range slots {
+		virtualMediaList = append(virtualMediaList, slot.VirtualMediaInfo)
+	}
+	return virtualMediaList, nil
+}
+
+// MountVirtualMedia mounts an image to the first free legacy-mode slot. Proxy-mode slots have no
+// InsertMedia action because they are fed over NBD by a browser session, so they are skipped.
+func (c *OpenBMCClient) MountVirtualMedia(ctx context.Context, imageURL string) error {
+	slots, err := c.getVirtualMediaSlots(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting virtual media info: %w", err)
+	}
+
+	var target *openBMCVirtualMedia
+	for i := range slots {
+		if slots[i].Actions.InsertMedia.Target != "" && !slots[i].Inserted {
+			target = &slots[i]
+			break
+		}
+	}
+
+	if target == nil {
+		return fmt.Errorf("no free legacy-mode virtual media slot found (proxy-mode slots cannot mount URLs)")
+	}
+
+	mountRequest := map[string]interface{}{
+		"Image":          imageURL,
+		"Inserted":       true,
+		"WriteProtected": true,
+	}
+	if protocol := openBMCTransferProtocol(imageURL); protocol != "" {
+		mountRequest["TransferProtocolType"] = protocol
+	}
+
+	resp, err := c.makeRequest(ctx, "POST", target.Actions.InsertMedia.Target, mountRequest)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkOpenBMCResponse(resp, "virtual media mount")
+}
+
+// openBMCTransferProtocol maps an image URL scheme to the Redfish TransferProtocolType
+func openBMCTransferProtocol(imageURL string) string {
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return ""
+	}
+
+	switch u.Scheme {
+	case "https":
+		return "HTTPS"
+	case "http":
+		return "HTTP"
+	case "smb", "cifs":
+		return "CIFS"
+	case "nfs":
+		return "NFS"
+	}
+	return ""
+}
+
+// UnmountVirtualMedia unmounts virtual media from all legacy-mode slots
+func (c *OpenBMCClient) UnmountVirtualMedia(ctx context.Context) error {
+	slots, err := c.getVirtualMediaSlots(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting virtual media info: %w", err)
+	}
+
+	for _, slot := range slots {
+		if !slot.Inserted || slot.Actions.EjectMedia.Target == "" {
+			continue
+		}
+
+		resp, err := c.makeRequest(ctx, "POST", slot.Actions.EjectMedia.Target, map[string]interface{}{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue // Continue with other slots
+		}
+		resp.Body.Close()
+	}
+
+	return nil
+}
+
+// SetBootOverride sets the boot source override for the next boot (or every boot when persistent)
+func (c *OpenBMCClient) SetBootOverride(ctx context.Context, target BootTarget, persistent bool) error {
+	resp, err := c.makeRequest(ctx, "PATCH", "/redfish/v1/Systems/system", newBootOverrideRequest(target, persistent))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkOpenBMCResponse(resp, "boot override")
+}
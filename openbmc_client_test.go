@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// newOpenBMCTestServer wraps a handler with the bmcweb session endpoints, rejecting any
+// request that does not carry the session token
+func newOpenBMCTestServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *int) {
+	logins := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/redfish/v1/SessionService/Sessions" && r.Method == "POST":
+			var credentials map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+				t.Errorf("Error decoding session request: %v", err)
+			}
+			if credentials["UserName"] != "root" || credentials["Password"] != "0penBmc" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			logins++
+			w.Header().Set("X-Auth-Token", "token123")
+			w.Header().Set("Location", "/redfish/v1/SessionService/Sessions/abc")
+			w.WriteHeader(http.StatusCreated)
+		case r.Header.Get("X-Auth-Token") != "token123":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/redfish/v1/SessionService/Sessions/abc" && r.Method == "DELETE":
+			w.WriteHeader(http.StatusOK)
+		default:
+			if _, _, ok := r.BasicAuth(); ok {
+				t.Error("Expected no basic auth credentials on session requests")
+			}
+			handler(w, r)
+		}
+	}))
+	return server, &logins
+}
+
+func TestOpenBMCClient_GetSystemInfo(t *testing.T) {
+	server, logins := newOpenBMCTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/redfish/v1/Systems/system" {
+			t.Errorf("Expected path '/redfish/v1/Systems/system', got: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"PowerState":"On","Status":{"Health":"OK","State":"Enabled"}}`))
+	})
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &OpenBMCClient{
+		baseURL:    server.URL,
+		username:   "root",
+		password:   "0penBmc",
+		httpClient: server.Client(),
+	}
+
+	systemInfo, err := client.GetSystemInfo(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if systemInfo.PowerState != "On" {
+		t.Errorf("Expected PowerState 'On', got: %s", systemInfo.PowerState)
+	}
+
+	// The session is reused for subsequent requests
+	if _, err := client.GetSystemInfo(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if *logins != 1 {
+		t.Errorf("Expected 1 login, got: %d", *logins)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Errorf("Expected no error closing session, got: %v", err)
+	}
+}
+
+func TestOpenBMCClient_ExpiredSession(t *testing.T) {
+	server, logins := newOpenBMCTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	defer server.Close()
+
+	// Create client holding a stale session token
+	client := &OpenBMCClient{
+		baseURL:      server.URL,
+		username:     "root",
+		password:     "0penBmc",
+		httpClient:   server.Client(),
+		sessionToken: "expired",
+	}
+
+	if err := client.SetPowerState(context.Background(), PowerStateOn); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if *logins != 1 {
+		t.Errorf("Expected client to log in again after 401, got %d logins", *logins)
+	}
+}
+
+func TestOpenBMCClient_ConcurrentExpiredSession(t *testing.T) {
+	var logins atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/redfish/v1/SessionService/Sessions" && r.Method == "POST":
+			logins.Add(1)
+			w.Header().Set("X-Auth-Token", "token123")
+			w.Header().Set("Location", "/redfish/v1/SessionService/Sessions/abc")
+			w.WriteHeader(http.StatusCreated)
+		case r.Header.Get("X-Auth-Token") != "token123":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	client := &OpenBMCClient{
+		baseURL:      server.URL,
+		username:     "root",
+		password:     "0penBmc",
+		httpClient:   server.Client(),
+		sessionToken: "expired",
+	}
+
+	// Workers sharing the client find the session expired together and log in once
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.RedfishRequest(context.Background(), "GET", "/redfish/v1/Systems", nil, nil)
+			if err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected 200, got %d", resp.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := logins.Load(); n != 1 {
+		t.Errorf("Expected one login, got %d", n)
+	}
+}
+
+func TestOpenBMCClient_MountVirtualMedia(t *testing.T) {
+	var mountRequest map[string]interface{}
+
+	server, _ := newOpenBMCTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/redfish/v1/Managers/bmc/VirtualMedia":
+			_, _ = w.Write([]byte(`{"Members":[{"@odata.id":"/redfish/v1/Managers/bmc/VirtualMedia/Slot_0"},{"@odata.id":"/redfish/v1/Managers/bmc/VirtualMedia/Slot_4"}]}`))
+		case "/redfish/v1/Managers/bmc/VirtualMedia/Slot_0":
+			// Proxy mode slot, no InsertMedia action
+			_, _ = w.Write([]byte(`{"Name":"Slot_0","MediaTypes":["CD","USBStick"],"Inserted":false}`))
+		case "/redfish/v1/Managers/bmc/VirtualMedia/Slot_4":
+			_, _ = w.Write([]byte(`{"Name":"Slot_4","MediaTypes":["CD","USBStick"],"Inserted":false,
+				"Actions":{"#VirtualMedia.InsertMedia":{"target":"/redfish/v1/Managers/bmc/VirtualMedia/Slot_4/Actions/VirtualMedia.InsertMedia"}}}`))
+		case "/redfish/v1/Managers/bmc/VirtualMedia/Slot_4/Actions/VirtualMedia.InsertMedia":
+			if err := json.NewDecoder(r.Body).Decode(&mountRequest); err != nil {
+				t.Errorf("Error decoding request body: %v", err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &OpenBMCClient{
+		baseURL:    server.URL,
+		username:   "root",
+		password:   "0penBmc",
+		httpClient: server.Client(),
+	}
+
+	if err := client.MountVirtualMedia(context.Background(), "https://example.com/image.iso"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if mountRequest["Image"] != "https://example.com/image.iso" {
+		t.Errorf("Expected image URL 'https://example.com/image.iso', got: %v", mountRequest["Image"])
+	}
+	if mountRequest["TransferProtocolType"] != "HTTPS" {
+		t.Errorf("Expected TransferProtocolType 'HTTPS', got: %v", mountRequest["TransferProtocolType"])
+	}
+}
+
+func TestOpenBMCClient_LoginFailure(t *testing.T) {
+	server, _ := newOpenBMCTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request without session: %s", r.URL.Path)
+	})
+	defer server.Close()
+
+	// Create client with wrong credentials
+	client := &OpenBMCClient{
+		baseURL:    server.URL,
+		username:   "root",
+		password:   "wrong",
+		httpClient: server.Client(),
+	}
+
+	if _, err := client.GetSystemInfo(context.Background()); err == nil {
+		t.Error("Expected error for failed login, got nil")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats for commands that print structured data
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// outputFlag is the --output format; empty uses the context default, then text
+var outputFlag string
+
+func validateOutputFormat(format string) error {
+	switch format {
+	case outputText, outputJSON, outputYAML:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q (use text, json or yaml)", format)
+}
+
+// outputFormat returns the format selected by --output or the active context
+func outputFormat() string {
+	if outputFlag != "" {
+		return outputFlag
+	}
+	if ctx, ok := config.activeContext(); ok && ctx.Output != "" {
+		return ctx.Output
+	}
+	return outputText
+}
+
+// printStructured prints v as JSON or YAML when that output format is selected, and
+// reports whether it did; for text output the caller prints its own layout
+func printStructured(v interface{}) (bool, error) {
+	switch outputFormat() {
+	case outputJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return true, encoder.Encode(v)
+	case outputYAML:
+		return true, printYAML(v)
+	}
+	return false, nil
+}
+
+// printYAML prints v as YAML with the two-space indentation used in config files
+func printYAML(v interface{}) error {
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// progressf prints a progress message for text output only, keeping JSON and YAML output
+// parseable
+func progressf(format string, args ...interface{}) {
+	if outputFormat() == outputText {
+		fmt.Printf(format, args...)
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Plan actions
+const (
+	planCreate = "create"
+	planUpdate = "update"
+	planDelete = "delete"
+)
+
+// planChange is one change 'apply' makes to reach the manifest's state
+type planChange struct {
+	Resource string `json:"resource" yaml:"resource"`
+	Action   string `json:"action" yaml:"action"`
+	From     string `json:"from,omitempty" yaml:"from,omitempty"`
+	To       string `json:"to,omitempty" yaml:"to,omitempty"`
+
+	apply func(ctx context.Context) error
+}
+
+// serverPlan is the ordered list of changes for one server
+type serverPlan struct {
+	Changes []planChange `json:"changes" yaml:"changes"`
+	// Notes explain changes that will only take effect later, or that could not be planned
+	Notes []string `json:"notes,omitempty" yaml:"notes,omitempty"`
+}
+
+// planner compares a manifest with the current state of a server
+type planner struct {
+	client   BMCClient
+	settings *redfishSettings
+	plan     serverPlan
+}
+
+// planServer computes the changes needed to bring the server to the manifest's state. The
+// changes are ordered so that apply is safe: BMC settings and accounts first, then media is
+// mounted before the boot override pointing at it, BIOS settings are staged before the
+// power change or restart that applies them.
+func planServer(ctx context.Context, client BMCClient, bmcType BMCType, manifest *ServerManifest) (*serverPlan, error) {
+	p := &planner{client: client}
+	if manifest.managesRedfishSettings() {
+		requester, ok := client.(RedfishRequester)
+		if !ok {
+			return nil, fmt.Errorf("BIOS, NTP, DNS and user settings are not supported for BMC type %s", bmcType)
+		}
+		p.settings = &redfishSettings{requester: requester, vendor: bmcType}
+	}
+
+	system, err := client.GetSystemInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get system info: %w", err)
+	}
+
+	steps := []struct {
+		name string
+		plan func(ctx context.Context) error
+	}{
+		{"users", func(ctx context.Context) error { return p.planUsers(ctx, manifest.Users) }},
+		{"ntp", func(ctx context.Context) error { return p.planNTP(ctx, manifest.NTP) }},
+		{"dns", func(ctx context.Context) error { return p.planDNS(ctx, manifest.DNS) }},
+		{"virtual media", func(ctx context.Context) error { return p.planVirtualMedia(ctx, manifest.VirtualMedia) }},
+		{"BIOS", func(ctx context.Context) error { return p.planBIOS(ctx, manifest.BIOS) }},
+		{"boot override", func(ctx context.Context) error { p.planBoot(system.Boot, manifest.Boot); return nil }},
+	}
+	for _, step := range steps {
+		if err := step.plan(ctx); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", step.name, err)
+		}
+	}
+	p.planPower(system.PowerState, manifest)
+	return &p.plan, nil
+}
+
+func (p *planner) add(change planChange) {
+	p.plan.Changes = append(p.plan.Changes, change)
+}
+
+func (p *planner) planUsers(ctx context.Context, users []UserManifest) error {
+	if len(users) == 0 {
+		return nil
+	}
+	accounts, err := p.settings.accounts(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		var existing *redfishAccount
+		for i := range accounts {
+			if accounts[i].UserName == user.Username {
+				existing = &accounts[i]
+				break
+			}
+		}
+		resource := "user." + user.Username
+
+		switch {
+		case user.Absent:
+			if existing == nil {
+				continue
+			}
+			account := *existing
+			p.add(planChange{Resource: resource, Action: planDelete, From: account.RoleID, apply: func(ctx context.Context) error {
+				return p.settings.deleteAccount(ctx, account)
+			}})
+
+		case existing == nil:
+			if user.Role == "" {
+				return fmt.Errorf("user %s does not exist and has no role to create it with", user.Username)
+			}
+			if user.Password == "" && user.PasswordSource == (PasswordSource{}) {
+				return fmt.Errorf("user %s does not exist and has no password to create it with", user.Username)
+			}
+			enabled := user.Enabled == nil || *user.Enabled
+			p.add(planChange{Resource: resource, Action: planCreate, To: describeAccount(user.Role, enabled), apply: func(ctx context.Context) error {
+				password, err := resolvePassword(user.Username, user.Password, user.PasswordSource)
+				if err != nil {
+					return err
+				}
+				return p.settings.createAccount(ctx, user.Username, password, user.Role, enabled)
+			}})
+
+		default:
+			account := *existing
+			changes := map[string]interface{}{}
+			role, enabled := account.RoleID, account.Enabled
+			if user.Role != "" && user.Role != account.RoleID {
+				changes["RoleId"] = user.Role
+				role = user.Role
+			}
+			if user.Enabled != nil && *user.Enabled != account.Enabled {
+				changes["Enabled"] = *user.Enabled
+				enabled = *user.Enabled
+			}
+			if len(changes) == 0 {
+				continue
+			}
+			p.add(planChange{Resource: resource, Action: planUpdate, From: describeAccount(account.RoleID, account.Enabled), To: describeAccount(role, enabled), apply: func(ctx context.Context) error {
+				return p.settings.updateAccount(ctx, account, changes)
+			}})
+		}
+	}
+	return nil
+}
+
+func describeAccount(role string, enabled bool) string {
+	if enabled {
+		return role
+	}
+	return role + " (disabled)"
+}
+
+func (p *planner) planNTP(ctx context.Context, ntp *NTPManifest) error {
+	if ntp == nil {
+		return nil
+	}
+	enabled, servers, err := p.settings.ntp(ctx)
+	if err != nil {
+		return err
+	}
+
+	var setEnabled *bool
+	if ntp.Enabled != nil && *ntp.Enabled != enabled {
+		setEnabled = ntp.Enabled
+		p.add(planChange{Resource: "ntp.enabled", Action: planUpdate, From: fmt.Sprint(enabled), To: fmt.Sprint(*ntp.Enabled)})
+	}
+	var setServers []string
+	if ntp.Servers != nil && !equalStrings(ntp.Servers, servers) {
+		setServers = ntp.Servers
+		p.add(planChange{Resource: "ntp.servers", Action: planUpdate, From: strings.Join(servers, ", "), To: strings.Join(ntp.Servers, ", ")})
+	}
+	if setEnabled == nil && setServers == nil {
+		return nil
+	}
+	// Both properties are changed with one request, made by the last change
+	p.plan.Changes[len(p.plan.Changes)-1].apply = func(ctx context.Context) error {
+		return p.settings.setNTP(ctx, setEnabled, setServers)
+	}
+	return nil
+}
+
+func (p *planner) planDNS(ctx context.Context, dns *DNSManifest) error {
+	if dns == nil {
+		return nil
+	}
+	servers, err := p.settings.dnsServers(ctx)
+	if err != nil {
+		return err
+	}
+	if equalStrings(dns.Servers, servers) {
+		return nil
+	}
+	p.add(planChange{Resource: "dns.servers", Action: planUpdate, From: strings.Join(servers, ", "), To: strings.Join(dns.Servers, ", "), apply: func(ctx context.Context) error {
+		return p.settings.setDNSServers(ctx, dns.Servers)
+	}})
+	return nil
+}
+
+func (p *planner) planVirtualMedia(ctx context.Context, media *VirtualMediaManifest) error {
+	if media == nil {
+		return nil
+	}
+	slots, err := p.client.GetVirtualMedia(ctx)
+	if err != nil {
+		return err
+	}
+	current := ""
+	for _, slot := range slots {
+		if slot.Inserted && slot.Image != "" {
+			current = slot.Image
+			break
+		}
+	}
+
+	switch {
+	case media.Image == current:
+	case media.Image == "":
+		p.add(planChange{Resource: "virtual_media", Action: planDelete, From: current, apply: p.client.UnmountVirtualMedia})
+	case current == "":
+		p.add(planChange{Resource: "virtual_media", Action: planCreate, To: media.Image, apply: func(ctx context.Context) error {
+			return p.client.MountVirtualMedia(ctx, media.Image)
+		}})
+	default:
+		p.add(planChange{Resource: "virtual_media", Action: planUpdate, From: current, To: media.Image, apply: func(ctx context.Context) error {
+			if err := p.client.UnmountVirtualMedia(ctx); err != nil {
+				return err
+			}
+			return p.client.MountVirtualMedia(ctx, media.Image)
+		}})
+	}
+	return nil
+}
+
+func (p *planner) planBIOS(ctx context.Context, desired map[string]interface{}) error {
+	if len(desired) == 0 {
+		return nil
+	}
+	current, pending, err := p.settings.biosAttributes(ctx)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changes := map[string]interface{}{}
+	for _, name := range names {
+		value, ok := current[name]
+		if !ok {
+			return fmt.Errorf("unknown BIOS attribute %s", name)
+		}
+		if sameValue(value, desired[name]) {
+			continue
+		}
+		// Already staged by an earlier apply, waiting for a restart
+		if staged, ok := pending[name]; ok && sameValue(staged, desired[name]) {
+			p.plan.Notes = append(p.plan.Notes, fmt.Sprintf("BIOS attribute %s is already staged as %v and applies at the next restart", name, desired[name]))
+			continue
+		}
+		changes[name] = desired[name]
+		p.add(planChange{Resource: "bios." + name, Action: planUpdate, From: fmt.Sprint(value), To: fmt.Sprint(desired[name])})
+	}
+	if len(changes) > 0 {
+		p.plan.Changes[len(p.plan.Changes)-1].apply = func(ctx context.Context) error {
+			return p.settings.setBIOSAttributes(ctx, changes)
+		}
+	}
+	return nil
+}
+
+func (p *planner) planBoot(current BootOverride, boot *BootManifest) {
+	if boot == nil {
+		return
+	}
+	target := bootTargets[strings.ToLower(boot.Target)]
+	want := newBootOverrideRequest(target, boot.Persistent).Boot
+	if describeBoot(current) == "unknown" {
+		// Without the current state the override would be set again on every apply
+		p.plan.Notes = append(p.plan.Notes, fmt.Sprintf("The BMC does not report its boot override, so %s is not planned (use the boot set command)", describeBoot(want)))
+		return
+	}
+	if current.BootSourceOverrideEnabled == want.BootSourceOverrideEnabled &&
+		(target == BootTargetNone || current.BootSourceOverrideTarget == want.BootSourceOverrideTarget) {
+		return
+	}
+	p.add(planChange{Resource: "boot", Action: planUpdate, From: describeBoot(current), To: describeBoot(want), apply: func(ctx context.Context) error {
+		return p.client.SetBootOverride(ctx, target, boot.Persistent)
+	}})
+}
+
+func describeBoot(boot BootOverride) string {
+	switch boot.BootSourceOverrideEnabled {
+	case "":
+		return "unknown"
+	case "Disabled":
+		return "None"
+	}
+	return fmt.Sprintf("%s (%s)", boot.BootSourceOverrideTarget, strings.ToLower(boot.BootSourceOverrideEnabled))
+}
+
+// planPower adds the power change, or the restart that applies staged BIOS settings and a
+// boot override to a server that stays on
+func (p *planner) planPower(state string, manifest *ServerManifest) {
+	on := state == "On" || state == "PoweringOn"
+	current := "Off"
+	if on {
+		current = "On"
+	}
+
+	switch {
+	case manifest.Power == "on" && !on:
+		p.add(planChange{Resource: "power", Action: planUpdate, From: current, To: "On", apply: func(ctx context.Context) error {
+			return p.client.SetPowerState(ctx, PowerStateOn)
+		}})
+		return
+	case manifest.Power == "off" && on:
+		p.add(planChange{Resource: "power", Action: planUpdate, From: current, To: "Off", apply: func(ctx context.Context) error {
+			return p.client.SetPowerState(ctx, PowerStateOff)
+		}})
+		return
+	}
+
+	needsRestart := false
+	for _, change := range p.plan.Changes {
+		if change.Resource == "boot" || strings.HasPrefix(change.Resource, "bios.") {
+			needsRestart = true
+		}
+	}
+	if !on || !needsRestart {
+		return
+	}
+	if !manifest.Reboot {
+		p.plan.Notes = append(p.plan.Notes, "BIOS settings and the boot override take effect at the next restart (set reboot: true to restart now)")
+		return
+	}
+	p.add(planChange{Resource: "power", Action: planUpdate, From: "On", To: "restart", apply: func(ctx context.Context) error {
+		return p.client.SetPowerState(ctx, PowerStateCycle)
+	}})
+}
+
+// sameValue compares a value read from the BMC (JSON) with one from the manifest (YAML),
+// which decode numbers to different types
+func sameValue(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b) || fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func equalStrings(a, b []string) bool {
+	return strings.Join(a, "\x00") == strings.Join(b, "\x00")
+}
+
+// applyPlan makes the planned changes in order, stopping at the first failure. Changes made
+// by a single request are reported together before it.
+func applyPlan(ctx context.Context, plan *serverPlan, report func(change planChange)) error {
+	for _, change := range plan.Changes {
+		report(change)
+		if change.apply == nil {
+			continue
+		}
+		if err := change.apply(ctx); err != nil {
+			return fmt.Errorf("%s: %w", change.Resource, err)
+		}
+	}
+	return nil
+}
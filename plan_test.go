@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	manifest, err := parseManifest([]byte("power: On\nboot:\n  target: PXE\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if manifest.Power != "on" || manifest.Boot.Target != "PXE" {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+
+	for _, invalid := range []string{
+		"power: standby\n",
+		"boot:\n  target: floppy\n",
+		"bootmode: uefi\n",
+		"users:\n  - username: ops\n    password: a\n    password_env: B\n",
+		"users:\n  - username: ops\n    absent: true\n    role: Operator\n",
+		"users:\n  - username: ops\n  - username: ops\n",
+	} {
+		if _, err := parseManifest([]byte(invalid)); err == nil {
+			t.Errorf("Expected error for manifest %q", invalid)
+		}
+	}
+}
+
+func planResources(plan *serverPlan) string {
+	var resources []string
+	for _, change := range plan.Changes {
+		resources = append(resources, change.Action+" "+change.Resource)
+	}
+	return strings.Join(resources, ", ")
+}
+
+func TestPlanAndApply(t *testing.T) {
+	t.Setenv("OPS_PASSWORD", "secret")
+	target := newCheckTarget(t, "password")
+	client, err := target.Config.newClient()
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(client)
+
+	manifest, err := parseManifest([]byte(`
+power: on
+reboot: true
+boot:
+  target: cd
+  persistent: true
+virtual_media:
+  image: http://10.0.0.5/ubuntu.iso
+bios:
+  LogicalProc: Disabled
+  BootMode: Uefi
+ntp:
+  enabled: true
+  servers: [10.0.0.1]
+dns:
+  servers: [10.0.0.53]
+users:
+  - username: ops
+    role: Operator
+    password_env: OPS_PASSWORD
+  - username: admin
+    role: Administrator
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+
+	ctx := context.Background()
+	plan, err := planServer(ctx, client, BMCTypeIDRAC, manifest)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	expected := "create user.ops, update ntp.enabled, update ntp.servers, update dns.servers, " +
+		"create virtual_media, update bios.LogicalProc, update boot, update power"
+	if got := planResources(plan); got != expected {
+		t.Errorf("Expected plan:\n%s\ngot:\n%s", expected, got)
+	}
+	if last := plan.Changes[len(plan.Changes)-1]; last.To != "restart" {
+		t.Errorf("Expected a restart to apply the BIOS settings, got: %+v", last)
+	}
+
+	if err := applyPlan(ctx, plan, func(planChange) {}); err != nil {
+		t.Fatalf("Failed to apply: %v", err)
+	}
+
+	// Applying again changes nothing; the iDRAC BIOS job is still running, so the attribute
+	// is reported as staged
+	plan, err = planServer(ctx, client, BMCTypeIDRAC, manifest)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if len(plan.Changes) != 0 {
+		t.Errorf("Expected no changes after apply, got: %s", planResources(plan))
+	}
+	if len(plan.Notes) != 1 || !strings.Contains(plan.Notes[0], "LogicalProc is already staged") {
+		t.Errorf("Expected a note about the staged attribute, got: %v", plan.Notes)
+	}
+
+	manifest, _ = parseManifest([]byte("power: off\nvirtual_media:\n  image: \"\"\nusers:\n  - username: ops\n    absent: true\n"))
+	plan, err = planServer(ctx, client, BMCTypeIDRAC, manifest)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if got := planResources(plan); got != "delete user.ops, delete virtual_media, update power" {
+		t.Errorf("Unexpected plan: %s", got)
+	}
+	if err := applyPlan(ctx, plan, func(planChange) {}); err != nil {
+		t.Fatalf("Failed to apply: %v", err)
+	}
+	accounts, err := (&redfishSettings{requester: client.(RedfishRequester), vendor: BMCTypeIDRAC}).accounts(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read accounts: %v", err)
+	}
+	for _, account := range accounts {
+		if account.UserName == "ops" {
+			t.Errorf("Expected the ops account slot to be cleared, got: %+v", account)
+		}
+	}
+}
+
+func TestPlan_UnknownBIOSAttribute(t *testing.T) {
+	target := newCheckTarget(t, "password")
+	client, err := target.Config.newClient()
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(client)
+
+	manifest, _ := parseManifest([]byte("bios:\n  NoSuchSetting: Enabled\n"))
+	if _, err := planServer(context.Background(), client, BMCTypeIDRAC, manifest); err == nil || !strings.Contains(err.Error(), "unknown BIOS attribute NoSuchSetting") {
+		t.Errorf("Expected unknown attribute error, got: %v", err)
+	}
+}
+
+func TestPlanBoot_UnknownState(t *testing.T) {
+	p := &planner{}
+	p.planBoot(BootOverride{BootSourceOverrideTarget: "Pxe"}, &BootManifest{Target: "pxe"})
+	if len(p.plan.Changes) != 0 {
+		t.Errorf("Expected no changes for an unknown boot override, got: %s", planResources(&p.plan))
+	}
+	if len(p.plan.Notes) != 1 || !strings.Contains(p.plan.Notes[0], "does not report its boot override") {
+		t.Errorf("Expected a note about the boot override, got: %v", p.plan.Notes)
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestConfigureProxy_HTTP(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	// A forward proxy receives the absolute URL of the BMC and relays the request
+	var mu sync.Mutex
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.String())
+		mu.Unlock()
+
+		r.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	defer proxy.Close()
+
+	transport := &http.Transport{}
+	if err := configureProxy(transport, ProxyConfig{URL: proxy.URL}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	resp, err := (&http.Client{Transport: transport}).Get(target.URL + "/redfish/v1")
+	if err != nil {
+		t.Fatalf("Expected request through proxy to succeed, got: %v", err)
+	}
+	resp.Body.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(proxied) != 1 || proxied[0] != target.URL+"/redfish/v1" {
+		t.Errorf("Expected request for %s/redfish/v1 through the proxy, got: %v", target.URL, proxied)
+	}
+}
+
+func TestConfigureProxy_Invalid(t *testing.T) {
+	if err := configureProxy(&http.Transport{}, ProxyConfig{URL: "ftp://proxy:21"}); err == nil {
+		t.Error("Expected error for unsupported proxy scheme")
+	}
+	if err := configureProxy(&http.Transport{}, ProxyConfig{URL: "socks5://proxy:1080", Jump: "ops@bastion"}); err == nil {
+		t.Error("Expected error combining a proxy with a jump host")
+	}
+	if err := configureProxy(&http.Transport{}, ProxyConfig{URL: "socks5://proxy:1080"}); err != nil {
+		t.Errorf("Expected SOCKS5 proxy to be accepted, got: %v", err)
+	}
+}
+
+func TestParseJumpHost(t *testing.T) {
+	username, address, err := parseJumpHost("ops@bastion.example.com")
+	if err != nil || username != "ops" || address != "bastion.example.com:22" {
+		t.Errorf("Unexpected result %q %q: %v", username, address, err)
+	}
+	username, address, err = parseJumpHost("ops@10.0.0.1:2222")
+	if err != nil || username != "ops" || address != "10.0.0.1:2222" {
+		t.Errorf("Unexpected result %q %q: %v", username, address, err)
+	}
+	if _, _, err := parseJumpHost("ops@"); err == nil {
+		t.Error("Expected error for jump host without a host")
+	}
+}
+
+// startTestSSHServer runs an SSH server that accepts clientKey and forwards direct-tcpip
+// channels, returning its address and the known_hosts file trusting it
+func startTestSSHServer(t *testing.T, clientKey ssh.PublicKey) (string, string) {
+	_, hostPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate host key: %v", err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPrivate)
+	if err != nil {
+		t.Fatalf("Failed to create host signer: %v", err)
+	}
+
+	serverConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "ops" && string(key.Marshal()) == string(clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	serverConfig.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSSHConn(conn, serverConfig)
+		}
+	}()
+
+	knownHostsPath := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{listener.Addr().String()}, hostSigner.PublicKey())
+	if err := os.WriteFile(knownHostsPath, []byte(line+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write known hosts: %v", err)
+	}
+	return listener.Addr().String(), knownHostsPath
+}
+
+func serveTestSSHConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		var payload struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+		if err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			target.Close()
+			continue
+		}
+		go ssh.DiscardRequests(channelRequests)
+		go func() {
+			defer channel.Close()
+			defer target.Close()
+			go func() { _, _ = io.Copy(target, channel) }()
+			_, _ = io.Copy(channel, target)
+		}()
+	}
+}
+
+// newTestJumpSettings starts a bastion and returns the proxy settings that reach it with a
+// fresh identity file
+func newTestJumpSettings(t *testing.T) ProxyConfig {
+	_, clientPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate client key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(clientPrivate, "")
+	if err != nil {
+		t.Fatalf("Failed to marshal client key: %v", err)
+	}
+	identityFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(identityFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("Failed to write client key: %v", err)
+	}
+	clientSigner, err := ssh.NewSignerFromKey(clientPrivate)
+	if err != nil {
+		t.Fatalf("Failed to create client signer: %v", err)
+	}
+
+	bastion, knownHostsPath := startTestSSHServer(t, clientSigner.PublicKey())
+	return ProxyConfig{Jump: "ops@" + bastion, IdentityFile: identityFile, KnownHostsFile: knownHostsPath}
+}
+
+func TestConfigureProxy_SSHJump(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"PowerState":"On"}`))
+	}))
+	defer target.Close()
+
+	transport := &http.Transport{}
+	settings := newTestJumpSettings(t)
+	if err := configureProxy(transport, settings); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer transport.CloseIdleConnections()
+
+	resp, err := (&http.Client{Transport: transport}).Get(target.URL + "/redfish/v1/Systems/1")
+	if err != nil {
+		t.Fatalf("Expected request through jump host to succeed, got: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"PowerState":"On"}` {
+		t.Errorf("Unexpected response: %s", body)
+	}
+
+	// A bastion whose host key is not trusted is refused
+	otherKnownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(otherKnownHosts, nil, 0600); err != nil {
+		t.Fatalf("Failed to write known hosts: %v", err)
+	}
+	untrusted := &http.Transport{}
+	settings.KnownHostsFile = otherKnownHosts
+	if err := configureProxy(untrusted, settings); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := (&http.Client{Transport: untrusted}).Get(target.URL); err == nil {
+		t.Error("Expected error for an unknown bastion host key")
+	}
+}
+
+func TestSSHJump_UnreachableTargetKeepsTunnel(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer target.Close()
+	go func() {
+		for {
+			conn, err := target.Accept()
+			if err != nil {
+				return
+			}
+			go func() { _, _ = io.Copy(conn, conn) }()
+		}
+	}()
+	refused, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	refused.Close()
+
+	jump, err := newSSHJump(newTestJumpSettings(t))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	ctx := context.Background()
+	conn, err := jump.DialContext(ctx, "tcp", target.Addr().String())
+	if err != nil {
+		t.Fatalf("Expected a tunnel to the target, got: %v", err)
+	}
+	defer conn.Close()
+	client := jump.client
+
+	// Another BMC refusing the connection leaves the shared tunnel and its connections open
+	if _, err := jump.DialContext(ctx, "tcp", refused.Addr().String()); err == nil {
+		t.Fatal("Expected error for a refused port")
+	}
+	if jump.client != client {
+		t.Error("Expected the bastion connection to be kept")
+	}
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("Expected the open tunnel to keep working, got: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("Expected the echo through the open tunnel, got %q (%v)", buf, err)
+	}
+
+	// A dead bastion connection is replaced on the next dial
+	client.Close()
+	if _, err := jump.DialContext(ctx, "tcp", target.Addr().String()); err == nil {
+		t.Error("Expected error through the closed bastion connection")
+	}
+	conn2, err := jump.DialContext(ctx, "tcp", target.Addr().String())
+	if err != nil {
+		t.Fatalf("Expected a new bastion connection, got: %v", err)
+	}
+	conn2.Close()
+}
+
+func TestSSHJump_AgentConnectionClosed(t *testing.T) {
+	_, clientPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate client key: %v", err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: clientPrivate}); err != nil {
+		t.Fatalf("Failed to add key to agent: %v", err)
+	}
+	clientSigner, err := ssh.NewSignerFromKey(clientPrivate)
+	if err != nil {
+		t.Fatalf("Failed to create client signer: %v", err)
+	}
+
+	// The agent reports when bmc-cli hangs up
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	served := make(chan struct{}, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_ = agent.ServeAgent(keyring, conn)
+		served <- struct{}{}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+	t.Setenv("HOME", t.TempDir())
+
+	bastion, knownHostsPath := startTestSSHServer(t, clientSigner.PublicKey())
+	jump, err := newSSHJump(ProxyConfig{Jump: "ops@" + bastion, KnownHostsFile: knownHostsPath})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := jump.connect(context.Background()); err != nil {
+		t.Fatalf("Expected to authenticate with the agent's key, got: %v", err)
+	}
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the agent connection to be closed after authenticating")
+	}
+}
@@ -0,0 +1,707 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// recipeRetryDelay is the pause between attempts of a step without retry_delay
+	recipeRetryDelay = 10 * time.Second
+	// recipeWaitTimeout bounds a wait step without a timeout
+	recipeWaitTimeout = 10 * time.Minute
+	// recipeWaitInterval is how often a wait step polls the power state
+	recipeWaitInterval = 5 * time.Second
+)
+
+// Progress of a host through a recipe, as recorded in the state file
+const (
+	recipeRunning   = "running"
+	recipeCompleted = "completed"
+	recipeFailed    = "failed"
+)
+
+// Recipe is an ordered list of provisioning steps for 'run'
+type Recipe struct {
+	Name string `yaml:"name"`
+	// Vars are available to templates as .Vars; their values are templates themselves,
+	// rendered per host
+	Vars  map[string]string `yaml:"vars"`
+	Steps []RecipeStep      `yaml:"steps"`
+}
+
+// RecipeStep is one step of a recipe: exactly one action, and how to run it
+type RecipeStep struct {
+	Name string `yaml:"name"`
+	// When is a template; the step is skipped when it renders to "", false, no or 0
+	When string `yaml:"when"`
+	// Timeout bounds each attempt of the step
+	Timeout    time.Duration `yaml:"timeout"`
+	Retries    int           `yaml:"retries"`
+	RetryDelay time.Duration `yaml:"retry_delay"`
+
+	Power   string                 `yaml:"power"`
+	Wait    *WaitStep              `yaml:"wait"`
+	Mount   string                 `yaml:"mount"`
+	Unmount bool                   `yaml:"unmount"`
+	Boot    *BootManifest          `yaml:"boot"`
+	BIOS    map[string]interface{} `yaml:"bios"`
+	Sleep   time.Duration          `yaml:"sleep"`
+	HTTP    *HTTPStep              `yaml:"http"`
+	Shell   string                 `yaml:"shell"`
+}
+
+// WaitStep waits until the server reaches a power state
+type WaitStep struct {
+	Power    string        `yaml:"power"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// HTTPStep checks that a URL answers with the expected status and, optionally, body text
+type HTTPStep struct {
+	URL      string `yaml:"url"`
+	Method   string `yaml:"method"`
+	Status   int    `yaml:"status"`
+	Contains string `yaml:"contains"`
+	Insecure bool   `yaml:"insecure"`
+}
+
+// recipePowerStates maps the power actions of a recipe to power states
+var recipePowerStates = map[string]PowerState{
+	"on":    PowerStateOn,
+	"off":   PowerStateOff,
+	"cycle": PowerStateCycle,
+}
+
+// loadRecipe reads and validates a recipe file
+func loadRecipe(path string) (*Recipe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading recipe: %w", err)
+	}
+	recipe, err := parseRecipe(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if recipe.Name == "" {
+		recipe.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return recipe, nil
+}
+
+// parseRecipe decodes a recipe, rejecting unknown keys, and validates its steps
+func parseRecipe(data []byte) (*Recipe, error) {
+	var recipe Recipe
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&recipe); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing recipe: %w", err)
+	}
+	if len(recipe.Steps) == 0 {
+		return nil, fmt.Errorf("recipe has no steps")
+	}
+
+	for i := range recipe.Steps {
+		step := &recipe.Steps[i]
+		if actions := step.actions(); len(actions) != 1 {
+			return nil, fmt.Errorf("steps[%d]: a step takes exactly one of power, wait, mount, unmount, boot, bios, sleep, http and shell (got %d)", i, len(actions))
+		}
+		if step.Retries < 0 {
+			return nil, fmt.Errorf("steps[%d]: retries must not be negative", i)
+		}
+
+		step.Power = strings.ToLower(step.Power)
+		if _, ok := recipePowerStates[step.Power]; step.Power != "" && !ok {
+			return nil, fmt.Errorf("steps[%d]: unsupported power action %q (use on, off or cycle)", i, step.Power)
+		}
+		if step.Wait != nil {
+			step.Wait.Power = strings.ToLower(step.Wait.Power)
+			if step.Wait.Power != "on" && step.Wait.Power != "off" {
+				return nil, fmt.Errorf("steps[%d]: wait needs a power state of on or off", i)
+			}
+		}
+		if step.HTTP != nil && step.HTTP.URL == "" {
+			return nil, fmt.Errorf("steps[%d]: http needs a url", i)
+		}
+	}
+	return &recipe, nil
+}
+
+// actions returns the names of the actions set on the step
+func (s *RecipeStep) actions() []string {
+	var actions []string
+	for _, action := range []struct {
+		name string
+		set  bool
+	}{
+		{"power", s.Power != ""},
+		{"wait", s.Wait != nil},
+		{"mount", s.Mount != ""},
+		{"unmount", s.Unmount},
+		{"boot", s.Boot != nil},
+		{"bios", len(s.BIOS) > 0},
+		{"sleep", s.Sleep > 0},
+		{"http", s.HTTP != nil},
+		{"shell", s.Shell != ""},
+	} {
+		if action.set {
+			actions = append(actions, action.name)
+		}
+	}
+	return actions
+}
+
+// usesBMC reports whether the step talks to the BMC
+func (s *RecipeStep) usesBMC() bool {
+	return s.Power != "" || s.Wait != nil || s.Mount != "" || s.Unmount || s.Boot != nil || len(s.BIOS) > 0
+}
+
+// describe summarizes what a rendered step does
+func (s *RecipeStep) describe() string {
+	var action string
+	switch {
+	case s.Power != "":
+		action = "power " + s.Power
+	case s.Wait != nil:
+		action = "wait for power " + s.Wait.Power
+	case s.Mount != "":
+		action = "mount " + s.Mount
+	case s.Unmount:
+		action = "unmount"
+	case s.Boot != nil:
+		action = "boot " + strings.ToLower(s.Boot.Target)
+		if s.Boot.Persistent {
+			action += " (persistent)"
+		}
+	case len(s.BIOS) > 0:
+		settings := make([]string, 0, len(s.BIOS))
+		for name, value := range s.BIOS {
+			settings = append(settings, fmt.Sprintf("%s=%v", name, value))
+		}
+		sort.Strings(settings)
+		action = "bios " + strings.Join(settings, " ")
+	case s.Sleep > 0:
+		action = "sleep " + s.Sleep.String()
+	case s.HTTP != nil:
+		action = "http " + s.HTTP.URL
+	default:
+		action = "shell " + s.Shell
+	}
+	if s.Name != "" {
+		return fmt.Sprintf("%s (%s)", s.Name, action)
+	}
+	return action
+}
+
+// checksum identifies the recipe's content, so a state file is only resumed for the recipe
+// that wrote it
+func (r *Recipe) checksum() string {
+	data, _ := json.Marshal(r)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// recipeData is the data templates in a recipe are rendered with
+type recipeData struct {
+	Host HostConfig
+	Vars map[string]string
+}
+
+// newRecipeData renders the recipe's variables for a host; overrides from --var are used
+// as given
+func newRecipeData(recipe *Recipe, host HostConfig, overrides map[string]string) (recipeData, error) {
+	data := recipeData{Host: host, Vars: map[string]string{}}
+	for name, value := range recipe.Vars {
+		if _, ok := overrides[name]; ok {
+			continue
+		}
+		rendered, err := renderTemplate("vars."+name, value, recipeData{Host: host})
+		if err != nil {
+			return data, err
+		}
+		data.Vars[name] = rendered
+	}
+	for name, value := range overrides {
+		data.Vars[name] = value
+	}
+	return data, nil
+}
+
+// recipeFuncs are the functions available to recipe templates besides the text/template
+// builtins
+var recipeFuncs = template.FuncMap{
+	"shellquote": shellQuote,
+}
+
+// shellQuote quotes a value as a single word for sh, so that inventory names and labels
+// can be used in shell steps as in ./register.sh {{ shellquote .Host.Name }}
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// renderTemplate renders a text/template; referencing a missing map key is an error, so
+// optional labels are read with index, as in {{ index .Host.Labels "rack" }}
+func renderTemplate(name, text string, data recipeData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(recipeFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return out.String(), nil
+}
+
+// render returns a copy of the step with its templates rendered for a host
+func (s RecipeStep) render(data recipeData) (RecipeStep, error) {
+	var err error
+	field := func(name string, value *string) {
+		if err == nil {
+			*value, err = renderTemplate(name, *value, data)
+		}
+	}
+
+	field("mount", &s.Mount)
+	field("shell", &s.Shell)
+	if s.Boot != nil {
+		boot := *s.Boot
+		field("boot.target", &boot.Target)
+		s.Boot = &boot
+		if _, ok := bootTargets[strings.ToLower(boot.Target)]; err == nil && !ok {
+			err = fmt.Errorf("unknown boot target %q (valid targets: none, pxe, cd, hdd, bios, usb)", boot.Target)
+		}
+	}
+	if s.HTTP != nil {
+		check := *s.HTTP
+		field("http.url", &check.URL)
+		field("http.contains", &check.Contains)
+		s.HTTP = &check
+	}
+	if len(s.BIOS) > 0 {
+		attributes := make(map[string]interface{}, len(s.BIOS))
+		for name, value := range s.BIOS {
+			if text, ok := value.(string); ok {
+				field("bios."+name, &text)
+				value = text
+			}
+			attributes[name] = value
+		}
+		s.BIOS = attributes
+	}
+	return s, err
+}
+
+// enabled renders the step's condition for a host
+func (s *RecipeStep) enabled(data recipeData) (bool, error) {
+	if s.When == "" {
+		return true, nil
+	}
+	value, err := renderTemplate("when", s.When, data)
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "false", "no", "0", "<no value>":
+		return false, nil
+	}
+	return true, nil
+}
+
+// recipeState records how far each host got through a recipe, so an interrupted or partly
+// failed run continues where it stopped
+type recipeState struct {
+	Recipe   string                   `json:"recipe"`
+	Checksum string                   `json:"checksum"`
+	Hosts    map[string]*hostProgress `json:"hosts"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// hostProgress is the progress of one host: the number of steps it completed and whether
+// it finished or failed
+type hostProgress struct {
+	Completed int       `json:"completed"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Updated   time.Time `json:"updated"`
+}
+
+// recipeStatePath is the default state file of a recipe, next to it
+func recipeStatePath(recipePath string) string {
+	return strings.TrimSuffix(recipePath, filepath.Ext(recipePath)) + ".state.json"
+}
+
+// loadRecipeState reads the state file of a recipe, or starts a new one when it does not
+// exist or fresh is set. A state file written for a different version of the recipe is
+// refused, since its step numbers no longer line up.
+func loadRecipeState(path string, recipe *Recipe, fresh bool) (*recipeState, error) {
+	state := &recipeState{Recipe: recipe.Name, Checksum: recipe.checksum(), Hosts: map[string]*hostProgress{}, path: path}
+	if fresh {
+		return state, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %w", err)
+	}
+	var saved recipeState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("error parsing state file %s: %w", path, err)
+	}
+	if saved.Checksum != state.Checksum {
+		return nil, fmt.Errorf("state file %s was written for a different version of the recipe; use --fresh to start over", path)
+	}
+	if saved.Hosts != nil {
+		state.Hosts = saved.Hosts
+	}
+	return state, nil
+}
+
+// progress returns a copy of a host's progress
+func (s *recipeState) progress(host string) hostProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.Hosts[host]; ok {
+		return *p
+	}
+	return hostProgress{}
+}
+
+// update records a host's progress and writes the state file
+func (s *recipeState) update(host string, progress hostProgress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	progress.Updated = time.Now().UTC()
+	s.Hosts[host] = &progress
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write a temporary file and rename it, so an interrupted write never loses the state
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	return nil
+}
+
+// recipeTarget is a host to run a recipe on, with the configuration selecting its BMC
+type recipeTarget struct {
+	Host   HostConfig
+	Config Config
+}
+
+// recipeResult is the outcome of a recipe on one host
+type recipeResult struct {
+	Host      string `json:"host" yaml:"host"`
+	Status    string `json:"status" yaml:"status"`
+	Completed int    `json:"completed" yaml:"completed"`
+	Steps     int    `json:"steps" yaml:"steps"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// recipeRunner runs a recipe on hosts, recording their progress in a state file
+type recipeRunner struct {
+	recipe *Recipe
+	vars   map[string]string
+	state  *recipeState
+	// report prints the progress of a host; it is called concurrently
+	report func(host, format string, args ...interface{})
+}
+
+// run runs the recipe on the targets with bounded concurrency, returning results in order
+func (r *recipeRunner) run(ctx context.Context, targets []recipeTarget, workers int) []recipeResult {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]recipeResult, len(targets))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = r.runHost(ctx, target)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// runHost runs the steps a host has not completed yet, stopping at the first step that
+// fails all its attempts
+func (r *recipeRunner) runHost(ctx context.Context, target recipeTarget) recipeResult {
+	name := target.Host.Name
+	progress := r.state.progress(name)
+	result := recipeResult{Host: name, Status: progress.Status, Completed: progress.Completed, Steps: len(r.recipe.Steps)}
+	if progress.Status == recipeCompleted {
+		r.report(name, "already completed")
+		return result
+	}
+	if progress.Completed > 0 {
+		r.report(name, "resuming at step %d", progress.Completed+1)
+	}
+
+	fail := func(err error) recipeResult {
+		progress.Status = recipeFailed
+		progress.Error = err.Error()
+		if saveErr := r.state.update(name, progress); saveErr != nil {
+			err = fmt.Errorf("%w (%v)", err, saveErr)
+		}
+		result.Status, result.Completed, result.Error = recipeFailed, progress.Completed, err.Error()
+		return result
+	}
+
+	data, err := newRecipeData(r.recipe, target.Host, r.vars)
+	if err != nil {
+		return fail(err)
+	}
+
+	var client BMCClient
+	defer func() {
+		if client != nil {
+			closeClient(client)
+		}
+	}()
+
+	for i := progress.Completed; i < len(r.recipe.Steps); i++ {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+		step := r.recipe.Steps[i]
+		prefix := fmt.Sprintf("step %d/%d", i+1, len(r.recipe.Steps))
+
+		enabled, err := step.enabled(data)
+		if err != nil {
+			return fail(fmt.Errorf("%s: %w", prefix, err))
+		}
+		if !enabled {
+			r.report(name, "%s %s: skipped", prefix, step.describe())
+		} else {
+			if step, err = step.render(data); err != nil {
+				return fail(fmt.Errorf("%s: %w", prefix, err))
+			}
+			if client == nil && step.usesBMC() {
+				if client, err = target.Config.newClient(); err != nil {
+					return fail(fmt.Errorf("%s: failed to create BMC client: %w", prefix, err))
+				}
+			}
+			r.report(name, "%s %s", prefix, step.describe())
+			started := time.Now()
+			if err := r.runStep(ctx, step, client, target, func(format string, args ...interface{}) {
+				r.report(name, prefix+": "+format, args...)
+			}); err != nil {
+				return fail(fmt.Errorf("%s %s: %w", prefix, step.describe(), err))
+			}
+			r.report(name, "%s done in %s", prefix, time.Since(started).Round(time.Millisecond))
+		}
+
+		progress.Completed = i + 1
+		progress.Status = recipeRunning
+		progress.Error = ""
+		if err := r.state.update(name, progress); err != nil {
+			return fail(err)
+		}
+	}
+
+	progress.Status = recipeCompleted
+	if err := r.state.update(name, progress); err != nil {
+		return fail(err)
+	}
+	result.Status, result.Completed = recipeCompleted, progress.Completed
+	return result
+}
+
+// runStep runs a step, retrying it after retry_delay; timeout bounds each attempt
+func (r *recipeRunner) runStep(ctx context.Context, step RecipeStep, client BMCClient, target recipeTarget, retrying func(format string, args ...interface{})) error {
+	delay := step.RetryDelay
+	if delay == 0 {
+		delay = recipeRetryDelay
+	}
+	timeout := step.Timeout
+	if timeout == 0 && step.Wait != nil {
+		timeout = recipeWaitTimeout
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		err := executeStep(attemptCtx, step, client, target)
+		cancel()
+		if err == nil || attempt >= step.Retries || ctx.Err() != nil {
+			return err
+		}
+
+		retrying("%v; retrying in %s (attempt %d of %d)", err, delay, attempt+2, step.Retries+1)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// executeStep performs a rendered step once
+func executeStep(ctx context.Context, step RecipeStep, client BMCClient, target recipeTarget) error {
+	switch {
+	case step.Power != "":
+		return client.SetPowerState(ctx, recipePowerStates[step.Power])
+	case step.Wait != nil:
+		return waitForPower(ctx, client, step.Wait)
+	case step.Mount != "":
+		return client.MountVirtualMedia(ctx, step.Mount)
+	case step.Unmount:
+		return client.UnmountVirtualMedia(ctx)
+	case step.Boot != nil:
+		return client.SetBootOverride(ctx, bootTargets[strings.ToLower(step.Boot.Target)], step.Boot.Persistent)
+	case len(step.BIOS) > 0:
+		requester, ok := client.(RedfishRequester)
+		if !ok {
+			return fmt.Errorf("BIOS settings are not supported for BMC type %s", target.Config.BMCType)
+		}
+		settings := &redfishSettings{requester: requester, vendor: target.Config.BMCType}
+		return settings.setBIOSAttributes(ctx, step.BIOS)
+	case step.Sleep > 0:
+		select {
+		case <-time.After(step.Sleep):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	case step.HTTP != nil:
+		return checkHTTP(ctx, step.HTTP)
+	default:
+		return runShellHook(ctx, step.Shell, target.Host)
+	}
+}
+
+// waitForPower polls the server until it reports the power state, ignoring transient
+// errors while the BMC is busy
+func waitForPower(ctx context.Context, client BMCClient, wait *WaitStep) error {
+	interval := wait.Interval
+	if interval == 0 {
+		interval = recipeWaitInterval
+	}
+	want := "On"
+	if wait.Power == "off" {
+		want = "Off"
+	}
+
+	var last string
+	for {
+		info, err := client.GetSystemInfo(ctx)
+		if err == nil {
+			if info.PowerState == want {
+				return nil
+			}
+			last = info.PowerState
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			if last != "" {
+				return fmt.Errorf("server still %s: %w", last, ctx.Err())
+			}
+			if err != nil {
+				return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// checkHTTP requests the URL of an http step and checks the status and body
+func checkHTTP(ctx context.Context, check *HTTPStep) error {
+	method := check.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	status := check.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), check.URL, nil)
+	if err != nil {
+		return err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if check.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	defer transport.CloseIdleConnections()
+
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+
+	if resp.StatusCode != status {
+		return fmt.Errorf("got status %d, want %d", resp.StatusCode, status)
+	}
+	if check.Contains != "" && !strings.Contains(string(body), check.Contains) {
+		return fmt.Errorf("response does not contain %q", check.Contains)
+	}
+	return nil
+}
+
+// runShellHook runs a command through the shell with the host in its environment
+func runShellHook(ctx context.Context, command string, host HostConfig) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"BMC_HOST_NAME="+host.Name,
+		"BMC_ADDRESS="+host.Address,
+		"BMC_TYPE="+string(host.BMCType),
+	)
+	output, err := cmd.CombinedOutput()
+	if verbose && len(output) > 0 {
+		fmt.Printf("[%s] %s\n", host.Name, strings.TrimRight(string(output), "\n"))
+	}
+	if err != nil {
+		if text := strings.TrimSpace(string(output)); text != "" {
+			return fmt.Errorf("%w: %s", err, text)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseRecipe(t *testing.T) {
+	recipe, err := parseRecipe([]byte("steps:\n  - power: Cycle\n  - wait: {power: On}\n  - sleep: 1m30s\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if recipe.Steps[0].Power != "cycle" || recipe.Steps[1].Wait.Power != "on" || recipe.Steps[2].Sleep.Seconds() != 90 {
+		t.Errorf("Unexpected recipe: %+v", recipe.Steps)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"steps:\n  - name: nothing\n",
+		"steps:\n  - power: on\n    mount: http://x/y.iso\n",
+		"steps:\n  - power: reset\n",
+		"steps:\n  - wait: {}\n",
+		"steps:\n  - http: {status: 200}\n",
+		"steps:\n  - reboot: true\n",
+		"steps:\n  - shell: true\n    retries: -1\n",
+	} {
+		if _, err := parseRecipe([]byte(invalid)); err == nil {
+			t.Errorf("Expected error for recipe %q", invalid)
+		}
+	}
+}
+
+func TestRecipeTemplates(t *testing.T) {
+	recipe, err := parseRecipe([]byte(`
+vars:
+  image: http://images/{{ .Host.Labels.os }}.iso
+  site: lab
+steps:
+  - mount: "{{ .Vars.image }}"
+  - shell: echo {{ .Host.Labels.rack }}
+    when: '{{ index .Host.Labels "rack" }}'
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse recipe: %v", err)
+	}
+	host := HostConfig{Name: "r740-01", Labels: map[string]string{"os": "rocky9"}}
+
+	data, err := newRecipeData(recipe, host, map[string]string{"site": "dc2"})
+	if err != nil {
+		t.Fatalf("Failed to render vars: %v", err)
+	}
+	if data.Vars["image"] != "http://images/rocky9.iso" || data.Vars["site"] != "dc2" {
+		t.Errorf("Unexpected vars: %v", data.Vars)
+	}
+
+	step, err := recipe.Steps[0].render(data)
+	if err != nil || step.Mount != "http://images/rocky9.iso" {
+		t.Errorf("Expected the mount image to be rendered, got %q (%v)", step.Mount, err)
+	}
+
+	if enabled, err := recipe.Steps[1].enabled(data); err != nil || enabled {
+		t.Errorf("Expected the step to be skipped without a rack label, got %v (%v)", enabled, err)
+	}
+	if _, err := recipe.Steps[1].render(data); err == nil || !strings.Contains(err.Error(), `no entry for key "rack"`) {
+		t.Errorf("Expected missing label error, got: %v", err)
+	}
+
+	if _, err := newRecipeData(recipe, HostConfig{Name: "bare"}, nil); err == nil {
+		t.Error("Expected error for a host without the os label")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	step := RecipeStep{Shell: "printf %s {{ shellquote .Host.Labels.rack }}"}
+	for _, rack := range []string{"r12", "", "r12; touch pwned", "it's $(id) `id`"} {
+		rendered, err := step.render(recipeData{Host: HostConfig{Labels: map[string]string{"rack": rack}}})
+		if err != nil {
+			t.Fatalf("Failed to render: %v", err)
+		}
+		output, err := exec.Command("sh", "-c", rendered.Shell).Output()
+		if err != nil || string(output) != rack {
+			t.Errorf("Expected %q to reach the command as one word, got %q (%v)", rack, output, err)
+		}
+	}
+}
+
+func TestRecipeRunner(t *testing.T) {
+	check := newCheckTarget(t, "password")
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "installed")
+	log := filepath.Join(dir, "log")
+
+	installer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := os.Stat(marker); err != nil {
+			http.Error(w, "installing", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "install complete")
+	}))
+	defer installer.Close()
+
+	recipe, err := parseRecipe([]byte(fmt.Sprintf(`
+name: reinstall
+steps:
+  - shell: echo start {{ .Host.Name }} >> %s
+  - mount: http://10.0.0.5/{{ .Host.Labels.os }}.iso
+  - boot: {target: cd}
+  - power: cycle
+  - wait: {power: on, interval: 1ms}
+  - name: installer finished
+    http: {url: "%s", contains: complete}
+    retries: 1
+    retry_delay: 1ms
+  - unmount: true
+  - shell: echo never >> %s
+    when: '{{ eq .Host.Labels.os "windows" }}'
+`, log, installer.URL, log)))
+	if err != nil {
+		t.Fatalf("Failed to parse recipe: %v", err)
+	}
+
+	host := HostConfig{Name: "r740-01", Labels: map[string]string{"os": "rocky9"}}
+	targets := []recipeTarget{{Host: host, Config: check.Config}}
+	statePath := filepath.Join(dir, "reinstall.state.json")
+
+	var mu sync.Mutex
+	var lines []string
+	run := func() recipeResult {
+		state, err := loadRecipeState(statePath, recipe, false)
+		if err != nil {
+			t.Fatalf("Failed to load state: %v", err)
+		}
+		runner := &recipeRunner{recipe: recipe, state: state, report: func(host, format string, args ...interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			lines = append(lines, fmt.Sprintf(format, args...))
+		}}
+		return runner.run(context.Background(), targets, 2)[0]
+	}
+
+	// The installer is not finished yet, so the http check fails both attempts
+	result := run()
+	if result.Status != recipeFailed || result.Completed != 5 || !strings.Contains(result.Error, "step 6/8 installer finished") {
+		t.Fatalf("Expected failure at step 6, got: %+v", result)
+	}
+	if !strings.Contains(strings.Join(lines, "\n"), "retrying in 1ms (attempt 2 of 2)") {
+		t.Errorf("Expected a retry to be reported, got:\n%s", strings.Join(lines, "\n"))
+	}
+
+	client, err := check.Config.newClient()
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(client)
+	media, err := client.GetVirtualMedia(context.Background())
+	if err != nil || len(media) == 0 || media[0].Image != "http://10.0.0.5/rocky9.iso" {
+		t.Errorf("Expected the image to be mounted, got: %+v (%v)", media, err)
+	}
+
+	// Resuming skips the steps that completed
+	if err := os.WriteFile(marker, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	lines = nil
+	result = run()
+	if result.Status != recipeCompleted || result.Completed != 8 {
+		t.Fatalf("Expected the recipe to complete, got: %+v", result)
+	}
+	if lines[0] != "resuming at step 6" || !strings.Contains(strings.Join(lines, "\n"), "step 8/8 shell echo never >> "+log+": skipped") {
+		t.Errorf("Unexpected progress:\n%s", strings.Join(lines, "\n"))
+	}
+	if data, _ := os.ReadFile(log); strings.Count(string(data), "start r740-01") != 1 || strings.Contains(string(data), "never") {
+		t.Errorf("Expected the first step to run once, log: %q", data)
+	}
+	if media, _ := client.GetVirtualMedia(context.Background()); len(media) > 0 && media[0].Inserted {
+		t.Errorf("Expected the image to be unmounted, got: %+v", media)
+	}
+
+	// A completed host is skipped, and a changed recipe needs --fresh
+	lines = nil
+	if result := run(); result.Status != recipeCompleted || len(lines) != 1 || lines[0] != "already completed" {
+		t.Errorf("Expected the host to stay completed, got: %+v %v", result, lines)
+	}
+	recipe.Steps = recipe.Steps[:7]
+	if _, err := loadRecipeState(statePath, recipe, false); err == nil || !strings.Contains(err.Error(), "--fresh") {
+		t.Errorf("Expected a changed recipe to be refused, got: %v", err)
+	}
+	if state, err := loadRecipeState(statePath, recipe, true); err != nil || len(state.Hosts) != 0 {
+		t.Errorf("Expected --fresh to start over, got: %+v (%v)", state, err)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// redfishSettings reads and changes server settings the BMCClient interface does not cover
+// (BIOS attributes, NTP, DNS and accounts) through the standard Redfish resources, with the
+// iDRAC conventions where it deviates
+type redfishSettings struct {
+	requester RedfishRequester
+	vendor    BMCType
+
+	systemPath  string
+	managerPath string
+}
+
+// redfishAccount is a ManagerAccount resource
+type redfishAccount struct {
+	OdataID  string `json:"@odata.id"`
+	ID       string `json:"Id"`
+	UserName string `json:"UserName"`
+	RoleID   string `json:"RoleId"`
+	Enabled  bool   `json:"Enabled"`
+}
+
+// firstMember returns the first member of a collection
+func (s *redfishSettings) firstMember(ctx context.Context, collection string) (string, error) {
+	var members struct {
+		Members []redfishLink `json:"Members"`
+	}
+	if err := redfishJSON(ctx, s.requester, http.MethodGet, collection, nil, &members); err != nil {
+		return "", err
+	}
+	if len(members.Members) == 0 {
+		return "", fmt.Errorf("%s has no members", collection)
+	}
+	return members.Members[0].OdataID, nil
+}
+
+// system returns the path of the first computer system
+func (s *redfishSettings) system(ctx context.Context) (string, error) {
+	if s.systemPath == "" {
+		path, err := s.firstMember(ctx, "/redfish/v1/Systems")
+		if err != nil {
+			return "", err
+		}
+		s.systemPath = path
+	}
+	return s.systemPath, nil
+}
+
+// manager returns the path of the first manager
+func (s *redfishSettings) manager(ctx context.Context) (string, error) {
+	if s.managerPath == "" {
+		path, err := s.firstMember(ctx, "/redfish/v1/Managers")
+		if err != nil {
+			return "", err
+		}
+		s.managerPath = path
+	}
+	return s.managerPath, nil
+}
+
+// biosAttributes returns the current BIOS attributes and those pending for the next reset
+func (s *redfishSettings) biosAttributes(ctx context.Context) (current, pending map[string]interface{}, err error) {
+	system, err := s.system(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var bios struct {
+		Attributes map[string]interface{} `json:"Attributes"`
+		Settings   struct {
+			SettingsObject redfishLink `json:"SettingsObject"`
+		} `json:"@Redfish.Settings"`
+	}
+	if err := redfishJSON(ctx, s.requester, http.MethodGet, system+"/Bios", nil, &bios); err != nil {
+		return nil, nil, err
+	}
+
+	pending = map[string]interface{}{}
+	if settings := bios.Settings.SettingsObject.OdataID; settings != "" {
+		var staged struct {
+			Attributes map[string]interface{} `json:"Attributes"`
+		}
+		if err := redfishJSON(ctx, s.requester, http.MethodGet, settings, nil, &staged); err != nil {
+			return nil, nil, err
+		}
+		for name, value := range staged.Attributes {
+			pending[name] = value
+		}
+	}
+	return bios.Attributes, pending, nil
+}
+
+// setBIOSAttributes stages BIOS attributes for the next reset. iDRAC only applies staged
+// settings through a configuration job, which is scheduled here.
+func (s *redfishSettings) setBIOSAttributes(ctx context.Context, attributes map[string]interface{}) error {
+	system, err := s.system(ctx)
+	if err != nil {
+		return err
+	}
+	settings := system + "/Bios/Settings"
+	if err := redfishJSON(ctx, s.requester, http.MethodPatch, settings, map[string]interface{}{"Attributes": attributes}, nil); err != nil {
+		return err
+	}
+	if s.vendor != BMCTypeIDRAC {
+		return nil
+	}
+
+	manager, err := s.manager(ctx)
+	if err != nil {
+		return err
+	}
+	return redfishJSON(ctx, s.requester, http.MethodPost, manager+"/Jobs", map[string]string{"TargetSettingsURI": settings}, nil)
+}
+
+// ntp returns whether NTP is enabled on the BMC and its NTP servers
+func (s *redfishSettings) ntp(ctx context.Context) (bool, []string, error) {
+	path, err := s.networkProtocol(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	var protocol struct {
+		NTP struct {
+			ProtocolEnabled bool     `json:"ProtocolEnabled"`
+			NTPServers      []string `json:"NTPServers"`
+		} `json:"NTP"`
+	}
+	if err := redfishJSON(ctx, s.requester, http.MethodGet, path, nil, &protocol); err != nil {
+		return false, nil, err
+	}
+	return protocol.NTP.ProtocolEnabled, nonEmptyStrings(protocol.NTP.NTPServers), nil
+}
+
+// setNTP changes the NTP settings of the BMC; a nil enabled or servers is left unchanged
+func (s *redfishSettings) setNTP(ctx context.Context, enabled *bool, servers []string) error {
+	path, err := s.networkProtocol(ctx)
+	if err != nil {
+		return err
+	}
+	ntp := map[string]interface{}{}
+	if enabled != nil {
+		ntp["ProtocolEnabled"] = *enabled
+	}
+	if servers != nil {
+		ntp["NTPServers"] = servers
+	}
+	return redfishJSON(ctx, s.requester, http.MethodPatch, path, map[string]interface{}{"NTP": ntp}, nil)
+}
+
+func (s *redfishSettings) networkProtocol(ctx context.Context) (string, error) {
+	manager, err := s.manager(ctx)
+	if err != nil {
+		return "", err
+	}
+	var resource struct {
+		NetworkProtocol redfishLink `json:"NetworkProtocol"`
+	}
+	if err := redfishJSON(ctx, s.requester, http.MethodGet, manager, nil, &resource); err != nil {
+		return "", err
+	}
+	if resource.NetworkProtocol.OdataID == "" {
+		return "", fmt.Errorf("manager %s has no NetworkProtocol", manager)
+	}
+	return resource.NetworkProtocol.OdataID, nil
+}
+
+// dnsServers returns the static DNS servers of the BMC's first network interface. Servers
+// learned through DHCP are not included, since setDNSServers only sets the static ones.
+func (s *redfishSettings) dnsServers(ctx context.Context) ([]string, error) {
+	path, err := s.managerInterface(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var nic struct {
+		StaticNameServers []string `json:"StaticNameServers"`
+	}
+	if err := redfishJSON(ctx, s.requester, http.MethodGet, path, nil, &nic); err != nil {
+		return nil, err
+	}
+	return nonEmptyStrings(nic.StaticNameServers), nil
+}
+
+// setDNSServers sets the static DNS servers of the BMC's first network interface
+func (s *redfishSettings) setDNSServers(ctx context.Context, servers []string) error {
+	path, err := s.managerInterface(ctx)
+	if err != nil {
+		return err
+	}
+	return redfishJSON(ctx, s.requester, http.MethodPatch, path, map[string]interface{}{"StaticNameServers": servers}, nil)
+}
+
+func (s *redfishSettings) managerInterface(ctx context.Context) (string, error) {
+	manager, err := s.manager(ctx)
+	if err != nil {
+		return "", err
+	}
+	return s.firstMember(ctx, manager+"/EthernetInterfaces")
+}
+
+// accounts returns the BMC accounts. iDRAC lists a fixed number of slots; unused slots have
+// an empty user name.
+func (s *redfishSettings) accounts(ctx context.Context) ([]redfishAccount, error) {
+	var collection struct {
+		Members []redfishLink `json:"Members"`
+	}
+	if err := redfishJSON(ctx, s.requester, http.MethodGet, "/redfish/v1/AccountService/Accounts", nil, &collection); err != nil {
+		return nil, err
+	}
+
+	accounts := make([]redfishAccount, 0, len(collection.Members))
+	for _, member := range collection.Members {
+		var account redfishAccount
+		if err := redfishJSON(ctx, s.requester, http.MethodGet, member.OdataID, nil, &account); err != nil {
+			return nil, err
+		}
+		account.OdataID = member.OdataID
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// createAccount creates an account, in the first free slot on iDRAC
+func (s *redfishSettings) createAccount(ctx context.Context, username, password, role string, enabled bool) error {
+	body := map[string]interface{}{"UserName": username, "Password": password, "RoleId": role, "Enabled": enabled}
+	if s.vendor != BMCTypeIDRAC {
+		return redfishJSON(ctx, s.requester, http.MethodPost, "/redfish/v1/AccountService/Accounts", body, nil)
+	}
+
+	accounts, err := s.accounts(ctx)
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		// Slot 1 is reserved on iDRAC
+		if account.UserName == "" && account.ID != "1" {
+			return redfishJSON(ctx, s.requester, http.MethodPatch, account.OdataID, body, nil)
+		}
+	}
+	return fmt.Errorf("no free account slot for %s", username)
+}
+
+// updateAccount changes properties of an existing account
+func (s *redfishSettings) updateAccount(ctx context.Context, account redfishAccount, changes map[string]interface{}) error {
+	return redfishJSON(ctx, s.requester, http.MethodPatch, account.OdataID, changes, nil)
+}
+
+// deleteAccount removes an account, clearing its slot on iDRAC
+func (s *redfishSettings) deleteAccount(ctx context.Context, account redfishAccount) error {
+	if s.vendor == BMCTypeIDRAC {
+		return redfishJSON(ctx, s.requester, http.MethodPatch, account.OdataID, map[string]interface{}{"UserName": "", "Enabled": false}, nil)
+	}
+	return redfishJSON(ctx, s.requester, http.MethodDelete, account.OdataID, nil, nil)
+}
+
+func nonEmptyStrings(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
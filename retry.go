@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// retryBaseDelay is the backoff before the first retry; it doubles on every attempt
+	retryBaseDelay = 500 * time.Millisecond
+	// retryMaxDelay caps both the backoff and any Retry-After the BMC asks for
+	retryMaxDelay = 30 * time.Second
+
+	// circuitBreakerThreshold is the number of consecutive failed attempts that opens the circuit
+	circuitBreakerThreshold = 5
+	// circuitBreakerCooldown is how long an open circuit rejects requests before letting one through
+	circuitBreakerCooldown = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned without contacting a host whose circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// retryTransport retries failed attempts with jittered exponential backoff. Idempotent
+// requests are retried on connection errors and on 429, 502, 503 and 504 responses; other
+// requests only when the connection could not be established, so they were never sent.
+// Each attempt is bounded by timeout.
+type retryTransport struct {
+	next     http.RoundTripper
+	retries  int
+	timeout  time.Duration
+	breakers *circuitBreakers
+
+	// baseDelay and maxDelay default to retryBaseDelay and retryMaxDelay
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	breaker := t.breakers.forHost(req.URL.Host)
+
+	for attempt := 0; ; attempt++ {
+		if !breaker.allow() {
+			return nil, fmt.Errorf("%w for %s", ErrCircuitOpen, req.URL.Host)
+		}
+
+		attemptReq, cancel, err := t.prepareAttempt(req, attempt)
+		if err != nil {
+			breaker.release()
+			return nil, err
+		}
+
+		resp, err := t.next.RoundTrip(attemptReq)
+		retryable := t.shouldRetry(req, resp, err)
+
+		// Only unreachable hosts and timeouts count against the breaker; a BMC answering
+		// 503 while busy is alive and is handled by the retries. Any other error, such as a
+		// canceled request, says nothing about the host.
+		switch {
+		case isConnectionError(err):
+			breaker.failure()
+		case err == nil:
+			breaker.success()
+		default:
+			breaker.release()
+		}
+
+		if !retryable || attempt >= t.retries || req.Context().Err() != nil {
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = min(retryAfter, t.maxDelayOrDefault())
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		cancel()
+
+		if verbose {
+			fmt.Printf("Retrying %s %s in %s (attempt %d of %d)\n", req.Method, req.URL, delay.Round(time.Millisecond), attempt+2, t.retries+1)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// prepareAttempt clones the request with a fresh body and the per-attempt timeout
+func (t *retryTransport) prepareAttempt(req *http.Request, attempt int) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if t.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+	}
+	attemptReq := req.Clone(ctx)
+
+	if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, nil, fmt.Errorf("error rewinding request body: %w", err)
+		}
+		attemptReq.Body = body
+	}
+
+	return attemptReq, cancel, nil
+}
+
+// shouldRetry reports whether an attempt may be repeated
+func (t *retryTransport) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return true
+		}
+		return isConnectionError(err) && isIdempotent(req.Method)
+	}
+
+	if !isIdempotent(req.Method) {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the jittered delay before the retry following attempt
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	base := t.baseDelay
+	if base == 0 {
+		base = retryBaseDelay
+	}
+
+	delay := base << attempt
+	if delay <= 0 || delay > t.maxDelayOrDefault() {
+		delay = t.maxDelayOrDefault()
+	}
+	// Spread retries from many clients over the second half of the interval
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func (t *retryTransport) maxDelayOrDefault() time.Duration {
+	if t.maxDelay == 0 {
+		return retryMaxDelay
+	}
+	return t.maxDelay
+}
+
+// isConnectionError reports whether err comes from the network (refused or dropped
+// connections, timeouts) rather than from a layer above it such as a replayed cassette
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// isIdempotent reports whether a request with this method can safely be sent twice
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// cancelOnClose releases the attempt's timeout context once the body has been read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// circuitBreakers holds one breaker per host, shared by every client in the process so
+// fleet runs stop hammering a BMC that is down
+type circuitBreakers struct {
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+
+	// threshold and cooldown default to circuitBreakerThreshold and circuitBreakerCooldown
+	threshold int
+	cooldown  time.Duration
+}
+
+var hostBreakers = &circuitBreakers{}
+
+func (c *circuitBreakers) forHost(host string) *circuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.breakers == nil {
+		c.breakers = map[string]*circuitBreaker{}
+	}
+	breaker, ok := c.breakers[host]
+	if !ok {
+		breaker = &circuitBreaker{threshold: c.threshold, cooldown: c.cooldown}
+		if breaker.threshold == 0 {
+			breaker.threshold = circuitBreakerThreshold
+		}
+		if breaker.cooldown == 0 {
+			breaker.cooldown = circuitBreakerCooldown
+		}
+		c.breakers[host] = breaker
+	}
+	return breaker
+}
+
+// circuitBreaker opens after threshold consecutive failures. Once the cooldown has passed
+// a single trial request is let through; its outcome closes or re-opens the circuit.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+}
+
+// release ends a trial request without a verdict on the host, so a later request can be
+// the trial
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestRetryClient wraps the test server transport with fast retries and its own breakers
+func newTestRetryClient(server *httptest.Server, retries int) *http.Client {
+	return &http.Client{
+		Transport: &retryTransport{
+			next:      server.Client().Transport,
+			retries:   retries,
+			timeout:   time.Second,
+			breakers:  &circuitBreakers{},
+			baseDelay: time.Millisecond,
+			maxDelay:  50 * time.Millisecond,
+		},
+	}
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryTransport_RetriesServiceUnavailable(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"PowerState":"On"}`))
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &IDRACClient{
+		baseURL:    server.URL,
+		username:   "root",
+		password:   "calvin",
+		httpClient: newTestRetryClient(server, 3),
+	}
+
+	systemInfo, err := client.GetSystemInfo(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if systemInfo.PowerState != "On" {
+		t.Errorf("Expected PowerState 'On', got: %s", systemInfo.PowerState)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got: %d", attempts)
+	}
+}
+
+func TestRetryTransport_GivesUpAfterRetries(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	resp, err := newTestRetryClient(server, 2).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the final response, got error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got: %d", resp.StatusCode)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got: %d", attempts)
+	}
+}
+
+func TestRetryTransport_DoesNotRetryPost(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	resp, err := newTestRetryClient(server, 3).Post(server.URL, "application/json", strings.NewReader(`{"ResetType":"On"}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp.Body.Close()
+
+	if attempts != 1 {
+		t.Errorf("Expected a single attempt for POST, got: %d", attempts)
+	}
+}
+
+func TestRetryTransport_ReplaysBody(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("PUT", server.URL, strings.NewReader(`{"Image":"a.iso"}`))
+	resp, err := newTestRetryClient(server, 1).Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp.Body.Close()
+
+	if len(bodies) != 2 || bodies[1] != `{"Image":"a.iso"}` {
+		t.Errorf("Expected the body to be resent, got: %q", bodies)
+	}
+}
+
+func TestRetryTransport_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client := newTestRetryClient(server, 0)
+	client.Transport.(*retryTransport).timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("Expected timeout error, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected request to time out after 50ms, took %s", elapsed)
+	}
+}
+
+func TestCircuitBreaker_OpensAfterFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close() // Connections are refused from now on
+
+	breakers := &circuitBreakers{threshold: 2, cooldown: time.Hour}
+	client := &http.Client{Transport: &retryTransport{
+		next:      http.DefaultTransport,
+		retries:   5,
+		breakers:  breakers,
+		baseDelay: time.Millisecond,
+	}}
+
+	_, err := client.Get(url)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected circuit to open during retries, got: %v", err)
+	}
+
+	// Further requests fail fast without contacting the host
+	if _, err := client.Get(url + "/redfish/v1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got: %v", err)
+	}
+}
+
+func TestCircuitBreaker_TrialAfterCooldown(t *testing.T) {
+	breaker := &circuitBreaker{threshold: 1, cooldown: time.Millisecond}
+	breaker.failure()
+
+	if breaker.allow() {
+		t.Error("Expected open circuit to reject requests")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if !breaker.allow() {
+		t.Error("Expected a trial request after the cooldown")
+	}
+	if breaker.allow() {
+		t.Error("Expected only one trial request")
+	}
+	breaker.success()
+	if !breaker.allow() {
+		t.Error("Expected circuit to close after a successful trial")
+	}
+}
+
+func TestCircuitBreaker_CanceledTrial(t *testing.T) {
+	breakers := &circuitBreakers{threshold: 1, cooldown: time.Millisecond}
+	var canceled atomic.Bool
+	canceled.Store(true)
+	client := &http.Client{Transport: &retryTransport{
+		next: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if canceled.Load() {
+				return nil, context.Canceled
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}")), Request: req}, nil
+		}),
+		breakers: breakers,
+	}}
+	breaker := breakers.forHost("bmc.example.com")
+	breaker.failure()
+	time.Sleep(5 * time.Millisecond)
+
+	// The trial is canceled, which neither closes nor re-opens the circuit
+	if _, err := client.Get("https://bmc.example.com/redfish/v1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the trial to be canceled, got: %v", err)
+	}
+	canceled.Store(false)
+	resp, err := client.Get("https://bmc.example.com/redfish/v1")
+	if err != nil {
+		t.Fatalf("Expected the next request to be let through as the trial, got: %v", err)
+	}
+	resp.Body.Close()
+	if !breaker.allow() {
+		t.Error("Expected circuit to close after a successful trial")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if delay, ok := parseRetryAfter("5"); !ok || delay != 5*time.Second {
+		t.Errorf("Expected 5s, got: %s (%t)", delay, ok)
+	}
+	if delay, ok := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); !ok || delay <= 0 || delay > time.Minute {
+		t.Errorf("Expected up to 1m, got: %s (%t)", delay, ok)
+	}
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Error("Expected invalid Retry-After to be ignored")
+	}
+}
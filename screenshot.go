@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	// Registered for screenshotPNG, as BMCs return JPEG screenshots too
+	_ "image/jpeg"
+)
+
+func init() {
+	image.RegisterFormat("bmp", "BM", decodeBMP, decodeBMPConfig)
+}
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// screenshotPNG returns a screenshot as PNG, converting the other formats BMCs return
+func screenshotPNG(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, pngSignature) {
+		return data, nil
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding screenshot: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("error converting %s screenshot to PNG: %w", format, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// bmpHeader is the file header and the BITMAPINFOHEADER fields of a BMP file
+type bmpHeader struct {
+	Magic       [2]byte
+	FileSize    uint32
+	Reserved    uint32
+	PixelOffset uint32
+	HeaderSize  uint32
+	Width       int32
+	Height      int32
+	Planes      uint16
+	BitCount    uint16
+	Compression uint32
+}
+
+const (
+	bmpRGB       = 0
+	bmpBitfields = 3
+
+	// maxBMPSide bounds the width and height of a BMP, so that a corrupt header cannot make
+	// the decoder allocate gigabytes; BMC screens are far smaller
+	maxBMPSide = 8192
+)
+
+// readBMPHeader reads the header of an uncompressed 24 or 32-bit BMP, which is what BMCs
+// produce for screen captures
+func readBMPHeader(r io.Reader) (bmpHeader, error) {
+	var h bmpHeader
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return h, fmt.Errorf("bmp: %w", err)
+	}
+	switch {
+	case string(h.Magic[:]) != "BM":
+		return h, fmt.Errorf("bmp: not a BMP file")
+	case h.HeaderSize < 40:
+		return h, fmt.Errorf("bmp: unsupported header size %d", h.HeaderSize)
+	case h.BitCount != 24 && h.BitCount != 32:
+		return h, fmt.Errorf("bmp: unsupported bit depth %d", h.BitCount)
+	case h.Compression != bmpRGB && !(h.Compression == bmpBitfields && h.BitCount == 32):
+		return h, fmt.Errorf("bmp: unsupported compression %d", h.Compression)
+	case h.Width <= 0 || h.Height == 0:
+		return h, fmt.Errorf("bmp: invalid size %dx%d", h.Width, h.Height)
+	case h.Width > maxBMPSide || h.Height > maxBMPSide || h.Height < -maxBMPSide:
+		return h, fmt.Errorf("bmp: image too large (%dx%d)", h.Width, h.Height)
+	case int64(h.PixelOffset) < int64(binary.Size(h)):
+		return h, fmt.Errorf("bmp: invalid pixel data offset %d", h.PixelOffset)
+	}
+	return h, nil
+}
+
+func decodeBMPConfig(r io.Reader) (image.Config, error) {
+	h, err := readBMPHeader(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	height := int(h.Height)
+	if height < 0 {
+		height = -height
+	}
+	return image.Config{ColorModel: color.RGBAModel, Width: int(h.Width), Height: height}, nil
+}
+
+// decodeBMP decodes an uncompressed BMP, whose rows are stored bottom-up unless the
+// height is negative. 32-bit bitfields are assumed to be in the usual BGRA order.
+func decodeBMP(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	h, err := readBMPHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	// The header read so far is 34 bytes; skip the rest of it and any color masks
+	if _, err := br.Discard(int(h.PixelOffset) - binary.Size(h)); err != nil {
+		return nil, fmt.Errorf("bmp: %w", err)
+	}
+
+	width, height, topDown := int(h.Width), int(h.Height), false
+	if height < 0 {
+		height, topDown = -height, true
+	}
+	bytesPerPixel := int(h.BitCount) / 8
+	// Rows are padded to a multiple of 4 bytes
+	row := make([]byte, (width*bytesPerPixel+3)&^3)
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < height; i++ {
+		if _, err := io.ReadFull(br, row); err != nil {
+			return nil, fmt.Errorf("bmp: %w", err)
+		}
+		y := height - 1 - i
+		if topDown {
+			y = i
+		}
+		for x := 0; x < width; x++ {
+			p := row[x*bytesPerPixel:]
+			img.SetRGBA(x, y, color.RGBA{R: p[2], G: p[1], B: p[0], A: 0xff})
+		}
+	}
+	return img, nil
+}
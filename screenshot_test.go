@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecodeBMP(t *testing.T) {
+	// BMP has no alpha channel, so the image is opaque
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+	src.SetRGBA(0, 0, color.RGBA{R: 0xff, A: 0xff})
+	src.SetRGBA(2, 1, color.RGBA{B: 0xff, A: 0xff})
+
+	img, format, err := image.Decode(bytes.NewReader(mockBMP(src)))
+	if err != nil || format != "bmp" {
+		t.Fatalf("Failed to decode BMP: %v (%s)", err, format)
+	}
+	if img.Bounds() != src.Bounds() {
+		t.Fatalf("Expected bounds %v, got %v", src.Bounds(), img.Bounds())
+	}
+	for _, p := range []image.Point{{0, 0}, {1, 0}, {2, 1}} {
+		if got, want := color.RGBAModel.Convert(img.At(p.X, p.Y)), src.At(p.X, p.Y); got != want {
+			t.Errorf("Pixel %v: expected %v, got %v", p, want, got)
+		}
+	}
+
+	if _, err := screenshotPNG([]byte("BM not really")); err == nil {
+		t.Error("Expected error for a truncated BMP")
+	}
+
+	// Corrupt headers are rejected before any pixels are allocated
+	for name, corrupt := range map[string]func(h *bmpHeader){
+		"huge width":    func(h *bmpHeader) { h.Width = 1 << 30 },
+		"huge height":   func(h *bmpHeader) { h.Height = -1 << 30 },
+		"pixel offset":  func(h *bmpHeader) { h.PixelOffset = 10 },
+		"negative size": func(h *bmpHeader) { h.Width = -3 },
+	} {
+		data := mockBMP(src)
+		var h bmpHeader
+		_ = binary.Read(bytes.NewReader(data), binary.LittleEndian, &h)
+		corrupt(&h)
+		var buf bytes.Buffer
+		_ = binary.Write(&buf, binary.LittleEndian, h)
+		copy(data, buf.Bytes())
+		if _, err := decodeBMP(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCaptureScreen(t *testing.T) {
+	check := newCheckTarget(t, "password")
+	idrac, err := check.Config.newClient()
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(idrac)
+
+	bmc, server := newMockBMCServer(t, "ilo5")
+	ilo := &ILOClient{baseURL: server.URL, username: "admin", password: "password", httpClient: server.Client()}
+
+	for name, client := range map[string]BMCClient{"idrac9": idrac, "ilo5": ilo} {
+		data, err := client.(ScreenCapturer).CaptureScreen(context.Background())
+		if err != nil {
+			t.Errorf("%s: expected no error, got: %v", name, err)
+			continue
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: expected a PNG image, got: %v", name, err)
+			continue
+		}
+		if img.Bounds().Dx() != 64 || color.RGBAModel.Convert(img.At(0, 0)) != (color.RGBA{0, 0, 0xaa, 0xff}) {
+			t.Errorf("%s: unexpected screen %v with %v", name, img.Bounds(), img.At(0, 0))
+		}
+	}
+
+	// The web session used for the thumbnail is logged out
+	bmc.mu.Lock()
+	sessions := len(bmc.sessions)
+	bmc.mu.Unlock()
+	if sessions != 0 {
+		t.Errorf("Expected the session to be deleted, %d left", sessions)
+	}
+
+	// iLO returns the session's Location as an absolute URL with its own address
+	absolute := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		bmc.ServeHTTP(rec, r)
+		for key, values := range rec.Header() {
+			w.Header()[key] = values
+		}
+		if location := rec.Header().Get("Location"); location != "" {
+			w.Header().Set("Location", "https://ilo.example.com"+location)
+		}
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(rec.Body.Bytes())
+	}))
+	defer absolute.Close()
+	ilo.baseURL, ilo.httpClient = absolute.URL, absolute.Client()
+	if _, err := ilo.CaptureScreen(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	bmc.mu.Lock()
+	sessions = len(bmc.sessions)
+	bmc.mu.Unlock()
+	if sessions != 0 {
+		t.Errorf("Expected the session at an absolute Location to be deleted, %d left", sessions)
+	}
+
+	ilo.password = "wrong"
+	if _, err := ilo.CaptureScreen(context.Background()); err == nil {
+		t.Error("Expected error with wrong credentials")
+	}
+}
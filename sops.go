@@ -0,0 +1,584 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// sopsVersion is the SOPS file format version written to the metadata
+	sopsVersion = "3.7.3"
+	// sopsMetadataKey is the top-level key holding the SOPS metadata
+	sopsMetadataKey = "sops"
+	// sopsUnencryptedSuffix marks keys whose values are left in plaintext when every other
+	// value is encrypted
+	sopsUnencryptedSuffix = "_unencrypted"
+	// defaultEncryptedRegex selects the values 'config encrypt' encrypts by default
+	defaultEncryptedRegex = "^password$"
+)
+
+// sopsEncryptedValue matches a value encrypted by SOPS
+var sopsEncryptedValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// configEncryption describes how a config file is encrypted
+type configEncryption struct {
+	// WholeFile is set for files encrypted as a single age payload rather than per value
+	WholeFile  bool
+	Recipients []string
+
+	// EncryptedRegex selects the keys whose values are encrypted; when empty every value is
+	// encrypted except those under keys ending in UnencryptedSuffix
+	EncryptedRegex    string
+	UnencryptedSuffix string
+}
+
+// sopsMetadata is the "sops" section of a SOPS encrypted file
+type sopsMetadata struct {
+	Age               []sopsAgeKey `yaml:"age"`
+	LastModified      string       `yaml:"lastmodified"`
+	MAC               string       `yaml:"mac"`
+	UnencryptedSuffix string       `yaml:"unencrypted_suffix,omitempty"`
+	EncryptedRegex    string       `yaml:"encrypted_regex,omitempty"`
+	Version           string       `yaml:"version"`
+}
+
+// sopsAgeKey is the data key encrypted to one age recipient
+type sopsAgeKey struct {
+	Recipient string `yaml:"recipient"`
+	Enc       string `yaml:"enc"`
+}
+
+// isAgeEncrypted reports whether data is an age file, binary or armored
+func isAgeEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("age-encryption.org/v1\n")) ||
+		bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header))
+}
+
+// decryptConfig returns the plaintext of a config file that is age encrypted or contains
+// SOPS metadata, together with how it was encrypted. Plaintext files are returned as is
+// with a nil encryption.
+func decryptConfig(data []byte) ([]byte, *configEncryption, error) {
+	if isAgeEncrypted(data) {
+		identities, err := loadAgeIdentities()
+		if err != nil {
+			return nil, nil, err
+		}
+		var src io.Reader = bytes.NewReader(data)
+		if !bytes.HasPrefix(data, []byte("age-encryption.org/v1\n")) {
+			src = armor.NewReader(bytes.NewReader(bytes.TrimSpace(data)))
+		}
+		r, err := age.Decrypt(src, identities...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error decrypting config: %w", err)
+		}
+		plaintext, err := io.ReadAll(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error decrypting config: %w", err)
+		}
+		return plaintext, &configEncryption{WholeFile: true}, nil
+	}
+
+	if !bytes.Contains(data, []byte("ENC[")) {
+		return data, nil, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("error parsing config: %w", err)
+	}
+	root := documentRoot(&doc)
+	metadataNode := removeMappingKey(root, sopsMetadataKey)
+	if metadataNode == nil {
+		return nil, nil, fmt.Errorf("config contains ENC[...] values but no sops metadata")
+	}
+
+	var metadata sopsMetadata
+	if err := metadataNode.Decode(&metadata); err != nil {
+		return nil, nil, fmt.Errorf("error parsing sops metadata: %w", err)
+	}
+	dataKey, err := sopsDataKey(metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hash := sha512.New()
+	err = walkLeaves(root, nil, func(leaf *yaml.Node, path []string) error {
+		if sopsEncryptedValue.MatchString(leaf.Value) {
+			if err := sopsDecryptLeaf(leaf, dataKey, sopsPath(path)); err != nil {
+				return fmt.Errorf("error decrypting %s: %w", strings.Join(path, "."), err)
+			}
+		}
+		hash.Write(sopsMACBytes(leaf))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mac, err := sopsDecrypt(metadata.MAC, dataKey, metadata.LastModified)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decrypting sops MAC: %w", err)
+	}
+	if !strings.EqualFold(string(mac), fmt.Sprintf("%X", hash.Sum(nil))) {
+		return nil, nil, fmt.Errorf("sops MAC mismatch: the config file has been modified without re-encrypting it")
+	}
+
+	plaintext, err := encodeYAML(&doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encryption := &configEncryption{
+		EncryptedRegex:    metadata.EncryptedRegex,
+		UnencryptedSuffix: metadata.UnencryptedSuffix,
+	}
+	for _, key := range metadata.Age {
+		encryption.Recipients = append(encryption.Recipients, key.Recipient)
+	}
+	return plaintext, encryption, nil
+}
+
+// encryptConfig encrypts a plaintext config for the recipients in encryption, either as a
+// whole or value by value in the SOPS format
+func encryptConfig(plaintext []byte, encryption configEncryption) ([]byte, error) {
+	recipients, err := parseAgeRecipients(encryption.Recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	if encryption.WholeFile {
+		var out bytes.Buffer
+		armorWriter := armor.NewWriter(&out)
+		w, err := age.Encrypt(armorWriter, recipients...)
+		if err != nil {
+			return nil, fmt.Errorf("error encrypting config: %w", err)
+		}
+		if _, err := w.Write(plaintext); err != nil {
+			return nil, fmt.Errorf("error encrypting config: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("error encrypting config: %w", err)
+		}
+		if err := armorWriter.Close(); err != nil {
+			return nil, fmt.Errorf("error encrypting config: %w", err)
+		}
+		return out.Bytes(), nil
+	}
+
+	var encryptedRegex *regexp.Regexp
+	if encryption.EncryptedRegex != "" {
+		if encryptedRegex, err = regexp.Compile(encryption.EncryptedRegex); err != nil {
+			return nil, fmt.Errorf("invalid encrypted regex: %w", err)
+		}
+	} else if encryption.UnencryptedSuffix == "" {
+		encryption.UnencryptedSuffix = sopsUnencryptedSuffix
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(plaintext, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing config: %w", err)
+	}
+	root := documentRoot(&doc)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config must be a YAML mapping")
+	}
+	if mappingValue(root, sopsMetadataKey) != nil {
+		return nil, fmt.Errorf("config is already encrypted")
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	hash := sha512.New()
+	err = walkLeaves(root, nil, func(leaf *yaml.Node, path []string) error {
+		hash.Write(sopsMACBytes(leaf))
+		if !sopsShouldEncrypt(path, encryptedRegex, encryption.UnencryptedSuffix) {
+			return nil
+		}
+		return sopsEncryptLeaf(leaf, dataKey, sopsPath(path))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := sopsMetadata{
+		LastModified:      time.Now().UTC().Format(time.RFC3339),
+		UnencryptedSuffix: encryption.UnencryptedSuffix,
+		EncryptedRegex:    encryption.EncryptedRegex,
+		Version:           sopsVersion,
+	}
+	if metadata.MAC, err = sopsEncrypt([]byte(fmt.Sprintf("%X", hash.Sum(nil))), "str", dataKey, metadata.LastModified); err != nil {
+		return nil, err
+	}
+	for i, recipient := range recipients {
+		var enc bytes.Buffer
+		armorWriter := armor.NewWriter(&enc)
+		w, err := age.Encrypt(armorWriter, recipient)
+		if err != nil {
+			return nil, fmt.Errorf("error encrypting data key: %w", err)
+		}
+		if _, err := w.Write(dataKey); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		if err := armorWriter.Close(); err != nil {
+			return nil, err
+		}
+		metadata.Age = append(metadata.Age, sopsAgeKey{Recipient: encryption.Recipients[i], Enc: enc.String()})
+	}
+
+	var metadataNode yaml.Node
+	if err := metadataNode.Encode(&metadata); err != nil {
+		return nil, err
+	}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: sopsMetadataKey}, &metadataNode)
+
+	return encodeYAML(&doc)
+}
+
+// loadAgeIdentities reads the age identities from $SOPS_AGE_KEY, $SOPS_AGE_KEY_FILE or the
+// SOPS default key file
+func loadAgeIdentities() ([]age.Identity, error) {
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		identities, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing SOPS_AGE_KEY: %w", err)
+		}
+		return identities, nil
+	}
+
+	path := os.Getenv("SOPS_AGE_KEY_FILE")
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("no age key found (set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE)")
+		}
+		path = filepath.Join(dir, "sops", "age", "keys.txt")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("no age key found (set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE): %w", err)
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing age key file %s: %w", path, err)
+	}
+	return identities, nil
+}
+
+// defaultAgeRecipients returns the recipients of the available age identities, so a file
+// can be encrypted for the key that will decrypt it
+func defaultAgeRecipients() ([]string, error) {
+	identities, err := loadAgeIdentities()
+	if err != nil {
+		return nil, err
+	}
+
+	var recipients []string
+	for _, identity := range identities {
+		if x25519, ok := identity.(*age.X25519Identity); ok {
+			recipients = append(recipients, x25519.Recipient().String())
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no age X25519 identities found to derive a recipient from")
+	}
+	return recipients, nil
+}
+
+func parseAgeRecipients(list []string) ([]age.Recipient, error) {
+	if len(list) == 0 {
+		return nil, fmt.Errorf("at least one age recipient is required")
+	}
+
+	recipients := make([]age.Recipient, 0, len(list))
+	for _, value := range list {
+		recipient, err := age.ParseX25519Recipient(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient %q: %w", value, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// sopsDataKey decrypts the data key with the first age identity that matches a recipient
+func sopsDataKey(metadata sopsMetadata) ([]byte, error) {
+	if len(metadata.Age) == 0 {
+		return nil, fmt.Errorf("sops metadata has no age recipients (only age is supported)")
+	}
+	identities, err := loadAgeIdentities()
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, key := range metadata.Age {
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(strings.TrimSpace(key.Enc))), identities...)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		dataKey, err := io.ReadAll(r)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return dataKey, nil
+	}
+	return nil, fmt.Errorf("error decrypting sops data key: %w", lastErr)
+}
+
+// sopsShouldEncrypt applies the SOPS encrypted_regex and unencrypted_suffix rules to a path
+func sopsShouldEncrypt(path []string, encryptedRegex *regexp.Regexp, unencryptedSuffix string) bool {
+	if encryptedRegex != nil {
+		for _, key := range path {
+			if encryptedRegex.MatchString(key) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, key := range path {
+		if strings.HasSuffix(key, unencryptedSuffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// sopsPath is the additional authenticated data binding a value to its location
+func sopsPath(path []string) string {
+	return strings.Join(path, ":") + ":"
+}
+
+// sopsMACBytes returns the representation SOPS hashes into the MAC for a leaf value
+func sopsMACBytes(leaf *yaml.Node) []byte {
+	value, _ := sopsScalar(leaf)
+	if leaf.ShortTag() == "!!bool" {
+		// SOPS hashes booleans as Python would print them
+		return []byte(strings.ToUpper(value[:1]) + value[1:])
+	}
+	return []byte(value)
+}
+
+// sopsScalar returns the canonical string form of a scalar and its SOPS type name
+func sopsScalar(leaf *yaml.Node) (string, string) {
+	switch leaf.ShortTag() {
+	case "!!int":
+		if n, err := strconv.ParseInt(leaf.Value, 0, 64); err == nil {
+			return strconv.FormatInt(n, 10), "int"
+		}
+	case "!!float":
+		if f, err := strconv.ParseFloat(leaf.Value, 64); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64), "float"
+		}
+	case "!!bool":
+		var b bool
+		if err := leaf.Decode(&b); err == nil {
+			return strconv.FormatBool(b), "bool"
+		}
+	}
+	return leaf.Value, "str"
+}
+
+// sopsEncryptLeaf replaces a scalar with its encrypted form; empty strings stay empty as in SOPS
+func sopsEncryptLeaf(leaf *yaml.Node, dataKey []byte, path string) error {
+	value, valueType := sopsScalar(leaf)
+	if valueType == "str" && value == "" {
+		return nil
+	}
+
+	encrypted, err := sopsEncrypt([]byte(value), valueType, dataKey, path)
+	if err != nil {
+		return err
+	}
+	leaf.Value = encrypted
+	leaf.Tag = "!!str"
+	leaf.Style = 0
+	return nil
+}
+
+// sopsDecryptLeaf replaces an encrypted scalar with its plaintext value and type
+func sopsDecryptLeaf(leaf *yaml.Node, dataKey []byte, path string) error {
+	match := sopsEncryptedValue.FindStringSubmatch(leaf.Value)
+	plaintext, err := sopsDecrypt(leaf.Value, dataKey, path)
+	if err != nil {
+		return err
+	}
+
+	leaf.Value = string(plaintext)
+	leaf.Style = 0
+	switch match[4] {
+	case "int":
+		leaf.Tag = "!!int"
+	case "float":
+		leaf.Tag = "!!float"
+	case "bool":
+		leaf.Tag = "!!bool"
+	default:
+		leaf.Tag = "!!str"
+	}
+	return nil
+}
+
+// sopsEncrypt encrypts a value with AES-256-GCM in the SOPS ENC[...] format
+func sopsEncrypt(plaintext []byte, valueType string, dataKey []byte, additionalData string) (string, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, 32)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, 32)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(additionalData))
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag),
+		valueType), nil
+}
+
+// sopsDecrypt decrypts an ENC[...] value
+func sopsDecrypt(value string, dataKey []byte, additionalData string) ([]byte, error) {
+	match := sopsEncryptedValue.FindStringSubmatch(value)
+	if match == nil {
+		return nil, fmt.Errorf("not a sops encrypted value")
+	}
+
+	var parts [3][]byte
+	for i, encoded := range match[1:4] {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encrypted value: %w", err)
+		}
+		parts[i] = decoded
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, errors.New("authentication failed (wrong key or tampered value)")
+	}
+	return plaintext, nil
+}
+
+// walkLeaves calls fn for every non-null scalar below node with the mapping keys leading
+// to it; sequence items share the path of their sequence as in SOPS
+func walkLeaves(node *yaml.Node, path []string, fn func(leaf *yaml.Node, path []string) error) error {
+	if node == nil {
+		return nil
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childPath := append(append([]string{}, path...), node.Content[i].Value)
+			if err := walkLeaves(node.Content[i+1], childPath, fn); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if err := walkLeaves(item, path, fn); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!null" {
+			return nil
+		}
+		return fn(node, path)
+	}
+	return nil
+}
+
+// documentRoot returns the top-level node of a parsed document
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return nil
+}
+
+// mappingValue returns the value for key in a mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeMappingKey deletes key from a mapping node and returns its value, or nil
+func removeMappingKey(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value := mapping.Content[i+1]
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return value
+		}
+	}
+	return nil
+}
+
+// encodeYAML marshals a document with the two-space indentation used in config files
+func encodeYAML(doc *yaml.Node) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("error encoding config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("error encoding config: %w", err)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/spf13/viper"
+)
+
+const testPlainConfig = `bmc_type: idrac
+idrac:
+  host: 192.168.1.101
+  username: root
+  password: calvin
+  port: 443
+  use_https: true
+`
+
+// setTestAgeKey generates an age identity, makes it available through SOPS_AGE_KEY and
+// returns its recipient
+func setTestAgeKey(t *testing.T) string {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("Failed to generate age identity: %v", err)
+	}
+	t.Setenv("SOPS_AGE_KEY", identity.String())
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+	return identity.Recipient().String()
+}
+
+func TestEncryptConfig_SOPSRoundTrip(t *testing.T) {
+	recipient := setTestAgeKey(t)
+
+	encrypted, err := encryptConfig([]byte(testPlainConfig), configEncryption{
+		Recipients:     []string{recipient},
+		EncryptedRegex: defaultEncryptedRegex,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error encrypting, got: %v", err)
+	}
+
+	text := string(encrypted)
+	if strings.Contains(text, "calvin") {
+		t.Errorf("Expected password to be encrypted, got:\n%s", text)
+	}
+	if !strings.Contains(text, "host: 192.168.1.101") || !strings.Contains(text, "password: ENC[AES256_GCM,") {
+		t.Errorf("Expected only the password to be encrypted, got:\n%s", text)
+	}
+	if !strings.Contains(text, "recipient: "+recipient) {
+		t.Errorf("Expected sops metadata for the recipient, got:\n%s", text)
+	}
+
+	plaintext, encryption, err := decryptConfig(encrypted)
+	if err != nil {
+		t.Fatalf("Expected no error decrypting, got: %v", err)
+	}
+	if string(plaintext) != testPlainConfig {
+		t.Errorf("Expected original config, got:\n%s", plaintext)
+	}
+	if encryption == nil || encryption.WholeFile || encryption.EncryptedRegex != defaultEncryptedRegex ||
+		len(encryption.Recipients) != 1 || encryption.Recipients[0] != recipient {
+		t.Errorf("Unexpected encryption: %+v", encryption)
+	}
+}
+
+func TestEncryptConfig_AllValuesKeepTypes(t *testing.T) {
+	recipient := setTestAgeKey(t)
+
+	encrypted, err := encryptConfig([]byte(testPlainConfig), configEncryption{Recipients: []string{recipient}})
+	if err != nil {
+		t.Fatalf("Expected no error encrypting, got: %v", err)
+	}
+	if strings.Contains(string(encrypted), "192.168.1.101") || !strings.Contains(string(encrypted), "type:int]") {
+		t.Errorf("Expected every value to be encrypted, got:\n%s", encrypted)
+	}
+
+	plaintext, _, err := decryptConfig(encrypted)
+	if err != nil {
+		t.Fatalf("Expected no error decrypting, got: %v", err)
+	}
+	if string(plaintext) != testPlainConfig {
+		t.Errorf("Expected port and use_https to keep their types, got:\n%s", plaintext)
+	}
+}
+
+func TestDecryptConfig_Tampered(t *testing.T) {
+	recipient := setTestAgeKey(t)
+
+	encrypted, err := encryptConfig([]byte(testPlainConfig), configEncryption{
+		Recipients:     []string{recipient},
+		EncryptedRegex: defaultEncryptedRegex,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error encrypting, got: %v", err)
+	}
+
+	// Redirecting the BMC without re-encrypting breaks the MAC
+	tampered := strings.Replace(string(encrypted), "192.168.1.101", "203.0.113.9", 1)
+	if _, _, err := decryptConfig([]byte(tampered)); err == nil || !strings.Contains(err.Error(), "MAC mismatch") {
+		t.Errorf("Expected MAC mismatch error, got: %v", err)
+	}
+
+	// A different key cannot decrypt the data key
+	setTestAgeKey(t)
+	if _, _, err := decryptConfig(encrypted); err == nil {
+		t.Error("Expected error decrypting with the wrong key")
+	}
+}
+
+func TestEncryptConfig_WholeFile(t *testing.T) {
+	recipient := setTestAgeKey(t)
+
+	encrypted, err := encryptConfig([]byte(testPlainConfig), configEncryption{WholeFile: true, Recipients: []string{recipient}})
+	if err != nil {
+		t.Fatalf("Expected no error encrypting, got: %v", err)
+	}
+	if !isAgeEncrypted(encrypted) || strings.Contains(string(encrypted), "192.168.1.101") {
+		t.Errorf("Expected an armored age file, got:\n%s", encrypted)
+	}
+
+	plaintext, encryption, err := decryptConfig(encrypted)
+	if err != nil {
+		t.Fatalf("Expected no error decrypting, got: %v", err)
+	}
+	if string(plaintext) != testPlainConfig || encryption == nil || !encryption.WholeFile {
+		t.Errorf("Unexpected result %+v:\n%s", encryption, plaintext)
+	}
+}
+
+func TestLoadConfig_Encrypted(t *testing.T) {
+	originalConfig, originalFile := config, cfgFile
+	defer func() { config, cfgFile = originalConfig, originalFile }()
+
+	recipient := setTestAgeKey(t)
+	encrypted, err := encryptConfig([]byte(testPlainConfig), configEncryption{
+		Recipients:     []string{recipient},
+		EncryptedRegex: defaultEncryptedRegex,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error encrypting, got: %v", err)
+	}
+
+	cfgFile = filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgFile, encrypted, 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	viper.Reset()
+
+	if err := loadConfig(); err != nil {
+		t.Fatalf("Expected no error loading encrypted config, got: %v", err)
+	}
+	if config.IDRAC.Host != "192.168.1.101" || config.IDRAC.Password != "calvin" || config.IDRAC.Port != 443 {
+		t.Errorf("Unexpected iDRAC config: %+v", config.IDRAC)
+	}
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SupermicroClient represents a Supermicro BMC API client
+type SupermicroClient struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// SupermicroLicenseError is returned when the BMC rejects an operation because
+// the feature is gated behind a Supermicro out-of-band license
+type SupermicroLicenseError struct {
+	Operation string
+	Message   string
+}
+
+func (e *SupermicroLicenseError) Error() string {
+	msg := fmt.Sprintf("%s requires a Supermicro SFT-DCMS-SINGLE (or SFT-OOB-LIC) license on this BMC", e.Operation)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// supermicroVirtualMedia is a virtual media slot as exposed by newer Supermicro firmware
+type supermicroVirtualMedia struct {
+	VirtualMediaInfo
+	OdataID string `json:"@odata.id"`
+	Actions struct {
+		InsertMedia struct {
+			Target string `json:"target"`
+		} `json:"#VirtualMedia.InsertMedia"`
+		EjectMedia struct {
+			Target string `json:"target"`
+		} `json:"#VirtualMedia.EjectMedia"`
+	} `json:"Actions"`
+}
+
+// supermicroVirtualMediaConfig is the Oem/Supermicro virtual media resource used by older (X11) firmware
+type supermicroVirtualMediaConfig struct {
+	Host     string `json:"Host"`
+	Path     string `json:"Path"`
+	Username string `json:"Username,omitempty"`
+	Password string `json:"Password,omitempty"`
+}
+
+// NewSupermicroClient creates a new Supermicro client
+func NewSupermicroClient(host, username, password string, port int, useHTTPS bool, transport *http.Transport) BMCClient {
+	scheme := "http"
+	if useHTTPS {
+		scheme = "https"
+	}
+
+	baseURL := fmt.Sprintf("%s://%s:%d", scheme, host, port)
+
+	return &SupermicroClient{
+		baseURL:    baseURL,
+		username:   username,
+		password:   password,
+		httpClient: newHTTPClient(transport),
+	}
+}
+
+// makeRequest makes an authenticated HTTP request to the Supermicro Redfish API
+func (c *SupermicroClient) makeRequest(ctx context.Context, method, endpoint string, body interface{}) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling request body: %w", err)
+		}
+		bodyReader = bytes.NewReader(jsonBody)
+	}
+
+	url := c.baseURL + endpoint
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.SetBasicAuth(c.username, c.password)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	if verbose {
+		fmt.Printf("Making %s request to %s\n", method, url)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+
+	return resp, nil
+}
+
+// checkResponse turns a failed response into an error, reporting license-gated features explicitly
+func (c *SupermicroClient) checkResponse(resp *http.Response, operation string) error {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return nil
+	}
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if msg, ok := supermicroLicenseMessage(bodyBytes); ok {
+		return &SupermicroLicenseError{Operation: operation, Message: msg}
+	}
+	return fmt.Errorf("%s failed with status %d: %s", operation, resp.StatusCode, string(bodyBytes))
+}
+
+// supermicroLicenseMessage extracts the license message from a Redfish error body, if the
+// error was caused by a missing Supermicro license
+func supermicroLicenseMessage(body []byte) (string, bool) {
+	var redfishErr struct {
+		Error struct {
+			Message      string `json:"message"`
+			ExtendedInfo []struct {
+				MessageID string `json:"MessageId"`
+				Message   string `json:"Message"`
+			} `json:"@Message.ExtendedInfo"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &redfishErr); err == nil {
+		for _, info := range redfishErr.Error.ExtendedInfo {
+			if strings.Contains(info.MessageID, "License") || strings.Contains(strings.ToLower(info.Message), "licen") {
+				return info.Message, true
+			}
+		}
+		if strings.Contains(strings.ToLower(redfishErr.Error.Message), "licen") {
+			return redfishErr.Error.Message, true
+		}
+	}
+
+	if strings.Contains(strings.ToLower(string(body)), "not licensed") {
+		return strings.TrimSpace(string(body)), true
+	}
+	return "", false
+}
+
+// getJSON retrieves a resource and decodes it into out
+func (c *SupermicroClient) getJSON(ctx context.Context, endpoint string, out interface{}) error {
+	resp, err := c.makeRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		if msg, ok := supermicroLicenseMessage(bodyBytes); ok {
+			return &SupermicroLicenseError{Operation: "access to " + endpoint, Message: msg}
+		}
+		return fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return nil
+}
+
+// GetSystemInfo retrieves basic system information
+func (c *SupermicroClient) GetSystemInfo(ctx context.Context) (*SystemInfo, error) {
+	var systemInfo SystemInfo
+	if err := c.getJSON(ctx, "/redfish/v1/Systems/1", &systemInfo); err != nil {
+		return nil, err
+	}
+	return &systemInfo, nil
+}
+
+// SetPowerState changes the server power state
+func (c *SupermicroClient) SetPowerState(ctx context.Context, state PowerState) error {
+	resetType, err := redfishResetType(ctx, c, "/redfish/v1/Systems/1", state)
+	if err != nil {
+		return err
+	}
+	powerRequest := PowerRequest{
+		ResetType: resetType,
+	}
+
+	resp, err := c.makeRequest(ctx, "POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", powerRequest)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return c.checkResponse(resp, "power operation")
+}
+
+// virtualMediaLocation reports where the manager exposes virtual media. Newer firmware has a
+// standard VirtualMedia collection; older X11 firmware only has Oem/Supermicro VirtualMediaConfig.
+func (c *SupermicroClient) virtualMediaLocation(ctx context.Context) (collection string, legacyConfig string, err error) {
+	var manager struct {
+		VirtualMedia struct {
+			OdataID string `json:"@odata.id"`
+		} `json:"VirtualMedia"`
+		Oem struct {
+			Supermicro struct {
+				VirtualMediaConfig struct {
+					OdataID string `json:"@odata.id"`
+				} `json:"VirtualMediaConfig"`
+			} `json:"Supermicro"`
+		} `json:"Oem"`
+	}
+	if err := c.getJSON(ctx, "/redfish/v1/Managers/1", &manager); err != nil {
+		return "", "", err
+	}
+
+	if manager.VirtualMedia.OdataID != "" {
+		var result struct {
+			Members []struct {
+				OdataID string `json:"@odata.id"`
+			} `json:"Members"`
+		}
+		err := c.getJSON(ctx, manager.VirtualMedia.OdataID, &result)
+		var licenseErr *SupermicroLicenseError
+		if errors.As(err, &licenseErr) {
+			return "", "", err
+		}
+		if err == nil && len(result.Members) > 0 {
+			return manager.VirtualMedia.OdataID, "", nil
+		}
+	}
+
+	if cfg := manager.Oem.Supermicro.VirtualMediaConfig.OdataID; cfg != "" {
+		return "", cfg, nil
+	}
+
+	return "", "", fmt.Errorf("BMC does not expose virtual media (VirtualMedia collection or Oem/Supermicro VirtualMediaConfig)")
+}
+
+// getVirtualMediaSlots lists the standard Redfish virtual media slots of a collection
+func (c *SupermicroClient) getVirtualMediaSlots(ctx context.Context, collection string) ([]supermicroVirtualMedia, error) {
+	var result struct {
+		Members []struct {
+			OdataID string `json:"@odata.id"`
+		} `json:"Members"`
+	}
+	if err := c.getJSON(ctx, collection, &result); err != nil {
+		return nil, err
+	}
+
+	var slots []supermicroVirtualMedia
+	for _, member := range result.Members {
+		var slot supermicroVirtualMedia
+		if err := c.getJSON(ctx, member.OdataID, &slot); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue // Skip this media slot if we can't get info
+		}
+		if slot.OdataID == "" {
+			slot.OdataID = member.OdataID
+		}
+		slots = append(slots, slot)
+	}
+
+	return slots, nil
+}
+
+// GetVirtualMedia lists available virtual media slots
+func (c *SupermicroClient) GetVirtualMedia(ctx context.Context) ([]VirtualMediaInfo, error) {
+	collection, legacyConfig, err := c.virtualMediaLocation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if legacyConfig != "" {
+		var cfg supermicroVirtualMediaConfig
+		if err := c.getJSON(ctx, legacyConfig, &cfg); err != nil {
+			return nil, err
+		}
+		vm := VirtualMediaInfo{
+			Name:       "CfgCD",
+			MediaTypes: []string{"CD"},
+			Inserted:   cfg.Path != "",
+			Connected:  cfg.Path != "",
+		}
+		if cfg.Path != "" {
+			vm.Image = fmt.Sprintf(`\\%s%s`, cfg.Host, cfg.Path)
+		}
+		return []VirtualMediaInfo{vm}, nil
+	}
+
+	slots, err := c.getVirtualMediaSlots(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	var virtualMediaList []VirtualMediaInfo
+	for _, slot := range slots {
+		virtualMediaList = append(virtualMediaList, slot.VirtualMediaInfo)
+	}
+	return virtualMediaList, nil
+}
+
+// MountVirtualMedia mounts an image to the first CD/DVD virtual media slot
+func (c *SupermicroClient) MountVirtualMedia(ctx context.Context, imageURL string) error {
+	collection, legacyConfig, err := c.virtualMediaLocation(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting virtual media info: %w", err)
+	}
+
+	if legacyConfig != "" {
+		return c.mountLegacyVirtualMedia(ctx, legacyConfig, imageURL)
+	}
+
+	slots, err := c.getVirtualMediaSlots(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("error getting virtual media info: %w", err)
+	}
+
+	var target *supermicroVirtualMedia
+	for i := range slots {
+		for _, mediaType := range slots[i].MediaTypes {
+			if mediaType == "CD" || mediaType == "DVD" {
+				target = &slots[i]
+				break
+			}
+		}
+		if target != nil {
+			break
+		}
+	}
+
+	if target == nil {
+		return fmt.Errorf("no CD/DVD virtual media slot found")
+	}
+
+	// Supermicro refuses to insert into a slot that already holds an image
+	if target.Inserted {
+		if err := c.ejectVirtualMedia(ctx, *target); err != nil {
+			return err
+		}
+	}
+
+	insertTarget := target.Actions.InsertMedia.Target
+	if insertTarget == "" {
+		insertTarget = target.OdataID + "/Actions/VirtualMedia.InsertMedia"
+	}
+
+	mountRequest := map[string]interface{}{
+		"Image":          imageURL,
+		"Inserted":       true,
+		"WriteProtected": true,
+	}
+
+	resp, err := c.makeRequest(ctx, "POST", insertTarget, mountRequest)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return c.checkResponse(resp, "virtual media mount")
+}
+
+// mountLegacyVirtualMedia configures and mounts an image through Oem/Supermicro VirtualMediaConfig.
+// Older firmware only supports images on a Windows (SMB/CIFS) share.
+func (c *SupermicroClient) mountLegacyVirtualMedia(ctx context.Context, configPath, imageURL string) error {
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return fmt.Errorf("invalid image URL: %w", err)
+	}
+	if u.Scheme != "smb" && u.Scheme != "cifs" {
+		return fmt.Errorf("this Supermicro firmware only supports virtual media from SMB shares (smb://host/share/image.iso), got %q", u.Scheme)
+	}
+
+	cfg := supermicroVirtualMediaConfig{
+		Host: u.Host,
+		Path: strings.ReplaceAll(u.Path, "/", `\`),
+	}
+	if u.User != nil {
+		cfg.Username = u.User.Username()
+		cfg.Password, _ = u.User.Password()
+	}
+
+	resp, err := c.makeRequest(ctx, "PATCH", configPath, cfg)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := c.checkResponse(resp, "virtual media configuration"); err != nil {
+		return err
+	}
+
+	mountResp, err := c.makeRequest(ctx, "POST", configPath+"/Actions/IsoConfig.Mount", map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+	defer mountResp.Body.Close()
+
+	return c.checkResponse(mountResp, "virtual media mount")
+}
+
+// ejectVirtualMedia ejects the image from a standard virtual media slot
+func (c *SupermicroClient) ejectVirtualMedia(ctx context.Context, slot supermicroVirtualMedia) error {
+	ejectTarget := slot.Actions.EjectMedia.Target
+	if ejectTarget == "" {
+		ejectTarget = slot.OdataID + "/Actions/VirtualMedia.EjectMedia"
+	}
+
+	resp, err := c.makeRequest(ctx, "POST", ejectTarget, map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return c.checkResponse(resp, "virtual media eject")
+}
+
+// UnmountVirtualMedia unmounts virtual media from all slots
+func (c *SupermicroClient) UnmountVirtualMedia(ctx context.Context) error {
+	collection, legacyConfig, err := c.virtualMediaLocation(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting virtual media info: %w", err)
+	}
+
+	if legacyConfig != "" {
+		resp, err := c.makeRequest(ctx, "POST", legacyConfig+"/Actions/IsoConfig.UMount", map[string]interface{}{})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return c.checkResponse(resp, "virtual media unmount")
+	}
+
+	slots, err := c.getVirtualMediaSlots(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("error getting virtual media info: %w", err)
+	}
+
+	for _, slot := range slots {
+		if slot.Inserted {
+			if err := c.ejectVirtualMedia(ctx, slot); err != nil {
+				var licenseErr *SupermicroLicenseError
+				if errors.As(err, &licenseErr) {
+					return err
+				}
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				continue // Continue with other slots
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetBootOverride sets the boot source override for the next boot (or every boot when persistent)
+func (c *SupermicroClient) SetBootOverride(ctx context.Context, target BootTarget, persistent bool) error {
+	resp, err := c.makeRequest(ctx, "PATCH", "/redfish/v1/Systems/1", newBootOverrideRequest(target, persistent))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return c.checkResponse(resp, "boot override")
+}
+
+// RedfishRequest sends a raw request to the Supermicro Redfish API
+func (c *SupermicroClient) RedfishRequest(ctx context.Context, method, endpoint string, body []byte, header http.Header) (*http.Response, error) {
+	return basicAuthRequest(ctx, c.httpClient, c.baseURL, c.username, c.password, method, endpoint, body, header)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSupermicroClient_GetSystemInfo(t *testing.T) {
+	// Create test server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/redfish/v1/Systems/1" {
+			t.Errorf("Expected path '/redfish/v1/Systems/1', got: %s", r.URL.Path)
+		}
+		if r.Method != "GET" {
+			t.Errorf("Expected GET request, got: %s", r.Method)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"PowerState":"On","Status":{"Health":"OK","State":"Enabled"}}`)); err != nil {
+			t.Errorf("Failed to write mock response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &SupermicroClient{
+		baseURL:    server.URL,
+		username:   "ADMIN",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	// Test GetSystemInfo
+	systemInfo, err := client.GetSystemInfo(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if systemInfo.PowerState != "On" {
+		t.Errorf("Expected PowerState 'On', got: %s", systemInfo.PowerState)
+	}
+	if systemInfo.Status.Health != "OK" {
+		t.Errorf("Expected Health 'OK', got: %s", systemInfo.Status.Health)
+	}
+}
+
+func TestSupermicroClient_SetPowerState(t *testing.T) {
+	// Create test server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset" {
+			t.Errorf("Expected path '/redfish/v1/Systems/1/Actions/ComputerSystem.Reset', got: %s", r.URL.Path)
+		}
+
+		// Verify request body
+		var powerRequest PowerRequest
+		if err := json.NewDecoder(r.Body).Decode(&powerRequest); err != nil {
+			t.Errorf("Error decoding request body: %v", err)
+		}
+		if powerRequest.ResetType != "ForceOff" {
+			t.Errorf("Expected ResetType 'ForceOff', got: %s", powerRequest.ResetType)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &SupermicroClient{
+		baseURL:    server.URL,
+		username:   "ADMIN",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	// Test SetPowerState
+	if err := client.SetPowerState(context.Background(), PowerStateOff); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestSupermicroClient_MountVirtualMedia_InsertMedia(t *testing.T) {
+	var inserted bool
+
+	// Create test server exposing the standard VirtualMedia collection
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/redfish/v1/Managers/1":
+			_, _ = w.Write([]byte(`{"VirtualMedia":{"@odata.id":"/redfish/v1/Managers/1/VirtualMedia"}}`))
+		case "/redfish/v1/Managers/1/VirtualMedia":
+			_, _ = w.Write([]byte(`{"Members":[{"@odata.id":"/redfish/v1/Managers/1/VirtualMedia/CD1"}]}`))
+		case "/redfish/v1/Managers/1/VirtualMedia/CD1":
+			_, _ = w.Write([]byte(`{"@odata.id":"/redfish/v1/Managers/1/VirtualMedia/CD1","Name":"CD1","MediaTypes":["CD","DVD"],"Inserted":false,
+				"Actions":{"#VirtualMedia.InsertMedia":{"target":"/redfish/v1/Managers/1/VirtualMedia/CD1/Actions/VirtualMedia.InsertMedia"}}}`))
+		case "/redfish/v1/Managers/1/VirtualMedia/CD1/Actions/VirtualMedia.InsertMedia":
+			if r.Method != "POST" {
+				t.Errorf("Expected POST request, got: %s", r.Method)
+			}
+			var body map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("Error decoding request body: %v", err)
+			}
+			if body["Image"] != "http://example.com/image.iso" {
+				t.Errorf("Expected image URL 'http://example.com/image.iso', got: %v", body["Image"])
+			}
+			inserted = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &SupermicroClient{
+		baseURL:    server.URL,
+		username:   "ADMIN",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	if err := client.MountVirtualMedia(context.Background(), "http://example.com/image.iso"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !inserted {
+		t.Error("Expected InsertMedia action to be called")
+	}
+}
+
+func TestSupermicroClient_MountVirtualMedia_LegacyConfig(t *testing.T) {
+	var configured, mounted bool
+
+	// Create test server emulating older firmware with Oem/Supermicro VirtualMediaConfig
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/redfish/v1/Managers/1":
+			_, _ = w.Write([]byte(`{"Oem":{"Supermicro":{"VirtualMediaConfig":{"@odata.id":"/redfish/v1/Managers/1/VM1/CfgCD"}}}}`))
+		case "/redfish/v1/Managers/1/VM1/CfgCD":
+			if r.Method != "PATCH" {
+				t.Errorf("Expected PATCH request, got: %s", r.Method)
+			}
+			var cfg supermicroVirtualMediaConfig
+			if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+				t.Errorf("Error decoding request body: %v", err)
+			}
+			if cfg.Host != "10.0.0.5" {
+				t.Errorf("Expected host '10.0.0.5', got: %s", cfg.Host)
+			}
+			if cfg.Path != `\isos\ubuntu.iso` {
+				t.Errorf("Expected path '\\isos\\ubuntu.iso', got: %s", cfg.Path)
+			}
+			configured = true
+			w.WriteHeader(http.StatusOK)
+		case "/redfish/v1/Managers/1/VM1/CfgCD/Actions/IsoConfig.Mount":
+			mounted = true
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &SupermicroClient{
+		baseURL:    server.URL,
+		username:   "ADMIN",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	if err := client.MountVirtualMedia(context.Background(), "smb://10.0.0.5/isos/ubuntu.iso"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !configured || !mounted {
+		t.Errorf("Expected configuration and mount calls, got configured=%t mounted=%t", configured, mounted)
+	}
+
+	// Older firmware cannot mount HTTP images
+	if err := client.MountVirtualMedia(context.Background(), "http://example.com/image.iso"); err == nil {
+		t.Error("Expected error for HTTP image on legacy firmware, got nil")
+	}
+}
+
+func TestSupermicroClient_LicenseError(t *testing.T) {
+	// Create test server that rejects virtual media for lack of a license
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/redfish/v1/Managers/1":
+			_, _ = w.Write([]byte(`{"VirtualMedia":{"@odata.id":"/redfish/v1/Managers/1/VirtualMedia"}}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":{"code":"Base.v1_4_0.GeneralError","message":"A general error has occurred.",
+				"@Message.ExtendedInfo":[{"MessageId":"SMC.1.0.OemLicenseNotPassed","Message":"Not licensed to perform this request. The following licenses SFT-DCMS-SINGLE were needed"}]}}`))
+		}
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &SupermicroClient{
+		baseURL:    server.URL,
+		username:   "ADMIN",
+		password:   "password",
+		httpClient: server.Client(),
+	}
+
+	err := client.MountVirtualMedia(context.Background(), "http://example.com/image.iso")
+	var licenseErr *SupermicroLicenseError
+	if !errors.As(err, &licenseErr) {
+		t.Fatalf("Expected SupermicroLicenseError, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "SFT-DCMS-SINGLE") {
+		t.Errorf("Expected error to mention the required license, got: %v", err)
+	}
+}
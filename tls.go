@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// knownHostsFile is the file holding trust-on-first-use certificate fingerprints; empty
+// means defaultKnownHostsFile
+var knownHostsFile string
+
+// TLSConfig represents the TLS verification settings of a BMC
+type TLSConfig struct {
+	// CAFile is a PEM bundle the BMC certificate must chain to. Without it the certificate
+	// is pinned on first use.
+	CAFile string `yaml:"ca_file" mapstructure:"ca_file"`
+	// ServerName overrides the name checked against the certificate, for BMCs reached by IP
+	ServerName string `yaml:"server_name" mapstructure:"server_name"`
+	// Insecure disables certificate verification altogether
+	Insecure bool `yaml:"insecure" mapstructure:"insecure"`
+}
+
+// CertificateChangedError is returned when a BMC presents a certificate other than the one
+// pinned for it in the known hosts file
+type CertificateChangedError struct {
+	Address  string
+	Expected string
+	Actual   string
+	File     string
+}
+
+func (e *CertificateChangedError) Error() string {
+	return fmt.Sprintf("TLS CERTIFICATE OF %s HAS CHANGED: expected fingerprint %s, got %s. "+
+		"Someone may be intercepting the connection, or the BMC certificate was replaced. "+
+		"If the change is expected, remove the entry for %s from %s",
+		e.Address, e.Expected, e.Actual, e.Address, e.File)
+}
+
+// newTLSConfig builds the client TLS configuration for the BMC at address (host:port)
+func newTLSConfig(address string, settings TLSConfig) (*tls.Config, error) {
+	if settings.Insecure {
+		fmt.Fprintf(os.Stderr, "Warning: TLS certificate verification is disabled for %s\n", address)
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+
+	if settings.CAFile != "" {
+		pem, err := os.ReadFile(settings.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", settings.CAFile)
+		}
+		return &tls.Config{RootCAs: pool, ServerName: settings.ServerName}, nil
+	}
+
+	// Trust on first use: chain verification is replaced by the pinned fingerprint check
+	hosts, err := newKnownHosts()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         settings.ServerName,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return fmt.Errorf("%s presented no certificate", address)
+			}
+			return hosts.verify(address, state.PeerCertificates[0])
+		},
+	}, nil
+}
+
+// bmcAddress joins a host and port for use as a known hosts key
+func bmcAddress(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// defaultKnownHostsFile returns the known hosts file in the user's config directory
+func defaultKnownHostsFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating known hosts file: %w", err)
+	}
+	return filepath.Join(dir, "bmc-cli", "known_hosts"), nil
+}
+
+// certificateFingerprint returns the SHA-256 fingerprint of a certificate
+func certificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return "SHA256:" + hex.EncodeToString(sum[:])
+}
+
+// knownHosts pins certificate fingerprints in a file with one "<host:port> <fingerprint>"
+// line per BMC
+type knownHosts struct {
+	path string
+	mu   sync.Mutex
+}
+
+// newKnownHosts returns the known hosts file selected by --known-hosts, or the default one
+func newKnownHosts() (*knownHosts, error) {
+	hosts := &knownHosts{path: knownHostsFile}
+	if hosts.path == "" {
+		path, err := defaultKnownHostsFile()
+		if err != nil {
+			return nil, err
+		}
+		hosts.path = path
+	}
+	return hosts, nil
+}
+
+// verify checks cert against the pinned fingerprint for address, pinning it if there is none
+func (k *knownHosts) verify(address string, cert *x509.Certificate) error {
+	fingerprint := certificateFingerprint(cert)
+	pinned, err := k.pin(address, fingerprint, "certificate")
+	if err != nil || pinned == fingerprint {
+		return err
+	}
+	return &CertificateChangedError{Address: address, Expected: pinned, Actual: fingerprint, File: k.path}
+}
+
+// pin returns the fingerprint pinned for address, first pinning fingerprint (of what, for
+// the message) if there is none
+func (k *knownHosts) pin(address, fingerprint, what string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	pinned, err := k.lookup(address)
+	if err != nil || pinned != "" {
+		return pinned, err
+	}
+	if err := k.add(address, fingerprint); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(os.Stderr, "Trusting %s of %s on first use (%s), pinned in %s\n", what, address, fingerprint, k.path)
+	return fingerprint, nil
+}
+
+// lookup returns the fingerprint pinned for address, or "" if there is none
+func (k *knownHosts) lookup(address string) (string, error) {
+	f, err := os.Open(k.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading known hosts file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] == address {
+			return fields[1], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("error reading known hosts file: %w", err)
+	}
+	return "", nil
+}
+
+// add appends a pinned fingerprint to the known hosts file
+func (k *knownHosts) add(address, fingerprint string) error {
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return fmt.Errorf("error creating known hosts directory: %w", err)
+	}
+	f, err := os.OpenFile(k.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error writing known hosts file: %w", err)
+	}
+	if _, err := fmt.Fprintf(f, "%s %s\n", address, fingerprint); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing known hosts file: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTLSTestServer starts an HTTPS server and returns it with its host:port address
+func newTLSTestServer(t *testing.T) (*httptest.Server, string) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, strings.TrimPrefix(server.URL, "https://")
+}
+
+// tlsGet sends a GET request with the given TLS settings
+func tlsGet(t *testing.T, server *httptest.Server, address string, settings TLSConfig) error {
+	tlsConfig, err := newTLSConfig(address, settings)
+	if err != nil {
+		t.Fatalf("Failed to create TLS config: %v", err)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func TestTLS_TrustOnFirstUse(t *testing.T) {
+	knownHostsFile = filepath.Join(t.TempDir(), "known_hosts")
+	defer func() { knownHostsFile = "" }()
+
+	server, address := newTLSTestServer(t)
+
+	if err := tlsGet(t, server, address, TLSConfig{}); err != nil {
+		t.Fatalf("Expected first connection to be trusted, got: %v", err)
+	}
+	data, err := os.ReadFile(knownHostsFile)
+	if err != nil {
+		t.Fatalf("Expected known hosts file to be written: %v", err)
+	}
+	expected := address + " " + certificateFingerprint(server.Certificate()) + "\n"
+	if string(data) != expected {
+		t.Errorf("Expected known hosts %q, got: %q", expected, data)
+	}
+
+	if err := tlsGet(t, server, address, TLSConfig{}); err != nil {
+		t.Fatalf("Expected pinned certificate to be accepted, got: %v", err)
+	}
+	if data, _ := os.ReadFile(knownHostsFile); string(data) != expected {
+		t.Errorf("Expected known hosts to be unchanged, got: %q", data)
+	}
+}
+
+func TestTLS_CertificateChanged(t *testing.T) {
+	knownHostsFile = filepath.Join(t.TempDir(), "known_hosts")
+	defer func() { knownHostsFile = "" }()
+
+	server, address := newTLSTestServer(t)
+	pinned := "SHA256:" + strings.Repeat("ab", 32)
+	if err := os.WriteFile(knownHostsFile, []byte("# pinned BMCs\n"+address+" "+pinned+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write known hosts: %v", err)
+	}
+
+	err := tlsGet(t, server, address, TLSConfig{})
+	var changed *CertificateChangedError
+	if !errors.As(err, &changed) {
+		t.Fatalf("Expected certificate changed error, got: %v", err)
+	}
+	if changed.Expected != pinned || changed.Actual != certificateFingerprint(server.Certificate()) {
+		t.Errorf("Unexpected fingerprints in error: %+v", changed)
+	}
+}
+
+func TestTLS_CAFile(t *testing.T) {
+	server, address := newTLSTestServer(t)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	if err := tlsGet(t, server, address, TLSConfig{CAFile: caFile}); err != nil {
+		t.Fatalf("Expected certificate to verify against the CA file, got: %v", err)
+	}
+
+	// The test certificate is issued for example.com, but not for other names
+	if err := tlsGet(t, server, address, TLSConfig{CAFile: caFile, ServerName: "example.com"}); err != nil {
+		t.Errorf("Expected server name example.com to verify, got: %v", err)
+	}
+	if err := tlsGet(t, server, address, TLSConfig{CAFile: caFile, ServerName: "bmc.example.org"}); err == nil {
+		t.Error("Expected verification to fail for a name not in the certificate")
+	}
+
+	invalidFile := filepath.Join(t.TempDir(), "invalid.pem")
+	if err := os.WriteFile(invalidFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+	if _, err := newTLSConfig(address, TLSConfig{CAFile: invalidFile}); err == nil {
+		t.Error("Expected error for a CA file without certificates")
+	}
+}
+
+func TestTLS_Insecure(t *testing.T) {
+	server, address := newTLSTestServer(t)
+
+	if err := tlsGet(t, server, address, TLSConfig{Insecure: true}); err != nil {
+		t.Fatalf("Expected insecure connection to succeed, got: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	recordDir      string
+	replayDir      string
+	requestTimeout = 30 * time.Second
+	requestRetries = 3
+)
+
+// newBMCTransport creates the transport for the BMC at host:port, verifying its certificate
+// according to tlsSettings and connecting through the proxy or jump host in proxySettings
+func newBMCTransport(host string, port int, tlsSettings TLSConfig, proxySettings ProxyConfig) (*http.Transport, error) {
+	tlsConfig, err := newTLSConfig(bmcAddress(host, port), tlsSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
+	if err := configureProxy(transport, proxySettings); err != nil {
+		return nil, err
+	}
+	return transport, nil
+}
+
+// newHTTPClient creates the HTTP client used by the Redfish based BMC clients on top of
+// base (a default transport when nil). Failed attempts are retried (--retries), each
+// attempt is bounded by --timeout and traffic is recorded to, or replayed from, a cassette
+// directory when --record or --replay is set.
+func newHTTPClient(base *http.Transport) *http.Client {
+	var transport http.RoundTripper = base
+	if base == nil {
+		transport = &http.Transport{}
+	}
+
+	switch {
+	case replayDir != "":
+		transport = newReplayTransport(replayDir)
+	case recordDir != "":
+		transport = &recordingTransport{dir: recordDir, next: transport}
+	}
+
+	return &http.Client{
+		Transport: &retryTransport{
+			next:     transport,
+			retries:  requestRetries,
+			timeout:  requestTimeout,
+			breakers: hostBreakers,
+		},
+	}
+}
+
+// basicAuthRequest sends a raw request with HTTP basic authentication for the clients that
+// authenticate every request. Headers in header are added to, or replace, the defaults.
+func basicAuthRequest(ctx context.Context, httpClient *http.Client, baseURL, username, password, method, endpoint string, body []byte, header http.Header) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	url := baseURL + endpoint
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.SetBasicAuth(username, password)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	if verbose {
+		fmt.Printf("Making %s request to %s\n", method, url)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+
+	return resp, nil
+}
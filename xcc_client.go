@@ -0,0 +1,450 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// XCCClient represents a Lenovo XClarity Controller API client
+type XCCClient struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// xccVirtualMedia is a virtual media slot as exposed by the XCC
+type xccVirtualMedia struct {
+	VirtualMediaInfo
+	OdataID string `json:"@odata.id"`
+	ID      string `json:"Id"`
+	ETag    string `json:"@odata.etag"`
+	Actions struct {
+		InsertMedia struct {
+			Target string `json:"target"`
+		} `json:"#VirtualMedia.InsertMedia"`
+		EjectMedia struct {
+			Target string `json:"target"`
+		} `json:"#VirtualMedia.EjectMedia"`
+	} `json:"Actions"`
+}
+
+// isRemoteMount reports whether the slot mounts images from a remote share. RDOC slots only
+// serve images uploaded to the XCC itself and cannot be pointed at a URL.
+func (vm xccVirtualMedia) isRemoteMount() bool {
+	id := strings.ToUpper(vm.ID)
+	if id == "" {
+		id = strings.ToUpper(path.Base(vm.OdataID))
+	}
+	return !strings.HasPrefix(id, "RDOC")
+}
+
+// xccRemoteMapMountRequest represents an Oem/Lenovo RemoteMap mount request
+type xccRemoteMapMountRequest struct {
+	FSIP       string `json:"FSIP"`
+	FSPort     int    `json:"FSPort,omitempty"`
+	FSProtocol string `json:"FSProtocol"`
+	FSDir      string `json:"FSDir"`
+	Image      string `json:"Image"`
+	FSUsername string `json:"FSUsername,omitempty"`
+	FSPassword string `json:"FSPassword,omitempty"`
+	ReadOnly   bool   `json:"ReadOnly"`
+}
+
+// NewXCCClient creates a new Lenovo XCC client
+func NewXCCClient(host, username, password string, port int, useHTTPS bool, transport *http.Transport) BMCClient {
+	scheme := "http"
+	if useHTTPS {
+		scheme = "https"
+	}
+
+	baseURL := fmt.Sprintf("%s://%s:%d", scheme, host, port)
+
+	return &XCCClient{
+		baseURL:    baseURL,
+		username:   username,
+		password:   password,
+		httpClient: newHTTPClient(transport),
+	}
+}
+
+// makeRequest makes an authenticated HTTP request to the XCC Redfish API. The XCC rejects
+// PATCH requests without an If-Match header, so callers pass the resource ETag when patching.
+func (c *XCCClient) makeRequest(ctx context.Context, method, endpoint string, body interface{}, etag string) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling request body: %w", err)
+		}
+		bodyReader = bytes.NewReader(jsonBody)
+	}
+
+	url := c.baseURL + endpoint
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.SetBasicAuth(c.username, c.password)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+
+	if verbose {
+		fmt.Printf("Making %s request to %s\n", method, url)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+
+	return resp, nil
+}
+
+// getJSON retrieves a resource, decodes it into out and returns its ETag
+func (c *XCCClient) getJSON(ctx context.Context, endpoint string, out interface{}) (string, error) {
+	resp, err := c.makeRequest(ctx, "GET", endpoint, nil, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return "", fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return resp.Header.Get("ETag"), nil
+}
+
+// checkXCCResponse turns a failed XCC response into an error
+func checkXCCResponse(resp *http.Response, operation string) error {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return nil
+	}
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("%s failed with status %d: %s", operation, resp.StatusCode, string(bodyBytes))
+}
+
+// GetSystemInfo retrieves basic system information
+func (c *XCCClient) GetSystemInfo(ctx context.Context) (*SystemInfo, error) {
+	var systemInfo SystemInfo
+	if _, err := c.getJSON(ctx, "/redfish/v1/Systems/1", &systemInfo); err != nil {
+		return nil, err
+	}
+	return &systemInfo, nil
+}
+
+// SetPowerState changes the server power state
+func (c *XCCClient) SetPowerState(ctx context.Context, state PowerState) error {
+	resetType, err := redfishResetType(ctx, c, "/redfish/v1/Systems/1", state)
+	if err != nil {
+		return err
+	}
+	powerRequest := PowerRequest{
+		ResetType: resetType,
+	}
+
+	resp, err := c.makeRequest(ctx, "POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", powerRequest, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkXCCResponse(resp, "power operation")
+}
+
+// getVirtualMediaSlots lists all virtual media slots of the XCC
+func (c *XCCClient) getVirtualMediaSlots(ctx context.Context) ([]xccVirtualMedia, error) {
+	var result struct {
+		Members []struct {
+			OdataID string `json:"@odata.id"`
+		} `json:"Members"`
+	}
+	if _, err := c.getJSON(ctx, "/redfish/v1/Managers/1/VirtualMedia", &result); err != nil {
+		return nil, err
+	}
+
+	var slots []xccVirtualMedia
+	for _, member := range result.Members {
+		var slot xccVirtualMedia
+		etag, err := c.getJSON(ctx, member.OdataID, &slot)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue // Skip this media slot if we can't get info
+		}
+		if slot.OdataID == "" {
+			slot.OdataID = member.OdataID
+		}
+		if etag != "" {
+			slot.ETag = etag
+		}
+		slots = append(slots, slot)
+	}
+
+	return slots, nil
+}
+
+// GetVirtualMedia lists available virtual media slots
+func (c *XCCClient) GetVirtualMedia(ctx context.Context) ([]VirtualMediaInfo, error) {
+	slots, err := c.getVirtualMediaSlots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var virtualMediaList []VirtualMediaInfo
+	for _, slot := range slots {
+		virtualMediaList = append(virtualMediaList, slot.VirtualMediaInfo)
+	}
+	return virtualMediaList, nil
+}
+
+// remoteMapTarget returns the Oem/Lenovo RemoteMap mount or unmount action target, if the XCC has one
+func (c *XCCClient) remoteMapTarget(ctx context.Context, action string) string {
+	var remoteMap struct {
+		Actions map[string]struct {
+			Target string `json:"target"`
+		} `json:"Actions"`
+	}
+	if _, err := c.getJSON(ctx, "/redfish/v1/Managers/1/Oem/Lenovo/RemoteMap", &remoteMap); err != nil {
+		return ""
+	}
+	return remoteMap.Actions["#LenovoRemoteMapService."+action].Target
+}
+
+// MountVirtualMedia mounts an image to the first free remote-mount CD/DVD slot. Firmware that
+// advertises InsertMedia uses the standard action, older firmware falls back to the Oem/Lenovo
+// RemoteMap mount action and finally to patching the slot.
+func (c *XCCClient) MountVirtualMedia(ctx context.Context, imageURL string) error {
+	slots, err := c.getVirtualMediaSlots(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting virtual media info: %w", err)
+	}
+
+	var target *xccVirtualMedia
+	for i := range slots {
+		if !slots[i].isRemoteMount() || slots[i].Inserted {
+			continue
+		}
+		for _, mediaType := range slots[i].MediaTypes {
+			if mediaType == "CD" || mediaType == "DVD" {
+				target = &slots[i]
+				break
+			}
+		}
+		if target != nil {
+			break
+		}
+	}
+
+	if target != nil && target.Actions.InsertMedia.Target != "" {
+		mountRequest := map[string]interface{}{
+			"Image":          imageURL,
+			"Inserted":       true,
+			"WriteProtected": true,
+		}
+
+		resp, err := c.makeRequest(ctx, "POST", target.Actions.InsertMedia.Target, mountRequest, "")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return checkXCCResponse(resp, "virtual media mount")
+	}
+
+	if mountTarget := c.remoteMapTarget(ctx, "Mount"); mountTarget != "" {
+		mountRequest, err := newXCCRemoteMapMountRequest(imageURL)
+		if err != nil {
+			return err
+		}
+
+		resp, err := c.makeRequest(ctx, "POST", mountTarget, mountRequest, "")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return checkXCCResponse(resp, "virtual media mount")
+	}
+
+	if target == nil {
+		return fmt.Errorf("no free remote-mount CD/DVD virtual media slot found (RDOC slots cannot mount URLs)")
+	}
+
+	mountRequest := map[string]interface{}{
+		"Image":          imageURL,
+		"Inserted":       true,
+		"WriteProtected": true,
+	}
+
+	resp, err := c.makeRequest(ctx, "PATCH", target.OdataID, mountRequest, etagOrWildcard(target.ETag))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkXCCResponse(resp, "virtual media mount")
+}
+
+// newXCCRemoteMapMountRequest splits an image URL into the fields of the Oem/Lenovo mount action
+func newXCCRemoteMapMountRequest(imageURL string) (*xccRemoteMapMountRequest, error) {
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image URL: %w", err)
+	}
+
+	protocols := map[string]string{
+		"http":  "HTTP",
+		"https": "HTTP",
+		"nfs":   "NFS",
+		"smb":   "SAMBA",
+		"cifs":  "SAMBA",
+		"sftp":  "SFTP",
+		"ftp":   "FTP",
+	}
+	protocol, ok := protocols[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported image URL scheme for XCC remote mount: %q", u.Scheme)
+	}
+
+	mountRequest := &xccRemoteMapMountRequest{
+		FSIP:       u.Hostname(),
+		FSProtocol: protocol,
+		FSDir:      path.Dir(u.Path),
+		Image:      path.Base(u.Path),
+		ReadOnly:   true,
+	}
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		mountRequest.FSPort = port
+	}
+	if u.User != nil {
+		mountRequest.FSUsername = u.User.Username()
+		mountRequest.FSPassword, _ = u.User.Password()
+	}
+
+	return mountRequest, nil
+}
+
+// etagOrWildcard returns the ETag to send in If-Match, matching any version when none is known
+func etagOrWildcard(etag string) string {
+	if etag == "" {
+		return "*"
+	}
+	return etag
+}
+
+// UnmountVirtualMedia unmounts virtual media from all remote-mount slots
+func (c *XCCClient) UnmountVirtualMedia(ctx context.Context) error {
+	slots, err := c.getVirtualMediaSlots(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting virtual media info: %w", err)
+	}
+
+	for _, slot := range slots {
+		if !slot.Inserted || !slot.isRemoteMount() {
+			continue
+		}
+
+		var resp *http.Response
+		if slot.Actions.EjectMedia.Target != "" {
+			resp, err = c.makeRequest(ctx, "POST", slot.Actions.EjectMedia.Target, map[string]interface{}{}, "")
+		} else {
+			unmountRequest := map[string]interface{}{
+				"Image":    nil,
+				"Inserted": false,
+			}
+			resp, err = c.makeRequest(ctx, "PATCH", slot.OdataID, unmountRequest, etagOrWildcard(slot.ETag))
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue // Continue with other slots
+		}
+		resp.Body.Close()
+	}
+
+	if umountTarget := c.remoteMapTarget(ctx, "UMount"); umountTarget != "" {
+		resp, err := c.makeRequest(ctx, "POST", umountTarget, map[string]interface{}{}, "")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	return nil
+}
+
+// SetBootOverride sets the boot source override. The XCC requires the system ETag in If-Match
+// and only some firmware levels accept a Continuous override.
+func (c *XCCClient) SetBootOverride(ctx context.Context, target BootTarget, persistent bool) error {
+	var system struct {
+		ETag string `json:"@odata.etag"`
+		Boot struct {
+			AllowableEnabled []string `json:"BootSourceOverrideEnabled@Redfish.AllowableValues"`
+			AllowableTargets []string `json:"BootSourceOverrideTarget@Redfish.AllowableValues"`
+		} `json:"Boot"`
+	}
+	etag, err := c.getJSON(ctx, "/redfish/v1/Systems/1", &system)
+	if err != nil {
+		return err
+	}
+	if etag == "" {
+		etag = system.ETag
+	}
+
+	bootRequest := newBootOverrideRequest(target, persistent)
+	if !allowedValue(system.Boot.AllowableEnabled, bootRequest.Boot.BootSourceOverrideEnabled) {
+		return fmt.Errorf("XCC does not support %s boot override (allowed: %s)",
+			bootRequest.Boot.BootSourceOverrideEnabled, strings.Join(system.Boot.AllowableEnabled, ", "))
+	}
+	if !allowedValue(system.Boot.AllowableTargets, bootRequest.Boot.BootSourceOverrideTarget) {
+		return fmt.Errorf("XCC does not support boot target %s (allowed: %s)",
+			bootRequest.Boot.BootSourceOverrideTarget, strings.Join(system.Boot.AllowableTargets, ", "))
+	}
+
+	resp, err := c.makeRequest(ctx, "PATCH", "/redfish/v1/Systems/1", bootRequest, etagOrWildcard(etag))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkXCCResponse(resp, "boot override")
+}
+
+// allowedValue reports whether value is in the allowable values list; an empty list allows anything
+func allowedValue(allowable []string, value string) bool {
+	if len(allowable) == 0 {
+		return true
+	}
+	for _, v := range allowable {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// RedfishRequest sends a raw request to the XCC Redfish API
+func (c *XCCClient) RedfishRequest(ctx context.Context, method, endpoint string, body []byte, header http.Header) (*http.Response, error) {
+	return basicAuthRequest(ctx, c.httpClient, c.baseURL, c.username, c.password, method, endpoint, body, header)
+}
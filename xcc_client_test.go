@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestXCCClient_GetSystemInfo(t *testing.T) {
+	// Create test server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/redfish/v1/Systems/1" {
+			t.Errorf("Expected path '/redfish/v1/Systems/1', got: %s", r.URL.Path)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"PowerState":"Off","Status":{"Health":"OK","State":"StandbyOffline"}}`)); err != nil {
+			t.Errorf("Failed to write mock response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &XCCClient{
+		baseURL:    server.URL,
+		username:   "USERID",
+		password:   "PASSW0RD",
+		httpClient: server.Client(),
+	}
+
+	systemInfo, err := client.GetSystemInfo(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if systemInfo.PowerState != "Off" {
+		t.Errorf("Expected PowerState 'Off', got: %s", systemInfo.PowerState)
+	}
+}
+
+func TestXCCClient_MountVirtualMedia_SkipsRDOC(t *testing.T) {
+	var patched bool
+
+	// Create test server with an RDOC slot listed before the remote-mount slot
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/redfish/v1/Managers/1/VirtualMedia":
+			_, _ = w.Write([]byte(`{"Members":[{"@odata.id":"/redfish/v1/Managers/1/VirtualMedia/RDOC1"},{"@odata.id":"/redfish/v1/Managers/1/VirtualMedia/EXT1"}]}`))
+		case "/redfish/v1/Managers/1/VirtualMedia/RDOC1":
+			_, _ = w.Write([]byte(`{"Id":"RDOC1","Name":"RDOC1","MediaTypes":["CD","DVD"],"Inserted":false}`))
+		case "/redfish/v1/Managers/1/VirtualMedia/EXT1":
+			if r.Method == "GET" {
+				w.Header().Set("ETag", `W/"1234"`)
+				_, _ = w.Write([]byte(`{"Id":"EXT1","Name":"EXT1","MediaTypes":["CD","DVD"],"Inserted":false}`))
+				return
+			}
+			if r.Method != "PATCH" {
+				t.Errorf("Expected PATCH request, got: %s", r.Method)
+			}
+			if r.Header.Get("If-Match") != `W/"1234"` {
+				t.Errorf("Expected If-Match 'W/\"1234\"', got: %s", r.Header.Get("If-Match"))
+			}
+			var body map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("Error decoding request body: %v", err)
+			}
+			if body["Image"] != "http://example.com/image.iso" {
+				t.Errorf("Expected image URL 'http://example.com/image.iso', got: %v", body["Image"])
+			}
+			patched = true
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &XCCClient{
+		baseURL:    server.URL,
+		username:   "USERID",
+		password:   "PASSW0RD",
+		httpClient: server.Client(),
+	}
+
+	if err := client.MountVirtualMedia(context.Background(), "http://example.com/image.iso"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !patched {
+		t.Error("Expected EXT1 slot to be patched")
+	}
+}
+
+func TestXCCClient_MountVirtualMedia_RemoteMap(t *testing.T) {
+	var mountRequest xccRemoteMapMountRequest
+
+	// Create test server without InsertMedia but with the Oem/Lenovo RemoteMap service
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/redfish/v1/Managers/1/VirtualMedia":
+			_, _ = w.Write([]byte(`{"Members":[{"@odata.id":"/redfish/v1/Managers/1/VirtualMedia/EXT1"}]}`))
+		case "/redfish/v1/Managers/1/VirtualMedia/EXT1":
+			_, _ = w.Write([]byte(`{"Id":"EXT1","Name":"EXT1","MediaTypes":["CD","DVD"],"Inserted":false}`))
+		case "/redfish/v1/Managers/1/Oem/Lenovo/RemoteMap":
+			_, _ = w.Write([]byte(`{"Actions":{"#LenovoRemoteMapService.Mount":{"target":"/redfish/v1/Managers/1/Oem/Lenovo/RemoteMap/Actions/LenovoRemoteMapService.Mount"}}}`))
+		case "/redfish/v1/Managers/1/Oem/Lenovo/RemoteMap/Actions/LenovoRemoteMapService.Mount":
+			if err := json.NewDecoder(r.Body).Decode(&mountRequest); err != nil {
+				t.Errorf("Error decoding request body: %v", err)
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &XCCClient{
+		baseURL:    server.URL,
+		username:   "USERID",
+		password:   "PASSW0RD",
+		httpClient: server.Client(),
+	}
+
+	if err := client.MountVirtualMedia(context.Background(), "nfs://10.0.0.5/exports/isos/rhel.iso"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if mountRequest.FSIP != "10.0.0.5" || mountRequest.FSProtocol != "NFS" {
+		t.Errorf("Unexpected share in mount request: %+v", mountRequest)
+	}
+	if mountRequest.FSDir != "/exports/isos" || mountRequest.Image != "rhel.iso" {
+		t.Errorf("Unexpected image path in mount request: %+v", mountRequest)
+	}
+}
+
+func TestXCCClient_SetBootOverride(t *testing.T) {
+	var bootRequest BootOverrideRequest
+
+	// Create test server that requires If-Match and only allows one-time overrides
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/redfish/v1/Systems/1" {
+			t.Errorf("Expected path '/redfish/v1/Systems/1', got: %s", r.URL.Path)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case "GET":
+			_, _ = w.Write([]byte(`{"@odata.etag":"W/\"abcd\"","Boot":{"BootSourceOverrideEnabled@Redfish.AllowableValues":["Once","Disabled"],
+				"BootSourceOverrideTarget@Redfish.AllowableValues":["None","Pxe","Cd","Hdd","BiosSetup"]}}`))
+		case "PATCH":
+			if r.Header.Get("If-Match") != `W/"abcd"` {
+				w.WriteHeader(http.StatusPreconditionRequired)
+				return
+			}
+			if err := json.NewDecoder(r.Body).Decode(&bootRequest); err != nil {
+				t.Errorf("Error decoding request body: %v", err)
+			}
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := &XCCClient{
+		baseURL:    server.URL,
+		username:   "USERID",
+		password:   "PASSW0RD",
+		httpClient: server.Client(),
+	}
+
+	if err := client.SetBootOverride(context.Background(), BootTargetCD, false); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if bootRequest.Boot.BootSourceOverrideTarget != "Cd" || bootRequest.Boot.BootSourceOverrideEnabled != "Once" {
+		t.Errorf("Unexpected boot override request: %+v", bootRequest.Boot)
+	}
+
+	// Continuous overrides are not allowed by this firmware
+	if err := client.SetBootOverride(context.Background(), BootTargetPXE, true); err == nil {
+		t.Error("Expected error for unsupported Continuous override, got nil")
+	}
+}